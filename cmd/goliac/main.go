@@ -1,8 +1,11 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/Alayacare/goliac/internal"
 	"github.com/Alayacare/goliac/internal/config"
@@ -10,6 +13,14 @@ import (
 	"github.com/spf13/cobra"
 )
 
+/*
+ * signalContext returns a context cancelled on SIGINT/SIGTERM,
+ * to stop the Github calls in flight when the command is interrupted
+ */
+func signalContext() (context.Context, context.CancelFunc) {
+	return signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+}
+
 func main() {
 	verifyCmd := &cobra.Command{
 		Use:   "verify [path]",
@@ -55,7 +66,9 @@ https://github.com/...`,
 			if err != nil {
 				logrus.Fatalf("failed to create goliac: %s", err)
 			}
-			err = goliac.Apply(true, repo, branch, true)
+			ctx, stop := signalContext()
+			defer stop()
+			err = goliac.Apply(ctx, true, repo, branch, true)
 			if err != nil {
 				logrus.Errorf("Failed to plan: %v", err)
 			}
@@ -87,13 +100,58 @@ https://github.com/...`,
 			if err != nil {
 				logrus.Fatalf("failed to create goliac: %s", err)
 			}
-			err = goliac.Apply(false, repo, branch, true)
+			ctx, stop := signalContext()
+			defer stop()
+			err = goliac.Apply(ctx, false, repo, branch, true)
 			if err != nil {
 				logrus.Errorf("Failed to apply: %v", err)
 			}
 		},
 	}
 
+	driftCmd := &cobra.Command{
+		Use:   "drift [repository] [branch]",
+		Short: "Report the changes made on Github outside of Goliac",
+		Long: `Compute the difference between the IAC directory structure and a Github
+organization, without applying it, and report each change (and its author,
+if the Github audit log can tell).
+repository: local or remote repository. A remote repository is in the form
+https://github.com/...`,
+		Run: func(cmd *cobra.Command, args []string) {
+			repo := ""
+			branch := ""
+
+			if len(args) == 2 {
+				repo = args[0]
+				branch = args[1]
+			} else {
+				repo = config.Config.ServerGitRepository
+				branch = config.Config.ServerGitBranch
+			}
+			if repo == "" || branch == "" {
+				logrus.Fatalf("missing arguments")
+			}
+
+			goliac, err := internal.NewGoliacImpl()
+			if err != nil {
+				logrus.Fatalf("failed to create goliac: %s", err)
+			}
+			ctx, stop := signalContext()
+			defer stop()
+			drifts, err := goliac.Drift(ctx, repo, branch)
+			if err != nil {
+				logrus.Fatalf("failed to compute the drift: %v", err)
+			}
+			if len(drifts) == 0 {
+				fmt.Println("No drift found")
+				return
+			}
+			for _, d := range drifts {
+				fmt.Printf("[%s] %s %s: %s (by %s)\n", d.Policy, d.Resource, d.Name, d.Details, d.Author)
+			}
+		},
+	}
+
 	postSyncUsersCmd := &cobra.Command{
 		Use:   "syncusers [repository] [branch]",
 		Short: "Update and commit users and teams definition",
@@ -117,7 +175,9 @@ https://github.com/...`,
 			if err != nil {
 				logrus.Fatalf("failed to create goliac: %s", err)
 			}
-			err = goliac.UsersUpdate(repo, branch)
+			ctx, stop := signalContext()
+			defer stop()
+			err = goliac.UsersUpdate(ctx, repo, branch)
 			if err != nil {
 				logrus.Fatalf("failed to update and commit teams: %s", err)
 			}
@@ -140,7 +200,9 @@ The adminteam is your current team that contains Github administrator`,
 			if err != nil {
 				logrus.Fatalf("failed to create scaffold: %s", err)
 			}
-			err = scaffold.Generate(directory, adminteam)
+			ctx, stop := signalContext()
+			defer stop()
+			err = scaffold.Generate(ctx, directory, adminteam)
 			if err != nil {
 				logrus.Fatalf("failed to create scaffold direcrory: %s", err)
 			}
@@ -174,6 +236,7 @@ Either local directory, or remote git repository`,
 	rootCmd.AddCommand(verifyCmd)
 	rootCmd.AddCommand(planCmd)
 	rootCmd.AddCommand(applyCmd)
+	rootCmd.AddCommand(driftCmd)
 	rootCmd.AddCommand(postSyncUsersCmd)
 	rootCmd.AddCommand(scaffoldcmd)
 	rootCmd.AddCommand(servecmd)
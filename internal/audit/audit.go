@@ -0,0 +1,106 @@
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+/*
+ * Record is one operation applied by Goliac to Github
+ */
+type Record struct {
+	Timestamp  time.Time         `json:"timestamp"`
+	CommitSha  string            `json:"commitSha"`
+	Author     string            `json:"author"`
+	Operation  string            `json:"operation"` // like update_repository_add_team
+	Entities   []string          `json:"entities"`  // like repo/foo, team/bar, user/baz, ruleset/qux or org
+	Parameters map[string]string `json:"parameters"`
+	Success    bool              `json:"success"`
+	Error      string            `json:"error,omitempty"`
+}
+
+/*
+ * AuditStore is an append-only store of the operations applied by Goliac
+ */
+type AuditStore interface {
+	Append(record *Record) error
+	// Query returns the records of an entity (like repo/foo), the most recent first
+	Query(entity string) ([]*Record, error)
+}
+
+/*
+ * FileAuditStore stores the records in a local JSONL file (one JSON record per line)
+ */
+type FileAuditStore struct {
+	filename string
+	mu       sync.Mutex
+}
+
+func NewFileAuditStore(filename string) AuditStore {
+	return &FileAuditStore{
+		filename: filename,
+	}
+}
+
+func (s *FileAuditStore) Append(record *Record) error {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, err := os.OpenFile(s.filename, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0640)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = f.Write(append(line, '\n'))
+	return err
+}
+
+func (s *FileAuditStore) Query(entity string) ([]*Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	records := make([]*Record, 0)
+
+	f, err := os.Open(s.filename)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return records, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var record Record
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			// skip a corrupted (or partially written) line
+			continue
+		}
+		for _, e := range record.Entities {
+			if e == entity {
+				records = append(records, &record)
+				break
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(records, func(i, j int) bool {
+		return records[i].Timestamp.After(records[j].Timestamp)
+	})
+	return records, nil
+}
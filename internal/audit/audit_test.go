@@ -0,0 +1,70 @@
+package audit
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileAuditStore(t *testing.T) {
+	t.Run("happy path: append and query", func(t *testing.T) {
+		filename := filepath.Join(t.TempDir(), "audit.jsonl")
+		store := NewFileAuditStore(filename)
+
+		err := store.Append(&Record{
+			Timestamp:  time.Date(2026, 10, 19, 10, 0, 0, 0, time.UTC),
+			CommitSha:  "sha1",
+			Author:     "author1",
+			Operation:  "create_repository",
+			Entities:   []string{"repo/foo"},
+			Parameters: map[string]string{"public": "false"},
+			Success:    true,
+		})
+		assert.Nil(t, err)
+		err = store.Append(&Record{
+			Timestamp: time.Date(2026, 10, 19, 11, 0, 0, 0, time.UTC),
+			CommitSha: "sha2",
+			Author:    "author2",
+			Operation: "update_repository_add_team",
+			Entities:  []string{"repo/foo", "team/bar"},
+			Success:   true,
+		})
+		assert.Nil(t, err)
+
+		records, err := store.Query("repo/foo")
+		assert.Nil(t, err)
+		assert.Equal(t, 2, len(records))
+		// most recent first
+		assert.Equal(t, "sha2", records[0].CommitSha)
+		assert.Equal(t, "false", records[1].Parameters["public"])
+
+		records, err = store.Query("team/bar")
+		assert.Nil(t, err)
+		assert.Equal(t, 1, len(records))
+
+		records, err = store.Query("repo/unknown")
+		assert.Nil(t, err)
+		assert.Equal(t, 0, len(records))
+	})
+
+	t.Run("happy path: no file yet", func(t *testing.T) {
+		store := NewFileAuditStore(filepath.Join(t.TempDir(), "audit.jsonl"))
+		records, err := store.Query("repo/foo")
+		assert.Nil(t, err)
+		assert.Equal(t, 0, len(records))
+	})
+
+	t.Run("not happy path: corrupted line is skipped", func(t *testing.T) {
+		filename := filepath.Join(t.TempDir(), "audit.jsonl")
+		err := os.WriteFile(filename, []byte("{\"operation\":\"delete_team\",\"entities\":[\"team/bar\"]}\n{not json\n"), 0640)
+		assert.Nil(t, err)
+
+		store := NewFileAuditStore(filename)
+		records, err := store.Query("team/bar")
+		assert.Nil(t, err)
+		assert.Equal(t, 1, len(records))
+	})
+}
@@ -15,12 +15,43 @@ var Config = struct {
 	GithubAppPrivateKeyFile string `env:"GOLIAC_GITHUB_APP_PRIVATE_KEY_FILE" envDefault:"github-app-private-key.pem"`
 	GoliacEmail             string `env:"GOLIAC_EMAIL" envDefault:"goliac@alayacare.com"`
 
+	// the private key (PEM) itself, instead of GOLIAC_GITHUB_APP_PRIVATE_KEY_FILE
+	GithubAppPrivateKey string `env:"GOLIAC_GITHUB_APP_PRIVATE_KEY" envDefault:""`
+	// or fetched from a secret provider, in the form <provider>:<key> (like file:/vault/secrets/goliac.pem)
+	GithubAppPrivateKeySecret string `env:"GOLIAC_GITHUB_APP_PRIVATE_KEY_SECRET" envDefault:""`
+
+	// when GOLIAC_GITHUB_APP_ID is not set, a personal access token (or the GITHUB_TOKEN of a Github action) is used instead
+	GithubToken        string `env:"GOLIAC_GITHUB_TOKEN" envDefault:""`
+	GithubActionsToken string `env:"GITHUB_TOKEN" envDefault:""`
+
+	// bind credentials of the ldap usersync plugin (anonymous bind if empty)
+	LdapBindDN       string `env:"GOLIAC_LDAP_BIND_DN" envDefault:""`
+	LdapBindPassword string `env:"GOLIAC_LDAP_BIND_PASSWORD" envDefault:""`
+	// bearer token of the http usersync plugin
+	UserSyncHttpToken string `env:"GOLIAC_USERSYNC_HTTP_TOKEN" envDefault:""`
+	// bearer token of the scim usersync plugin
+	ScimToken string `env:"GOLIAC_SCIM_TOKEN" envDefault:""`
+
+	// SecretsDirectory is the base directory used by the `file` secret provider for relative paths
+	SecretsDirectory string `env:"GOLIAC_SECRETS_DIRECTORY" envDefault:""`
+	// SecretsHashesFile is where the hashes of the secrets pushed by Goliac are kept (in memory only if empty)
+	SecretsHashesFile string `env:"GOLIAC_SECRETS_HASHES_FILE" envDefault:"goliac-secrets-hashes.json"`
+
 	GithubConcurrentThreads int64 `env:"GOLIAC_GITHUB_CONCURRENT_THREADS" envDefault:"1"`
 	GithubCacheTTL          int64 `env:"GOLIAC_GITHUB_CACHE_TTL" envDefault:"86400"`
 
-	ServerApplyInterval int64  `env:"GOLIAC_SERVER_APPLY_INTERVAL" envDefault:"600"`
+	ServerApplyInterval int64 `env:"GOLIAC_SERVER_APPLY_INTERVAL" envDefault:"600"`
+	// maximum duration (in seconds) of a sync run, 0 means no timeout
+	ServerApplyTimeout  int64  `env:"GOLIAC_SERVER_APPLY_TIMEOUT" envDefault:"0"`
 	ServerGitRepository string `env:"GOLIAC_SERVER_GIT_REPOSITORY" envDefault:""`
 	ServerGitBranch     string `env:"GOLIAC_SERVER_GIT_BRANCH" envDefault:"main"`
+	// secret shared with the Github webhook (the /webhook endpoint is disabled if empty)
+	ServerGitWebhookSecret string `env:"GOLIAC_SERVER_GIT_WEBHOOK_SECRET" envDefault:""`
+	// bearer token expected on the inbound SCIM endpoint (the /scim/v2 endpoint is disabled if empty)
+	ServerScimToken string `env:"GOLIAC_SERVER_SCIM_TOKEN" envDefault:""`
+	// SCIM attributes used for the user name and the Github login of the users provisioned via the SCIM endpoint
+	ServerScimUsernameAttribute string `env:"GOLIAC_SERVER_SCIM_USERNAME_ATTRIBUTE" envDefault:"userName"`
+	ServerScimGithubIDAttribute string `env:"GOLIAC_SERVER_SCIM_GITHUBID_ATTRIBUTE" envDefault:"userName"`
 	// the name of the CI validating each PR on the teams repsotiry. See scaffold.go for the Github action
 	ServerGitBranchProtectionRequiredCheck string `env:"GOLIAC_SERVER_GIT_BRANCH_PROTECTION_REQUIRED_CHECK" envDefault:"validate"`
 
@@ -36,6 +67,12 @@ var Config = struct {
 	// MiddlewareGzipEnabled - to enable gzip middleware
 	MiddlewareGzipEnabled bool `env:"GOLIAC_MIDDLEWARE_GZIP_ENABLED" envDefault:"true"`
 
+	// MetricsEnabled - to expose the Prometheus metrics on /metrics
+	MetricsEnabled bool `env:"GOLIAC_SERVER_METRICS_ENABLED" envDefault:"true"`
+
+	// AuditFile - JSONL file where each applied change is recorded (the audit trail is disabled if empty)
+	AuditFile string `env:"GOLIAC_SERVER_AUDIT_FILE" envDefault:""`
+
 	// CORSEnabled - enable CORS
 	CORSEnabled          bool     `env:"GOLIAC__CORS_ENABLED" envDefault:"true"`
 	CORSAllowCredentials bool     `env:"GOLIAC__CORS_ALLOW_CREDENTIALS" envDefault:"true"`
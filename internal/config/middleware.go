@@ -3,6 +3,7 @@ package config
 import (
 	"net/http"
 
+	"github.com/Alayacare/goliac/internal/metrics"
 	negronilogrus "github.com/meatballhat/negroni-logrus"
 	"github.com/phyber/negroni-gzip/gzip"
 	"github.com/rs/cors"
@@ -43,6 +44,17 @@ func SetupGlobalMiddleware(handler http.Handler) http.Handler {
 		}))
 	}
 
+	if Config.MetricsEnabled {
+		metricsHandler := metrics.Handler()
+		n.Use(negroni.HandlerFunc(func(rw http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+			if r.URL.Path == Config.WebPrefix+"/metrics" && r.Method == http.MethodGet {
+				metricsHandler.ServeHTTP(rw, r)
+				return
+			}
+			next(rw, r)
+		}))
+	}
+
 	n.Use(&negroni.Static{
 		Dir:       http.Dir("./browser/goliac-ui/dist/"),
 		Prefix:    Config.WebPrefix,
@@ -8,22 +8,185 @@ type RepositoryConfig struct {
 	AdminTeam           string `yaml:"admin_team"`
 	EveryoneTeamEnabled bool   `yaml:"everyone_team_enabled"`
 
-	Rulesets []struct {
-		Pattern string
-		Ruleset string
-	}
+	Rulesets                []RulesetMapping
 	MaxChangesets           int `yaml:"max_changesets"`
 	GithubConcurrentThreads int `yaml:"github_concurrent_threads"`
 	UserSync                struct {
 		Plugin string `yaml:"plugin"`
-		Path   string `yaml:"path"`
+		Path   string `yaml:"path"` // shellscript and file plugins
+		// http and file plugins
+		Url     string              `yaml:"url"`
+		Format  string              `yaml:"format"` // json or csv (default: from the url/path extension, else json)
+		Mapping UserSyncListMapping `yaml:"mapping"`
+
+		Ldap UserSyncLdap `yaml:"ldap"`
+		Scim UserSyncScim `yaml:"scim"`
+
+		// open (or update) a pull request from the goliac/usersync branch instead of pushing the changes
+		PullRequest bool `yaml:"pull_request"`
+		// who adopts a team losing all its owners (and without manager), default: the admin team owners
+		FallbackOwners []string `yaml:"fallback_owners"`
 	}
+	// invite the new users by their email (instead of their Github login), if they have one
+	InviteByEmail bool `yaml:"invite_by_email"`
+
+	// Enterprise Managed Users: the organization members are provisioned by the IdP
+	Emu Emu `yaml:"emu"`
+
 	DestructiveOperations struct {
 		AllowDestructiveRepositories bool `yaml:"repositories"`
 		AllowDestructiveTeams        bool `yaml:"teams"`
 		AllowDestructiveUsers        bool `yaml:"users"`
 		AllowDestructiveRulesets     bool `yaml:"rulesets"`
+		AllowDestructiveActions      bool `yaml:"actions"`
+		AllowDestructiveProperties   bool `yaml:"custom_properties"`
 	} `yaml:"destructive_operations"`
+
+	// what to do when Github differs from this repository outside of a new commit
+	DriftPolicy DriftPolicy `yaml:"drift_policy"`
+
+	// organization level Github Actions variables and secrets
+	Actions struct {
+		Variables map[string]string `yaml:"variables"`
+		Secrets   []SecretReference `yaml:"secrets"`
+	} `yaml:"actions"`
+
+	// organization custom properties schema (repositories values are set in each repository file)
+	CustomProperties []CustomProperty `yaml:"custom_properties"`
+
+	// organization settings (unset values are not managed by Goliac)
+	Organization OrganizationSettings `yaml:"organization"`
+}
+
+/*
+ * UserSyncListMapping tells which fields (JSON keys or CSV columns) of
+ * a user list hold the goliac username and the Github login
+ */
+type UserSyncListMapping struct {
+	Username string `yaml:"username"` // default username
+	GithubID string `yaml:"githubID"` // default githubID
+}
+
+/*
+ * UserSyncLdap configures the ldap usersync plugin: each entry found under
+ * BaseDN matching Filter becomes a user named after UsernameAttribute, with
+ * the Github login found in GithubIDAttribute.
+ * The bind credentials come from the GOLIAC_LDAP_BIND_DN and
+ * GOLIAC_LDAP_BIND_PASSWORD environment variables
+ */
+type UserSyncLdap struct {
+	Url                string `yaml:"url"` // ldap://host:389 or ldaps://host:636
+	StartTLS           bool   `yaml:"start_tls"`
+	InsecureSkipVerify bool   `yaml:"insecure_skip_verify"`
+	BaseDN             string `yaml:"base_dn"`
+	Filter             string `yaml:"filter"`             // default (objectClass=person)
+	UsernameAttribute  string `yaml:"username_attribute"` // default uid (sAMAccountName on Active Directory)
+	GithubIDAttribute  string `yaml:"githubid_attribute"`
+	// groups (for the teams with spec.externallyManaged)
+	GroupBaseDN          string `yaml:"group_base_dn"`          // default base_dn
+	GroupFilter          string `yaml:"group_filter"`           // default (objectClass=groupOfNames)
+	GroupNameAttribute   string `yaml:"group_name_attribute"`   // default cn
+	GroupMemberAttribute string `yaml:"group_member_attribute"` // default member (the DN of each member)
+}
+
+/*
+ * UserSyncScim configures the scim usersync plugin: the (active) users of the
+ * SCIM service provider at Url (optionally only the members of the Group
+ * display name) become users named after UsernameAttribute, with the Github
+ * login found in GithubIDAttribute.
+ * An attribute can be a simple one (userName, nickName, emails, name.givenName)
+ * or an extension one (urn:ietf:params:scim:schemas:extension:enterprise:2.0:User:employeeNumber).
+ * The bearer token comes from the GOLIAC_SCIM_TOKEN environment variable
+ */
+type UserSyncScim struct {
+	Url               string `yaml:"url"` // like https://idp.mycompany.com/scim/v2
+	Group             string `yaml:"group"`
+	UsernameAttribute string `yaml:"username_attribute"` // default userName
+	GithubIDAttribute string `yaml:"githubid_attribute"`
+}
+
+/*
+ * Emu configures an Enterprise Managed Users organization: Goliac doesn't
+ * invite (or remove) members, and the user sync suffixes the Github logins
+ * with the enterprise Shortcode (alice -> alice_acme).
+ * With LinkExternalGroups, the teams with spec.externallyManaged are linked
+ * to their IdP group, and Github manages their members
+ */
+type Emu struct {
+	Enabled            bool   `yaml:"enabled"`
+	Shortcode          string `yaml:"shortcode"`
+	LinkExternalGroups bool   `yaml:"link_external_groups"`
+}
+
+/*
+ * OrganizationSettings are the Github organization settings managed by Goliac
+ */
+type OrganizationSettings struct {
+	DefaultRepositoryPermission       *string `yaml:"default_repository_permission"` // read, write, admin or none
+	MembersCanCreateRepositories      *bool   `yaml:"members_can_create_repositories"`
+	MembersCanForkPrivateRepositories *bool   `yaml:"members_can_fork_private_repositories"`
+	WebCommitSignoffRequired          *bool   `yaml:"web_commit_signoff_required"`
+	TwoFactorRequirement              *bool   `yaml:"two_factor_requirement"` // report only: it cannot be changed via the Github API
+}
+
+// IsEmpty returns true if no organization setting is managed
+func (s OrganizationSettings) IsEmpty() bool {
+	return s.DefaultRepositoryPermission == nil &&
+		s.MembersCanCreateRepositories == nil &&
+		s.MembersCanForkPrivateRepositories == nil &&
+		s.WebCommitSignoffRequired == nil &&
+		s.TwoFactorRequirement == nil
+}
+
+/*
+ * DriftPolicy chooses, per kind of resource, between
+ * enforce (fix the drift), report (only report it) or ignore
+ * Unset values fall back to Default (and then to enforce)
+ */
+type DriftPolicy struct {
+	Default          string `yaml:"default"`
+	Organization     string `yaml:"organization"`
+	Users            string `yaml:"users"`
+	Teams            string `yaml:"teams"`
+	Repositories     string `yaml:"repositories"`
+	Rulesets         string `yaml:"rulesets"`
+	Actions          string `yaml:"actions"`
+	CustomProperties string `yaml:"custom_properties"`
+}
+
+/*
+ * RulesetMapping attaches a ruleset to the repositories whose name
+ * matches Pattern and (optionally) whose custom properties
+ * match all the Properties values
+ */
+type RulesetMapping struct {
+	Pattern    string            `yaml:"pattern"`
+	Ruleset    string            `yaml:"ruleset"`
+	Properties map[string]string `yaml:"properties,omitempty"`
+}
+
+/*
+ * CustomProperty is an organization custom property definition
+ * value_type can be string, single_select, multi_select or true_false
+ */
+type CustomProperty struct {
+	Name          string   `yaml:"name"`
+	ValueType     string   `yaml:"value_type"`
+	Required      bool     `yaml:"required"`
+	DefaultValue  string   `yaml:"default_value"`
+	Description   string   `yaml:"description"`
+	AllowedValues []string `yaml:"allowed_values"`
+}
+
+/*
+ * SecretReference points to a secret value stored outside of the IAC repository.
+ * The value is fetched via the secret provider named `provider` (like `env` or `file`)
+ * using the `key` (an environment variable name, a filename, ...)
+ */
+type SecretReference struct {
+	Name     string `yaml:"name"`
+	Provider string `yaml:"provider"`
+	Key      string `yaml:"key"`
 }
 
 // set default values
@@ -34,6 +197,14 @@ func (rc *RepositoryConfig) UnmarshalYAML(value *yaml.Node) error {
 	x.MaxChangesets = 50
 	x.GithubConcurrentThreads = 4
 	x.UserSync.Plugin = "noop"
+	x.UserSync.Mapping.Username = "username"
+	x.UserSync.Mapping.GithubID = "githubID"
+	x.UserSync.Ldap.Filter = "(objectClass=person)"
+	x.UserSync.Ldap.UsernameAttribute = "uid"
+	x.UserSync.Ldap.GroupFilter = "(objectClass=groupOfNames)"
+	x.UserSync.Ldap.GroupNameAttribute = "cn"
+	x.UserSync.Ldap.GroupMemberAttribute = "member"
+	x.UserSync.Scim.UsernameAttribute = "userName"
 
 	if err := value.Decode(x); err != nil {
 		return err
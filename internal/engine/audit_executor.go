@@ -0,0 +1,277 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Alayacare/goliac/internal/audit"
+	"github.com/gosimple/slug"
+	"github.com/sirupsen/logrus"
+)
+
+/*
+ * AuditExecutor wraps the ReconciliatorExecutor that applies the changes to Github
+ * and records each applied operation (not the dryrun ones) in an audit store
+ */
+type AuditExecutor struct {
+	executor  ReconciliatorExecutor
+	store     audit.AuditStore
+	commitSha string
+	author    string
+}
+
+func NewAuditExecutor(executor ReconciliatorExecutor, store audit.AuditStore, commitSha string, author string) *AuditExecutor {
+	return &AuditExecutor{
+		executor:  executor,
+		store:     store,
+		commitSha: commitSha,
+		author:    author,
+	}
+}
+
+func (a *AuditExecutor) record(dryrun bool, operation string, entities []string, parameters map[string]string, apply func() error) error {
+	applyErr := apply()
+	if dryrun {
+		return applyErr
+	}
+	record := &audit.Record{
+		Timestamp:  time.Now(),
+		CommitSha:  a.commitSha,
+		Author:     a.author,
+		Operation:  operation,
+		Entities:   entities,
+		Parameters: parameters,
+		Success:    applyErr == nil,
+	}
+	if applyErr != nil {
+		record.Error = applyErr.Error()
+	}
+	if err := a.store.Append(record); err != nil {
+		logrus.Errorf("not able to record %s in the audit trail: %v", operation, err)
+	}
+	return applyErr
+}
+
+func (a *AuditExecutor) AddUserToOrg(ctx context.Context, dryrun bool, ghuserid string) error {
+	return a.record(dryrun, "add_user_to_org", []string{"user/" + ghuserid}, map[string]string{"githubid": ghuserid}, func() error {
+		return a.executor.AddUserToOrg(ctx, dryrun, ghuserid)
+	})
+}
+
+func (a *AuditExecutor) RemoveUserFromOrg(ctx context.Context, dryrun bool, ghuserid string) error {
+	return a.record(dryrun, "remove_user_from_org", []string{"user/" + ghuserid}, map[string]string{"githubid": ghuserid}, func() error {
+		return a.executor.RemoveUserFromOrg(ctx, dryrun, ghuserid)
+	})
+}
+
+func (a *AuditExecutor) InviteUserByEmail(ctx context.Context, dryrun bool, email string) error {
+	return a.record(dryrun, "invite_user_by_email", []string{"user/" + email}, map[string]string{"email": email}, func() error {
+		return a.executor.InviteUserByEmail(ctx, dryrun, email)
+	})
+}
+
+func (a *AuditExecutor) CancelOrgInvitation(ctx context.Context, dryrun bool, invitee string) error {
+	return a.record(dryrun, "cancel_org_invitation", []string{"user/" + invitee}, map[string]string{"invitee": invitee}, func() error {
+		return a.executor.CancelOrgInvitation(ctx, dryrun, invitee)
+	})
+}
+
+func (a *AuditExecutor) CreateTeam(ctx context.Context, dryrun bool, teamname string, description string, members []string) error {
+	return a.record(dryrun, "create_team", []string{"team/" + slug.Make(teamname)}, map[string]string{"teamname": teamname, "members": strings.Join(members, ",")}, func() error {
+		return a.executor.CreateTeam(ctx, dryrun, teamname, description, members)
+	})
+}
+
+func (a *AuditExecutor) UpdateTeamAddMember(ctx context.Context, dryrun bool, teamslug string, username string, role string) error {
+	return a.record(dryrun, "update_team_add_member", []string{"team/" + teamslug, "user/" + username}, map[string]string{"member": username, "role": role}, func() error {
+		return a.executor.UpdateTeamAddMember(ctx, dryrun, teamslug, username, role)
+	})
+}
+
+func (a *AuditExecutor) UpdateTeamRemoveMember(ctx context.Context, dryrun bool, teamslug string, username string) error {
+	return a.record(dryrun, "update_team_remove_member", []string{"team/" + teamslug, "user/" + username}, map[string]string{"member": username}, func() error {
+		return a.executor.UpdateTeamRemoveMember(ctx, dryrun, teamslug, username)
+	})
+}
+
+func (a *AuditExecutor) DeleteTeam(ctx context.Context, dryrun bool, teamslug string) error {
+	return a.record(dryrun, "delete_team", []string{"team/" + teamslug}, map[string]string{}, func() error {
+		return a.executor.DeleteTeam(ctx, dryrun, teamslug)
+	})
+}
+
+func (a *AuditExecutor) UpdateTeamSetExternalGroup(ctx context.Context, dryrun bool, teamslug string, groupname string) error {
+	return a.record(dryrun, "update_team_set_external_group", []string{"team/" + teamslug}, map[string]string{"group": groupname}, func() error {
+		return a.executor.UpdateTeamSetExternalGroup(ctx, dryrun, teamslug, groupname)
+	})
+}
+
+func (a *AuditExecutor) UpdateTeamRemoveExternalGroup(ctx context.Context, dryrun bool, teamslug string) error {
+	return a.record(dryrun, "update_team_remove_external_group", []string{"team/" + teamslug}, map[string]string{}, func() error {
+		return a.executor.UpdateTeamRemoveExternalGroup(ctx, dryrun, teamslug)
+	})
+}
+
+func (a *AuditExecutor) CreateRepository(ctx context.Context, dryrun bool, reponame string, descrition string, writers []string, readers []string, public bool) error {
+	return a.record(dryrun, "create_repository", []string{"repo/" + reponame}, map[string]string{"writers": strings.Join(writers, ","), "readers": strings.Join(readers, ","), "public": fmt.Sprintf("%v", public)}, func() error {
+		return a.executor.CreateRepository(ctx, dryrun, reponame, descrition, writers, readers, public)
+	})
+}
+
+func (a *AuditExecutor) UpdateRepositoryUpdateArchived(ctx context.Context, dryrun bool, reponame string, archived bool) error {
+	return a.record(dryrun, "update_repository_update_archived", []string{"repo/" + reponame}, map[string]string{"archived": fmt.Sprintf("%v", archived)}, func() error {
+		return a.executor.UpdateRepositoryUpdateArchived(ctx, dryrun, reponame, archived)
+	})
+}
+
+func (a *AuditExecutor) UpdateRepositoryUpdatePrivate(ctx context.Context, dryrun bool, reponame string, private bool) error {
+	return a.record(dryrun, "update_repository_update_private", []string{"repo/" + reponame}, map[string]string{"private": fmt.Sprintf("%v", private)}, func() error {
+		return a.executor.UpdateRepositoryUpdatePrivate(ctx, dryrun, reponame, private)
+	})
+}
+
+func (a *AuditExecutor) UpdateRepositoryAddTeamAccess(ctx context.Context, dryrun bool, reponame string, teamslug string, permission string) error {
+	return a.record(dryrun, "update_repository_add_team", []string{"repo/" + reponame, "team/" + teamslug}, map[string]string{"teamslug": teamslug, "permission": permission}, func() error {
+		return a.executor.UpdateRepositoryAddTeamAccess(ctx, dryrun, reponame, teamslug, permission)
+	})
+}
+
+func (a *AuditExecutor) UpdateRepositoryUpdateTeamAccess(ctx context.Context, dryrun bool, reponame string, teamslug string, permission string) error {
+	return a.record(dryrun, "update_repository_update_team", []string{"repo/" + reponame, "team/" + teamslug}, map[string]string{"teamslug": teamslug, "permission": permission}, func() error {
+		return a.executor.UpdateRepositoryUpdateTeamAccess(ctx, dryrun, reponame, teamslug, permission)
+	})
+}
+
+func (a *AuditExecutor) UpdateRepositoryRemoveTeamAccess(ctx context.Context, dryrun bool, reponame string, teamslug string) error {
+	return a.record(dryrun, "update_repository_remove_team", []string{"repo/" + reponame, "team/" + teamslug}, map[string]string{"teamslug": teamslug}, func() error {
+		return a.executor.UpdateRepositoryRemoveTeamAccess(ctx, dryrun, reponame, teamslug)
+	})
+}
+
+func (a *AuditExecutor) AddRuleset(ctx context.Context, dryrun bool, ruleset *GithubRuleSet) error {
+	return a.record(dryrun, "add_ruleset", []string{"ruleset/" + ruleset.Name}, map[string]string{"enforcement": ruleset.Enforcement, "repositories": strings.Join(ruleset.Repositories, ",")}, func() error {
+		return a.executor.AddRuleset(ctx, dryrun, ruleset)
+	})
+}
+
+func (a *AuditExecutor) UpdateRuleset(ctx context.Context, dryrun bool, ruleset *GithubRuleSet) error {
+	return a.record(dryrun, "update_ruleset", []string{"ruleset/" + ruleset.Name}, map[string]string{"enforcement": ruleset.Enforcement, "repositories": strings.Join(ruleset.Repositories, ",")}, func() error {
+		return a.executor.UpdateRuleset(ctx, dryrun, ruleset)
+	})
+}
+
+func (a *AuditExecutor) DeleteRuleset(ctx context.Context, dryrun bool, rulesetid int) error {
+	return a.record(dryrun, "delete_ruleset", []string{fmt.Sprintf("ruleset/%d", rulesetid)}, map[string]string{"id": fmt.Sprintf("%d", rulesetid)}, func() error {
+		return a.executor.DeleteRuleset(ctx, dryrun, rulesetid)
+	})
+}
+
+func (a *AuditExecutor) UpdateRepositorySetExternalUser(ctx context.Context, dryrun bool, reponame string, githubid string, permission string) error {
+	return a.record(dryrun, "update_repository_set_external_user", []string{"repo/" + reponame, "user/" + githubid}, map[string]string{"githubid": githubid, "permission": permission}, func() error {
+		return a.executor.UpdateRepositorySetExternalUser(ctx, dryrun, reponame, githubid, permission)
+	})
+}
+
+func (a *AuditExecutor) UpdateRepositoryRemoveExternalUser(ctx context.Context, dryrun bool, reponame string, githubid string) error {
+	return a.record(dryrun, "update_repository_remove_external_user", []string{"repo/" + reponame, "user/" + githubid}, map[string]string{"githubid": githubid}, func() error {
+		return a.executor.UpdateRepositoryRemoveExternalUser(ctx, dryrun, reponame, githubid)
+	})
+}
+
+func (a *AuditExecutor) DeleteRepository(ctx context.Context, dryrun bool, reponame string) error {
+	return a.record(dryrun, "delete_repository", []string{"repo/" + reponame}, map[string]string{}, func() error {
+		return a.executor.DeleteRepository(ctx, dryrun, reponame)
+	})
+}
+
+func (a *AuditExecutor) UpdateRepositorySetVariable(ctx context.Context, dryrun bool, reponame string, name string, value string) error {
+	return a.record(dryrun, "update_repository_set_variable", []string{"repo/" + reponame}, map[string]string{"name": name, "value": value}, func() error {
+		return a.executor.UpdateRepositorySetVariable(ctx, dryrun, reponame, name, value)
+	})
+}
+
+func (a *AuditExecutor) UpdateRepositoryRemoveVariable(ctx context.Context, dryrun bool, reponame string, name string) error {
+	return a.record(dryrun, "update_repository_remove_variable", []string{"repo/" + reponame}, map[string]string{"name": name}, func() error {
+		return a.executor.UpdateRepositoryRemoveVariable(ctx, dryrun, reponame, name)
+	})
+}
+
+// the secret value is never recorded
+func (a *AuditExecutor) UpdateRepositorySetSecret(ctx context.Context, dryrun bool, reponame string, name string, value string) error {
+	return a.record(dryrun, "update_repository_set_secret", []string{"repo/" + reponame}, map[string]string{"name": name}, func() error {
+		return a.executor.UpdateRepositorySetSecret(ctx, dryrun, reponame, name, value)
+	})
+}
+
+func (a *AuditExecutor) UpdateRepositoryRemoveSecret(ctx context.Context, dryrun bool, reponame string, name string) error {
+	return a.record(dryrun, "update_repository_remove_secret", []string{"repo/" + reponame}, map[string]string{"name": name}, func() error {
+		return a.executor.UpdateRepositoryRemoveSecret(ctx, dryrun, reponame, name)
+	})
+}
+
+func (a *AuditExecutor) SetOrgVariable(ctx context.Context, dryrun bool, name string, value string) error {
+	return a.record(dryrun, "set_org_variable", []string{"org"}, map[string]string{"name": name, "value": value}, func() error {
+		return a.executor.SetOrgVariable(ctx, dryrun, name, value)
+	})
+}
+
+func (a *AuditExecutor) RemoveOrgVariable(ctx context.Context, dryrun bool, name string) error {
+	return a.record(dryrun, "remove_org_variable", []string{"org"}, map[string]string{"name": name}, func() error {
+		return a.executor.RemoveOrgVariable(ctx, dryrun, name)
+	})
+}
+
+// the secret value is never recorded
+func (a *AuditExecutor) SetOrgSecret(ctx context.Context, dryrun bool, name string, value string) error {
+	return a.record(dryrun, "set_org_secret", []string{"org"}, map[string]string{"name": name}, func() error {
+		return a.executor.SetOrgSecret(ctx, dryrun, name, value)
+	})
+}
+
+func (a *AuditExecutor) RemoveOrgSecret(ctx context.Context, dryrun bool, name string) error {
+	return a.record(dryrun, "remove_org_secret", []string{"org"}, map[string]string{"name": name}, func() error {
+		return a.executor.RemoveOrgSecret(ctx, dryrun, name)
+	})
+}
+
+func (a *AuditExecutor) UpsertCustomProperty(ctx context.Context, dryrun bool, property *GithubCustomProperty) error {
+	return a.record(dryrun, "upsert_custom_property", []string{"org"}, map[string]string{"name": property.Name, "value_type": property.ValueType}, func() error {
+		return a.executor.UpsertCustomProperty(ctx, dryrun, property)
+	})
+}
+
+func (a *AuditExecutor) DeleteCustomProperty(ctx context.Context, dryrun bool, name string) error {
+	return a.record(dryrun, "delete_custom_property", []string{"org"}, map[string]string{"name": name}, func() error {
+		return a.executor.DeleteCustomProperty(ctx, dryrun, name)
+	})
+}
+
+func (a *AuditExecutor) UpdateRepositorySetCustomProperty(ctx context.Context, dryrun bool, reponame string, name string, value []string) error {
+	return a.record(dryrun, "update_repository_set_custom_property", []string{"repo/" + reponame}, map[string]string{"name": name, "value": strings.Join(value, ",")}, func() error {
+		return a.executor.UpdateRepositorySetCustomProperty(ctx, dryrun, reponame, name, value)
+	})
+}
+
+func (a *AuditExecutor) UpdateOrganizationSettings(ctx context.Context, dryrun bool, settings *GithubOrganizationSettingsUpdate) error {
+	parameters := map[string]string{}
+	for name, value := range settings.Payload() {
+		parameters[name] = fmt.Sprintf("%v", value)
+	}
+	return a.record(dryrun, "update_organization_settings", []string{"org"}, parameters, func() error {
+		return a.executor.UpdateOrganizationSettings(ctx, dryrun, settings)
+	})
+}
+
+func (a *AuditExecutor) Begin(ctx context.Context, dryrun bool) {
+	a.executor.Begin(ctx, dryrun)
+}
+
+func (a *AuditExecutor) Rollback(ctx context.Context, dryrun bool, err error) {
+	a.executor.Rollback(ctx, dryrun, err)
+}
+
+func (a *AuditExecutor) Commit(ctx context.Context, dryrun bool) error {
+	return a.executor.Commit(ctx, dryrun)
+}
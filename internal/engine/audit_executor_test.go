@@ -0,0 +1,122 @@
+package engine
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Alayacare/goliac/internal/audit"
+	"github.com/Alayacare/goliac/internal/config"
+	"github.com/Alayacare/goliac/internal/entity"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAuditExecutor(t *testing.T) {
+	newLocal := func() *GoliacLocalMock {
+		local := GoliacLocalMock{
+			users: make(map[string]*entity.User),
+			teams: make(map[string]*entity.Team),
+			repos: make(map[string]*entity.Repository),
+		}
+		newTeam := &entity.Team{}
+		newTeam.Name = "new team"
+		newTeam.Spec.Owners = []string{"new.owner"}
+		local.teams["new team"] = newTeam
+
+		newOwner := entity.User{}
+		newOwner.Name = "new.owner"
+		newOwner.Spec.GithubID = "new_owner"
+		local.users["new.owner"] = &newOwner
+
+		newRepo := &entity.Repository{}
+		newRepo.Name = "myrepo"
+		owner := "new team"
+		newRepo.Owner = &owner
+		local.repos["myrepo"] = newRepo
+		return &local
+	}
+	newRemote := func() *GoliacRemoteMock {
+		remote := newGoliacRemoteMock()
+		remote.users["new_owner"] = "MEMBER"
+		return remote
+	}
+
+	t.Run("happy path: applied changes are recorded", func(t *testing.T) {
+		recorder := NewReconciliatorListenerRecorder()
+		store := audit.NewFileAuditStore(filepath.Join(t.TempDir(), "audit.jsonl"))
+		executor := NewAuditExecutor(recorder, store, "abcdef", "John Doe <john@doe.com>")
+		r := NewGoliacReconciliatorImpl(executor, &config.RepositoryConfig{})
+
+		err := r.Reconciliate(context.TODO(), newLocal(), newRemote(), "teams", false)
+		assert.Nil(t, err)
+
+		// the change is still applied
+		assert.True(t, recorder.RepositoryCreated["myrepo"])
+
+		records, err := store.Query("team/new-team")
+		assert.Nil(t, err)
+		assert.Equal(t, 1, len(records))
+		assert.Equal(t, "create_team", records[0].Operation)
+		assert.Equal(t, "abcdef", records[0].CommitSha)
+		assert.Equal(t, "John Doe <john@doe.com>", records[0].Author)
+		assert.True(t, records[0].Success)
+
+		records, err = store.Query("repo/myrepo")
+		assert.Nil(t, err)
+		assert.Equal(t, 1, len(records))
+		assert.Equal(t, "create_repository", records[0].Operation)
+	})
+
+	t.Run("happy path: dryrun changes are not recorded", func(t *testing.T) {
+		recorder := NewReconciliatorListenerRecorder()
+		store := audit.NewFileAuditStore(filepath.Join(t.TempDir(), "audit.jsonl"))
+		executor := NewAuditExecutor(recorder, store, "abcdef", "John Doe <john@doe.com>")
+		r := NewGoliacReconciliatorImpl(executor, &config.RepositoryConfig{})
+
+		err := r.Reconciliate(context.TODO(), newLocal(), newRemote(), "teams", true)
+		assert.Nil(t, err)
+
+		records, err := store.Query("repo/myrepo")
+		assert.Nil(t, err)
+		assert.Equal(t, 0, len(records))
+	})
+
+	t.Run("not happy path: failed changes are recorded as failed", func(t *testing.T) {
+		executor := &FailingReconciliatorExecutor{
+			ReconciliatorListenerRecorder: NewReconciliatorListenerRecorder(),
+			failingTeams:                  map[string]bool{"new team": true},
+		}
+		store := audit.NewFileAuditStore(filepath.Join(t.TempDir(), "audit.jsonl"))
+		auditExecutor := NewAuditExecutor(executor, store, "abcdef", "John Doe <john@doe.com>")
+
+		err := auditExecutor.CreateTeam(context.TODO(), false, "new team", "new team", []string{})
+		assert.NotNil(t, err)
+
+		records, err := store.Query("team/new-team")
+		assert.Nil(t, err)
+		assert.Equal(t, 1, len(records))
+		assert.False(t, records[0].Success)
+		assert.Equal(t, "failed to create team new team", records[0].Error)
+	})
+
+	t.Run("happy path: secret values are not recorded", func(t *testing.T) {
+		recorder := NewReconciliatorListenerRecorder()
+		filename := filepath.Join(t.TempDir(), "audit.jsonl")
+		store := audit.NewFileAuditStore(filename)
+		executor := NewAuditExecutor(recorder, store, "abcdef", "John Doe <john@doe.com>")
+
+		executor.UpdateRepositorySetSecret(context.TODO(), false, "myrepo", "TOKEN", "supersecretvalue")
+		executor.SetOrgSecret(context.TODO(), false, "ORGTOKEN", "supersecretvalue")
+
+		records, err := store.Query("repo/myrepo")
+		assert.Nil(t, err)
+		assert.Equal(t, 1, len(records))
+		assert.Equal(t, "TOKEN", records[0].Parameters["name"])
+
+		content, err := os.ReadFile(filename)
+		assert.Nil(t, err)
+		assert.False(t, strings.Contains(string(content), "supersecretvalue"))
+	})
+}
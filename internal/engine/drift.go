@@ -0,0 +1,343 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Alayacare/goliac/internal/config"
+)
+
+const (
+	DriftPolicyEnforce = "enforce"
+	DriftPolicyReport  = "report"
+	DriftPolicyIgnore  = "ignore"
+
+	DriftResourceOrganization     = "organization"
+	DriftResourceUsers            = "users"
+	DriftResourceTeams            = "teams"
+	DriftResourceRepositories     = "repositories"
+	DriftResourceRulesets         = "rulesets"
+	DriftResourceActions          = "actions"
+	DriftResourceCustomProperties = "custom_properties"
+)
+
+/*
+ * Drift is a difference between the teams repository and Github
+ * that was not introduced by a new commit (i.e. a change made outside of Goliac)
+ */
+type Drift struct {
+	Resource   string    `json:"resource"` // organization, users, teams, repositories, rulesets, actions or custom_properties
+	Name       string    `json:"name"`     // the resource name (github id, team slug, repository name, ...)
+	Command    string    `json:"command"`  // the command Goliac would run to fix it
+	Details    string    `json:"details"`
+	Policy     string    `json:"policy"` // enforce or report
+	Author     string    `json:"author"` // who made the change (if known)
+	DetectedAt time.Time `json:"detectedAt"`
+}
+
+/*
+ * GetDriftPolicy returns the drift policy of a kind of resource
+ */
+func GetDriftPolicy(policy config.DriftPolicy, resource string) string {
+	value := ""
+	switch resource {
+	case DriftResourceOrganization:
+		value = policy.Organization
+	case DriftResourceUsers:
+		value = policy.Users
+	case DriftResourceTeams:
+		value = policy.Teams
+	case DriftResourceRepositories:
+		value = policy.Repositories
+	case DriftResourceRulesets:
+		value = policy.Rulesets
+	case DriftResourceActions:
+		value = policy.Actions
+	case DriftResourceCustomProperties:
+		value = policy.CustomProperties
+	}
+	if value == "" {
+		value = policy.Default
+	}
+	if value == "" {
+		value = DriftPolicyEnforce
+	}
+	return value
+}
+
+/*
+ * DriftExecutor is a ReconciliatorExecutor used when there is no new commit to apply:
+ * every change is then a drift, that is recorded, and forwarded to the
+ * underlying executor only if the drift policy of the resource is 'enforce'.
+ * Scheduled changes (see KeyScheduled) are not drifts and are always forwarded
+ */
+type DriftExecutor struct {
+	executor ReconciliatorExecutor
+	policy   config.DriftPolicy
+	drifts   []*Drift
+}
+
+func NewDriftExecutor(executor ReconciliatorExecutor, policy config.DriftPolicy) *DriftExecutor {
+	return &DriftExecutor{
+		executor: executor,
+		policy:   policy,
+		drifts:   make([]*Drift, 0),
+	}
+}
+
+func (d *DriftExecutor) Drifts() []*Drift {
+	return d.drifts
+}
+
+func (d *DriftExecutor) drift(ctx context.Context, resource string, name string, command string, details string, apply func(executor ReconciliatorExecutor) error) error {
+	if scheduled, ok := ctx.Value(KeyScheduled).(bool); ok && scheduled {
+		// expired accesses and time-boxed grants are not drifts: they always run
+		if d.executor != nil {
+			return apply(d.executor)
+		}
+		return nil
+	}
+	policy := GetDriftPolicy(d.policy, resource)
+	if policy == DriftPolicyIgnore {
+		return nil
+	}
+	d.drifts = append(d.drifts, &Drift{
+		Resource:   resource,
+		Name:       name,
+		Command:    command,
+		Details:    details,
+		Policy:     policy,
+		Author:     "unknown",
+		DetectedAt: time.Now(),
+	})
+	if policy == DriftPolicyEnforce && d.executor != nil {
+		return apply(d.executor)
+	}
+	return nil
+}
+
+func (d *DriftExecutor) AddUserToOrg(ctx context.Context, dryrun bool, ghuserid string) error {
+	return d.drift(ctx, DriftResourceUsers, ghuserid, "add_user_to_org", "user is not member of the organization", func(e ReconciliatorExecutor) error {
+		return e.AddUserToOrg(ctx, dryrun, ghuserid)
+	})
+}
+
+func (d *DriftExecutor) RemoveUserFromOrg(ctx context.Context, dryrun bool, ghuserid string) error {
+	return d.drift(ctx, DriftResourceUsers, ghuserid, "remove_user_from_org", "user is member of the organization", func(e ReconciliatorExecutor) error {
+		return e.RemoveUserFromOrg(ctx, dryrun, ghuserid)
+	})
+}
+
+func (d *DriftExecutor) InviteUserByEmail(ctx context.Context, dryrun bool, email string) error {
+	return d.drift(ctx, DriftResourceUsers, email, "invite_user_by_email", "user is not member of the organization", func(e ReconciliatorExecutor) error {
+		return e.InviteUserByEmail(ctx, dryrun, email)
+	})
+}
+
+func (d *DriftExecutor) CancelOrgInvitation(ctx context.Context, dryrun bool, invitee string) error {
+	return d.drift(ctx, DriftResourceUsers, invitee, "cancel_org_invitation", "user is invited to the organization", func(e ReconciliatorExecutor) error {
+		return e.CancelOrgInvitation(ctx, dryrun, invitee)
+	})
+}
+
+func (d *DriftExecutor) CreateTeam(ctx context.Context, dryrun bool, teamname string, description string, members []string) error {
+	return d.drift(ctx, DriftResourceTeams, teamname, "create_team", "team is missing", func(e ReconciliatorExecutor) error {
+		return e.CreateTeam(ctx, dryrun, teamname, description, members)
+	})
+}
+
+func (d *DriftExecutor) UpdateTeamAddMember(ctx context.Context, dryrun bool, teamslug string, username string, role string) error {
+	return d.drift(ctx, DriftResourceTeams, teamslug, "update_team_add_member", fmt.Sprintf("member %s is missing", username), func(e ReconciliatorExecutor) error {
+		return e.UpdateTeamAddMember(ctx, dryrun, teamslug, username, role)
+	})
+}
+
+func (d *DriftExecutor) UpdateTeamRemoveMember(ctx context.Context, dryrun bool, teamslug string, username string) error {
+	return d.drift(ctx, DriftResourceTeams, teamslug, "update_team_remove_member", fmt.Sprintf("member %s was added", username), func(e ReconciliatorExecutor) error {
+		return e.UpdateTeamRemoveMember(ctx, dryrun, teamslug, username)
+	})
+}
+
+func (d *DriftExecutor) DeleteTeam(ctx context.Context, dryrun bool, teamslug string) error {
+	return d.drift(ctx, DriftResourceTeams, teamslug, "delete_team", "team was created", func(e ReconciliatorExecutor) error {
+		return e.DeleteTeam(ctx, dryrun, teamslug)
+	})
+}
+
+func (d *DriftExecutor) UpdateTeamSetExternalGroup(ctx context.Context, dryrun bool, teamslug string, groupname string) error {
+	return d.drift(ctx, DriftResourceTeams, teamslug, "update_team_set_external_group", fmt.Sprintf("team is not linked to external group %s", groupname), func(e ReconciliatorExecutor) error {
+		return e.UpdateTeamSetExternalGroup(ctx, dryrun, teamslug, groupname)
+	})
+}
+
+func (d *DriftExecutor) UpdateTeamRemoveExternalGroup(ctx context.Context, dryrun bool, teamslug string) error {
+	return d.drift(ctx, DriftResourceTeams, teamslug, "update_team_remove_external_group", "team was linked to an external group", func(e ReconciliatorExecutor) error {
+		return e.UpdateTeamRemoveExternalGroup(ctx, dryrun, teamslug)
+	})
+}
+
+func (d *DriftExecutor) CreateRepository(ctx context.Context, dryrun bool, reponame string, descrition string, writers []string, readers []string, public bool) error {
+	return d.drift(ctx, DriftResourceRepositories, reponame, "create_repository", "repository is missing", func(e ReconciliatorExecutor) error {
+		return e.CreateRepository(ctx, dryrun, reponame, descrition, writers, readers, public)
+	})
+}
+
+func (d *DriftExecutor) UpdateRepositoryUpdateArchived(ctx context.Context, dryrun bool, reponame string, archived bool) error {
+	return d.drift(ctx, DriftResourceRepositories, reponame, "update_repository_update_archived", fmt.Sprintf("archived should be %v", archived), func(e ReconciliatorExecutor) error {
+		return e.UpdateRepositoryUpdateArchived(ctx, dryrun, reponame, archived)
+	})
+}
+
+func (d *DriftExecutor) UpdateRepositoryUpdatePrivate(ctx context.Context, dryrun bool, reponame string, private bool) error {
+	return d.drift(ctx, DriftResourceRepositories, reponame, "update_repository_update_private", fmt.Sprintf("private should be %v", private), func(e ReconciliatorExecutor) error {
+		return e.UpdateRepositoryUpdatePrivate(ctx, dryrun, reponame, private)
+	})
+}
+
+func (d *DriftExecutor) UpdateRepositoryAddTeamAccess(ctx context.Context, dryrun bool, reponame string, teamslug string, permission string) error {
+	return d.drift(ctx, DriftResourceRepositories, reponame, "update_repository_add_team", fmt.Sprintf("team %s access (%s) is missing", teamslug, permission), func(e ReconciliatorExecutor) error {
+		return e.UpdateRepositoryAddTeamAccess(ctx, dryrun, reponame, teamslug, permission)
+	})
+}
+
+func (d *DriftExecutor) UpdateRepositoryUpdateTeamAccess(ctx context.Context, dryrun bool, reponame string, teamslug string, permission string) error {
+	return d.drift(ctx, DriftResourceRepositories, reponame, "update_repository_update_team", fmt.Sprintf("team %s access should be %s", teamslug, permission), func(e ReconciliatorExecutor) error {
+		return e.UpdateRepositoryUpdateTeamAccess(ctx, dryrun, reponame, teamslug, permission)
+	})
+}
+
+func (d *DriftExecutor) UpdateRepositoryRemoveTeamAccess(ctx context.Context, dryrun bool, reponame string, teamslug string) error {
+	return d.drift(ctx, DriftResourceRepositories, reponame, "update_repository_remove_team", fmt.Sprintf("team %s was given access", teamslug), func(e ReconciliatorExecutor) error {
+		return e.UpdateRepositoryRemoveTeamAccess(ctx, dryrun, reponame, teamslug)
+	})
+}
+
+func (d *DriftExecutor) AddRuleset(ctx context.Context, dryrun bool, ruleset *GithubRuleSet) error {
+	return d.drift(ctx, DriftResourceRulesets, ruleset.Name, "add_ruleset", "ruleset is missing", func(e ReconciliatorExecutor) error {
+		return e.AddRuleset(ctx, dryrun, ruleset)
+	})
+}
+
+func (d *DriftExecutor) UpdateRuleset(ctx context.Context, dryrun bool, ruleset *GithubRuleSet) error {
+	return d.drift(ctx, DriftResourceRulesets, ruleset.Name, "update_ruleset", "ruleset was changed", func(e ReconciliatorExecutor) error {
+		return e.UpdateRuleset(ctx, dryrun, ruleset)
+	})
+}
+
+func (d *DriftExecutor) DeleteRuleset(ctx context.Context, dryrun bool, rulesetid int) error {
+	return d.drift(ctx, DriftResourceRulesets, fmt.Sprintf("%d", rulesetid), "delete_ruleset", "ruleset was created", func(e ReconciliatorExecutor) error {
+		return e.DeleteRuleset(ctx, dryrun, rulesetid)
+	})
+}
+
+func (d *DriftExecutor) UpdateRepositorySetExternalUser(ctx context.Context, dryrun bool, reponame string, githubid string, permission string) error {
+	return d.drift(ctx, DriftResourceRepositories, reponame, "update_repository_set_external_user", fmt.Sprintf("collaborator %s access should be %s", githubid, permission), func(e ReconciliatorExecutor) error {
+		return e.UpdateRepositorySetExternalUser(ctx, dryrun, reponame, githubid, permission)
+	})
+}
+
+func (d *DriftExecutor) UpdateRepositoryRemoveExternalUser(ctx context.Context, dryrun bool, reponame string, githubid string) error {
+	return d.drift(ctx, DriftResourceRepositories, reponame, "update_repository_remove_external_user", fmt.Sprintf("collaborator %s was given access", githubid), func(e ReconciliatorExecutor) error {
+		return e.UpdateRepositoryRemoveExternalUser(ctx, dryrun, reponame, githubid)
+	})
+}
+
+func (d *DriftExecutor) DeleteRepository(ctx context.Context, dryrun bool, reponame string) error {
+	return d.drift(ctx, DriftResourceRepositories, reponame, "delete_repository", "repository was created", func(e ReconciliatorExecutor) error {
+		return e.DeleteRepository(ctx, dryrun, reponame)
+	})
+}
+
+func (d *DriftExecutor) UpdateRepositorySetVariable(ctx context.Context, dryrun bool, reponame string, name string, value string) error {
+	return d.drift(ctx, DriftResourceActions, reponame, "update_repository_set_variable", fmt.Sprintf("variable %s was changed", name), func(e ReconciliatorExecutor) error {
+		return e.UpdateRepositorySetVariable(ctx, dryrun, reponame, name, value)
+	})
+}
+
+func (d *DriftExecutor) UpdateRepositoryRemoveVariable(ctx context.Context, dryrun bool, reponame string, name string) error {
+	return d.drift(ctx, DriftResourceActions, reponame, "update_repository_remove_variable", fmt.Sprintf("variable %s was created", name), func(e ReconciliatorExecutor) error {
+		return e.UpdateRepositoryRemoveVariable(ctx, dryrun, reponame, name)
+	})
+}
+
+func (d *DriftExecutor) UpdateRepositorySetSecret(ctx context.Context, dryrun bool, reponame string, name string, value string) error {
+	return d.drift(ctx, DriftResourceActions, reponame, "update_repository_set_secret", fmt.Sprintf("secret %s was changed", name), func(e ReconciliatorExecutor) error {
+		return e.UpdateRepositorySetSecret(ctx, dryrun, reponame, name, value)
+	})
+}
+
+func (d *DriftExecutor) UpdateRepositoryRemoveSecret(ctx context.Context, dryrun bool, reponame string, name string) error {
+	return d.drift(ctx, DriftResourceActions, reponame, "update_repository_remove_secret", fmt.Sprintf("secret %s was created", name), func(e ReconciliatorExecutor) error {
+		return e.UpdateRepositoryRemoveSecret(ctx, dryrun, reponame, name)
+	})
+}
+
+func (d *DriftExecutor) SetOrgVariable(ctx context.Context, dryrun bool, name string, value string) error {
+	return d.drift(ctx, DriftResourceActions, name, "set_org_variable", "organization variable was changed", func(e ReconciliatorExecutor) error {
+		return e.SetOrgVariable(ctx, dryrun, name, value)
+	})
+}
+
+func (d *DriftExecutor) RemoveOrgVariable(ctx context.Context, dryrun bool, name string) error {
+	return d.drift(ctx, DriftResourceActions, name, "remove_org_variable", "organization variable was created", func(e ReconciliatorExecutor) error {
+		return e.RemoveOrgVariable(ctx, dryrun, name)
+	})
+}
+
+func (d *DriftExecutor) SetOrgSecret(ctx context.Context, dryrun bool, name string, value string) error {
+	return d.drift(ctx, DriftResourceActions, name, "set_org_secret", "organization secret was changed", func(e ReconciliatorExecutor) error {
+		return e.SetOrgSecret(ctx, dryrun, name, value)
+	})
+}
+
+func (d *DriftExecutor) RemoveOrgSecret(ctx context.Context, dryrun bool, name string) error {
+	return d.drift(ctx, DriftResourceActions, name, "remove_org_secret", "organization secret was created", func(e ReconciliatorExecutor) error {
+		return e.RemoveOrgSecret(ctx, dryrun, name)
+	})
+}
+
+func (d *DriftExecutor) UpsertCustomProperty(ctx context.Context, dryrun bool, property *GithubCustomProperty) error {
+	return d.drift(ctx, DriftResourceCustomProperties, property.Name, "upsert_custom_property", "custom property definition was changed", func(e ReconciliatorExecutor) error {
+		return e.UpsertCustomProperty(ctx, dryrun, property)
+	})
+}
+
+func (d *DriftExecutor) DeleteCustomProperty(ctx context.Context, dryrun bool, name string) error {
+	return d.drift(ctx, DriftResourceCustomProperties, name, "delete_custom_property", "custom property was created", func(e ReconciliatorExecutor) error {
+		return e.DeleteCustomProperty(ctx, dryrun, name)
+	})
+}
+
+func (d *DriftExecutor) UpdateRepositorySetCustomProperty(ctx context.Context, dryrun bool, reponame string, name string, value []string) error {
+	return d.drift(ctx, DriftResourceCustomProperties, reponame, "update_repository_set_custom_property", fmt.Sprintf("custom property %s should be [%s]", name, strings.Join(value, ",")), func(e ReconciliatorExecutor) error {
+		return e.UpdateRepositorySetCustomProperty(ctx, dryrun, reponame, name, value)
+	})
+}
+
+func (d *DriftExecutor) UpdateOrganizationSettings(ctx context.Context, dryrun bool, settings *GithubOrganizationSettingsUpdate) error {
+	return d.drift(ctx, DriftResourceOrganization, "settings", "update_organization_settings", "organization settings were changed", func(e ReconciliatorExecutor) error {
+		return e.UpdateOrganizationSettings(ctx, dryrun, settings)
+	})
+}
+
+func (d *DriftExecutor) Begin(ctx context.Context, dryrun bool) {
+	d.drifts = make([]*Drift, 0)
+	if d.executor != nil {
+		d.executor.Begin(ctx, dryrun)
+	}
+}
+
+func (d *DriftExecutor) Rollback(ctx context.Context, dryrun bool, err error) {
+	if d.executor != nil {
+		d.executor.Rollback(ctx, dryrun, err)
+	}
+}
+
+func (d *DriftExecutor) Commit(ctx context.Context, dryrun bool) error {
+	if d.executor != nil {
+		return d.executor.Commit(ctx, dryrun)
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+package engine
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Alayacare/goliac/internal/config"
+	"github.com/Alayacare/goliac/internal/entity"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDriftExecutor(t *testing.T) {
+	newLocal := func() *GoliacLocalMock {
+		local := GoliacLocalMock{
+			users: make(map[string]*entity.User),
+			teams: make(map[string]*entity.Team),
+			repos: make(map[string]*entity.Repository),
+		}
+		newTeam := &entity.Team{}
+		newTeam.Name = "new"
+		newTeam.Spec.Owners = []string{"new.owner"}
+		local.teams["new"] = newTeam
+
+		newOwner := entity.User{}
+		newOwner.Name = "new.owner"
+		newOwner.Spec.GithubID = "new_owner"
+		local.users["new.owner"] = &newOwner
+
+		newRepo := &entity.Repository{}
+		newRepo.Name = "myrepo"
+		owner := "new"
+		newRepo.Owner = &owner
+		local.repos["myrepo"] = newRepo
+		return &local
+	}
+	newRemote := func() *GoliacRemoteMock {
+		remote := newGoliacRemoteMock()
+		remote.users["new_owner"] = "MEMBER"
+		return remote
+	}
+
+	t.Run("happy path: drift is enforced by default", func(t *testing.T) {
+		recorder := NewReconciliatorListenerRecorder()
+		repoconf := config.RepositoryConfig{}
+		executor := NewDriftExecutor(recorder, repoconf.DriftPolicy)
+		r := NewGoliacReconciliatorImpl(executor, &repoconf)
+
+		err := r.Reconciliate(context.TODO(), newLocal(), newRemote(), "teams", false)
+		assert.Nil(t, err)
+
+		assert.Equal(t, 1, len(recorder.TeamsCreated["new"]))
+		assert.True(t, recorder.RepositoryCreated["myrepo"])
+
+		resources := map[string]string{}
+		for _, d := range executor.Drifts() {
+			resources[d.Resource+"/"+d.Name] = d.Policy
+		}
+		assert.Equal(t, DriftPolicyEnforce, resources["teams/new"])
+		assert.Equal(t, DriftPolicyEnforce, resources["repositories/myrepo"])
+	})
+
+	t.Run("happy path: drift is only reported or ignored", func(t *testing.T) {
+		recorder := NewReconciliatorListenerRecorder()
+		repoconf := config.RepositoryConfig{}
+		repoconf.DriftPolicy.Default = DriftPolicyReport
+		repoconf.DriftPolicy.Teams = DriftPolicyIgnore
+		executor := NewDriftExecutor(recorder, repoconf.DriftPolicy)
+		r := NewGoliacReconciliatorImpl(executor, &repoconf)
+
+		err := r.Reconciliate(context.TODO(), newLocal(), newRemote(), "teams", false)
+		assert.Nil(t, err)
+
+		// nothing applied
+		assert.Equal(t, 0, len(recorder.TeamsCreated))
+		assert.Equal(t, 0, len(recorder.RepositoryCreated))
+
+		for _, d := range executor.Drifts() {
+			assert.NotEqual(t, DriftResourceTeams, d.Resource)
+			assert.Equal(t, DriftPolicyReport, d.Policy)
+		}
+		found := false
+		for _, d := range executor.Drifts() {
+			if d.Resource == DriftResourceRepositories && d.Name == "myrepo" && d.Command == "create_repository" {
+				found = true
+			}
+		}
+		assert.True(t, found)
+	})
+
+	t.Run("happy path: drift policy fallback", func(t *testing.T) {
+		policy := config.DriftPolicy{}
+		assert.Equal(t, DriftPolicyEnforce, GetDriftPolicy(policy, DriftResourceUsers))
+
+		policy.Default = DriftPolicyReport
+		policy.Rulesets = DriftPolicyIgnore
+		assert.Equal(t, DriftPolicyReport, GetDriftPolicy(policy, DriftResourceUsers))
+		assert.Equal(t, DriftPolicyIgnore, GetDriftPolicy(policy, DriftResourceRulesets))
+	})
+}
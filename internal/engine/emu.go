@@ -0,0 +1,92 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/Alayacare/goliac/internal/config"
+	"github.com/Alayacare/goliac/internal/entity"
+	"github.com/Alayacare/goliac/internal/github"
+)
+
+var emuInvalidLoginChars = regexp.MustCompile(`[^a-zA-Z0-9-]`)
+
+/*
+ * EmuLogin returns the Enterprise Managed Users login of an IdP identity,
+ * the way Github normalizes it: the email domain is removed, the characters
+ * other than alphanumerics and dashes become dashes, and the enterprise
+ * shortcode is appended (alice.smith@mycompany.com -> alice-smith_acme).
+ * A login already ending with _shortcode is kept as is
+ */
+func EmuLogin(identity string, shortcode string) string {
+	if identity == "" || shortcode == "" {
+		return identity
+	}
+	suffix := "_" + shortcode
+	if strings.HasSuffix(strings.ToLower(identity), strings.ToLower(suffix)) {
+		return identity
+	}
+	if handle, _, found := strings.Cut(identity, "@"); found {
+		identity = handle
+	}
+	return emuInvalidLoginChars.ReplaceAllString(identity, "-") + suffix
+}
+
+/*
+ * LoadUsersFromGithubOrgEmu returns the members of an Enterprise Managed Users
+ * organization (all provisioned by the IdP), named after their login without
+ * the _shortcode suffix
+ */
+func LoadUsersFromGithubOrgEmu(ctx context.Context, client github.GitHubClient, shortcode string) (map[string]*entity.User, error) {
+	users := make(map[string]*entity.User)
+
+	variables := make(map[string]interface{})
+	variables["orgLogin"] = config.Config.GithubAppOrganization
+	variables["endCursor"] = nil
+
+	hasNextPage := true
+	count := 0
+	for hasNextPage {
+		data, err := client.QueryGraphQLAPI(ctx, listAllOrgMembers, variables)
+		if err != nil {
+			return users, err
+		}
+		var gResult GraplQLUsers
+
+		err = json.Unmarshal(data, &gResult)
+		if err != nil {
+			return users, err
+		}
+		if len(gResult.Errors) > 0 {
+			return users, fmt.Errorf("Graphql error: %v", gResult.Errors[0].Message)
+		}
+
+		for _, c := range gResult.Data.Organization.MembersWithRole.Nodes {
+			username := c.Login
+			if shortcode != "" && strings.HasSuffix(strings.ToLower(username), strings.ToLower("_"+shortcode)) {
+				username = username[:len(username)-len(shortcode)-1]
+			}
+			user := &entity.User{}
+			user.ApiVersion = "v1"
+			user.Kind = "User"
+			user.Name = username
+			user.Spec.GithubID = c.Login
+
+			users[username] = user
+		}
+
+		hasNextPage = gResult.Data.Organization.MembersWithRole.PageInfo.HasNextPage
+		variables["endCursor"] = gResult.Data.Organization.MembersWithRole.PageInfo.EndCursor
+
+		count++
+		// sanity check to avoid loops
+		if count > FORLOOP_STOP {
+			break
+		}
+	}
+
+	return users, nil
+}
@@ -0,0 +1,25 @@
+package engine
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEmuLogin(t *testing.T) {
+	t.Run("happy path: IdP identities", func(t *testing.T) {
+		assert.Equal(t, "alice_acme", EmuLogin("alice", "acme"))
+		assert.Equal(t, "alice-smith_acme", EmuLogin("alice.smith@mycompany.com", "acme"))
+		assert.Equal(t, "bob-jr_acme", EmuLogin("bob_jr", "acme"))
+	})
+
+	t.Run("happy path: already an EMU login", func(t *testing.T) {
+		assert.Equal(t, "alice_acme", EmuLogin("alice_acme", "acme"))
+		assert.Equal(t, "Alice_ACME", EmuLogin("Alice_ACME", "acme"))
+	})
+
+	t.Run("happy path: without shortcode", func(t *testing.T) {
+		assert.Equal(t, "alice", EmuLogin("alice", ""))
+		assert.Equal(t, "", EmuLogin("", "acme"))
+	})
+}
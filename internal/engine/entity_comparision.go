@@ -1,7 +1,7 @@
 package engine
 
 type Comparable interface {
-	*GithubTeam | *GithubRepoComparable | *GithubRuleSet
+	*GithubTeam | *GithubRepoComparable | *GithubRuleSet | *GithubCustomProperty
 }
 
 type CompareEqualAB[A Comparable, B Comparable] func(value1 A, value2 B) bool
@@ -4,7 +4,9 @@ import (
 	"context"
 	"fmt"
 	"regexp"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/Alayacare/goliac/internal/config"
 	"github.com/Alayacare/goliac/internal/entity"
@@ -16,6 +18,9 @@ type key string
 
 const (
 	KeyAuthor key = "author"
+	// set (to true) on the changes due to the passing of time (expired accesses,
+	// time-boxed access requests), that are applied even when drifts are not enforced
+	KeyScheduled key = "scheduled"
 )
 
 /*
@@ -28,6 +33,8 @@ type GoliacReconciliator interface {
 type GoliacReconciliatorImpl struct {
 	executor   ReconciliatorExecutor
 	repoconfig *config.RepositoryConfig
+	errs       []error         // operations that failed during the reconciliation
+	protected  map[string]bool // githubids of the protected users
 }
 
 func NewGoliacReconciliatorImpl(executor ReconciliatorExecutor, repoconfig *config.RepositoryConfig) GoliacReconciliator {
@@ -38,15 +45,29 @@ func NewGoliacReconciliatorImpl(executor ReconciliatorExecutor, repoconfig *conf
 }
 
 func (r *GoliacReconciliatorImpl) Reconciliate(ctx context.Context, local GoliacLocal, remote GoliacRemote, teamsreponame string, dryrun bool) error {
-	rremote := NewMutableGoliacRemoteImpl(remote)
+	rremote := NewMutableGoliacRemoteImpl(ctx, remote)
+	r.errs = make([]error, 0)
+	r.protected = make(map[string]bool)
+	for _, user := range local.ProtectedUsers() {
+		r.protected[user.Spec.GithubID] = true
+	}
+	for _, user := range local.AppliedProtectedUsers() {
+		r.protected[user.Spec.GithubID] = true
+	}
 	r.Begin(ctx, dryrun)
-	err := r.reconciliateUsers(ctx, local, rremote, dryrun)
+	err := r.reconciliateOrganization(ctx, rremote, dryrun)
+	if err != nil {
+		r.Rollback(ctx, dryrun, err)
+		return err
+	}
+
+	err = r.reconciliateUsers(ctx, local, remote, rremote, dryrun)
 	if err != nil {
 		r.Rollback(ctx, dryrun, err)
 		return err
 	}
 
-	err = r.reconciliateTeams(ctx, local, rremote, dryrun)
+	err = r.reconciliateTeams(ctx, local, remote, rremote, dryrun)
 	if err != nil {
 		r.Rollback(ctx, dryrun, err)
 		return err
@@ -58,6 +79,18 @@ func (r *GoliacReconciliatorImpl) Reconciliate(ctx context.Context, local Goliac
 		return err
 	}
 
+	err = r.reconciliateActions(ctx, local, remote, rremote, dryrun)
+	if err != nil {
+		r.Rollback(ctx, dryrun, err)
+		return err
+	}
+
+	err = r.reconciliateCustomProperties(ctx, local, rremote, dryrun)
+	if err != nil {
+		r.Rollback(ctx, dryrun, err)
+		return err
+	}
+
 	if remote.IsEnterprise() {
 		err = r.reconciliateRulesets(ctx, local, rremote, r.repoconfig, dryrun)
 		if err != nil {
@@ -66,7 +99,66 @@ func (r *GoliacReconciliatorImpl) Reconciliate(ctx context.Context, local Goliac
 		}
 	}
 
-	r.Commit(ctx, dryrun)
+	err = r.Commit(ctx, dryrun)
+
+	return NewApplyErrors(append(r.errs, err))
+}
+
+/*
+ * addError keeps the error of an operation (if any), to report it at the end of the reconciliation
+ */
+func (r *GoliacReconciliatorImpl) addError(err error) {
+	if err != nil {
+		r.errs = append(r.errs, err)
+	}
+}
+
+/*
+ * This function sync the organization settings (only the ones defined in goliac.yaml)
+ */
+func (r *GoliacReconciliatorImpl) reconciliateOrganization(ctx context.Context, remote *MutableGoliacRemoteImpl, dryrun bool) error {
+	conf := r.repoconfig.Organization
+	rSettings := remote.OrganizationSettings()
+	if rSettings == nil {
+		// not loaded: they cannot be compared
+		if !conf.IsEmpty() {
+			return fmt.Errorf("the organization settings could not be loaded from Github (is the organization Administration permission granted?)")
+		}
+		return nil
+	}
+	// only the settings defined in goliac.yaml (and different) are updated
+	update := GithubOrganizationSettingsUpdate{}
+	changed := false
+
+	if conf.DefaultRepositoryPermission != nil && *conf.DefaultRepositoryPermission != rSettings.DefaultRepositoryPermission {
+		update.DefaultRepositoryPermission = conf.DefaultRepositoryPermission
+		changed = true
+	}
+	if conf.MembersCanCreateRepositories != nil {
+		if *conf.MembersCanCreateRepositories {
+			logrus.Warnf("members_can_create_repositories is enabled: repositories created outside of Goliac will not be managed")
+		}
+		if *conf.MembersCanCreateRepositories != rSettings.MembersCanCreateRepositories {
+			update.MembersCanCreateRepositories = conf.MembersCanCreateRepositories
+			changed = true
+		}
+	}
+	if conf.MembersCanForkPrivateRepositories != nil && *conf.MembersCanForkPrivateRepositories != rSettings.MembersCanForkPrivateRepositories {
+		update.MembersCanForkPrivateRepositories = conf.MembersCanForkPrivateRepositories
+		changed = true
+	}
+	if conf.WebCommitSignoffRequired != nil && *conf.WebCommitSignoffRequired != rSettings.WebCommitSignoffRequired {
+		update.WebCommitSignoffRequired = conf.WebCommitSignoffRequired
+		changed = true
+	}
+	if conf.TwoFactorRequirement != nil && *conf.TwoFactorRequirement != rSettings.TwoFactorRequirementEnabled {
+		// cannot be changed via the API, we can only report it
+		logrus.Warnf("two_factor_requirement is expected to be %v but is %v: it must be changed manually in the organization settings", *conf.TwoFactorRequirement, rSettings.TwoFactorRequirementEnabled)
+	}
+
+	if changed {
+		r.UpdateOrganizationSettings(ctx, dryrun, remote, &update)
+	}
 
 	return nil
 }
@@ -74,29 +166,105 @@ func (r *GoliacReconciliatorImpl) Reconciliate(ctx context.Context, local Goliac
 /*
  * This function sync teams and team's members
  */
-func (r *GoliacReconciliatorImpl) reconciliateUsers(ctx context.Context, local GoliacLocal, remote *MutableGoliacRemoteImpl, dryrun bool) error {
-	ghUsers := remote.Users()
-
+func (r *GoliacReconciliatorImpl) reconciliateUsers(ctx context.Context, local GoliacLocal, githubRemote GoliacRemote, remote *MutableGoliacRemoteImpl, dryrun bool) error {
+	// githubid (or email) -> state
 	rUsers := make(map[string]string)
-	for _, u := range ghUsers {
-		rUsers[u] = u
+	for u, state := range remote.Users() {
+		rUsers[u] = state
 	}
 
+	// check the githubIDs of the users not known by the organization
+	// (misspelled ones are not invited). It fails a plan, but doesn't
+	// block an apply (the other changes of the commit are applied)
+	invalid := make(map[string]bool)
 	for _, lUser := range local.Users() {
-		user, ok := rUsers[lUser.Spec.GithubID]
+		if _, ok := rUsers[lUser.Spec.GithubID]; ok {
+			continue
+		}
+		if err := checkGithubID(ctx, githubRemote, lUser, !r.repoconfig.Emu.Enabled); err != nil {
+			invalid[lUser.Name] = true
+			if dryrun {
+				r.addError(err)
+			} else {
+				logrus.Error(err)
+			}
+		}
+	}
 
-		if !ok {
-			// deal with non existing remote user
-			r.AddUserToOrg(ctx, dryrun, remote, user)
-		} else {
-			delete(rUsers, user)
+	if r.repoconfig.Emu.Enabled {
+		// Enterprise Managed Users: the IdP provisions (and deprovisions) the members
+		for _, lUser := range local.Users() {
+			if _, ok := rUsers[lUser.Spec.GithubID]; !ok {
+				logrus.Warnf("user %s (%s) is not provisioned by the IdP yet", lUser.Name, lUser.Spec.GithubID)
+			}
+		}
+		return nil
+	}
+
+	for _, lUser := range local.Users() {
+		invitee := lUser.Spec.GithubID
+		state, ok := rUsers[invitee]
+		if !ok && lUser.Spec.Email != "" {
+			// maybe invited by email
+			if state, ok = rUsers[lUser.Spec.Email]; ok {
+				invitee = lUser.Spec.Email
+			}
+		}
+
+		if ok {
+			delete(rUsers, invitee)
+			if state != UserStateInvitationFailed {
+				// member, or waiting for the user to accept the invitation
+				continue
+			}
+			logrus.Infof("the invitation of %s has expired (or failed): inviting again", invitee)
+		}
+
+		// deal with non existing remote user
+		if invalid[lUser.Name] {
+			continue
 		}
+		if r.repoconfig.InviteByEmail && lUser.Spec.Email != "" {
+			r.InviteUserByEmail(ctx, dryrun, remote, lUser.Spec.Email)
+			continue
+		}
+		r.AddUserToOrg(ctx, dryrun, remote, lUser.Spec.GithubID)
 	}
 
 	// remaining (GH) users (aka not found locally)
-	for _, rUser := range rUsers {
-		// DELETE User
-		r.RemoveUserFromOrg(ctx, dryrun, remote, rUser)
+	for rUser, state := range rUsers {
+		switch state {
+		case UserStateInvited:
+			r.CancelOrgInvitation(ctx, dryrun, remote, rUser)
+		case UserStateInvitationFailed:
+			// nothing to cancel
+		default:
+			// DELETE User
+			r.RemoveUserFromOrg(ctx, dryrun, remote, rUser)
+		}
+	}
+	return nil
+}
+
+/*
+ * checkGithubID checks that the githubID of a user is an existing Github login,
+ * with the same case (with EMU, a user not provisioned yet doesn't exist)
+ */
+func checkGithubID(ctx context.Context, remote GoliacRemote, user *entity.User, mustExist bool) error {
+	login, err := remote.GetUserLogin(ctx, user.Spec.GithubID)
+	if err != nil {
+		// not able to check: let Github decide
+		logrus.Debugf("not able to check the githubID %s of user %s: %v", user.Spec.GithubID, user.Name, err)
+		return nil
+	}
+	if login == "" {
+		if !mustExist {
+			return nil
+		}
+		return fmt.Errorf("githubID %s of user %s doesn't exist on Github", user.Spec.GithubID, user.Name)
+	}
+	if login != user.Spec.GithubID {
+		return fmt.Errorf("githubID %s of user %s must be written %s", user.Spec.GithubID, user.Name, login)
 	}
 	return nil
 }
@@ -104,8 +272,36 @@ func (r *GoliacReconciliatorImpl) reconciliateUsers(ctx context.Context, local G
 /*
  * This function sync teams and team's members
  */
-func (r *GoliacReconciliatorImpl) reconciliateTeams(ctx context.Context, local GoliacLocal, remote *MutableGoliacRemoteImpl, dryrun bool) error {
+func (r *GoliacReconciliatorImpl) reconciliateTeams(ctx context.Context, local GoliacLocal, githubRemote GoliacRemote, remote *MutableGoliacRemoteImpl, dryrun bool) error {
 	ghTeams := remote.Teams()
+	rUsers := remote.Users()
+
+	// the Github logins of the team members that can be added to a team
+	githubIDs := func(members []string) []string {
+		ids := make([]string, 0, len(members))
+		for _, m := range members {
+			ghuserid, ok := local.Users()[m]
+			if !ok {
+				continue
+			}
+			if r.repoconfig.Emu.Enabled && rUsers[ghuserid.Spec.GithubID] != UserStateMember {
+				// not provisioned by the IdP yet
+				continue
+			}
+			ids = append(ids, ghuserid.Spec.GithubID)
+		}
+		return ids
+	}
+
+	// teams whose members are managed by Github, from their IdP group
+	linkedTeams := make(map[string]bool)
+	if r.repoconfig.Emu.Enabled && r.repoconfig.Emu.LinkExternalGroups {
+		for teamname, teamvalue := range local.Teams() {
+			if teamvalue.Spec.ExternallyManaged != nil {
+				linkedTeams[slug.Make(teamname)] = true
+			}
+		}
+	}
 
 	rTeams := make(map[string]*GithubTeam)
 	for k, v := range ghTeams {
@@ -118,6 +314,14 @@ func (r *GoliacReconciliatorImpl) reconciliateTeams(ctx context.Context, local G
 		members := []string{}
 		members = append(members, teamvalue.Spec.Members...)
 		members = append(members, teamvalue.Spec.Owners...)
+		if teamname == r.repoconfig.AdminTeam {
+			// the protected users are always members of the admin team
+			for username := range local.ProtectedUsers() {
+				if !containsString(members, username) {
+					members = append(members, username)
+				}
+			}
+		}
 
 		teamslug := slug.Make(teamname)
 		slugTeams[teamslug] = &GithubTeam{
@@ -155,11 +359,9 @@ func (r *GoliacReconciliatorImpl) reconciliateTeams(ctx context.Context, local G
 	}
 
 	onAdded := func(key string, lTeam *GithubTeam, rTeam *GithubTeam) {
-		members := make([]string, 0)
-		for _, m := range lTeam.Members {
-			if ghuserid, ok := local.Users()[m]; ok {
-				members = append(members, ghuserid.Spec.GithubID)
-			}
+		members := githubIDs(lTeam.Members)
+		if linkedTeams[lTeam.Slug] {
+			members = []string{}
 		}
 		// CREATE team
 		r.CreateTeam(ctx, dryrun, remote, lTeam.Slug, lTeam.Name, members)
@@ -171,11 +373,12 @@ func (r *GoliacReconciliatorImpl) reconciliateTeams(ctx context.Context, local G
 	}
 
 	onChanged := func(slugTeam string, lTeam *GithubTeam, rTeam *GithubTeam) {
+		if linkedTeams[slugTeam] {
+			return
+		}
 		localMembers := make(map[string]bool)
-		for _, m := range lTeam.Members {
-			if ghuserid, ok := local.Users()[m]; ok {
-				localMembers[ghuserid.Spec.GithubID] = true
-			}
+		for _, m := range githubIDs(lTeam.Members) {
+			localMembers[m] = true
 		}
 
 		for _, m := range rTeam.Members {
@@ -194,9 +397,51 @@ func (r *GoliacReconciliatorImpl) reconciliateTeams(ctx context.Context, local G
 
 	CompareEntities(slugTeams, rTeams, compareTeam, onAdded, onRemoved, onChanged)
 
+	if r.repoconfig.Emu.Enabled && r.repoconfig.Emu.LinkExternalGroups {
+		r.reconciliateTeamsExternalGroups(ctx, local, githubRemote, dryrun)
+	}
+
 	return nil
 }
 
+/*
+ * reconciliateTeamsExternalGroups links the teams with spec.externallyManaged
+ * to their IdP group, and unlinks the other (Goliac) teams
+ */
+func (r *GoliacReconciliatorImpl) reconciliateTeamsExternalGroups(ctx context.Context, local GoliacLocal, githubRemote GoliacRemote, dryrun bool) {
+	groups := githubRemote.ExternalGroups(ctx)
+
+	// teamslug -> group name
+	linked := make(map[string]string)
+	for groupname, group := range groups {
+		for _, teamslug := range group.Teams {
+			linked[teamslug] = groupname
+		}
+	}
+
+	for teamname, teamvalue := range local.Teams() {
+		teamslug := slug.Make(teamname)
+		current, isLinked := linked[teamslug]
+
+		if teamvalue.Spec.ExternallyManaged == nil {
+			if isLinked {
+				r.UpdateTeamRemoveExternalGroup(ctx, dryrun, teamslug)
+			}
+			continue
+		}
+
+		groupname := teamvalue.Spec.ExternallyManaged.Group
+		if isLinked && current == groupname {
+			continue
+		}
+		if _, ok := groups[groupname]; !ok {
+			r.addError(fmt.Errorf("not able to link team %s: external group %s not found", teamname, groupname))
+			continue
+		}
+		r.UpdateTeamSetExternalGroup(ctx, dryrun, teamslug, groupname)
+	}
+}
+
 type GithubRepoComparable struct {
 	IsPublic            bool
 	IsArchived          bool
@@ -247,6 +492,8 @@ func (r *GoliacReconciliatorImpl) reconciliateRepositories(ctx context.Context,
 	}
 
 	lRepos := make(map[string]*GithubRepoComparable)
+	// reponame/grantee whose changes are scheduled (see KeyScheduled)
+	scheduled := make(map[string]bool)
 	for reponame, lRepo := range local.Repositories() {
 		writers := make([]string, 0)
 		for _, w := range lRepo.Spec.Writers {
@@ -273,18 +520,27 @@ func (r *GoliacReconciliatorImpl) reconciliateRepositories(ctx context.Context,
 			readers = append(readers, "everyone")
 		}
 
-		// adding exernal reader/writer
+		// adding exernal reader/writer (expired accesses are considered as absent)
+		now := time.Now()
 		eReaders := make([]string, 0)
 		for _, r := range lRepo.Spec.ExternalUserReaders {
 			if user, ok := local.ExternalUsers()[r]; ok {
-				eReaders = append(eReaders, user.Spec.GithubID)
+				if entity.IsExternalUserExpired(lRepo, r, local.ExternalUsers(), now) {
+					scheduled[slug.Make(reponame)+"/"+user.Spec.GithubID] = true
+				} else {
+					eReaders = append(eReaders, user.Spec.GithubID)
+				}
 			}
 		}
 
 		eWriters := make([]string, 0)
 		for _, w := range lRepo.Spec.ExternalUserWriters {
 			if user, ok := local.ExternalUsers()[w]; ok {
-				eWriters = append(eWriters, user.Spec.GithubID)
+				if entity.IsExternalUserExpired(lRepo, w, local.ExternalUsers(), now) {
+					scheduled[slug.Make(reponame)+"/"+user.Spec.GithubID] = true
+				} else {
+					eWriters = append(eWriters, user.Spec.GithubID)
+				}
 			}
 		}
 
@@ -298,6 +554,17 @@ func (r *GoliacReconciliatorImpl) reconciliateRepositories(ctx context.Context,
 		}
 	}
 
+	// adding the temporary access requests
+	r.applyAccessRequests(ctx, dryrun, local, lRepos, rRepos, scheduled)
+
+	// the changes of a scheduled grantee are not drifts (see KeyScheduled)
+	grantCtx := func(reponame string, grantee string) context.Context {
+		if scheduled[reponame+"/"+grantee] {
+			return context.WithValue(ctx, KeyScheduled, true)
+		}
+		return ctx
+	}
+
 	// now we compare local (slugTeams) and remote (rTeams)
 
 	compareRepos := func(lRepo *GithubRepoComparable, rRepo *GithubRepoComparable) bool {
@@ -351,19 +618,19 @@ func (r *GoliacReconciliatorImpl) reconciliateRepositories(ctx context.Context,
 
 		if res, readToRemove, readToAdd := entity.StringArrayEquivalent(lRepo.Readers, rRepo.Readers); !res {
 			for _, teamSlug := range readToAdd {
-				r.UpdateRepositoryAddTeamAccess(ctx, dryrun, remote, reponame, teamSlug, "pull")
+				r.UpdateRepositoryAddTeamAccess(grantCtx(reponame, teamSlug), dryrun, remote, reponame, teamSlug, "pull")
 			}
 			for _, teamSlug := range readToRemove {
-				r.UpdateRepositoryRemoveTeamAccess(ctx, dryrun, remote, reponame, teamSlug)
+				r.UpdateRepositoryRemoveTeamAccess(grantCtx(reponame, teamSlug), dryrun, remote, reponame, teamSlug)
 			}
 		}
 
 		if res, writeToRemove, writeToAdd := entity.StringArrayEquivalent(lRepo.Writers, rRepo.Writers); !res {
 			for _, teamSlug := range writeToAdd {
-				r.UpdateRepositoryAddTeamAccess(ctx, dryrun, remote, reponame, teamSlug, "push")
+				r.UpdateRepositoryAddTeamAccess(grantCtx(reponame, teamSlug), dryrun, remote, reponame, teamSlug, "push")
 			}
 			for _, teamSlug := range writeToRemove {
-				r.UpdateRepositoryRemoveTeamAccess(ctx, dryrun, remote, reponame, teamSlug)
+				r.UpdateRepositoryRemoveTeamAccess(grantCtx(reponame, teamSlug), dryrun, remote, reponame, teamSlug)
 			}
 		}
 
@@ -381,11 +648,11 @@ func (r *GoliacReconciliatorImpl) reconciliateRepositories(ctx context.Context,
 					}
 				}
 				if !found {
-					r.UpdateRepositoryRemoveExternalUser(ctx, dryrun, remote, reponame, eReader)
+					r.UpdateRepositoryRemoveExternalUser(grantCtx(reponame, eReader), dryrun, remote, reponame, eReader)
 				}
 			}
 			for _, eReader := range ereaderToAdd {
-				r.UpdateRepositorySetExternalUser(ctx, dryrun, remote, reponame, eReader, "pull")
+				r.UpdateRepositorySetExternalUser(grantCtx(reponame, eReader), dryrun, remote, reponame, eReader, "pull")
 			}
 		}
 
@@ -400,11 +667,11 @@ func (r *GoliacReconciliatorImpl) reconciliateRepositories(ctx context.Context,
 					}
 				}
 				if !found {
-					r.UpdateRepositoryRemoveExternalUser(ctx, dryrun, remote, reponame, eWriter)
+					r.UpdateRepositoryRemoveExternalUser(grantCtx(reponame, eWriter), dryrun, remote, reponame, eWriter)
 				}
 			}
 			for _, eWriter := range ewriteToAdd {
-				r.UpdateRepositorySetExternalUser(ctx, dryrun, remote, reponame, eWriter, "push")
+				r.UpdateRepositorySetExternalUser(grantCtx(reponame, eWriter), dryrun, remote, reponame, eWriter, "push")
 			}
 		}
 
@@ -444,8 +711,8 @@ func (r *GoliacReconciliatorImpl) reconciliateRulesets(ctx context.Context, loca
 		for _, r := range rs.Spec.Rules {
 			grs.Rules[r.Ruletype] = r.Parameters
 		}
-		for reponame := range repositories {
-			if match.Match([]byte(slug.Make(reponame))) {
+		for reponame, repo := range repositories {
+			if match.Match([]byte(slug.Make(reponame))) && matchCustomProperties(repo, confrs.Properties, conf.CustomProperties) {
 				grs.Repositories = append(grs.Repositories, slug.Make(reponame))
 			}
 		}
@@ -512,6 +779,344 @@ func (r *GoliacReconciliatorImpl) reconciliateRulesets(ctx context.Context, loca
 	return nil
 }
 
+/*
+ * resolveSecrets fetches the secret values via the secret providers
+ * and returns a map [name]value.
+ * The keys of repository secrets are restricted (see RepositorySecretProvider)
+ */
+func resolveSecrets(secrets []config.SecretReference, repository bool) (map[string]string, error) {
+	values := make(map[string]string)
+	for _, s := range secrets {
+		provider, ok := GetSecretProvider(s.Provider)
+		if !ok {
+			return nil, fmt.Errorf("secret provider %s not found (for secret %s)", s.Provider, s.Name)
+		}
+		if repository {
+			rp, ok := provider.(RepositorySecretProvider)
+			if !ok {
+				return nil, fmt.Errorf("secret provider %s cannot be used for repository secrets (for secret %s)", s.Provider, s.Name)
+			}
+			if err := rp.CheckRepositoryKey(s.Key); err != nil {
+				return nil, fmt.Errorf("invalid key for secret %s: %v", s.Name, err)
+			}
+		}
+		value, err := provider.GetSecret(s.Key)
+		if err != nil {
+			return nil, fmt.Errorf("not able to fetch secret %s: %v", s.Name, err)
+		}
+		values[s.Name] = value
+	}
+	return values, nil
+}
+
+/*
+ * actionsConfigured returns true if some Github Actions variables or secrets
+ * are defined (at the organization level or for a repository)
+ */
+func actionsConfigured(local GoliacLocal, repoconfig *config.RepositoryConfig) bool {
+	if len(repoconfig.Actions.Variables) > 0 || len(repoconfig.Actions.Secrets) > 0 {
+		return true
+	}
+	for _, repo := range local.Repositories() {
+		if len(repo.Spec.Variables) > 0 || len(repo.Spec.Secrets) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+/*
+ * This function sync Github Actions variables and secrets (at the organization level and for each repository)
+ * If none are configured, the Github Actions variables and secrets are left as is (and not even loaded from Github)
+ */
+func (r *GoliacReconciliatorImpl) reconciliateActions(ctx context.Context, local GoliacLocal, githubRemote GoliacRemote, remote *MutableGoliacRemoteImpl, dryrun bool) error {
+	if !actionsConfigured(local, r.repoconfig) {
+		return nil
+	}
+	if err := remote.loadActions(ctx, githubRemote); err != nil {
+		return err
+	}
+
+	// organization level
+	orgSecrets, err := resolveSecrets(r.repoconfig.Actions.Secrets, false)
+	if err != nil {
+		return err
+	}
+	rOrgActions := remote.OrgActions()
+	for name, value := range r.repoconfig.Actions.Variables {
+		if rValue, ok := rOrgActions.Variables[name]; !ok || rValue != value {
+			r.SetOrgVariable(ctx, dryrun, remote, name, value)
+		}
+	}
+	for name := range rOrgActions.Variables {
+		if _, ok := r.repoconfig.Actions.Variables[name]; !ok {
+			r.RemoveOrgVariable(ctx, dryrun, remote, name)
+		}
+	}
+	for name, value := range orgSecrets {
+		if rHash, ok := rOrgActions.Secrets[name]; !ok || rHash != hashSecretValue(value) {
+			r.SetOrgSecret(ctx, dryrun, remote, name, value)
+		}
+	}
+	for name := range rOrgActions.Secrets {
+		if _, ok := orgSecrets[name]; !ok {
+			r.RemoveOrgSecret(ctx, dryrun, remote, name)
+		}
+	}
+
+	// repositories level
+	rReposActions := remote.RepositoriesActions()
+	for reponame, lRepo := range local.Repositories() {
+		if lRepo.Archived {
+			continue
+		}
+		reponame = slug.Make(reponame)
+		if _, ok := remote.Repositories()[reponame]; !ok {
+			continue
+		}
+		secrets, err := resolveSecrets(lRepo.Spec.Secrets, true)
+		if err != nil {
+			return fmt.Errorf("repository %s: %v", reponame, err)
+		}
+		rActions, ok := rReposActions[reponame]
+		if !ok {
+			rActions = &GithubActions{
+				Variables: map[string]string{},
+				Secrets:   map[string]string{},
+			}
+		}
+
+		for name, value := range lRepo.Spec.Variables {
+			if rValue, ok := rActions.Variables[name]; !ok || rValue != value {
+				r.UpdateRepositorySetVariable(ctx, dryrun, remote, reponame, name, value)
+			}
+		}
+		for name := range rActions.Variables {
+			if _, ok := lRepo.Spec.Variables[name]; !ok {
+				r.UpdateRepositoryRemoveVariable(ctx, dryrun, remote, reponame, name)
+			}
+		}
+		for name, value := range secrets {
+			if rHash, ok := rActions.Secrets[name]; !ok || rHash != hashSecretValue(value) {
+				r.UpdateRepositorySetSecret(ctx, dryrun, remote, reponame, name, value)
+			}
+		}
+		for name := range rActions.Secrets {
+			if _, ok := secrets[name]; !ok {
+				r.UpdateRepositoryRemoveSecret(ctx, dryrun, remote, reponame, name)
+			}
+		}
+	}
+
+	return nil
+}
+
+func containsString(list []string, value string) bool {
+	for _, v := range list {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func removeString(list []string, value string) []string {
+	result := make([]string, 0, len(list))
+	for _, v := range list {
+		if v != value {
+			result = append(result, v)
+		}
+	}
+	return result
+}
+
+/*
+ * applyAccessRequests adds the active temporary access requests to the local repositories.
+ * Expired requests are simply ignored (so the access will be removed), but
+ * grants and revokes are logged, and added to scheduled (as reponame/grantee)
+ */
+func (r *GoliacReconciliatorImpl) applyAccessRequests(ctx context.Context, dryrun bool, local GoliacLocal, lRepos map[string]*GithubRepoComparable, rRepos map[string]*GithubRepoComparable, scheduled map[string]bool) {
+	author := "unknown"
+	if a := ctx.Value(KeyAuthor); a != nil {
+		author = a.(string)
+	}
+	now := time.Now()
+
+	requestnames := make([]string, 0, len(local.AccessRequests()))
+	for name := range local.AccessRequests() {
+		requestnames = append(requestnames, name)
+	}
+	sort.Strings(requestnames)
+
+	for _, name := range requestnames {
+		request := local.AccessRequests()[name]
+		reponame := slug.Make(request.Spec.Repository)
+		lRepo, ok := lRepos[reponame]
+		if !ok {
+			continue
+		}
+		rRepo, ok := rRepos[reponame]
+		if !ok {
+			rRepo = &GithubRepoComparable{}
+		}
+
+		// who is granted: a team, or an external user (via the collaborator API)
+		grantee := ""
+		lReaders, lWriters := &lRepo.Readers, &lRepo.Writers
+		rReaders, rWriters := rRepo.Readers, rRepo.Writers
+		if request.Spec.Team != "" {
+			grantee = slug.Make(request.Spec.Team)
+		} else {
+			user, ok := local.ExternalUsers()[request.Spec.ExternalUser]
+			if !ok {
+				continue
+			}
+			if user.Spec.Expires != "" {
+				if expiration, err := entity.ParseExpirationDate(user.Spec.Expires); err == nil && !now.Before(expiration) {
+					continue
+				}
+			}
+			grantee = user.Spec.GithubID
+			lReaders, lWriters = &lRepo.ExternalUserReaders, &lRepo.ExternalUserWriters
+			rReaders, rWriters = rRepo.ExternalUserReaders, rRepo.ExternalUserWriters
+		}
+
+		_, end := request.Window()
+		fields := map[string]interface{}{"dryrun": dryrun, "author": author}
+
+		if request.IsActive(now) {
+			alreadyGranted := containsString(rWriters, grantee) || (request.Spec.Permission == "read" && containsString(rReaders, grantee))
+			if request.Spec.Permission == "write" {
+				if !containsString(*lWriters, grantee) {
+					*lWriters = append(*lWriters, grantee)
+				}
+				*lReaders = removeString(*lReaders, grantee)
+			} else if !containsString(*lWriters, grantee) && !containsString(*lReaders, grantee) {
+				*lReaders = append(*lReaders, grantee)
+			}
+			if !alreadyGranted {
+				scheduled[reponame+"/"+grantee] = true
+				fields["command"] = "grant_access_request"
+				logrus.WithFields(fields).Infof("request: %s, repositoryname: %s, grantee: %s, permission: %s, until: %s, justification: %s", name, reponame, grantee, request.Spec.Permission, end.UTC().Format(time.RFC3339), request.Spec.Justification)
+			}
+		} else if request.IsExpired(now) {
+			stillGranted := containsString(rWriters, grantee) || (request.Spec.Permission == "read" && containsString(rReaders, grantee))
+			permanent := containsString(*lWriters, grantee) || (request.Spec.Permission == "read" && containsString(*lReaders, grantee))
+			if stillGranted && !permanent {
+				scheduled[reponame+"/"+grantee] = true
+				fields["command"] = "revoke_access_request"
+				logrus.WithFields(fields).Infof("request: %s, repositoryname: %s, grantee: %s, expired: %s", name, reponame, grantee, end.UTC().Format(time.RFC3339))
+			}
+		}
+	}
+}
+
+/*
+ * localCustomPropertyValue returns the value of a repository custom property
+ * (or the property default value if not set)
+ */
+func localCustomPropertyValue(repo *entity.Repository, property config.CustomProperty) []string {
+	if value, ok := repo.Spec.CustomProperties[property.Name]; ok {
+		return value
+	}
+	if property.DefaultValue != "" {
+		return []string{property.DefaultValue}
+	}
+	return nil
+}
+
+/*
+ * matchCustomProperties checks if a repository custom properties
+ * match all the expected properties values
+ */
+func matchCustomProperties(repo *entity.Repository, expected map[string]string, schema []config.CustomProperty) bool {
+	for name, expectedValue := range expected {
+		found := false
+		for _, property := range schema {
+			if property.Name != name {
+				continue
+			}
+			for _, v := range localCustomPropertyValue(repo, property) {
+				if v == expectedValue {
+					found = true
+					break
+				}
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+/*
+ * This function sync the organization custom properties definitions
+ * and the repositories custom properties values.
+ * It is only enabled if custom_properties is defined in goliac.yaml
+ */
+func (r *GoliacReconciliatorImpl) reconciliateCustomProperties(ctx context.Context, local GoliacLocal, remote *MutableGoliacRemoteImpl, dryrun bool) error {
+	if r.repoconfig.CustomProperties == nil {
+		return nil
+	}
+
+	// organization schema
+	lProperties := make(map[string]*GithubCustomProperty)
+	for _, p := range r.repoconfig.CustomProperties {
+		lProperties[p.Name] = &GithubCustomProperty{
+			Name:          p.Name,
+			ValueType:     p.ValueType,
+			Required:      p.Required,
+			DefaultValue:  p.DefaultValue,
+			Description:   p.Description,
+			AllowedValues: p.AllowedValues,
+		}
+	}
+
+	compareProperties := func(lp *GithubCustomProperty, rp *GithubCustomProperty) bool {
+		if lp.ValueType != rp.ValueType || lp.Required != rp.Required || lp.DefaultValue != rp.DefaultValue || lp.Description != rp.Description {
+			return false
+		}
+		if res, _, _ := entity.StringArrayEquivalent(lp.AllowedValues, rp.AllowedValues); !res {
+			return false
+		}
+		return true
+	}
+
+	onAdded := func(name string, lp *GithubCustomProperty, rp *GithubCustomProperty) {
+		r.UpsertCustomProperty(ctx, dryrun, remote, lp)
+	}
+	onRemoved := func(name string, lp *GithubCustomProperty, rp *GithubCustomProperty) {
+		r.DeleteCustomProperty(ctx, dryrun, remote, name)
+	}
+	onChanged := func(name string, lp *GithubCustomProperty, rp *GithubCustomProperty) {
+		r.UpsertCustomProperty(ctx, dryrun, remote, lp)
+	}
+
+	CompareEntities(lProperties, remote.CustomProperties(), compareProperties, onAdded, onRemoved, onChanged)
+
+	// repositories values
+	rReposProperties := remote.RepositoriesCustomProperties()
+	for reponame, lRepo := range local.Repositories() {
+		if lRepo.Archived {
+			continue
+		}
+		reponame = slug.Make(reponame)
+		if _, ok := remote.Repositories()[reponame]; !ok {
+			continue
+		}
+		rValues := rReposProperties[reponame]
+		for _, p := range r.repoconfig.CustomProperties {
+			lValue := localCustomPropertyValue(lRepo, p)
+			if res, _, _ := entity.StringArrayEquivalent(lValue, rValues[p.Name]); !res {
+				r.UpdateRepositorySetCustomProperty(ctx, dryrun, remote, reponame, p.Name, lValue)
+			}
+		}
+	}
+
+	return nil
+}
+
 func (r *GoliacReconciliatorImpl) AddUserToOrg(ctx context.Context, dryrun bool, remote *MutableGoliacRemoteImpl, ghuserid string) {
 	author := "unknown"
 	if a := ctx.Value(KeyAuthor); a != nil {
@@ -520,7 +1125,31 @@ func (r *GoliacReconciliatorImpl) AddUserToOrg(ctx context.Context, dryrun bool,
 	logrus.WithFields(map[string]interface{}{"dryrun": dryrun, "author": author, "command": "add_user_to_org"}).Infof("ghusername: %s", ghuserid)
 	remote.AddUserToOrg(ghuserid)
 	if r.executor != nil {
-		r.executor.AddUserToOrg(dryrun, ghuserid)
+		r.addError(r.executor.AddUserToOrg(ctx, dryrun, ghuserid))
+	}
+}
+
+func (r *GoliacReconciliatorImpl) InviteUserByEmail(ctx context.Context, dryrun bool, remote *MutableGoliacRemoteImpl, email string) {
+	author := "unknown"
+	if a := ctx.Value(KeyAuthor); a != nil {
+		author = a.(string)
+	}
+	logrus.WithFields(map[string]interface{}{"dryrun": dryrun, "author": author, "command": "invite_user_by_email"}).Infof("email: %s", email)
+	remote.InviteUserByEmail(email)
+	if r.executor != nil {
+		r.addError(r.executor.InviteUserByEmail(ctx, dryrun, email))
+	}
+}
+
+func (r *GoliacReconciliatorImpl) CancelOrgInvitation(ctx context.Context, dryrun bool, remote *MutableGoliacRemoteImpl, invitee string) {
+	author := "unknown"
+	if a := ctx.Value(KeyAuthor); a != nil {
+		author = a.(string)
+	}
+	logrus.WithFields(map[string]interface{}{"dryrun": dryrun, "author": author, "command": "cancel_org_invitation"}).Infof("invitee: %s", invitee)
+	remote.CancelOrgInvitation(invitee)
+	if r.executor != nil {
+		r.addError(r.executor.CancelOrgInvitation(ctx, dryrun, invitee))
 	}
 }
 
@@ -529,11 +1158,15 @@ func (r *GoliacReconciliatorImpl) RemoveUserFromOrg(ctx context.Context, dryrun
 	if a := ctx.Value(KeyAuthor); a != nil {
 		author = a.(string)
 	}
+	if r.protected[ghuserid] {
+		logrus.Warnf("user %s is protected: not removed from the organization", ghuserid)
+		return
+	}
 	logrus.WithFields(map[string]interface{}{"dryrun": dryrun, "author": author, "command": "remove_user_from_org"}).Infof("ghusername: %s", ghuserid)
 	remote.RemoveUserFromOrg(ghuserid)
 	if r.executor != nil {
 		if r.repoconfig.DestructiveOperations.AllowDestructiveUsers {
-			r.executor.RemoveUserFromOrg(dryrun, ghuserid)
+			r.addError(r.executor.RemoveUserFromOrg(ctx, dryrun, ghuserid))
 		}
 	}
 }
@@ -546,7 +1179,7 @@ func (r *GoliacReconciliatorImpl) CreateTeam(ctx context.Context, dryrun bool, r
 	logrus.WithFields(map[string]interface{}{"dryrun": dryrun, "author": author, "command": "create_team"}).Infof("teamname: %s, members: %s", teamname, strings.Join(members, ","))
 	remote.CreateTeam(teamname, description, members)
 	if r.executor != nil {
-		r.executor.CreateTeam(dryrun, teamname, description, members)
+		r.addError(r.executor.CreateTeam(ctx, dryrun, teamname, description, members))
 	}
 }
 func (r *GoliacReconciliatorImpl) UpdateTeamAddMember(ctx context.Context, dryrun bool, remote *MutableGoliacRemoteImpl, teamslug string, username string, role string) {
@@ -557,7 +1190,7 @@ func (r *GoliacReconciliatorImpl) UpdateTeamAddMember(ctx context.Context, dryru
 	logrus.WithFields(map[string]interface{}{"dryrun": dryrun, "author": author, "command": "update_team_add_member"}).Infof("teamslug: %s, username: %s, role: %s", teamslug, username, role)
 	remote.UpdateTeamAddMember(teamslug, username, "member")
 	if r.executor != nil {
-		r.executor.UpdateTeamAddMember(dryrun, teamslug, username, "member")
+		r.addError(r.executor.UpdateTeamAddMember(ctx, dryrun, teamslug, username, "member"))
 	}
 }
 func (r *GoliacReconciliatorImpl) UpdateTeamRemoveMember(ctx context.Context, dryrun bool, remote *MutableGoliacRemoteImpl, teamslug string, username string) {
@@ -568,7 +1201,7 @@ func (r *GoliacReconciliatorImpl) UpdateTeamRemoveMember(ctx context.Context, dr
 	logrus.WithFields(map[string]interface{}{"dryrun": dryrun, "author": author, "command": "update_team_remove_member"}).Infof("teamslug: %s, username: %s", teamslug, username)
 	remote.UpdateTeamRemoveMember(teamslug, username)
 	if r.executor != nil {
-		r.executor.UpdateTeamRemoveMember(dryrun, teamslug, username)
+		r.addError(r.executor.UpdateTeamRemoveMember(ctx, dryrun, teamslug, username))
 	}
 }
 func (r *GoliacReconciliatorImpl) DeleteTeam(ctx context.Context, dryrun bool, remote *MutableGoliacRemoteImpl, teamslug string) {
@@ -580,10 +1213,30 @@ func (r *GoliacReconciliatorImpl) DeleteTeam(ctx context.Context, dryrun bool, r
 		logrus.WithFields(map[string]interface{}{"dryrun": dryrun, "author": author, "command": "delete_team"}).Infof("teamslug: %s", teamslug)
 		remote.DeleteTeam(teamslug)
 		if r.executor != nil {
-			r.executor.DeleteTeam(dryrun, teamslug)
+			r.addError(r.executor.DeleteTeam(ctx, dryrun, teamslug))
 		}
 	}
 }
+func (r *GoliacReconciliatorImpl) UpdateTeamSetExternalGroup(ctx context.Context, dryrun bool, teamslug string, groupname string) {
+	author := "unknown"
+	if a := ctx.Value(KeyAuthor); a != nil {
+		author = a.(string)
+	}
+	logrus.WithFields(map[string]interface{}{"dryrun": dryrun, "author": author, "command": "update_team_set_external_group"}).Infof("teamslug: %s, group: %s", teamslug, groupname)
+	if r.executor != nil {
+		r.addError(r.executor.UpdateTeamSetExternalGroup(ctx, dryrun, teamslug, groupname))
+	}
+}
+func (r *GoliacReconciliatorImpl) UpdateTeamRemoveExternalGroup(ctx context.Context, dryrun bool, teamslug string) {
+	author := "unknown"
+	if a := ctx.Value(KeyAuthor); a != nil {
+		author = a.(string)
+	}
+	logrus.WithFields(map[string]interface{}{"dryrun": dryrun, "author": author, "command": "update_team_remove_external_group"}).Infof("teamslug: %s", teamslug)
+	if r.executor != nil {
+		r.addError(r.executor.UpdateTeamRemoveExternalGroup(ctx, dryrun, teamslug))
+	}
+}
 func (r *GoliacReconciliatorImpl) CreateRepository(ctx context.Context, dryrun bool, remote *MutableGoliacRemoteImpl, reponame string, descrition string, writers []string, readers []string, public bool) {
 	author := "unknown"
 	if a := ctx.Value(KeyAuthor); a != nil {
@@ -592,7 +1245,7 @@ func (r *GoliacReconciliatorImpl) CreateRepository(ctx context.Context, dryrun b
 	logrus.WithFields(map[string]interface{}{"dryrun": dryrun, "author": author, "command": "create_repository"}).Infof("repositoryname: %s, readers: %s, writers: %s, public: %v", reponame, strings.Join(readers, ","), strings.Join(writers, ","), public)
 	remote.CreateRepository(reponame, reponame, writers, readers, public)
 	if r.executor != nil {
-		r.executor.CreateRepository(dryrun, reponame, reponame, writers, readers, public)
+		r.addError(r.executor.CreateRepository(ctx, dryrun, reponame, reponame, writers, readers, public))
 	}
 }
 func (r *GoliacReconciliatorImpl) UpdateRepositoryAddTeamAccess(ctx context.Context, dryrun bool, remote *MutableGoliacRemoteImpl, reponame string, teamslug string, permission string) {
@@ -603,7 +1256,7 @@ func (r *GoliacReconciliatorImpl) UpdateRepositoryAddTeamAccess(ctx context.Cont
 	logrus.WithFields(map[string]interface{}{"dryrun": dryrun, "author": author, "command": "update_repository_add_team"}).Infof("repositoryname: %s, teamslug: %s, permission: %s", reponame, teamslug, permission)
 	remote.UpdateRepositoryAddTeamAccess(reponame, teamslug, permission)
 	if r.executor != nil {
-		r.executor.UpdateRepositoryAddTeamAccess(dryrun, reponame, teamslug, permission)
+		r.addError(r.executor.UpdateRepositoryAddTeamAccess(ctx, dryrun, reponame, teamslug, permission))
 	}
 }
 
@@ -615,7 +1268,7 @@ func (r *GoliacReconciliatorImpl) UpdateRepositoryUpdateTeamAccess(ctx context.C
 	logrus.WithFields(map[string]interface{}{"dryrun": dryrun, "author": author, "command": "update_repository_update_team"}).Infof("repositoryname: %s, teamslug:%s, permission: %s", reponame, teamslug, permission)
 	remote.UpdateRepositoryUpdateTeamAccess(reponame, teamslug, permission)
 	if r.executor != nil {
-		r.executor.UpdateRepositoryUpdateTeamAccess(dryrun, reponame, teamslug, permission)
+		r.addError(r.executor.UpdateRepositoryUpdateTeamAccess(ctx, dryrun, reponame, teamslug, permission))
 	}
 }
 func (r *GoliacReconciliatorImpl) UpdateRepositoryRemoveTeamAccess(ctx context.Context, dryrun bool, remote *MutableGoliacRemoteImpl, reponame string, teamslug string) {
@@ -626,7 +1279,7 @@ func (r *GoliacReconciliatorImpl) UpdateRepositoryRemoveTeamAccess(ctx context.C
 	logrus.WithFields(map[string]interface{}{"dryrun": dryrun, "author": author, "command": "update_repository_remove_team"}).Infof("repositoryname: %s, teamslug:%s", reponame, teamslug)
 	remote.UpdateRepositoryRemoveTeamAccess(reponame, teamslug)
 	if r.executor != nil {
-		r.executor.UpdateRepositoryRemoveTeamAccess(dryrun, reponame, teamslug)
+		r.addError(r.executor.UpdateRepositoryRemoveTeamAccess(ctx, dryrun, reponame, teamslug))
 	}
 }
 
@@ -639,7 +1292,7 @@ func (r *GoliacReconciliatorImpl) DeleteRepository(ctx context.Context, dryrun b
 		logrus.WithFields(map[string]interface{}{"dryrun": dryrun, "author": author, "command": "delete_repository"}).Infof("repositoryname: %s", reponame)
 		remote.DeleteRepository(reponame)
 		if r.executor != nil {
-			r.executor.DeleteRepository(dryrun, reponame)
+			r.addError(r.executor.DeleteRepository(ctx, dryrun, reponame))
 		}
 	}
 }
@@ -651,7 +1304,7 @@ func (r *GoliacReconciliatorImpl) UpdateRepositoryUpdatePrivate(ctx context.Cont
 	logrus.WithFields(map[string]interface{}{"dryrun": dryrun, "author": author, "command": "update_repository_update_private"}).Infof("repositoryname: %s private:%v", reponame, private)
 	remote.UpdateRepositoryUpdatePrivate(reponame, private)
 	if r.executor != nil {
-		r.executor.UpdateRepositoryUpdatePrivate(dryrun, reponame, private)
+		r.addError(r.executor.UpdateRepositoryUpdatePrivate(ctx, dryrun, reponame, private))
 	}
 }
 func (r *GoliacReconciliatorImpl) UpdateRepositoryUpdateArchived(ctx context.Context, dryrun bool, remote *MutableGoliacRemoteImpl, reponame string, archived bool) {
@@ -662,7 +1315,7 @@ func (r *GoliacReconciliatorImpl) UpdateRepositoryUpdateArchived(ctx context.Con
 	logrus.WithFields(map[string]interface{}{"dryrun": dryrun, "author": author, "command": "update_repository_update_archived"}).Infof("repositoryname: %s archived:%v", reponame, archived)
 	remote.UpdateRepositoryUpdateArchived(reponame, archived)
 	if r.executor != nil {
-		r.executor.UpdateRepositoryUpdateArchived(dryrun, reponame, archived)
+		r.addError(r.executor.UpdateRepositoryUpdateArchived(ctx, dryrun, reponame, archived))
 	}
 }
 func (r *GoliacReconciliatorImpl) AddRuleset(ctx context.Context, dryrun bool, ruleset *GithubRuleSet) {
@@ -672,7 +1325,7 @@ func (r *GoliacReconciliatorImpl) AddRuleset(ctx context.Context, dryrun bool, r
 	}
 	logrus.WithFields(map[string]interface{}{"dryrun": dryrun, "author": author, "command": "add_ruleset"}).Infof("ruleset: %s (id: %d) enforcement: %s", ruleset.Name, ruleset.Id, ruleset.Enforcement)
 	if r.executor != nil {
-		r.executor.AddRuleset(dryrun, ruleset)
+		r.addError(r.executor.AddRuleset(ctx, dryrun, ruleset))
 	}
 }
 func (r *GoliacReconciliatorImpl) UpdateRuleset(ctx context.Context, dryrun bool, ruleset *GithubRuleSet) {
@@ -682,7 +1335,7 @@ func (r *GoliacReconciliatorImpl) UpdateRuleset(ctx context.Context, dryrun bool
 	}
 	logrus.WithFields(map[string]interface{}{"dryrun": dryrun, "author": author, "command": "update_ruleset"}).Infof("ruleset: %s (id: %d) enforcement: %s", ruleset.Name, ruleset.Id, ruleset.Enforcement)
 	if r.executor != nil {
-		r.executor.UpdateRuleset(dryrun, ruleset)
+		r.addError(r.executor.UpdateRuleset(ctx, dryrun, ruleset))
 	}
 }
 func (r *GoliacReconciliatorImpl) DeleteRuleset(ctx context.Context, dryrun bool, rulesetid int) {
@@ -693,7 +1346,7 @@ func (r *GoliacReconciliatorImpl) DeleteRuleset(ctx context.Context, dryrun bool
 	if r.repoconfig.DestructiveOperations.AllowDestructiveRulesets {
 		logrus.WithFields(map[string]interface{}{"dryrun": dryrun, "author": author, "command": "delete_ruleset"}).Infof("ruleset id:%d", rulesetid)
 		if r.executor != nil {
-			r.executor.DeleteRuleset(dryrun, rulesetid)
+			r.addError(r.executor.DeleteRuleset(ctx, dryrun, rulesetid))
 		}
 	}
 }
@@ -705,7 +1358,7 @@ func (r *GoliacReconciliatorImpl) UpdateRepositorySetExternalUser(ctx context.Co
 	logrus.WithFields(map[string]interface{}{"dryrun": dryrun, "author": author, "command": "update_repository_set_external_user"}).Infof("repositoryname: %s collaborator:%s permission:%s", reponame, collaboatorGithubId, permission)
 	remote.UpdateRepositorySetExternalUser(reponame, collaboatorGithubId, permission)
 	if r.executor != nil {
-		r.executor.UpdateRepositorySetExternalUser(dryrun, reponame, collaboatorGithubId, permission)
+		r.addError(r.executor.UpdateRepositorySetExternalUser(ctx, dryrun, reponame, collaboatorGithubId, permission))
 	}
 }
 func (r *GoliacReconciliatorImpl) UpdateRepositoryRemoveExternalUser(ctx context.Context, dryrun bool, remote *MutableGoliacRemoteImpl, reponame string, collaboatorGithubId string) {
@@ -716,24 +1369,169 @@ func (r *GoliacReconciliatorImpl) UpdateRepositoryRemoveExternalUser(ctx context
 	logrus.WithFields(map[string]interface{}{"dryrun": dryrun, "author": author, "command": "update_repository_remove_external_user"}).Infof("repositoryname: %s collaborator:%s", reponame, collaboatorGithubId)
 	remote.UpdateRepositoryRemoveExternalUser(reponame, collaboatorGithubId)
 	if r.executor != nil {
-		r.executor.UpdateRepositoryRemoveExternalUser(dryrun, reponame, collaboatorGithubId)
+		r.addError(r.executor.UpdateRepositoryRemoveExternalUser(ctx, dryrun, reponame, collaboatorGithubId))
+	}
+}
+func (r *GoliacReconciliatorImpl) UpdateRepositorySetVariable(ctx context.Context, dryrun bool, remote *MutableGoliacRemoteImpl, reponame string, name string, value string) {
+	author := "unknown"
+	if a := ctx.Value(KeyAuthor); a != nil {
+		author = a.(string)
+	}
+	logrus.WithFields(map[string]interface{}{"dryrun": dryrun, "author": author, "command": "update_repository_set_variable"}).Infof("repositoryname: %s variable:%s value:%s", reponame, name, value)
+	remote.UpdateRepositorySetVariable(reponame, name, value)
+	if r.executor != nil {
+		r.addError(r.executor.UpdateRepositorySetVariable(ctx, dryrun, reponame, name, value))
+	}
+}
+func (r *GoliacReconciliatorImpl) UpdateRepositoryRemoveVariable(ctx context.Context, dryrun bool, remote *MutableGoliacRemoteImpl, reponame string, name string) {
+	author := "unknown"
+	if a := ctx.Value(KeyAuthor); a != nil {
+		author = a.(string)
+	}
+	if r.repoconfig.DestructiveOperations.AllowDestructiveActions {
+		logrus.WithFields(map[string]interface{}{"dryrun": dryrun, "author": author, "command": "update_repository_remove_variable"}).Infof("repositoryname: %s variable:%s", reponame, name)
+		remote.UpdateRepositoryRemoveVariable(reponame, name)
+		if r.executor != nil {
+			r.addError(r.executor.UpdateRepositoryRemoveVariable(ctx, dryrun, reponame, name))
+		}
+	}
+}
+func (r *GoliacReconciliatorImpl) UpdateRepositorySetSecret(ctx context.Context, dryrun bool, remote *MutableGoliacRemoteImpl, reponame string, name string, value string) {
+	author := "unknown"
+	if a := ctx.Value(KeyAuthor); a != nil {
+		author = a.(string)
+	}
+	// never log the secret value
+	logrus.WithFields(map[string]interface{}{"dryrun": dryrun, "author": author, "command": "update_repository_set_secret"}).Infof("repositoryname: %s secret:%s value:<redacted>", reponame, name)
+	remote.UpdateRepositorySetSecret(reponame, name, value)
+	if r.executor != nil {
+		r.addError(r.executor.UpdateRepositorySetSecret(ctx, dryrun, reponame, name, value))
+	}
+}
+func (r *GoliacReconciliatorImpl) UpdateRepositoryRemoveSecret(ctx context.Context, dryrun bool, remote *MutableGoliacRemoteImpl, reponame string, name string) {
+	author := "unknown"
+	if a := ctx.Value(KeyAuthor); a != nil {
+		author = a.(string)
+	}
+	if r.repoconfig.DestructiveOperations.AllowDestructiveActions {
+		logrus.WithFields(map[string]interface{}{"dryrun": dryrun, "author": author, "command": "update_repository_remove_secret"}).Infof("repositoryname: %s secret:%s", reponame, name)
+		remote.UpdateRepositoryRemoveSecret(reponame, name)
+		if r.executor != nil {
+			r.addError(r.executor.UpdateRepositoryRemoveSecret(ctx, dryrun, reponame, name))
+		}
+	}
+}
+func (r *GoliacReconciliatorImpl) SetOrgVariable(ctx context.Context, dryrun bool, remote *MutableGoliacRemoteImpl, name string, value string) {
+	author := "unknown"
+	if a := ctx.Value(KeyAuthor); a != nil {
+		author = a.(string)
+	}
+	logrus.WithFields(map[string]interface{}{"dryrun": dryrun, "author": author, "command": "set_org_variable"}).Infof("variable:%s value:%s", name, value)
+	remote.SetOrgVariable(name, value)
+	if r.executor != nil {
+		r.addError(r.executor.SetOrgVariable(ctx, dryrun, name, value))
+	}
+}
+func (r *GoliacReconciliatorImpl) RemoveOrgVariable(ctx context.Context, dryrun bool, remote *MutableGoliacRemoteImpl, name string) {
+	author := "unknown"
+	if a := ctx.Value(KeyAuthor); a != nil {
+		author = a.(string)
+	}
+	if r.repoconfig.DestructiveOperations.AllowDestructiveActions {
+		logrus.WithFields(map[string]interface{}{"dryrun": dryrun, "author": author, "command": "remove_org_variable"}).Infof("variable:%s", name)
+		remote.RemoveOrgVariable(name)
+		if r.executor != nil {
+			r.addError(r.executor.RemoveOrgVariable(ctx, dryrun, name))
+		}
+	}
+}
+func (r *GoliacReconciliatorImpl) SetOrgSecret(ctx context.Context, dryrun bool, remote *MutableGoliacRemoteImpl, name string, value string) {
+	author := "unknown"
+	if a := ctx.Value(KeyAuthor); a != nil {
+		author = a.(string)
+	}
+	// never log the secret value
+	logrus.WithFields(map[string]interface{}{"dryrun": dryrun, "author": author, "command": "set_org_secret"}).Infof("secret:%s value:<redacted>", name)
+	remote.SetOrgSecret(name, value)
+	if r.executor != nil {
+		r.addError(r.executor.SetOrgSecret(ctx, dryrun, name, value))
+	}
+}
+func (r *GoliacReconciliatorImpl) RemoveOrgSecret(ctx context.Context, dryrun bool, remote *MutableGoliacRemoteImpl, name string) {
+	author := "unknown"
+	if a := ctx.Value(KeyAuthor); a != nil {
+		author = a.(string)
+	}
+	if r.repoconfig.DestructiveOperations.AllowDestructiveActions {
+		logrus.WithFields(map[string]interface{}{"dryrun": dryrun, "author": author, "command": "remove_org_secret"}).Infof("secret:%s", name)
+		remote.RemoveOrgSecret(name)
+		if r.executor != nil {
+			r.addError(r.executor.RemoveOrgSecret(ctx, dryrun, name))
+		}
+	}
+}
+func (r *GoliacReconciliatorImpl) UpsertCustomProperty(ctx context.Context, dryrun bool, remote *MutableGoliacRemoteImpl, property *GithubCustomProperty) {
+	author := "unknown"
+	if a := ctx.Value(KeyAuthor); a != nil {
+		author = a.(string)
+	}
+	logrus.WithFields(map[string]interface{}{"dryrun": dryrun, "author": author, "command": "upsert_custom_property"}).Infof("property:%s type:%s required:%v default:%s allowed_values:%s", property.Name, property.ValueType, property.Required, property.DefaultValue, strings.Join(property.AllowedValues, ","))
+	remote.UpsertCustomProperty(property)
+	if r.executor != nil {
+		r.addError(r.executor.UpsertCustomProperty(ctx, dryrun, property))
+	}
+}
+func (r *GoliacReconciliatorImpl) DeleteCustomProperty(ctx context.Context, dryrun bool, remote *MutableGoliacRemoteImpl, name string) {
+	author := "unknown"
+	if a := ctx.Value(KeyAuthor); a != nil {
+		author = a.(string)
+	}
+	if r.repoconfig.DestructiveOperations.AllowDestructiveProperties {
+		logrus.WithFields(map[string]interface{}{"dryrun": dryrun, "author": author, "command": "delete_custom_property"}).Infof("property:%s", name)
+		remote.DeleteCustomProperty(name)
+		if r.executor != nil {
+			r.addError(r.executor.DeleteCustomProperty(ctx, dryrun, name))
+		}
+	}
+}
+func (r *GoliacReconciliatorImpl) UpdateRepositorySetCustomProperty(ctx context.Context, dryrun bool, remote *MutableGoliacRemoteImpl, reponame string, name string, value []string) {
+	author := "unknown"
+	if a := ctx.Value(KeyAuthor); a != nil {
+		author = a.(string)
+	}
+	logrus.WithFields(map[string]interface{}{"dryrun": dryrun, "author": author, "command": "update_repository_set_custom_property"}).Infof("repositoryname: %s property:%s value:%s", reponame, name, strings.Join(value, ","))
+	remote.UpdateRepositorySetCustomProperty(reponame, name, value)
+	if r.executor != nil {
+		r.addError(r.executor.UpdateRepositorySetCustomProperty(ctx, dryrun, reponame, name, value))
+	}
+}
+func (r *GoliacReconciliatorImpl) UpdateOrganizationSettings(ctx context.Context, dryrun bool, remote *MutableGoliacRemoteImpl, settings *GithubOrganizationSettingsUpdate) {
+	author := "unknown"
+	if a := ctx.Value(KeyAuthor); a != nil {
+		author = a.(string)
+	}
+	logrus.WithFields(map[string]interface{}{"dryrun": dryrun, "author": author, "command": "update_organization_settings"}).Infof("settings: %v", settings.Payload())
+	remote.UpdateOrganizationSettings(settings)
+	if r.executor != nil {
+		r.addError(r.executor.UpdateOrganizationSettings(ctx, dryrun, settings))
 	}
 }
 func (r *GoliacReconciliatorImpl) Begin(ctx context.Context, dryrun bool) {
 	logrus.WithFields(map[string]interface{}{"dryrun": dryrun}).Debugf("reconciliation begin")
 	if r.executor != nil {
-		r.executor.Begin(dryrun)
+		r.executor.Begin(ctx, dryrun)
 	}
 }
 func (r *GoliacReconciliatorImpl) Rollback(ctx context.Context, dryrun bool, err error) {
 	logrus.WithFields(map[string]interface{}{"dryrun": dryrun}).Debugf("reconciliation rollback")
 	if r.executor != nil {
-		r.executor.Rollback(dryrun, err)
+		r.executor.Rollback(ctx, dryrun, err)
 	}
 }
-func (r *GoliacReconciliatorImpl) Commit(ctx context.Context, dryrun bool) {
+func (r *GoliacReconciliatorImpl) Commit(ctx context.Context, dryrun bool) error {
 	logrus.WithFields(map[string]interface{}{"dryrun": dryrun}).Debugf("reconciliation commit")
 	if r.executor != nil {
-		r.executor.Commit(dryrun)
+		return r.executor.Commit(ctx, dryrun)
 	}
+	return nil
 }
@@ -3,7 +3,9 @@ package engine
 import (
 	"context"
 	"fmt"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/Alayacare/goliac/internal/config"
 	"github.com/Alayacare/goliac/internal/entity"
@@ -17,24 +19,35 @@ import (
 type GoliacLocalMock struct {
 	users     map[string]*entity.User
 	externals map[string]*entity.User
+	protected map[string]*entity.User
 	teams     map[string]*entity.Team
 	repos     map[string]*entity.Repository
 	rulesets  map[string]*entity.RuleSet
+	requests  map[string]*entity.AccessRequest
 }
 
-func (m *GoliacLocalMock) Clone(accesstoken, repositoryUrl, branch string) error {
+func (m *GoliacLocalMock) Clone(ctx context.Context, accesstoken, repositoryUrl, branch string) error {
 	return nil
 }
 func (m *GoliacLocalMock) ListCommitsFromTag(tagname string) ([]*object.Commit, error) {
 	return nil, fmt.Errorf("not tag %s found", tagname)
 }
+func (m *GoliacLocalMock) GetTagCommit(tagname string) (*object.Commit, error) {
+	return nil, nil
+}
 func (m *GoliacLocalMock) GetHeadCommit() (*object.Commit, error) {
 	return nil, nil
 }
 func (m *GoliacLocalMock) CheckoutCommit(commit *object.Commit) error {
 	return nil
 }
-func (m *GoliacLocalMock) PushTag(tagname string, hash plumbing.Hash, accesstoken string) error {
+func (m *GoliacLocalMock) PushTag(ctx context.Context, tagname string, hash plumbing.Hash, accesstoken string) error {
+	return nil
+}
+func (m *GoliacLocalMock) LoadAppliedProtectedUsers(tagname string) error {
+	return nil
+}
+func (m *GoliacLocalMock) LoadAppliedProtectedUsersFromDirectory(path string, tagname string) error {
 	return nil
 }
 func (m *GoliacLocalMock) LoadRepoConfig() (error, *config.RepositoryConfig) {
@@ -58,75 +71,142 @@ func (m *GoliacLocalMock) Users() map[string]*entity.User {
 func (m *GoliacLocalMock) ExternalUsers() map[string]*entity.User {
 	return m.externals
 }
+func (m *GoliacLocalMock) ProtectedUsers() map[string]*entity.User {
+	return m.protected
+}
+func (m *GoliacLocalMock) AppliedProtectedUsers() map[string]*entity.User {
+	return m.protected
+}
 func (m *GoliacLocalMock) RuleSets() map[string]*entity.RuleSet {
 	return m.rulesets
 }
-func (m *GoliacLocalMock) UpdateAndCommitCodeOwners(repoconfig *config.RepositoryConfig, dryrun bool, accesstoken string, branch string, tagname string) error {
-	return nil
+func (m *GoliacLocalMock) AccessRequests() map[string]*entity.AccessRequest {
+	return m.requests
 }
-func (m *GoliacLocalMock) SyncUsersAndTeams(repoconfig *config.RepositoryConfig, plugin UserSyncPlugin, dryrun bool) error {
+func (m *GoliacLocalMock) UpdateAndCommitCodeOwners(ctx context.Context, repoconfig *config.RepositoryConfig, dryrun bool, accesstoken string, branch string, tagname string) error {
 	return nil
 }
+func (m *GoliacLocalMock) SyncUsersAndTeams(ctx context.Context, repoconfig *config.RepositoryConfig, plugin UserSyncPlugin, accesstoken string, branch string, dryrun bool) (*UserSyncReport, error) {
+	return &UserSyncReport{}, nil
+}
 func (m *GoliacLocalMock) Close() {
 
 }
 
 type GoliacRemoteMock struct {
-	users      map[string]string
-	teams      map[string]*GithubTeam // key is the slug team
-	repos      map[string]*GithubRepository
-	teamsrepos map[string]map[string]*GithubTeamRepo // key is the slug team
-	rulesets   map[string]*GithubRuleSet
-	appids     map[string]int
+	users       map[string]string
+	teams       map[string]*GithubTeam // key is the slug team
+	repos       map[string]*GithubRepository
+	teamsrepos  map[string]map[string]*GithubTeamRepo // key is the slug team
+	rulesets    map[string]*GithubRuleSet
+	appids      map[string]int
+	actions     map[string]*GithubActions
+	orgactions  *GithubActions
+	properties  map[string]*GithubCustomProperty
+	reposprops  map[string]map[string][]string
+	orgsettings *GithubOrganizationSettings
+	extgroups   map[string]*GithubExternalGroup
 }
 
-func (m *GoliacRemoteMock) Load() error {
+/*
+ * newGoliacRemoteMock returns an empty GoliacRemoteMock (no user, team, repository, ...)
+ */
+func newGoliacRemoteMock() *GoliacRemoteMock {
+	return &GoliacRemoteMock{
+		users:       make(map[string]string),
+		teams:       make(map[string]*GithubTeam),
+		repos:       make(map[string]*GithubRepository),
+		teamsrepos:  make(map[string]map[string]*GithubTeamRepo),
+		rulesets:    make(map[string]*GithubRuleSet),
+		appids:      make(map[string]int),
+		actions:     make(map[string]*GithubActions),
+		orgactions:  &GithubActions{Variables: map[string]string{}, Secrets: map[string]string{}},
+		properties:  make(map[string]*GithubCustomProperty),
+		reposprops:  make(map[string]map[string][]string),
+		orgsettings: &GithubOrganizationSettings{},
+		extgroups:   make(map[string]*GithubExternalGroup),
+	}
+}
+
+func (m *GoliacRemoteMock) Load(ctx context.Context) error {
 	return nil
 }
 func (m *GoliacRemoteMock) IsEnterprise() bool {
 	return true
 }
+func (m *GoliacRemoteMock) GetUserLogin(ctx context.Context, githubid string) (string, error) {
+	return githubid, nil
+}
+func (m *GoliacRemoteMock) ExternalGroups(ctx context.Context) map[string]*GithubExternalGroup {
+	return m.extgroups
+}
 func (m *GoliacRemoteMock) FlushCache() {
 
 }
-func (m *GoliacRemoteMock) RuleSets() map[string]*GithubRuleSet {
+func (m *GoliacRemoteMock) InvalidateCache(caches ...string) {
+}
+func (m *GoliacRemoteMock) RuleSets(ctx context.Context) map[string]*GithubRuleSet {
 	return m.rulesets
 }
-func (m *GoliacRemoteMock) Users() map[string]string {
+func (m *GoliacRemoteMock) Users(ctx context.Context) map[string]string {
 	return m.users
 }
 
-func (m *GoliacRemoteMock) TeamSlugByName() map[string]string {
+func (m *GoliacRemoteMock) TeamSlugByName(ctx context.Context) map[string]string {
 	slugs := make(map[string]string)
 	for _, v := range m.teams {
 		slugs[v.Name] = slug.Make(v.Name)
 	}
 	return slugs
 }
-func (m *GoliacRemoteMock) Teams() map[string]*GithubTeam {
+func (m *GoliacRemoteMock) Teams(ctx context.Context) map[string]*GithubTeam {
 	return m.teams
 }
-func (m *GoliacRemoteMock) Repositories() map[string]*GithubRepository {
+func (m *GoliacRemoteMock) Repositories(ctx context.Context) map[string]*GithubRepository {
 	return m.repos
 }
 func (m *GoliacRemoteMock) RepositoriesByRefId() map[string]*GithubRepository {
 	return make(map[string]*GithubRepository)
 }
-func (m *GoliacRemoteMock) TeamRepositories() map[string]map[string]*GithubTeamRepo {
+func (m *GoliacRemoteMock) TeamRepositories(ctx context.Context) map[string]map[string]*GithubTeamRepo {
 	return m.teamsrepos
 }
-func (m *GoliacRemoteMock) AppIds() map[string]int {
+func (m *GoliacRemoteMock) AppIds(ctx context.Context) map[string]int {
 	return m.appids
 }
+func (m *GoliacRemoteMock) LoadActions(ctx context.Context) error {
+	return nil
+}
+func (m *GoliacRemoteMock) RepositoriesActions(ctx context.Context) map[string]*GithubActions {
+	return m.actions
+}
+func (m *GoliacRemoteMock) OrgActions(ctx context.Context) *GithubActions {
+	return m.orgactions
+}
+func (m *GoliacRemoteMock) CustomProperties(ctx context.Context) map[string]*GithubCustomProperty {
+	return m.properties
+}
+func (m *GoliacRemoteMock) RepositoriesCustomProperties(ctx context.Context) map[string]map[string][]string {
+	return m.reposprops
+}
+func (m *GoliacRemoteMock) OrganizationSettings(ctx context.Context) *GithubOrganizationSettings {
+	return m.orgsettings
+}
 
 type ReconciliatorListenerRecorder struct {
 	UsersCreated map[string]string
 	UsersRemoved map[string]string
+	// invitations
+	UsersInvitedByEmail  map[string]string
+	InvitationsCancelled map[string]string
 
 	TeamsCreated      map[string][]string
 	TeamMemberAdded   map[string][]string
 	TeamMemberRemoved map[string][]string
 	TeamDeleted       map[string]bool
+	// Enterprise Managed Users
+	TeamsExternalGroupSet     map[string]string
+	TeamsExternalGroupRemoved map[string]bool
 
 	RepositoryCreated              map[string]bool
 	RepositoryTeamAdded            map[string][]string
@@ -141,16 +221,35 @@ type ReconciliatorListenerRecorder struct {
 	RuleSetCreated map[string]*GithubRuleSet
 	RuleSetUpdated map[string]*GithubRuleSet
 	RuleSetDeleted []int
+
+	RepositoriesVariablesSet     map[string]map[string]string
+	RepositoriesVariablesRemoved map[string][]string
+	RepositoriesSecretsSet       map[string]map[string]string
+	RepositoriesSecretsRemoved   map[string][]string
+	OrgVariablesSet              map[string]string
+	OrgVariablesRemoved          []string
+	OrgSecretsSet                map[string]string
+	OrgSecretsRemoved            []string
+
+	CustomPropertiesUpserted     map[string]*GithubCustomProperty
+	CustomPropertiesDeleted      []string
+	RepositoriesCustomProperties map[string]map[string][]string
+
+	OrganizationSettingsUpdated *GithubOrganizationSettingsUpdate
 }
 
 func NewReconciliatorListenerRecorder() *ReconciliatorListenerRecorder {
 	r := ReconciliatorListenerRecorder{
 		UsersCreated:                   make(map[string]string),
 		UsersRemoved:                   make(map[string]string),
+		UsersInvitedByEmail:            make(map[string]string),
+		InvitationsCancelled:           make(map[string]string),
 		TeamsCreated:                   make(map[string][]string),
 		TeamMemberAdded:                make(map[string][]string),
 		TeamMemberRemoved:              make(map[string][]string),
 		TeamDeleted:                    make(map[string]bool),
+		TeamsExternalGroupSet:          make(map[string]string),
+		TeamsExternalGroupRemoved:      make(map[string]bool),
 		RepositoryCreated:              make(map[string]bool),
 		RepositoryTeamAdded:            make(map[string][]string),
 		RepositoryTeamUpdated:          make(map[string][]string),
@@ -163,68 +262,171 @@ func NewReconciliatorListenerRecorder() *ReconciliatorListenerRecorder {
 		RuleSetCreated:                 make(map[string]*GithubRuleSet),
 		RuleSetUpdated:                 make(map[string]*GithubRuleSet),
 		RuleSetDeleted:                 make([]int, 0),
+		RepositoriesVariablesSet:       make(map[string]map[string]string),
+		RepositoriesVariablesRemoved:   make(map[string][]string),
+		RepositoriesSecretsSet:         make(map[string]map[string]string),
+		RepositoriesSecretsRemoved:     make(map[string][]string),
+		OrgVariablesSet:                make(map[string]string),
+		OrgVariablesRemoved:            make([]string, 0),
+		OrgSecretsSet:                  make(map[string]string),
+		OrgSecretsRemoved:              make([]string, 0),
+		CustomPropertiesUpserted:       make(map[string]*GithubCustomProperty),
+		CustomPropertiesDeleted:        make([]string, 0),
+		RepositoriesCustomProperties:   make(map[string]map[string][]string),
 	}
 	return &r
 }
-func (r *ReconciliatorListenerRecorder) AddUserToOrg(dryrun bool, ghuserid string) {
+func (r *ReconciliatorListenerRecorder) AddUserToOrg(ctx context.Context, dryrun bool, ghuserid string) error {
 	r.UsersCreated[ghuserid] = ghuserid
+	return nil
 }
-func (r *ReconciliatorListenerRecorder) RemoveUserFromOrg(dryrun bool, ghuserid string) {
+func (r *ReconciliatorListenerRecorder) RemoveUserFromOrg(ctx context.Context, dryrun bool, ghuserid string) error {
 	r.UsersRemoved[ghuserid] = ghuserid
+	return nil
+}
+func (r *ReconciliatorListenerRecorder) InviteUserByEmail(ctx context.Context, dryrun bool, email string) error {
+	r.UsersInvitedByEmail[email] = email
+	return nil
 }
-func (r *ReconciliatorListenerRecorder) CreateTeam(dryrun bool, teamname string, description string, members []string) {
+func (r *ReconciliatorListenerRecorder) CancelOrgInvitation(ctx context.Context, dryrun bool, invitee string) error {
+	r.InvitationsCancelled[invitee] = invitee
+	return nil
+}
+func (r *ReconciliatorListenerRecorder) CreateTeam(ctx context.Context, dryrun bool, teamname string, description string, members []string) error {
 	r.TeamsCreated[teamname] = append(r.TeamsCreated[teamname], members...)
+	return nil
 }
-func (r *ReconciliatorListenerRecorder) UpdateTeamAddMember(dryrun bool, teamslug string, username string, role string) {
+func (r *ReconciliatorListenerRecorder) UpdateTeamAddMember(ctx context.Context, dryrun bool, teamslug string, username string, role string) error {
 	r.TeamMemberAdded[teamslug] = append(r.TeamMemberAdded[teamslug], username)
+	return nil
 }
-func (r *ReconciliatorListenerRecorder) UpdateTeamRemoveMember(dryrun bool, teamslug string, username string) {
+func (r *ReconciliatorListenerRecorder) UpdateTeamRemoveMember(ctx context.Context, dryrun bool, teamslug string, username string) error {
 	r.TeamMemberRemoved[teamslug] = append(r.TeamMemberRemoved[teamslug], username)
+	return nil
 }
-func (r *ReconciliatorListenerRecorder) DeleteTeam(dryrun bool, teamslug string) {
+func (r *ReconciliatorListenerRecorder) DeleteTeam(ctx context.Context, dryrun bool, teamslug string) error {
 	r.TeamDeleted[teamslug] = true
+	return nil
+}
+func (r *ReconciliatorListenerRecorder) UpdateTeamSetExternalGroup(ctx context.Context, dryrun bool, teamslug string, groupname string) error {
+	r.TeamsExternalGroupSet[teamslug] = groupname
+	return nil
 }
-func (r *ReconciliatorListenerRecorder) CreateRepository(dryrun bool, reponame string, descrition string, writers []string, readers []string, public bool) {
+func (r *ReconciliatorListenerRecorder) UpdateTeamRemoveExternalGroup(ctx context.Context, dryrun bool, teamslug string) error {
+	r.TeamsExternalGroupRemoved[teamslug] = true
+	return nil
+}
+func (r *ReconciliatorListenerRecorder) CreateRepository(ctx context.Context, dryrun bool, reponame string, descrition string, writers []string, readers []string, public bool) error {
 	r.RepositoryCreated[reponame] = true
+	return nil
 }
-func (r *ReconciliatorListenerRecorder) UpdateRepositoryAddTeamAccess(dryrun bool, reponame string, teamslug string, permission string) {
+func (r *ReconciliatorListenerRecorder) UpdateRepositoryAddTeamAccess(ctx context.Context, dryrun bool, reponame string, teamslug string, permission string) error {
 	r.RepositoryTeamAdded[reponame] = append(r.RepositoryTeamAdded[reponame], teamslug)
+	return nil
 }
-func (r *ReconciliatorListenerRecorder) UpdateRepositoryUpdateTeamAccess(dryrun bool, reponame string, teamslug string, permission string) {
+func (r *ReconciliatorListenerRecorder) UpdateRepositoryUpdateTeamAccess(ctx context.Context, dryrun bool, reponame string, teamslug string, permission string) error {
 	r.RepositoryTeamUpdated[reponame] = append(r.RepositoryTeamUpdated[reponame], teamslug)
+	return nil
 }
-func (r *ReconciliatorListenerRecorder) UpdateRepositoryRemoveTeamAccess(dryrun bool, reponame string, teamslug string) {
+func (r *ReconciliatorListenerRecorder) UpdateRepositoryRemoveTeamAccess(ctx context.Context, dryrun bool, reponame string, teamslug string) error {
 	r.RepositoryTeamRemoved[reponame] = append(r.RepositoryTeamRemoved[reponame], teamslug)
+	return nil
 }
-func (r *ReconciliatorListenerRecorder) DeleteRepository(dryrun bool, reponame string) {
+func (r *ReconciliatorListenerRecorder) DeleteRepository(ctx context.Context, dryrun bool, reponame string) error {
 	r.RepositoriesDeleted[reponame] = true
+	return nil
 }
-func (r *ReconciliatorListenerRecorder) UpdateRepositoryUpdatePrivate(dryrun bool, reponame string, private bool) {
+func (r *ReconciliatorListenerRecorder) UpdateRepositoryUpdatePrivate(ctx context.Context, dryrun bool, reponame string, private bool) error {
 	r.RepositoriesUpdatePrivate[reponame] = true
+	return nil
 }
-func (r *ReconciliatorListenerRecorder) UpdateRepositoryUpdateArchived(dryrun bool, reponame string, archived bool) {
+func (r *ReconciliatorListenerRecorder) UpdateRepositoryUpdateArchived(ctx context.Context, dryrun bool, reponame string, archived bool) error {
 	r.RepositoriesUpdateArchived[reponame] = true
+	return nil
 }
-func (r *ReconciliatorListenerRecorder) UpdateRepositorySetExternalUser(dryrun bool, reponame string, githubid string, permission string) {
+func (r *ReconciliatorListenerRecorder) UpdateRepositorySetExternalUser(ctx context.Context, dryrun bool, reponame string, githubid string, permission string) error {
 	r.RepositoriesSetExternalUser[githubid] = permission
+	return nil
 }
-func (r *ReconciliatorListenerRecorder) UpdateRepositoryRemoveExternalUser(dryrun bool, reponame string, githubid string) {
+func (r *ReconciliatorListenerRecorder) UpdateRepositoryRemoveExternalUser(ctx context.Context, dryrun bool, reponame string, githubid string) error {
 	r.RepositoriesRemoveExternalUser[githubid] = true
+	return nil
 }
-func (r *ReconciliatorListenerRecorder) AddRuleset(dryrun bool, ruleset *GithubRuleSet) {
+func (r *ReconciliatorListenerRecorder) AddRuleset(ctx context.Context, dryrun bool, ruleset *GithubRuleSet) error {
 	r.RuleSetCreated[ruleset.Name] = ruleset
+	return nil
 }
-func (r *ReconciliatorListenerRecorder) UpdateRuleset(dryrun bool, ruleset *GithubRuleSet) {
+func (r *ReconciliatorListenerRecorder) UpdateRuleset(ctx context.Context, dryrun bool, ruleset *GithubRuleSet) error {
 	r.RuleSetUpdated[ruleset.Name] = ruleset
+	return nil
 }
-func (r *ReconciliatorListenerRecorder) DeleteRuleset(dryrun bool, rulesetid int) {
+func (r *ReconciliatorListenerRecorder) DeleteRuleset(ctx context.Context, dryrun bool, rulesetid int) error {
 	r.RuleSetDeleted = append(r.RuleSetDeleted, rulesetid)
+	return nil
 }
-func (r *ReconciliatorListenerRecorder) Begin(dryrun bool) {
+func (r *ReconciliatorListenerRecorder) UpdateRepositorySetVariable(ctx context.Context, dryrun bool, reponame string, name string, value string) error {
+	if r.RepositoriesVariablesSet[reponame] == nil {
+		r.RepositoriesVariablesSet[reponame] = make(map[string]string)
+	}
+	r.RepositoriesVariablesSet[reponame][name] = value
+	return nil
 }
-func (r *ReconciliatorListenerRecorder) Rollback(dryrun bool, err error) {
+func (r *ReconciliatorListenerRecorder) UpdateRepositoryRemoveVariable(ctx context.Context, dryrun bool, reponame string, name string) error {
+	r.RepositoriesVariablesRemoved[reponame] = append(r.RepositoriesVariablesRemoved[reponame], name)
+	return nil
 }
-func (r *ReconciliatorListenerRecorder) Commit(dryrun bool) {
+func (r *ReconciliatorListenerRecorder) UpdateRepositorySetSecret(ctx context.Context, dryrun bool, reponame string, name string, value string) error {
+	if r.RepositoriesSecretsSet[reponame] == nil {
+		r.RepositoriesSecretsSet[reponame] = make(map[string]string)
+	}
+	r.RepositoriesSecretsSet[reponame][name] = value
+	return nil
+}
+func (r *ReconciliatorListenerRecorder) UpdateRepositoryRemoveSecret(ctx context.Context, dryrun bool, reponame string, name string) error {
+	r.RepositoriesSecretsRemoved[reponame] = append(r.RepositoriesSecretsRemoved[reponame], name)
+	return nil
+}
+func (r *ReconciliatorListenerRecorder) SetOrgVariable(ctx context.Context, dryrun bool, name string, value string) error {
+	r.OrgVariablesSet[name] = value
+	return nil
+}
+func (r *ReconciliatorListenerRecorder) RemoveOrgVariable(ctx context.Context, dryrun bool, name string) error {
+	r.OrgVariablesRemoved = append(r.OrgVariablesRemoved, name)
+	return nil
+}
+func (r *ReconciliatorListenerRecorder) SetOrgSecret(ctx context.Context, dryrun bool, name string, value string) error {
+	r.OrgSecretsSet[name] = value
+	return nil
+}
+func (r *ReconciliatorListenerRecorder) RemoveOrgSecret(ctx context.Context, dryrun bool, name string) error {
+	r.OrgSecretsRemoved = append(r.OrgSecretsRemoved, name)
+	return nil
+}
+func (r *ReconciliatorListenerRecorder) UpsertCustomProperty(ctx context.Context, dryrun bool, property *GithubCustomProperty) error {
+	r.CustomPropertiesUpserted[property.Name] = property
+	return nil
+}
+func (r *ReconciliatorListenerRecorder) DeleteCustomProperty(ctx context.Context, dryrun bool, name string) error {
+	r.CustomPropertiesDeleted = append(r.CustomPropertiesDeleted, name)
+	return nil
+}
+func (r *ReconciliatorListenerRecorder) UpdateRepositorySetCustomProperty(ctx context.Context, dryrun bool, reponame string, name string, value []string) error {
+	if _, ok := r.RepositoriesCustomProperties[reponame]; !ok {
+		r.RepositoriesCustomProperties[reponame] = make(map[string][]string)
+	}
+	r.RepositoriesCustomProperties[reponame][name] = value
+	return nil
+}
+func (r *ReconciliatorListenerRecorder) UpdateOrganizationSettings(ctx context.Context, dryrun bool, settings *GithubOrganizationSettingsUpdate) error {
+	r.OrganizationSettingsUpdated = settings
+	return nil
+}
+func (r *ReconciliatorListenerRecorder) Begin(ctx context.Context, dryrun bool) {
+}
+func (r *ReconciliatorListenerRecorder) Rollback(ctx context.Context, dryrun bool, err error) {
+}
+func (r *ReconciliatorListenerRecorder) Commit(ctx context.Context, dryrun bool) error {
+	return nil
 }
 
 func TestReconciliation(t *testing.T) {
@@ -434,7 +636,7 @@ func TestReconciliation(t *testing.T) {
 		r.Reconciliate(context.TODO(), &local, &remote, "teams", false)
 
 		// 1 members added
-		assert.Equal(t, "exist-ing", remote.TeamSlugByName()["exist ing"])
+		assert.Equal(t, "exist-ing", remote.TeamSlugByName(context.TODO())["exist ing"])
 		assert.Equal(t, 0, len(recorder.TeamsCreated))
 		assert.Equal(t, 1, len(recorder.TeamMemberAdded["exist-ing"]))
 	})
@@ -1247,6 +1449,62 @@ func TestReconciliation(t *testing.T) {
 		assert.Equal(t, 0, len(recorder.RepositoriesRemoveExternalUser))
 	})
 
+	t.Run("happy path: existing repo with an expired external collaborator", func(t *testing.T) {
+		recorder := NewReconciliatorListenerRecorder()
+
+		repoconf := config.RepositoryConfig{}
+
+		r := NewGoliacReconciliatorImpl(recorder, &repoconf)
+
+		local := GoliacLocalMock{
+			users:     make(map[string]*entity.User),
+			externals: make(map[string]*entity.User),
+			teams:     make(map[string]*entity.Team),
+			repos:     make(map[string]*entity.Repository),
+		}
+
+		outside1 := entity.User{}
+		outside1.Name = "outside1"
+		outside1.Spec.GithubID = "outside1-githubid"
+		local.externals["outside1"] = &outside1
+
+		outside2 := entity.User{}
+		outside2.Name = "outside2"
+		outside2.Spec.GithubID = "outside2-githubid"
+		outside2.Spec.Expires = "2020-01-01"
+		local.externals["outside2"] = &outside2
+
+		lRepo := &entity.Repository{}
+		lRepo.Name = "myrepo"
+		lRepo.Spec.Readers = []string{}
+		lRepo.Spec.Writers = []string{}
+		lRepo.Spec.ExternalUserWriters = []string{"outside2"}
+		lRepo.Spec.ExternalUserReaders = []string{"outside1"}
+		lRepo.Spec.ExternalUserExpirations = map[string]string{"outside1": "2020-01-01"}
+		local.repos["myrepo"] = lRepo
+
+		remote := GoliacRemoteMock{
+			users:      make(map[string]string),
+			teams:      make(map[string]*GithubTeam),
+			repos:      make(map[string]*GithubRepository),
+			teamsrepos: make(map[string]map[string]*GithubTeamRepo),
+			rulesets:   make(map[string]*GithubRuleSet),
+			appids:     make(map[string]int),
+		}
+		rRepo := GithubRepository{
+			Name:          "myrepo",
+			ExternalUsers: make(map[string]string),
+		}
+		rRepo.ExternalUsers["outside1-githubid"] = "READ"
+		remote.repos["myrepo"] = &rRepo
+
+		r.Reconciliate(context.TODO(), &local, &remote, "teams", false)
+
+		// outside1 access is removed, outside2 access is not granted
+		assert.Equal(t, 0, len(recorder.RepositoriesSetExternalUser))
+		assert.Equal(t, 1, len(recorder.RepositoriesRemoveExternalUser))
+	})
+
 	t.Run("happy path: removed repo without destructive operation", func(t *testing.T) {
 		recorder := NewReconciliatorListenerRecorder()
 
@@ -1358,15 +1616,9 @@ func TestReconciliationRulesets(t *testing.T) {
 		recorder := NewReconciliatorListenerRecorder()
 
 		repoconf := config.RepositoryConfig{
-			Rulesets: make([]struct {
-				Pattern string
-				Ruleset string
-			}, 0),
-		}
-		repoconf.Rulesets = append(repoconf.Rulesets, struct {
-			Pattern string
-			Ruleset string
-		}{
+			Rulesets: make([]config.RulesetMapping, 0),
+		}
+		repoconf.Rulesets = append(repoconf.Rulesets, config.RulesetMapping{
 			Pattern: ".*",
 			Ruleset: "new",
 		})
@@ -1412,15 +1664,9 @@ func TestReconciliationRulesets(t *testing.T) {
 		recorder := NewReconciliatorListenerRecorder()
 
 		repoconf := config.RepositoryConfig{
-			Rulesets: make([]struct {
-				Pattern string
-				Ruleset string
-			}, 0),
-		}
-		repoconf.Rulesets = append(repoconf.Rulesets, struct {
-			Pattern string
-			Ruleset string
-		}{
+			Rulesets: make([]config.RulesetMapping, 0),
+		}
+		repoconf.Rulesets = append(repoconf.Rulesets, config.RulesetMapping{
 			Pattern: ".*",
 			Ruleset: "update",
 		})
@@ -1474,10 +1720,7 @@ func TestReconciliationRulesets(t *testing.T) {
 		recorder := NewReconciliatorListenerRecorder()
 
 		repoconf := config.RepositoryConfig{
-			Rulesets: make([]struct {
-				Pattern string
-				Ruleset string
-			}, 0),
+			Rulesets: make([]config.RulesetMapping, 0),
 		}
 		repoconf.DestructiveOperations.AllowDestructiveRulesets = true
 
@@ -1515,3 +1758,944 @@ func TestReconciliationRulesets(t *testing.T) {
 		assert.Equal(t, 1, len(recorder.RuleSetDeleted))
 	})
 }
+
+type SecretProviderMock struct {
+	secrets map[string]string
+}
+
+func (s *SecretProviderMock) GetSecret(key string) (string, error) {
+	if v, ok := s.secrets[key]; ok {
+		return v, nil
+	}
+	return "", fmt.Errorf("secret %s not found", key)
+}
+
+func (s *SecretProviderMock) CheckRepositoryKey(key string) error {
+	if key == "org" {
+		return fmt.Errorf("%s is not a repository secret", key)
+	}
+	return nil
+}
+
+func TestReconciliationActions(t *testing.T) {
+	RegisterSecretProvider("mock", &SecretProviderMock{
+		secrets: map[string]string{
+			"npm": "npmtoken",
+			"org": "orgtoken",
+		},
+	})
+
+	newRemote := func() *GoliacRemoteMock {
+		remote := newGoliacRemoteMock()
+		remote.repos["myrepo"] = &GithubRepository{Name: "myrepo", ExternalUsers: map[string]string{}}
+		return remote
+	}
+	newLocal := func() *GoliacLocalMock {
+		local := GoliacLocalMock{
+			users: make(map[string]*entity.User),
+			teams: make(map[string]*entity.Team),
+			repos: make(map[string]*entity.Repository),
+		}
+		repo := &entity.Repository{}
+		repo.Name = "myrepo"
+		repo.Spec.Variables = map[string]string{"ENV": "prod"}
+		repo.Spec.Secrets = []config.SecretReference{{Name: "NPM_TOKEN", Provider: "mock", Key: "npm"}}
+		local.repos["myrepo"] = repo
+		return &local
+	}
+
+	t.Run("happy path: new repository variable and secret", func(t *testing.T) {
+		recorder := NewReconciliatorListenerRecorder()
+		repoconf := config.RepositoryConfig{}
+		r := NewGoliacReconciliatorImpl(recorder, &repoconf)
+
+		err := r.Reconciliate(context.TODO(), newLocal(), newRemote(), "teams", false)
+
+		assert.Nil(t, err)
+		assert.Equal(t, "prod", recorder.RepositoriesVariablesSet["myrepo"]["ENV"])
+		assert.Equal(t, "npmtoken", recorder.RepositoriesSecretsSet["myrepo"]["NPM_TOKEN"])
+	})
+
+	t.Run("not happy path: repository secret with a restricted key", func(t *testing.T) {
+		recorder := NewReconciliatorListenerRecorder()
+		repoconf := config.RepositoryConfig{}
+		r := NewGoliacReconciliatorImpl(recorder, &repoconf)
+
+		local := newLocal()
+		local.repos["myrepo"].Spec.Secrets = []config.SecretReference{{Name: "NPM_TOKEN", Provider: "mock", Key: "org"}}
+		err := r.Reconciliate(context.TODO(), local, newRemote(), "teams", false)
+
+		assert.NotNil(t, err)
+		assert.Contains(t, err.Error(), "invalid key for secret NPM_TOKEN")
+		assert.Equal(t, 0, len(recorder.RepositoriesSecretsSet))
+	})
+
+	t.Run("happy path: secret already pushed with the same value", func(t *testing.T) {
+		recorder := NewReconciliatorListenerRecorder()
+		repoconf := config.RepositoryConfig{}
+		r := NewGoliacReconciliatorImpl(recorder, &repoconf)
+
+		remote := newRemote()
+		remote.actions["myrepo"] = &GithubActions{
+			Variables: map[string]string{"ENV": "prod"},
+			Secrets:   map[string]string{"NPM_TOKEN": hashSecretValue("npmtoken")},
+		}
+
+		err := r.Reconciliate(context.TODO(), newLocal(), remote, "teams", false)
+
+		assert.Nil(t, err)
+		assert.Equal(t, 0, len(recorder.RepositoriesVariablesSet))
+		assert.Equal(t, 0, len(recorder.RepositoriesSecretsSet))
+	})
+
+	t.Run("happy path: secret with an unknown value is pushed again", func(t *testing.T) {
+		recorder := NewReconciliatorListenerRecorder()
+		repoconf := config.RepositoryConfig{}
+		r := NewGoliacReconciliatorImpl(recorder, &repoconf)
+
+		remote := newRemote()
+		remote.actions["myrepo"] = &GithubActions{
+			Variables: map[string]string{"ENV": "staging"},
+			Secrets:   map[string]string{"NPM_TOKEN": ""},
+		}
+
+		err := r.Reconciliate(context.TODO(), newLocal(), remote, "teams", false)
+
+		assert.Nil(t, err)
+		assert.Equal(t, "prod", recorder.RepositoriesVariablesSet["myrepo"]["ENV"])
+		assert.Equal(t, 1, len(recorder.RepositoriesSecretsSet["myrepo"]))
+	})
+
+	t.Run("happy path: removed variables and secrets only with destructive operations", func(t *testing.T) {
+		remote := newRemote()
+		remote.actions["myrepo"] = &GithubActions{
+			Variables: map[string]string{"ENV": "prod", "OLD": "value"},
+			Secrets:   map[string]string{"NPM_TOKEN": hashSecretValue("npmtoken"), "OLD_TOKEN": ""},
+		}
+
+		recorder := NewReconciliatorListenerRecorder()
+		repoconf := config.RepositoryConfig{}
+		r := NewGoliacReconciliatorImpl(recorder, &repoconf)
+		err := r.Reconciliate(context.TODO(), newLocal(), remote, "teams", false)
+		assert.Nil(t, err)
+		assert.Equal(t, 0, len(recorder.RepositoriesVariablesRemoved))
+		assert.Equal(t, 0, len(recorder.RepositoriesSecretsRemoved))
+
+		recorder = NewReconciliatorListenerRecorder()
+		repoconf.DestructiveOperations.AllowDestructiveActions = true
+		r = NewGoliacReconciliatorImpl(recorder, &repoconf)
+		err = r.Reconciliate(context.TODO(), newLocal(), remote, "teams", false)
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"OLD"}, recorder.RepositoriesVariablesRemoved["myrepo"])
+		assert.Equal(t, []string{"OLD_TOKEN"}, recorder.RepositoriesSecretsRemoved["myrepo"])
+	})
+
+	t.Run("happy path: no actions configured, Github Actions are left as is", func(t *testing.T) {
+		remote := newRemote()
+		remote.actions["myrepo"] = &GithubActions{
+			Variables: map[string]string{"OLD": "value"},
+			Secrets:   map[string]string{"OLD_TOKEN": ""},
+		}
+		local := newLocal()
+		local.repos["myrepo"].Spec.Variables = nil
+		local.repos["myrepo"].Spec.Secrets = nil
+
+		recorder := NewReconciliatorListenerRecorder()
+		repoconf := config.RepositoryConfig{}
+		repoconf.DestructiveOperations.AllowDestructiveActions = true
+		r := NewGoliacReconciliatorImpl(recorder, &repoconf)
+		err := r.Reconciliate(context.TODO(), local, remote, "teams", false)
+		assert.Nil(t, err)
+		assert.Equal(t, 0, len(recorder.RepositoriesVariablesRemoved))
+		assert.Equal(t, 0, len(recorder.RepositoriesSecretsRemoved))
+	})
+
+	t.Run("happy path: organization variable and secret", func(t *testing.T) {
+		recorder := NewReconciliatorListenerRecorder()
+		repoconf := config.RepositoryConfig{}
+		repoconf.Actions.Variables = map[string]string{"REGION": "us-east-1"}
+		repoconf.Actions.Secrets = []config.SecretReference{{Name: "ORG_TOKEN", Provider: "mock", Key: "org"}}
+		r := NewGoliacReconciliatorImpl(recorder, &repoconf)
+
+		err := r.Reconciliate(context.TODO(), newLocal(), newRemote(), "teams", false)
+
+		assert.Nil(t, err)
+		assert.Equal(t, "us-east-1", recorder.OrgVariablesSet["REGION"])
+		assert.Equal(t, "orgtoken", recorder.OrgSecretsSet["ORG_TOKEN"])
+	})
+
+	t.Run("not happy path: unknown secret", func(t *testing.T) {
+		recorder := NewReconciliatorListenerRecorder()
+		repoconf := config.RepositoryConfig{}
+		r := NewGoliacReconciliatorImpl(recorder, &repoconf)
+
+		local := newLocal()
+		local.repos["myrepo"].Spec.Secrets = []config.SecretReference{{Name: "NPM_TOKEN", Provider: "mock", Key: "unknown"}}
+
+		err := r.Reconciliate(context.TODO(), local, newRemote(), "teams", false)
+
+		assert.NotNil(t, err)
+		assert.Equal(t, 0, len(recorder.RepositoriesSecretsSet))
+	})
+}
+
+func TestReconciliationCustomProperties(t *testing.T) {
+	newRemote := func() *GoliacRemoteMock {
+		remote := newGoliacRemoteMock()
+		remote.repos["myrepo"] = &GithubRepository{Name: "myrepo", ExternalUsers: map[string]string{}}
+		return remote
+	}
+	newLocal := func() *GoliacLocalMock {
+		local := GoliacLocalMock{
+			users:    make(map[string]*entity.User),
+			teams:    make(map[string]*entity.Team),
+			repos:    make(map[string]*entity.Repository),
+			rulesets: make(map[string]*entity.RuleSet),
+		}
+		repo := &entity.Repository{}
+		repo.Name = "myrepo"
+		repo.Spec.CustomProperties = map[string]entity.PropertyValue{"tier": {"critical"}}
+		local.repos["myrepo"] = repo
+		return &local
+	}
+	newConfig := func() *config.RepositoryConfig {
+		return &config.RepositoryConfig{
+			CustomProperties: []config.CustomProperty{
+				{Name: "tier", ValueType: "single_select", AllowedValues: []string{"critical", "standard"}},
+			},
+		}
+	}
+
+	t.Run("happy path: custom properties are not managed without a schema", func(t *testing.T) {
+		recorder := NewReconciliatorListenerRecorder()
+		r := NewGoliacReconciliatorImpl(recorder, &config.RepositoryConfig{})
+
+		remote := newRemote()
+		remote.properties["manual"] = &GithubCustomProperty{Name: "manual", ValueType: "string"}
+
+		err := r.Reconciliate(context.TODO(), newLocal(), remote, "teams", false)
+
+		assert.Nil(t, err)
+		assert.Equal(t, 0, len(recorder.CustomPropertiesUpserted))
+		assert.Equal(t, 0, len(recorder.CustomPropertiesDeleted))
+		assert.Equal(t, 0, len(recorder.RepositoriesCustomProperties))
+	})
+
+	t.Run("happy path: new custom property and repository value", func(t *testing.T) {
+		recorder := NewReconciliatorListenerRecorder()
+		r := NewGoliacReconciliatorImpl(recorder, newConfig())
+
+		err := r.Reconciliate(context.TODO(), newLocal(), newRemote(), "teams", false)
+
+		assert.Nil(t, err)
+		assert.Equal(t, "single_select", recorder.CustomPropertiesUpserted["tier"].ValueType)
+		assert.Equal(t, []string{"critical"}, recorder.RepositoriesCustomProperties["myrepo"]["tier"])
+	})
+
+	t.Run("happy path: nothing to do", func(t *testing.T) {
+		recorder := NewReconciliatorListenerRecorder()
+		r := NewGoliacReconciliatorImpl(recorder, newConfig())
+
+		remote := newRemote()
+		remote.properties["tier"] = &GithubCustomProperty{Name: "tier", ValueType: "single_select", AllowedValues: []string{"standard", "critical"}}
+		remote.reposprops["myrepo"] = map[string][]string{"tier": {"critical"}}
+
+		err := r.Reconciliate(context.TODO(), newLocal(), remote, "teams", false)
+
+		assert.Nil(t, err)
+		assert.Equal(t, 0, len(recorder.CustomPropertiesUpserted))
+		assert.Equal(t, 0, len(recorder.RepositoriesCustomProperties))
+	})
+
+	t.Run("happy path: unset repository value", func(t *testing.T) {
+		recorder := NewReconciliatorListenerRecorder()
+		r := NewGoliacReconciliatorImpl(recorder, newConfig())
+
+		remote := newRemote()
+		remote.properties["tier"] = &GithubCustomProperty{Name: "tier", ValueType: "single_select", AllowedValues: []string{"standard", "critical"}}
+		remote.reposprops["myrepo"] = map[string][]string{"tier": {"critical"}}
+
+		local := newLocal()
+		local.repos["myrepo"].Spec.CustomProperties = nil
+
+		err := r.Reconciliate(context.TODO(), local, remote, "teams", false)
+
+		assert.Nil(t, err)
+		v, ok := recorder.RepositoriesCustomProperties["myrepo"]["tier"]
+		assert.True(t, ok)
+		assert.Equal(t, 0, len(v))
+	})
+
+	t.Run("happy path: removed custom property only with destructive operations", func(t *testing.T) {
+		remote := newRemote()
+		remote.properties["tier"] = &GithubCustomProperty{Name: "tier", ValueType: "single_select", AllowedValues: []string{"standard", "critical"}}
+		remote.properties["old"] = &GithubCustomProperty{Name: "old", ValueType: "string"}
+		remote.reposprops["myrepo"] = map[string][]string{"tier": {"critical"}}
+
+		recorder := NewReconciliatorListenerRecorder()
+		r := NewGoliacReconciliatorImpl(recorder, newConfig())
+		err := r.Reconciliate(context.TODO(), newLocal(), remote, "teams", false)
+		assert.Nil(t, err)
+		assert.Equal(t, 0, len(recorder.CustomPropertiesDeleted))
+
+		recorder = NewReconciliatorListenerRecorder()
+		conf := newConfig()
+		conf.DestructiveOperations.AllowDestructiveProperties = true
+		r = NewGoliacReconciliatorImpl(recorder, conf)
+		err = r.Reconciliate(context.TODO(), newLocal(), remote, "teams", false)
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"old"}, recorder.CustomPropertiesDeleted)
+	})
+
+	t.Run("happy path: ruleset targeting repositories by custom property", func(t *testing.T) {
+		recorder := NewReconciliatorListenerRecorder()
+		conf := newConfig()
+		conf.Rulesets = append(conf.Rulesets, config.RulesetMapping{
+			Pattern:    ".*",
+			Ruleset:    "critical",
+			Properties: map[string]string{"tier": "critical"},
+		})
+		r := NewGoliacReconciliatorImpl(recorder, conf)
+
+		local := newLocal()
+		other := &entity.Repository{}
+		other.Name = "otherrepo"
+		other.Spec.CustomProperties = map[string]entity.PropertyValue{"tier": {"standard"}}
+		local.repos["otherrepo"] = other
+
+		lRuleset := &entity.RuleSet{}
+		lRuleset.Name = "critical"
+		lRuleset.Spec.Enforcement = "active"
+		local.rulesets["critical"] = lRuleset
+
+		remote := newRemote()
+		remote.repos["otherrepo"] = &GithubRepository{Name: "otherrepo", ExternalUsers: map[string]string{}}
+
+		err := r.Reconciliate(context.TODO(), local, remote, "teams", false)
+
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"myrepo"}, recorder.RuleSetCreated["critical"].Repositories)
+	})
+}
+
+func TestReconciliationOrganization(t *testing.T) {
+	newRemote := func() *GoliacRemoteMock {
+		remote := newGoliacRemoteMock()
+		remote.orgsettings = &GithubOrganizationSettings{
+			DefaultRepositoryPermission:  "read",
+			MembersCanCreateRepositories: true,
+		}
+		return remote
+	}
+	newLocal := func() *GoliacLocalMock {
+		return &GoliacLocalMock{
+			users: make(map[string]*entity.User),
+			teams: make(map[string]*entity.Team),
+			repos: make(map[string]*entity.Repository),
+		}
+	}
+
+	t.Run("happy path: no organization settings defined", func(t *testing.T) {
+		recorder := NewReconciliatorListenerRecorder()
+		r := NewGoliacReconciliatorImpl(recorder, &config.RepositoryConfig{})
+
+		err := r.Reconciliate(context.TODO(), newLocal(), newRemote(), "teams", false)
+
+		assert.Nil(t, err)
+		assert.Nil(t, recorder.OrganizationSettingsUpdated)
+	})
+
+	t.Run("happy path: update organization settings", func(t *testing.T) {
+		recorder := NewReconciliatorListenerRecorder()
+		conf := config.RepositoryConfig{}
+		permission := "none"
+		membersCanCreate := false
+		conf.Organization.DefaultRepositoryPermission = &permission
+		conf.Organization.MembersCanCreateRepositories = &membersCanCreate
+		r := NewGoliacReconciliatorImpl(recorder, &conf)
+
+		err := r.Reconciliate(context.TODO(), newLocal(), newRemote(), "teams", false)
+
+		assert.Nil(t, err)
+		assert.NotNil(t, recorder.OrganizationSettingsUpdated)
+		// only the settings defined (and different) are sent
+		assert.Equal(t, map[string]interface{}{
+			"default_repository_permission":   "none",
+			"members_can_create_repositories": false,
+		}, recorder.OrganizationSettingsUpdated.Payload())
+	})
+
+	t.Run("happy path: settings already set are not sent", func(t *testing.T) {
+		recorder := NewReconciliatorListenerRecorder()
+		conf := config.RepositoryConfig{}
+		permission := "read"
+		signoff := true
+		conf.Organization.DefaultRepositoryPermission = &permission
+		conf.Organization.WebCommitSignoffRequired = &signoff
+		r := NewGoliacReconciliatorImpl(recorder, &conf)
+
+		err := r.Reconciliate(context.TODO(), newLocal(), newRemote(), "teams", false)
+
+		assert.Nil(t, err)
+		assert.NotNil(t, recorder.OrganizationSettingsUpdated)
+		assert.Equal(t, map[string]interface{}{"web_commit_signoff_required": true}, recorder.OrganizationSettingsUpdated.Payload())
+	})
+
+	t.Run("happy path: two factor requirement is only reported", func(t *testing.T) {
+		recorder := NewReconciliatorListenerRecorder()
+		conf := config.RepositoryConfig{}
+		twoFactor := true
+		conf.Organization.TwoFactorRequirement = &twoFactor
+		r := NewGoliacReconciliatorImpl(recorder, &conf)
+
+		err := r.Reconciliate(context.TODO(), newLocal(), newRemote(), "teams", false)
+
+		assert.Nil(t, err)
+		assert.Nil(t, recorder.OrganizationSettingsUpdated)
+	})
+
+	t.Run("happy path: settings not loaded and not defined", func(t *testing.T) {
+		recorder := NewReconciliatorListenerRecorder()
+		r := NewGoliacReconciliatorImpl(recorder, &config.RepositoryConfig{})
+		remote := newRemote()
+		remote.orgsettings = nil
+
+		err := r.Reconciliate(context.TODO(), newLocal(), remote, "teams", false)
+
+		assert.Nil(t, err)
+		assert.Nil(t, recorder.OrganizationSettingsUpdated)
+	})
+
+	t.Run("not happy path: settings defined but not loaded", func(t *testing.T) {
+		recorder := NewReconciliatorListenerRecorder()
+		conf := config.RepositoryConfig{}
+		membersCanCreate := false
+		conf.Organization.MembersCanCreateRepositories = &membersCanCreate
+		r := NewGoliacReconciliatorImpl(recorder, &conf)
+		remote := newRemote()
+		remote.orgsettings = nil
+
+		err := r.Reconciliate(context.TODO(), newLocal(), remote, "teams", false)
+
+		assert.NotNil(t, err)
+		assert.Contains(t, err.Error(), "the organization settings could not be loaded")
+		assert.Nil(t, recorder.OrganizationSettingsUpdated)
+	})
+}
+
+func TestReconciliationAccessRequests(t *testing.T) {
+	newRequest := func(name string, permission string, startsAt time.Time) *entity.AccessRequest {
+		request := &entity.AccessRequest{}
+		request.Name = name
+		request.Spec.Repository = "myrepo"
+		request.Spec.Permission = permission
+		request.Spec.StartsAt = startsAt.UTC().Format(time.RFC3339)
+		request.Spec.Duration = "4h"
+		request.Spec.Justification = "incident"
+		return request
+	}
+	newLocal := func() *GoliacLocalMock {
+		local := GoliacLocalMock{
+			users:     make(map[string]*entity.User),
+			externals: make(map[string]*entity.User),
+			teams:     make(map[string]*entity.Team),
+			repos:     make(map[string]*entity.Repository),
+			requests:  make(map[string]*entity.AccessRequest),
+		}
+		owner := &entity.Team{}
+		owner.Name = "owner"
+		local.teams["owner"] = owner
+		responders := &entity.Team{}
+		responders.Name = "responders"
+		local.teams["responders"] = responders
+
+		outside1 := entity.User{}
+		outside1.Name = "outside1"
+		outside1.Spec.GithubID = "outside1-githubid"
+		local.externals["outside1"] = &outside1
+
+		lRepo := &entity.Repository{}
+		lRepo.Name = "myrepo"
+		lowner := "owner"
+		lRepo.Owner = &lowner
+		local.repos["myrepo"] = lRepo
+		return &local
+	}
+	newRemote := func() *GoliacRemoteMock {
+		remote := newGoliacRemoteMock()
+		remote.teams["owner"] = &GithubTeam{Name: "owner", Slug: "owner"}
+		remote.teams["responders"] = &GithubTeam{Name: "responders", Slug: "responders"}
+		remote.repos["myrepo"] = &GithubRepository{Name: "myrepo", ExternalUsers: map[string]string{}}
+		remote.teamsrepos["owner"] = map[string]*GithubTeamRepo{"myrepo": {Name: "myrepo", Permission: "WRITE"}}
+		return remote
+	}
+
+	t.Run("happy path: active team access request", func(t *testing.T) {
+		recorder := NewReconciliatorListenerRecorder()
+		r := NewGoliacReconciliatorImpl(recorder, &config.RepositoryConfig{})
+
+		local := newLocal()
+		request := newRequest("incident-1", "write", time.Now().Add(-time.Hour))
+		request.Spec.Team = "responders"
+		local.requests["incident-1"] = request
+
+		err := r.Reconciliate(context.TODO(), local, newRemote(), "teams", false)
+
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"responders"}, recorder.RepositoryTeamAdded["myrepo"])
+	})
+
+	t.Run("happy path: expired access request is removed even if drifts are only reported", func(t *testing.T) {
+		recorder := NewReconciliatorListenerRecorder()
+		repoconf := config.RepositoryConfig{}
+		repoconf.DriftPolicy.Default = DriftPolicyReport
+		executor := NewDriftExecutor(recorder, repoconf.DriftPolicy)
+		r := NewGoliacReconciliatorImpl(executor, &repoconf)
+
+		local := newLocal()
+		request := newRequest("incident-1", "write", time.Now().Add(-5*time.Hour))
+		request.Spec.Team = "responders"
+		local.requests["incident-1"] = request
+		// an expired external collaborator
+		local.repos["myrepo"].Spec.ExternalUserReaders = []string{"outside1"}
+		local.repos["myrepo"].Spec.ExternalUserExpirations = map[string]string{"outside1": "2020-01-01"}
+
+		remote := newRemote()
+		remote.teamsrepos["responders"] = map[string]*GithubTeamRepo{"myrepo": {Name: "myrepo", Permission: "WRITE"}}
+		remote.repos["myrepo"].ExternalUsers["outside1-githubid"] = "READ"
+		// a drift
+		remote.teamsrepos["owner"]["myrepo"].Permission = "READ"
+
+		err := r.Reconciliate(context.TODO(), local, remote, "teams", false)
+
+		assert.Nil(t, err)
+		// expirations are applied, and are not drifts
+		assert.Equal(t, []string{"responders"}, recorder.RepositoryTeamRemoved["myrepo"])
+		assert.True(t, recorder.RepositoriesRemoveExternalUser["outside1-githubid"])
+		found := false
+		for _, d := range executor.Drifts() {
+			assert.NotContains(t, d.Details, "responders")
+			assert.NotContains(t, d.Details, "outside1")
+			if d.Command == "update_repository_add_team" && d.Details == "team owner access (push) is missing" {
+				found = true
+			}
+		}
+		// the drift is only reported
+		assert.True(t, found)
+		assert.Equal(t, 0, len(recorder.RepositoryTeamAdded))
+	})
+
+	t.Run("happy path: expired team access request is removed", func(t *testing.T) {
+		recorder := NewReconciliatorListenerRecorder()
+		r := NewGoliacReconciliatorImpl(recorder, &config.RepositoryConfig{})
+
+		local := newLocal()
+		request := newRequest("incident-1", "write", time.Now().Add(-5*time.Hour))
+		request.Spec.Team = "responders"
+		local.requests["incident-1"] = request
+
+		remote := newRemote()
+		remote.teamsrepos["responders"] = map[string]*GithubTeamRepo{"myrepo": {Name: "myrepo", Permission: "WRITE"}}
+
+		err := r.Reconciliate(context.TODO(), local, remote, "teams", false)
+
+		assert.Nil(t, err)
+		assert.Equal(t, 0, len(recorder.RepositoryTeamAdded))
+		assert.Equal(t, []string{"responders"}, recorder.RepositoryTeamRemoved["myrepo"])
+	})
+
+	t.Run("happy path: future access request is not applied yet", func(t *testing.T) {
+		recorder := NewReconciliatorListenerRecorder()
+		r := NewGoliacReconciliatorImpl(recorder, &config.RepositoryConfig{})
+
+		local := newLocal()
+		request := newRequest("incident-1", "write", time.Now().Add(time.Hour))
+		request.Spec.Team = "responders"
+		local.requests["incident-1"] = request
+
+		err := r.Reconciliate(context.TODO(), local, newRemote(), "teams", false)
+
+		assert.Nil(t, err)
+		assert.Equal(t, 0, len(recorder.RepositoryTeamAdded))
+	})
+
+	t.Run("happy path: active external user access request", func(t *testing.T) {
+		recorder := NewReconciliatorListenerRecorder()
+		r := NewGoliacReconciliatorImpl(recorder, &config.RepositoryConfig{})
+
+		local := newLocal()
+		request := newRequest("incident-1", "read", time.Now().Add(-time.Hour))
+		request.Spec.ExternalUser = "outside1"
+		local.requests["incident-1"] = request
+
+		err := r.Reconciliate(context.TODO(), local, newRemote(), "teams", false)
+
+		assert.Nil(t, err)
+		assert.Equal(t, "pull", recorder.RepositoriesSetExternalUser["outside1-githubid"])
+	})
+}
+
+/*
+ * FailingReconciliatorExecutor fails to create some teams, and to commit
+ */
+type FailingReconciliatorExecutor struct {
+	*ReconciliatorListenerRecorder
+	failingTeams map[string]bool
+	commitError  error
+}
+
+func (f *FailingReconciliatorExecutor) CreateTeam(ctx context.Context, dryrun bool, teamname string, description string, members []string) error {
+	if f.failingTeams[teamname] {
+		return fmt.Errorf("failed to create team %s", teamname)
+	}
+	return f.ReconciliatorListenerRecorder.CreateTeam(ctx, dryrun, teamname, description, members)
+}
+
+func (f *FailingReconciliatorExecutor) Commit(ctx context.Context, dryrun bool) error {
+	return f.commitError
+}
+
+func TestReconciliationErrors(t *testing.T) {
+	newLocal := func() *GoliacLocalMock {
+		local := GoliacLocalMock{
+			users: make(map[string]*entity.User),
+			teams: make(map[string]*entity.Team),
+			repos: make(map[string]*entity.Repository),
+		}
+		for _, name := range []string{"team1", "team2"} {
+			team := &entity.Team{}
+			team.Name = name
+			local.teams[name] = team
+		}
+		return &local
+	}
+	t.Run("happy path: no error", func(t *testing.T) {
+		executor := &FailingReconciliatorExecutor{ReconciliatorListenerRecorder: NewReconciliatorListenerRecorder()}
+		r := NewGoliacReconciliatorImpl(executor, &config.RepositoryConfig{})
+
+		err := r.Reconciliate(context.TODO(), newLocal(), newGoliacRemoteMock(), "teams", false)
+		assert.Nil(t, err)
+		// teams and their -owners teams
+		assert.Equal(t, 4, len(executor.TeamsCreated))
+	})
+
+	t.Run("not happy path: failed operations are reported", func(t *testing.T) {
+		executor := &FailingReconciliatorExecutor{
+			ReconciliatorListenerRecorder: NewReconciliatorListenerRecorder(),
+			failingTeams:                  map[string]bool{"team1": true},
+		}
+		r := NewGoliacReconciliatorImpl(executor, &config.RepositoryConfig{})
+
+		err := r.Reconciliate(context.TODO(), newLocal(), newGoliacRemoteMock(), "teams", false)
+		assert.NotNil(t, err)
+		assert.Contains(t, err.Error(), "failed to create team team1")
+		// the other operations are still applied
+		assert.Equal(t, 3, len(executor.TeamsCreated))
+		_, found := executor.TeamsCreated["team1"]
+		assert.False(t, found)
+	})
+
+	t.Run("not happy path: commit errors are aggregated", func(t *testing.T) {
+		executor := &FailingReconciliatorExecutor{
+			ReconciliatorListenerRecorder: NewReconciliatorListenerRecorder(),
+			failingTeams:                  map[string]bool{"team1": true},
+			commitError:                   ApplyErrors{fmt.Errorf("failed to add team access"), fmt.Errorf("failed to delete repository")},
+		}
+		r := NewGoliacReconciliatorImpl(executor, &config.RepositoryConfig{})
+
+		err := r.Reconciliate(context.TODO(), newLocal(), newGoliacRemoteMock(), "teams", false)
+		applyErrors, ok := err.(ApplyErrors)
+		assert.True(t, ok)
+		assert.Equal(t, 3, len(applyErrors))
+	})
+}
+
+/*
+ * GithubLoginsRemoteMock knows only the given Github logins
+ */
+type GithubLoginsRemoteMock struct {
+	GoliacRemoteMock
+	logins map[string]string // lower case login -> login
+}
+
+func (m *GithubLoginsRemoteMock) GetUserLogin(ctx context.Context, githubid string) (string, error) {
+	return m.logins[strings.ToLower(githubid)], nil
+}
+
+func TestReconciliationUsers(t *testing.T) {
+	newLocal := func(githubids ...string) *GoliacLocalMock {
+		local := GoliacLocalMock{
+			users: make(map[string]*entity.User),
+			teams: make(map[string]*entity.Team),
+			repos: make(map[string]*entity.Repository),
+		}
+		for i, githubid := range githubids {
+			user := &entity.User{}
+			user.Name = fmt.Sprintf("user%d", i+1)
+			user.Spec.GithubID = githubid
+			local.users[user.Name] = user
+		}
+		return &local
+	}
+	remote := &GithubLoginsRemoteMock{
+		GoliacRemoteMock: *newGoliacRemoteMock(),
+		logins:           map[string]string{"member": "member", "newcomer": "newcomer", "camelcase": "CamelCase"},
+	}
+	remote.users["member"] = "member"
+
+	t.Run("happy path: new users are added to the organization", func(t *testing.T) {
+		recorder := NewReconciliatorListenerRecorder()
+		r := NewGoliacReconciliatorImpl(recorder, &config.RepositoryConfig{})
+
+		err := r.Reconciliate(context.TODO(), newLocal("member", "newcomer"), remote, "teams", true)
+
+		assert.Nil(t, err)
+		assert.Equal(t, map[string]string{"newcomer": "newcomer"}, recorder.UsersCreated)
+		assert.Equal(t, 0, len(recorder.UsersRemoved))
+	})
+
+	t.Run("not happy path: unknown or misspelled githubID", func(t *testing.T) {
+		recorder := NewReconciliatorListenerRecorder()
+		r := NewGoliacReconciliatorImpl(recorder, &config.RepositoryConfig{})
+
+		err := r.Reconciliate(context.TODO(), newLocal("member", "typo", "camelcase"), remote, "teams", true)
+
+		assert.NotNil(t, err)
+		assert.Contains(t, err.Error(), "githubID typo of user user2 doesn't exist on Github")
+		assert.Contains(t, err.Error(), "githubID camelcase of user user3 must be written CamelCase")
+		assert.Equal(t, 0, len(recorder.UsersCreated))
+	})
+
+	t.Run("happy path: a misspelled githubID doesn't block an apply", func(t *testing.T) {
+		recorder := NewReconciliatorListenerRecorder()
+		r := NewGoliacReconciliatorImpl(recorder, &config.RepositoryConfig{})
+
+		err := r.Reconciliate(context.TODO(), newLocal("member", "typo", "newcomer"), remote, "teams", false)
+
+		assert.Nil(t, err)
+		assert.Equal(t, map[string]string{"newcomer": "newcomer"}, recorder.UsersCreated)
+	})
+
+	t.Run("happy path: the members githubIDs are not looked up", func(t *testing.T) {
+		recorder := NewReconciliatorListenerRecorder()
+		r := NewGoliacReconciliatorImpl(recorder, &config.RepositoryConfig{})
+		membersRemote := &GithubLoginsRemoteMock{
+			GoliacRemoteMock: remote.GoliacRemoteMock,
+			// member is not known anymore (like a deleted account)
+			logins: map[string]string{},
+		}
+
+		err := r.Reconciliate(context.TODO(), newLocal("member"), membersRemote, "teams", true)
+
+		assert.Nil(t, err)
+	})
+
+	t.Run("happy path: pending and failed invitations", func(t *testing.T) {
+		recorder := NewReconciliatorListenerRecorder()
+		r := NewGoliacReconciliatorImpl(recorder, &config.RepositoryConfig{})
+		invitationsRemote := &GithubLoginsRemoteMock{
+			GoliacRemoteMock: remote.GoliacRemoteMock,
+			logins:           map[string]string{"member": "member", "newcomer": "newcomer", "expired": "expired", "alice": "alice"},
+		}
+		invitationsRemote.users = map[string]string{
+			"member":              UserStateMember,
+			"newcomer":            UserStateInvited,
+			"expired":             UserStateInvitationFailed,
+			"removed":             UserStateInvited,
+			"alice@mycompany.com": UserStateInvited,
+			"oldfailure":          UserStateInvitationFailed,
+		}
+		local := newLocal("member", "newcomer", "expired", "alice")
+		local.users["user4"].Spec.Email = "alice@mycompany.com"
+
+		err := r.Reconciliate(context.TODO(), local, invitationsRemote, "teams", true)
+
+		assert.Nil(t, err)
+		// pending invitations are not sent again, expired ones are
+		assert.Equal(t, map[string]string{"expired": "expired"}, recorder.UsersCreated)
+		// the invitation of a removed user is cancelled
+		assert.Equal(t, map[string]string{"removed": "removed"}, recorder.InvitationsCancelled)
+		assert.Equal(t, 0, len(recorder.UsersRemoved))
+	})
+
+	t.Run("not happy path: misspelled githubID of a user already invited", func(t *testing.T) {
+		recorder := NewReconciliatorListenerRecorder()
+		r := NewGoliacReconciliatorImpl(recorder, &config.RepositoryConfig{})
+		invitationsRemote := &GithubLoginsRemoteMock{
+			GoliacRemoteMock: remote.GoliacRemoteMock,
+			logins:           map[string]string{"member": "member"},
+		}
+		invitationsRemote.users = map[string]string{
+			"member":              UserStateMember,
+			"alice@mycompany.com": UserStateInvited,
+		}
+		local := newLocal("member", "alcie")
+		local.users["user2"].Spec.Email = "alice@mycompany.com"
+
+		err := r.Reconciliate(context.TODO(), local, invitationsRemote, "teams", true)
+
+		assert.NotNil(t, err)
+		assert.Contains(t, err.Error(), "githubID alcie of user user2 doesn't exist on Github")
+		// the pending invitation is kept
+		assert.Equal(t, 0, len(recorder.InvitationsCancelled))
+	})
+
+	t.Run("happy path: invite by email", func(t *testing.T) {
+		recorder := NewReconciliatorListenerRecorder()
+		r := NewGoliacReconciliatorImpl(recorder, &config.RepositoryConfig{InviteByEmail: true})
+		local := newLocal("member", "newcomer", "nomail")
+		local.users["user2"].Spec.Email = "newcomer@mycompany.com"
+
+		err := r.Reconciliate(context.TODO(), local, remote, "teams", true)
+
+		assert.NotNil(t, err) // nomail doesn't exist on Github
+		assert.Equal(t, map[string]string{"newcomer@mycompany.com": "newcomer@mycompany.com"}, recorder.UsersInvitedByEmail)
+		assert.Equal(t, 0, len(recorder.UsersCreated))
+	})
+}
+
+func TestReconciliationEmu(t *testing.T) {
+	newTeam := func(name string, owners []string, members []string, group string) *entity.Team {
+		team := &entity.Team{}
+		team.Name = name
+		team.Spec.Owners = owners
+		team.Spec.Members = members
+		if group != "" {
+			team.Spec.ExternallyManaged = &entity.TeamExternallyManaged{Group: group}
+		}
+		return team
+	}
+
+	t.Run("happy path: the IdP provisions the users, teams are linked to their IdP group", func(t *testing.T) {
+		local := GoliacLocalMock{
+			users: make(map[string]*entity.User),
+			teams: map[string]*entity.Team{
+				"dev":    newTeam("dev", []string{"alice"}, []string{"bob"}, ""),
+				"idp":    newTeam("idp", []string{"alice"}, []string{"alice", "bob"}, "idp-devs"),
+				"legacy": newTeam("legacy", []string{"alice"}, []string{}, ""),
+			},
+			repos: make(map[string]*entity.Repository),
+		}
+		for _, name := range []string{"alice", "bob"} {
+			user := &entity.User{}
+			user.Name = name
+			user.Spec.GithubID = name + "_acme"
+			local.users[name] = user
+		}
+
+		remote := GoliacRemoteMock{
+			users: map[string]string{"alice_acme": UserStateMember, "leaver_acme": UserStateMember},
+			teams: map[string]*GithubTeam{
+				"idp":           {Name: "idp", Slug: "idp", Members: []string{"leaver_acme"}},
+				"idp-owners":    {Name: "idp-owners", Slug: "idp-owners", Members: []string{"alice_acme"}},
+				"legacy":        {Name: "legacy", Slug: "legacy", Members: []string{}},
+				"legacy-owners": {Name: "legacy-owners", Slug: "legacy-owners", Members: []string{"alice_acme"}},
+			},
+			repos:      make(map[string]*GithubRepository),
+			teamsrepos: make(map[string]map[string]*GithubTeamRepo),
+			rulesets:   make(map[string]*GithubRuleSet),
+			appids:     make(map[string]int),
+			extgroups: map[string]*GithubExternalGroup{
+				"idp-devs":  {Id: 1, Name: "idp-devs", Teams: []string{}},
+				"old-group": {Id: 2, Name: "old-group", Teams: []string{"legacy"}},
+			},
+		}
+
+		recorder := NewReconciliatorListenerRecorder()
+		repoconfig := &config.RepositoryConfig{}
+		repoconfig.Emu.Enabled = true
+		repoconfig.Emu.Shortcode = "acme"
+		repoconfig.Emu.LinkExternalGroups = true
+		r := NewGoliacReconciliatorImpl(recorder, repoconfig)
+
+		err := r.Reconciliate(context.TODO(), &local, &remote, "teams", true)
+
+		assert.Nil(t, err)
+		// no org membership mutation
+		assert.Equal(t, 0, len(recorder.UsersCreated))
+		assert.Equal(t, 0, len(recorder.UsersRemoved))
+		// bob is not provisioned yet
+		assert.Equal(t, []string{"alice_acme"}, recorder.TeamsCreated["dev"])
+		// the members of a linked team are managed by Github
+		assert.Equal(t, 0, len(recorder.TeamMemberAdded["idp"]))
+		assert.Equal(t, 0, len(recorder.TeamMemberRemoved["idp"]))
+		assert.Equal(t, map[string]string{"idp": "idp-devs"}, recorder.TeamsExternalGroupSet)
+		assert.Equal(t, map[string]bool{"legacy": true}, recorder.TeamsExternalGroupRemoved)
+	})
+
+	t.Run("not happy path: unknown IdP group", func(t *testing.T) {
+		local := GoliacLocalMock{
+			users: make(map[string]*entity.User),
+			teams: map[string]*entity.Team{
+				"idp": newTeam("idp", []string{}, []string{}, "unknown"),
+			},
+			repos: make(map[string]*entity.Repository),
+		}
+		remote := newGoliacRemoteMock()
+
+		recorder := NewReconciliatorListenerRecorder()
+		repoconfig := &config.RepositoryConfig{}
+		repoconfig.Emu.Enabled = true
+		repoconfig.Emu.LinkExternalGroups = true
+		r := NewGoliacReconciliatorImpl(recorder, repoconfig)
+
+		err := r.Reconciliate(context.TODO(), &local, remote, "teams", true)
+
+		assert.NotNil(t, err)
+		assert.Contains(t, err.Error(), "external group unknown not found")
+		assert.Equal(t, 0, len(recorder.TeamsExternalGroupSet))
+	})
+}
+
+func TestReconciliationProtectedUsers(t *testing.T) {
+	t.Run("happy path: protected users are admins and never removed", func(t *testing.T) {
+		newUser := func(name string, githubid string) *entity.User {
+			user := &entity.User{}
+			user.Name = name
+			user.Spec.GithubID = githubid
+			return user
+		}
+		admin := &entity.Team{}
+		admin.Name = "admin"
+		admin.Spec.Owners = []string{"alice"}
+
+		local := GoliacLocalMock{
+			users: map[string]*entity.User{
+				"alice":      newUser("alice", "alice-gh"),
+				"breakglass": newUser("breakglass", "breakglass-gh"),
+			},
+			protected: map[string]*entity.User{
+				"breakglass": newUser("breakglass", "breakglass-gh"),
+			},
+			teams: map[string]*entity.Team{"admin": admin},
+			repos: make(map[string]*entity.Repository),
+		}
+		remote := GoliacRemoteMock{
+			users: map[string]string{
+				"alice-gh":      UserStateMember,
+				"breakglass-gh": UserStateMember,
+				"leaver-gh":     UserStateMember,
+			},
+			teams: map[string]*GithubTeam{
+				"admin":        {Name: "admin", Slug: "admin", Members: []string{"alice-gh"}},
+				"admin-owners": {Name: "admin-owners", Slug: "admin-owners", Members: []string{"alice-gh"}},
+			},
+			repos:      make(map[string]*GithubRepository),
+			teamsrepos: make(map[string]map[string]*GithubTeamRepo),
+			rulesets:   make(map[string]*GithubRuleSet),
+			appids:     make(map[string]int),
+		}
+
+		recorder := NewReconciliatorListenerRecorder()
+		repoconfig := &config.RepositoryConfig{AdminTeam: "admin"}
+		repoconfig.DestructiveOperations.AllowDestructiveUsers = true
+		r := NewGoliacReconciliatorImpl(recorder, repoconfig)
+
+		err := r.Reconciliate(context.TODO(), &local, &remote, "teams", false)
+
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"breakglass-gh"}, recorder.TeamMemberAdded["admin"])
+		assert.Equal(t, 0, len(recorder.TeamMemberRemoved["admin"]))
+		assert.Equal(t, map[string]string{"leaver-gh": "leaver-gh"}, recorder.UsersRemoved)
+	})
+}
@@ -1,6 +1,7 @@
 package engine
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io"
@@ -9,6 +10,7 @@ import (
 	"path/filepath"
 	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/Alayacare/goliac/internal/config"
@@ -36,22 +38,28 @@ type GoliacLocal interface {
 }
 
 type GoliacLocalGit interface {
-	Clone(accesstoken, repositoryUrl, branch string) error
+	Clone(ctx context.Context, accesstoken, repositoryUrl, branch string) error
 
 	// Return commits from tagname to HEAD
 	ListCommitsFromTag(tagname string) ([]*object.Commit, error)
 	GetHeadCommit() (*object.Commit, error)
+	// Return the commit pointed by the tag
+	GetTagCommit(tagname string) (*object.Commit, error)
 	CheckoutCommit(commit *object.Commit) error
-	PushTag(tagname string, hash plumbing.Hash, accesstoken string) error
+	PushTag(ctx context.Context, tagname string, hash plumbing.Hash, accesstoken string) error
+	// Load the protected users of the commit pointed by the tag (see AppliedProtectedUsers)
+	LoadAppliedProtectedUsers(tagname string) error
+	LoadAppliedProtectedUsersFromDirectory(path string, tagname string) error
 
 	LoadRepoConfig() (error, *config.RepositoryConfig)
 
 	// Load and Validate from a github repository
 	LoadAndValidate() ([]error, []entity.Warning)
 	// whenever someone create/delete a team, we must update the github CODEOWNERS
-	UpdateAndCommitCodeOwners(repoconfig *config.RepositoryConfig, dryrun bool, accesstoken string, branch string, tagname string) error
+	UpdateAndCommitCodeOwners(ctx context.Context, repoconfig *config.RepositoryConfig, dryrun bool, accesstoken string, branch string, tagname string) error
 	// whenever the users list is changing, reload users and teams, and commit them
-	SyncUsersAndTeams(repoconfig *config.RepositoryConfig, plugin UserSyncPlugin, dryrun bool) error
+	// (pushed to branch, or to the UserSyncBranch if usersync.pull_request is set)
+	SyncUsersAndTeams(ctx context.Context, repoconfig *config.RepositoryConfig, plugin UserSyncPlugin, accesstoken string, branch string, dryrun bool) (*UserSyncReport, error)
 	Close()
 
 	// Load and Validate from a local directory
@@ -63,26 +71,39 @@ type GoliacLocalResources interface {
 	Repositories() map[string]*entity.Repository // reponame, repo definition
 	Users() map[string]*entity.User              // github username, user definition
 	ExternalUsers() map[string]*entity.User
+	ProtectedUsers() map[string]*entity.User // the users of users/protected (also part of Users())
+	// the users of users/protected at the last applied commit: they stay
+	// protected even if their file is removed (until it is applied)
+	AppliedProtectedUsers() map[string]*entity.User
 	RuleSets() map[string]*entity.RuleSet
+	AccessRequests() map[string]*entity.AccessRequest
 }
 
 type GoliacLocalImpl struct {
-	teams         map[string]*entity.Team
-	repositories  map[string]*entity.Repository
-	users         map[string]*entity.User
-	externalUsers map[string]*entity.User
-	rulesets      map[string]*entity.RuleSet
-	repo          *git.Repository
+	teams          map[string]*entity.Team
+	repositories   map[string]*entity.Repository
+	usersMutex     sync.RWMutex // users can be read (by the server) while being loaded
+	users          map[string]*entity.User
+	externalUsers  map[string]*entity.User
+	protectedUsers map[string]*entity.User
+	appliedUsers   map[string]*entity.User // protected users at the last applied commit
+	rulesets       map[string]*entity.RuleSet
+	accessRequests map[string]*entity.AccessRequest
+	repoconfig     *config.RepositoryConfig // goliac.yaml, once parsed
+	repo           *git.Repository
 }
 
 func NewGoliacLocalImpl() GoliacLocal {
 	return &GoliacLocalImpl{
-		teams:         map[string]*entity.Team{},
-		repositories:  map[string]*entity.Repository{},
-		users:         map[string]*entity.User{},
-		externalUsers: map[string]*entity.User{},
-		rulesets:      map[string]*entity.RuleSet{},
-		repo:          nil,
+		teams:          map[string]*entity.Team{},
+		repositories:   map[string]*entity.Repository{},
+		users:          map[string]*entity.User{},
+		externalUsers:  map[string]*entity.User{},
+		protectedUsers: map[string]*entity.User{},
+		appliedUsers:   map[string]*entity.User{},
+		rulesets:       map[string]*entity.RuleSet{},
+		accessRequests: map[string]*entity.AccessRequest{},
+		repo:           nil,
 	}
 }
 
@@ -94,7 +115,12 @@ func (g *GoliacLocalImpl) Repositories() map[string]*entity.Repository {
 	return g.repositories
 }
 
+/*
+ * Users returns the users loaded: the map is replaced (not modified) by each load
+ */
 func (g *GoliacLocalImpl) Users() map[string]*entity.User {
+	g.usersMutex.RLock()
+	defer g.usersMutex.RUnlock()
 	return g.users
 }
 
@@ -102,11 +128,23 @@ func (g *GoliacLocalImpl) ExternalUsers() map[string]*entity.User {
 	return g.externalUsers
 }
 
+func (g *GoliacLocalImpl) ProtectedUsers() map[string]*entity.User {
+	return g.protectedUsers
+}
+
+func (g *GoliacLocalImpl) AppliedProtectedUsers() map[string]*entity.User {
+	return g.appliedUsers
+}
+
 func (g *GoliacLocalImpl) RuleSets() map[string]*entity.RuleSet {
 	return g.rulesets
 }
 
-func (g *GoliacLocalImpl) Clone(accesstoken, repositoryUrl, branch string) error {
+func (g *GoliacLocalImpl) AccessRequests() map[string]*entity.AccessRequest {
+	return g.accessRequests
+}
+
+func (g *GoliacLocalImpl) Clone(ctx context.Context, accesstoken, repositoryUrl, branch string) error {
 	if g.repo != nil {
 		g.Close()
 	}
@@ -126,7 +164,7 @@ func (g *GoliacLocalImpl) Clone(accesstoken, repositoryUrl, branch string) error
 		// ssh clone not supported yet
 		return fmt.Errorf("not supported")
 	}
-	repo, err := git.PlainClone(tmpDir, false, &git.CloneOptions{
+	repo, err := git.PlainCloneContext(ctx, tmpDir, false, &git.CloneOptions{
 		URL:  repositoryUrl,
 		Auth: auth,
 	})
@@ -150,7 +188,7 @@ func (g *GoliacLocalImpl) Clone(accesstoken, repositoryUrl, branch string) error
 	return err
 }
 
-func (g *GoliacLocalImpl) PushTag(tagname string, hash plumbing.Hash, accesstoken string) error {
+func (g *GoliacLocalImpl) PushTag(ctx context.Context, tagname string, hash plumbing.Hash, accesstoken string) error {
 	// Create or move the tag to the commit
 	tagRefName := plumbing.ReferenceName("refs/tags/" + tagname)
 	tagRef := plumbing.NewHashReference(tagRefName, hash)
@@ -166,7 +204,7 @@ func (g *GoliacLocalImpl) PushTag(tagname string, hash plumbing.Hash, accesstoke
 
 	// Force push with '+refs/tags/your_tag_name_here:refs/tags/your_tag_name_here'
 	pushRefSpec := fmt.Sprintf("+%s:%s", tagRefName, tagRefName)
-	err := g.repo.Push(&git.PushOptions{
+	err := g.repo.PushContext(ctx, &git.PushOptions{
 		RefSpecs: []goconfig.RefSpec{goconfig.RefSpec(pushRefSpec)},
 		Auth:     auth,
 	})
@@ -207,6 +245,103 @@ func (g *GoliacLocalImpl) GetHeadCommit() (*object.Commit, error) {
 	return headCommit, nil
 }
 
+func (g *GoliacLocalImpl) GetTagCommit(tagname string) (*object.Commit, error) {
+	refTag, err := g.repo.Tag(tagname)
+	if err != nil {
+		return nil, err
+	}
+
+	tagCommit, err := g.repo.CommitObject(refTag.Hash())
+	if err != nil {
+		return nil, err
+	}
+	return tagCommit, nil
+}
+
+/*
+ * LoadAppliedProtectedUsers reads users/protected in the commit of the tag
+ * (to be called before LoadAndValidate).
+ * Without tag (nothing applied yet), there is no applied protected user
+ */
+func (g *GoliacLocalImpl) LoadAppliedProtectedUsers(tagname string) error {
+	g.appliedUsers = map[string]*entity.User{}
+	if g.repo == nil {
+		return fmt.Errorf("git repository not cloned")
+	}
+	users, err := readProtectedUsersAtTag(g.repo, tagname)
+	if err != nil {
+		return err
+	}
+	g.appliedUsers = users
+	return nil
+}
+
+/*
+ * LoadAppliedProtectedUsersFromDirectory is LoadAppliedProtectedUsers for a local
+ * directory (to be called before LoadAndValidateLocal). If the directory is not
+ * a git repository, there is no applied protected user
+ */
+func (g *GoliacLocalImpl) LoadAppliedProtectedUsersFromDirectory(path string, tagname string) error {
+	g.appliedUsers = map[string]*entity.User{}
+	repo, err := git.PlainOpen(path)
+	if err != nil {
+		if errors.Is(err, git.ErrRepositoryNotExists) {
+			return nil
+		}
+		return err
+	}
+	users, err := readProtectedUsersAtTag(repo, tagname)
+	if err != nil {
+		return err
+	}
+	g.appliedUsers = users
+	return nil
+}
+
+func readProtectedUsersAtTag(repo *git.Repository, tagname string) (map[string]*entity.User, error) {
+	users := map[string]*entity.User{}
+	refTag, err := repo.Tag(tagname)
+	if err != nil {
+		if errors.Is(err, git.ErrTagNotFound) {
+			return users, nil
+		}
+		return nil, err
+	}
+	tagCommit, err := repo.CommitObject(refTag.Hash())
+	if err != nil {
+		return nil, err
+	}
+	tree, err := tagCommit.Tree()
+	if err != nil {
+		return nil, err
+	}
+	dir, err := tree.Tree("users/protected")
+	if err != nil {
+		if errors.Is(err, object.ErrDirectoryNotFound) {
+			return users, nil
+		}
+		return nil, err
+	}
+
+	// copy the files to read them like the worktree ones
+	fs := afero.NewMemMapFs()
+	err = dir.Files().ForEach(func(f *object.File) error {
+		content, err := f.Contents()
+		if err != nil {
+			return err
+		}
+		return afero.WriteFile(fs, filepath.Join("users", "protected", f.Name), []byte(content), 0644)
+	})
+	if err != nil {
+		return nil, err
+	}
+	users, errs, _ := entity.ReadUserDirectory(fs, filepath.Join("users", "protected"))
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("cannot read the protected users of the %s tag (for example: %v)", tagname, errs[0])
+	}
+	return users, nil
+}
+
 func (g *GoliacLocalImpl) ListCommitsFromTag(tagname string) ([]*object.Commit, error) {
 
 	commits := make([]*object.Commit, 0)
@@ -267,34 +402,46 @@ func (g *GoliacLocalImpl) Close() {
 		}
 	}
 	g.repo = nil
+	g.repoconfig = nil
+	g.appliedUsers = map[string]*entity.User{}
 }
 
+/*
+ * LoadRepoConfig returns the goliac.yaml configuration of the cloned repository
+ * (the one already parsed by LoadAndValidate if it was called before)
+ */
 func (g *GoliacLocalImpl) LoadRepoConfig() (error, *config.RepositoryConfig) {
 	if g.repo == nil {
 		return fmt.Errorf("git repository not cloned"), nil
 	}
+	if g.repoconfig != nil {
+		return nil, g.repoconfig
+	}
 	w, err := g.repo.Worktree()
 	if err != nil {
 		return err, nil
 	}
 
-	var repoconfig config.RepositoryConfig
-	file, err := os.Open(path.Join(w.Filesystem.Root(), "goliac.yaml"))
+	repoconfig, err := readRepoConfig(afero.NewOsFs(), w.Filesystem.Root())
 	if err != nil {
-		return fmt.Errorf("not able to open the /goliac.yaml configuration file: %v", err), nil
+		return err, nil
 	}
-	defer file.Close()
+	g.repoconfig = repoconfig
+
+	return nil, repoconfig
+}
 
-	content, err := io.ReadAll(file)
+func readRepoConfig(fs afero.Fs, orgDirectory string) (*config.RepositoryConfig, error) {
+	content, err := afero.ReadFile(fs, filepath.Join(orgDirectory, "goliac.yaml"))
 	if err != nil {
-		return fmt.Errorf("not able to find the /goliac.yaml configuration file: %v", err), nil
+		return nil, fmt.Errorf("not able to open the /goliac.yaml configuration file: %v", err)
 	}
+	var repoconfig config.RepositoryConfig
 	err = yaml.Unmarshal(content, &repoconfig)
 	if err != nil {
-		return fmt.Errorf("not able to unmarshall the /goliac.yaml configuration file: %v", err), nil
+		return nil, fmt.Errorf("not able to unmarshall the /goliac.yaml configuration file: %v", err)
 	}
-
-	return nil, &repoconfig
+	return &repoconfig, nil
 }
 
 func (g *GoliacLocalImpl) codeowners_regenerate(adminteam string) string {
@@ -318,7 +465,7 @@ func (g *GoliacLocalImpl) codeowners_regenerate(adminteam string) string {
  * UpdateAndCommitCodeOwners will collects all teams definition to update the .github/CODEOWNERS file
  * cf https://docs.github.com/en/repositories/managing-your-repositorys-settings-and-features/customizing-your-repository/about-code-owners
  */
-func (g *GoliacLocalImpl) UpdateAndCommitCodeOwners(repoconfig *config.RepositoryConfig, dryrun bool, accesstoken string, branch string, tagname string) error {
+func (g *GoliacLocalImpl) UpdateAndCommitCodeOwners(ctx context.Context, repoconfig *config.RepositoryConfig, dryrun bool, accesstoken string, branch string, tagname string) error {
 	if g.repo == nil {
 		return fmt.Errorf("git repository not cloned")
 	}
@@ -385,7 +532,7 @@ func (g *GoliacLocalImpl) UpdateAndCommitCodeOwners(repoconfig *config.Repositor
 		}
 
 		refSpec := fmt.Sprintf("%s:refs/heads/%s", headRef.Name(), branch)
-		err = g.repo.Push(&git.PushOptions{
+		err = g.repo.PushContext(ctx, &git.PushOptions{
 			RemoteName: "origin",
 			Auth: &http.BasicAuth{
 				Username: "x-access-token", // This can be anything except an empty string
@@ -408,7 +555,7 @@ func (g *GoliacLocalImpl) UpdateAndCommitCodeOwners(repoconfig *config.Repositor
 			return err
 		}
 
-		return g.PushTag(tagname, headRef.Hash(), accesstoken)
+		return g.PushTag(ctx, tagname, headRef.Hash(), accesstoken)
 	}
 
 	return nil
@@ -421,17 +568,23 @@ func (g *GoliacLocalImpl) UpdateAndCommitCodeOwners(repoconfig *config.Repositor
  * - collect the difference
  * - returns deleted users, and add/updated users
  */
-func syncUsersViaUserPlugin(repoconfig *config.RepositoryConfig, fs afero.Fs, userplugin UserSyncPlugin, rootDir string) ([]string, []string, error) {
+func syncUsersViaUserPlugin(ctx context.Context, repoconfig *config.RepositoryConfig, fs afero.Fs, userplugin UserSyncPlugin, rootDir string) ([]string, []string, error) {
 	orgUsers, errs, _ := entity.ReadUserDirectory(fs, filepath.Join(rootDir, "users", "org"))
 	if len(errs) > 0 {
 		return nil, nil, fmt.Errorf("cannot load org users (for example: %v)", errs[0])
 	}
 
 	// use usersync to update the users
-	newOrgUsers, err := userplugin.UpdateUsers(repoconfig, filepath.Join(rootDir, "users", "org"))
+	newOrgUsers, err := userplugin.UpdateUsers(ctx, repoconfig, filepath.Join(rootDir, "users", "org"))
 	if err != nil {
 		return nil, nil, err
 	}
+	if repoconfig.Emu.Enabled {
+		// the IdP identities become <handle>_<shortcode> Github logins
+		for _, user := range newOrgUsers {
+			user.Spec.GithubID = EmuLogin(user.Spec.GithubID, repoconfig.Emu.Shortcode)
+		}
+	}
 
 	// write back to disk
 	deletedusers := []string{}
@@ -468,13 +621,63 @@ func syncUsersViaUserPlugin(repoconfig *config.RepositoryConfig, fs afero.Fs, us
 	return deletedusers, updatedusers, nil
 }
 
-func (g *GoliacLocalImpl) SyncUsersAndTeams(repoconfig *config.RepositoryConfig, userplugin UserSyncPlugin, dryrun bool) error {
+/**
+ * syncGroupsViaUserPlugin returns the members of the groups used by the
+ * externally managed teams (nil if the user sync plugin doesn't know the groups)
+ */
+func syncGroupsViaUserPlugin(ctx context.Context, repoconfig *config.RepositoryConfig, fs afero.Fs, userplugin UserSyncPlugin, rootDir string) (map[string][]string, error) {
+	groupnames, err := entity.ReadTeamsExternalGroups(fs, filepath.Join(rootDir, "teams"))
+	if err != nil {
+		return nil, err
+	}
+	if len(groupnames) == 0 {
+		return nil, nil
+	}
+
+	groupplugin, ok := userplugin.(UserSyncPluginGroups)
+	if !ok {
+		logrus.Warnf("the %s user sync plugin doesn't support groups: externally managed teams are not updated", repoconfig.UserSync.Plugin)
+		return nil, nil
+	}
+
+	groups, err := groupplugin.UpdateGroups(ctx, repoconfig, groupnames)
+	if err != nil {
+		return nil, err
+	}
+	for _, groupname := range groupnames {
+		if _, ok := groups[groupname]; !ok {
+			logrus.Warnf("group %s not found: the members of its team are not updated", groupname)
+		}
+	}
+	return groups, nil
+}
+
+/*
+ * teamsFallbackOwners returns who adopts the teams losing all their owners (without manager):
+ * usersync.fallback_owners, else the owners of the admin team
+ */
+func teamsFallbackOwners(fs afero.Fs, repoconfig *config.RepositoryConfig, rootDir string) []string {
+	if len(repoconfig.UserSync.FallbackOwners) > 0 {
+		return repoconfig.UserSync.FallbackOwners
+	}
+	if repoconfig.AdminTeam == "" {
+		return nil
+	}
+	adminteam, err := entity.NewTeam(fs, filepath.Join(rootDir, "teams", repoconfig.AdminTeam, "team.yaml"))
+	if err != nil {
+		logrus.Debugf("not able to read the admin team %s: %v", repoconfig.AdminTeam, err)
+		return nil
+	}
+	return adminteam.Spec.Owners
+}
+
+func (g *GoliacLocalImpl) SyncUsersAndTeams(ctx context.Context, repoconfig *config.RepositoryConfig, userplugin UserSyncPlugin, accesstoken string, branch string, dryrun bool) (*UserSyncReport, error) {
 	if g.repo == nil {
-		return fmt.Errorf("git repository not cloned")
+		return nil, fmt.Errorf("git repository not cloned")
 	}
 	w, err := g.repo.Worktree()
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	// read the organization files
@@ -486,9 +689,13 @@ func (g *GoliacLocalImpl) SyncUsersAndTeams(repoconfig *config.RepositoryConfig,
 
 	// Parse all the users in the <orgDirectory>/org-users directory
 	fs := afero.NewOsFs()
-	deletedusers, addedusers, err := syncUsersViaUserPlugin(repoconfig, fs, userplugin, rootDir)
+	previousUsers, errs, _ := entity.ReadUserDirectory(fs, filepath.Join(rootDir, "users", "org"))
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("cannot load org users (for example: %v)", errs[0])
+	}
+	deletedusers, addedusers, err := syncUsersViaUserPlugin(ctx, repoconfig, fs, userplugin, rootDir)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	//
@@ -497,12 +704,26 @@ func (g *GoliacLocalImpl) SyncUsersAndTeams(repoconfig *config.RepositoryConfig,
 
 	errors, _ := g.loadUsers(fs, rootDir)
 	if len(errors) > 0 {
-		return fmt.Errorf("cannot read users (for example: %v)", errors[0])
+		return nil, fmt.Errorf("cannot read users (for example: %v)", errors[0])
 	}
 
-	teamschanged, err := entity.ReadAndAdjustTeamDirectory(fs, filepath.Join(rootDir, "teams"), g.users)
+	groups, err := syncGroupsViaUserPlugin(ctx, repoconfig, fs, userplugin, rootDir)
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	teamschanged, err := entity.ReadAndAdjustTeamDirectory(fs, filepath.Join(rootDir, "teams"), g.users, groups, teamsFallbackOwners(fs, repoconfig, rootDir))
+	if err != nil {
+		return nil, err
+	}
+
+	currentUsers, _, _ := entity.ReadUserDirectory(fs, filepath.Join(rootDir, "users", "org"))
+	report := NewUserSyncReport(previousUsers, currentUsers, teamschanged)
+
+	teams, _, _ := entity.ReadTeamDirectory(fs, filepath.Join(rootDir, "teams"), g.users)
+	for _, orphan := range entity.ListOrphans(teams, nil, g.users) {
+		logrus.Warnf("team %s has no owner (set its manager, or usersync.fallback_owners)", orphan.Team)
+		report.OrphanedTeams = append(report.OrphanedTeams, orphan.Team)
 	}
 
 	//
@@ -512,14 +733,14 @@ func (g *GoliacLocalImpl) SyncUsersAndTeams(repoconfig *config.RepositoryConfig,
 
 		logrus.Info("some users and/or teams must be commited")
 		if dryrun {
-			return nil
+			return report, nil
 		}
 
 		for _, u := range deletedusers {
 			logrus.WithFields(map[string]interface{}{"dryrun": dryrun, "author": "goliac", "command": "remove_user_from_repository"}).Infof("user: %s", u)
 			_, err = w.Remove(u)
 			if err != nil {
-				return err
+				return nil, err
 			}
 		}
 
@@ -527,7 +748,7 @@ func (g *GoliacLocalImpl) SyncUsersAndTeams(repoconfig *config.RepositoryConfig,
 			logrus.WithFields(map[string]interface{}{"dryrun": dryrun, "author": "goliac", "command": "add_user_to_repository"}).Infof("user: %s", u)
 			_, err = w.Add(u)
 			if err != nil {
-				return err
+				return nil, err
 			}
 		}
 
@@ -535,7 +756,7 @@ func (g *GoliacLocalImpl) SyncUsersAndTeams(repoconfig *config.RepositoryConfig,
 			logrus.WithFields(map[string]interface{}{"dryrun": dryrun, "author": "goliac", "command": "update_team_to_repository"}).Infof("team: %s", t)
 			_, err = w.Add(t)
 			if err != nil {
-				return err
+				return nil, err
 			}
 		}
 
@@ -548,19 +769,40 @@ func (g *GoliacLocalImpl) SyncUsersAndTeams(repoconfig *config.RepositoryConfig,
 		})
 
 		if err != nil {
-			return err
+			return nil, err
 		}
 
 		_, err = g.repo.CommitObject(commit)
 		if err != nil {
-			return err
+			return nil, err
 		}
 
-		err = g.repo.Push(&git.PushOptions{})
+		// the pull request branch is rebuilt from branch at each sync
+		force := false
+		if repoconfig.UserSync.PullRequest {
+			branch = UserSyncBranch
+			force = true
+		}
 
-		return err
+		headRef, err := g.repo.Head()
+		if err != nil {
+			return nil, err
+		}
+		refSpec := fmt.Sprintf("%s:refs/heads/%s", headRef.Name(), branch)
+		err = g.repo.PushContext(ctx, &git.PushOptions{
+			RemoteName: "origin",
+			Auth: &http.BasicAuth{
+				Username: "x-access-token", // This can be anything except an empty string
+				Password: accesstoken,
+			},
+			Force:    force,
+			RefSpecs: []goconfig.RefSpec{goconfig.RefSpec(refSpec)},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("Error pushing to remote: %v", err)
+		}
 	}
-	return nil
+	return report, nil
 }
 
 /*
@@ -594,22 +836,38 @@ func (g *GoliacLocalImpl) loadUsers(fs afero.Fs, orgDirectory string) ([]error,
 	protectedUsers, errs, warns := entity.ReadUserDirectory(fs, filepath.Join(orgDirectory, "users", "protected"))
 	errors = append(errors, errs...)
 	warnings = append(warnings, warns...)
-	g.users = protectedUsers
+	if protectedUsers == nil {
+		protectedUsers = map[string]*entity.User{}
+	}
+	g.protectedUsers = protectedUsers
+	users := make(map[string]*entity.User)
+	for k, v := range protectedUsers {
+		users[k] = v
+	}
 
 	// Parse all the users in the <orgDirectory>/org-users directory
 	orgUsers, errs, warns := entity.ReadUserDirectory(fs, filepath.Join(orgDirectory, "users", "org"))
 	errors = append(errors, errs...)
 	warnings = append(warnings, warns...)
 
+	for k, v := range orgUsers {
+		if _, ok := protectedUsers[k]; ok {
+			// a protected user cannot be changed (or removed) via users/org
+			warnings = append(warnings, fmt.Errorf("user %s is defined in users/org and users/protected: the protected definition is used", k))
+			continue
+		}
+		users[k] = v
+	}
+
+	g.usersMutex.Lock()
+	g.users = users
+	g.usersMutex.Unlock()
+
 	// not users? not good
 	if orgUsers == nil {
 		return errors, warnings
 	}
 
-	for k, v := range orgUsers {
-		g.users[k] = v
-	}
-
 	// Parse all the users in the <orgDirectory>/external-users directory
 	externalUsers, errs, warns := entity.ReadUserDirectory(fs, filepath.Join(orgDirectory, "users", "external"))
 	errors = append(errors, errs...)
@@ -636,6 +894,13 @@ func (g *GoliacLocalImpl) LoadAndValidateLocal(fs afero.Fs, orgDirectory string)
 		return errors, warnings
 	}
 
+	// a protected user cannot be removed at once
+	for username := range g.appliedUsers {
+		if _, ok := g.users[username]; !ok {
+			errors = append(errors, fmt.Errorf("the protected user %s cannot be removed: move it from users/protected to users/org first", username))
+		}
+	}
+
 	// Parse all the teams in the <orgDirectory>/teams directory
 	teams, errs, warns := entity.ReadTeamDirectory(fs, filepath.Join(orgDirectory, "teams"), g.users)
 	errors = append(errors, errs...)
@@ -648,6 +913,66 @@ func (g *GoliacLocalImpl) LoadAndValidateLocal(fs afero.Fs, orgDirectory string)
 	warnings = append(warnings, warns...)
 	g.repositories = repos
 
+	// goliac.yaml is optional when validating a local directory
+	g.repoconfig = nil
+	repoconfig := &config.RepositoryConfig{}
+	exist, err := afero.Exists(fs, filepath.Join(orgDirectory, "goliac.yaml"))
+	if err != nil {
+		return append(errors, err), warnings
+	}
+	if exist {
+		if parsed, err := readRepoConfig(fs, orgDirectory); err != nil {
+			errors = append(errors, err)
+		} else {
+			repoconfig = parsed
+			g.repoconfig = parsed
+			errors = append(errors, g.validateRepoConfig(repoconfig)...)
+		}
+	}
+	for reponame, repo := range g.repositories {
+		if err := entity.ValidateCustomProperties(repo.Spec.CustomProperties, repoconfig.CustomProperties); err != nil {
+			errors = append(errors, fmt.Errorf("invalid customProperties for repository %s: %v", reponame, err))
+		}
+	}
+
+	// managerOf must agree with the manager of the teams
+	for username, user := range g.users {
+		for _, teamname := range user.Spec.ManagerOf {
+			team, ok := g.teams[teamname]
+			if !ok {
+				warnings = append(warnings, fmt.Errorf("user %s is manager of the unknown team %s", username, teamname))
+				continue
+			}
+			if team.Spec.Manager != username {
+				errors = append(errors, fmt.Errorf("user %s is manager of the team %s, but the team manager is %q (see teams/%s/team.yaml)", username, teamname, team.Spec.Manager, teamname))
+			}
+		}
+	}
+
+	// warn about teams that nobody (but the admin team) can manage anymore
+	for _, orphan := range entity.ListOrphans(g.teams, g.repositories, g.users) {
+		if len(orphan.Repositories) > 0 {
+			warnings = append(warnings, fmt.Errorf("team %s has no owner: only the admin team can approve changes to it and its repositories (%s)", orphan.Team, strings.Join(orphan.Repositories, ", ")))
+		} else {
+			warnings = append(warnings, fmt.Errorf("team %s has no owner: only the admin team can approve changes to it", orphan.Team))
+		}
+	}
+
+	// Parse all the temporary access requests in the <orgDirectory>/requests directory
+	accessRequests, errs, warns := entity.ReadAccessRequestDirectory(fs, filepath.Join(orgDirectory, "requests"), g.repositories, g.teams, g.externalUsers)
+	errors = append(errors, errs...)
+	warnings = append(warnings, warns...)
+	g.accessRequests = accessRequests
+
+	// warn about external users accesses expiring soon
+	for _, e := range entity.ListExpirations(g.repositories, g.externalUsers, time.Now(), entity.ExpirationWarningDays*24*time.Hour) {
+		if time.Now().Before(e.Expires) {
+			warnings = append(warnings, fmt.Errorf("external user %s access to repository %s will expire on %s", e.Username, e.Repository, e.Date))
+		} else {
+			warnings = append(warnings, fmt.Errorf("external user %s access to repository %s has expired on %s", e.Username, e.Repository, e.Date))
+		}
+	}
+
 	rulesets, errs, warns := entity.ReadRuleSetDirectory(fs, filepath.Join(orgDirectory, "rulesets"))
 	errors = append(errors, errs...)
 	warnings = append(warnings, warns...)
@@ -660,3 +985,64 @@ func (g *GoliacLocalImpl) LoadAndValidateLocal(fs afero.Fs, orgDirectory string)
 
 	return errors, warnings
 }
+
+/*
+ * validateAdminTeam checks that the admin team would not be empty
+ * (the protected users are always members of it)
+ */
+func (g *GoliacLocalImpl) validateAdminTeam(adminteam string) error {
+	if len(g.protectedUsers) > 0 {
+		return nil
+	}
+	if team, ok := g.teams[adminteam]; ok {
+		for _, members := range [][]string{team.Spec.Owners, team.Spec.Members} {
+			for _, u := range members {
+				if _, ok := g.users[u]; ok {
+					return nil
+				}
+			}
+		}
+	}
+	return fmt.Errorf("the admin team %s would be empty: it needs at least one owner, member or protected user", adminteam)
+}
+
+/*
+ * validateRepoConfig checks the goliac.yaml file: the organization settings,
+ * the drift policy, the actions, the custom properties schema and the rulesets
+ * properties against it
+ */
+func (g *GoliacLocalImpl) validateRepoConfig(repoconfig *config.RepositoryConfig) []error {
+	errors := []error{}
+
+	if err := entity.ValidateOrganizationSettings(repoconfig.Organization); err != nil {
+		return []error{fmt.Errorf("invalid organization in goliac.yaml: %v", err)}
+	}
+	if err := entity.ValidateDriftPolicy(repoconfig.DriftPolicy); err != nil {
+		return []error{fmt.Errorf("invalid drift_policy in goliac.yaml: %v", err)}
+	}
+	if err := entity.ValidateCustomPropertiesSchema(repoconfig.CustomProperties); err != nil {
+		return []error{fmt.Errorf("invalid custom_properties in goliac.yaml: %v", err)}
+	}
+	for name := range repoconfig.Actions.Variables {
+		if err := entity.ValidateActionsName(name); err != nil {
+			errors = append(errors, fmt.Errorf("invalid actions variable in goliac.yaml: %v", err))
+		}
+	}
+	if err := entity.ValidateSecretReferences(repoconfig.Actions.Secrets); err != nil {
+		errors = append(errors, fmt.Errorf("invalid actions secret in goliac.yaml: %v", err))
+	}
+	for _, rs := range repoconfig.Rulesets {
+		properties := make(map[string]entity.PropertyValue)
+		for name, value := range rs.Properties {
+			properties[name] = entity.PropertyValue{value}
+		}
+		if err := entity.ValidateCustomProperties(properties, repoconfig.CustomProperties); err != nil {
+			errors = append(errors, fmt.Errorf("invalid properties for the ruleset %s in goliac.yaml: %v", rs.Ruleset, err))
+		}
+	}
+
+	if err := g.validateAdminTeam(repoconfig.AdminTeam); err != nil {
+		errors = append(errors, err)
+	}
+	return errors
+}
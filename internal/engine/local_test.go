@@ -1,6 +1,7 @@
 package engine
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -23,6 +24,7 @@ func createBasicStructure(fs afero.Fs, path string) error {
 	}
 
 	err = afero.WriteFile(fs, filepath.Join(path, "goliac.yaml"), []byte(`
+admin_team: team1
 `), 0644)
 	if err != nil {
 		return err
@@ -141,12 +143,330 @@ func TestRepository(t *testing.T) {
 		assert.Equal(t, 0, len(errs))
 		assert.Equal(t, 0, len(warns))
 	})
+
+	t.Run("happy path: protected users", func(t *testing.T) {
+		fs := afero.NewMemMapFs()
+		createBasicStructure(fs, "/tmp/goliac")
+		err := afero.WriteFile(fs, "/tmp/goliac/users/protected/user1.yaml", []byte(`
+apiVersion: v1
+kind: User
+name: user1
+spec:
+  githubID: github1-protected
+`), 0644)
+		assert.Nil(t, err)
+		g := NewGoliacLocalImpl()
+		errs, warns := g.LoadAndValidateLocal(fs, "/tmp/goliac")
+
+		assert.Equal(t, 0, len(errs))
+		assert.Equal(t, 1, len(warns))
+		// the protected definition wins
+		assert.Equal(t, "github1-protected", g.Users()["user1"].Spec.GithubID)
+		assert.Equal(t, 1, len(g.ProtectedUsers()))
+	})
+
+	t.Run("not happy path: invalid organization actions variable", func(t *testing.T) {
+		fs := afero.NewMemMapFs()
+		createBasicStructure(fs, "/tmp/goliac")
+		err := afero.WriteFile(fs, "/tmp/goliac/goliac.yaml", []byte(`
+admin_team: team1
+actions:
+  variables:
+    GITHUB_REGION: us-east-1
+`), 0644)
+		assert.Nil(t, err)
+		g := NewGoliacLocalImpl()
+		errs, _ := g.LoadAndValidateLocal(fs, "/tmp/goliac")
+
+		assert.Equal(t, 1, len(errs))
+		assert.Contains(t, errs[0].Error(), "invalid actions variable in goliac.yaml")
+	})
+
+	t.Run("not happy path: ruleset properties not in the custom properties schema", func(t *testing.T) {
+		fs := afero.NewMemMapFs()
+		createBasicStructure(fs, "/tmp/goliac")
+		err := afero.WriteFile(fs, "/tmp/goliac/goliac.yaml", []byte(`
+admin_team: team1
+custom_properties:
+  - name: tier
+    value_type: single_select
+    allowed_values: [critical, standard]
+rulesets:
+  - pattern: .*
+    ruleset: default
+    properties:
+      tier: critical
+  - pattern: .*
+    ruleset: other
+    properties:
+      teir: critical
+`), 0644)
+		assert.Nil(t, err)
+		g := NewGoliacLocalImpl()
+		errs, _ := g.LoadAndValidateLocal(fs, "/tmp/goliac")
+
+		assert.Equal(t, 1, len(errs))
+		assert.Contains(t, errs[0].Error(), "invalid properties for the ruleset other in goliac.yaml")
+	})
+
+	t.Run("not happy path: managerOf and the team manager disagree", func(t *testing.T) {
+		fs := afero.NewMemMapFs()
+		createBasicStructure(fs, "/tmp/goliac")
+		err := afero.WriteFile(fs, "/tmp/goliac/users/org/user2.yaml", []byte(`
+apiVersion: v1
+kind: User
+name: user2
+spec:
+  githubID: github2
+  managerOf:
+    - team1
+`), 0644)
+		assert.Nil(t, err)
+		g := NewGoliacLocalImpl()
+		errs, _ := g.LoadAndValidateLocal(fs, "/tmp/goliac")
+
+		assert.Equal(t, 1, len(errs))
+		assert.Contains(t, errs[0].Error(), "user user2 is manager of the team team1, but the team manager is \"\"")
+
+		// unless the team says so
+		err = afero.WriteFile(fs, "/tmp/goliac/teams/team1/team.yaml", []byte(`
+apiVersion: v1
+kind: Team
+name: team1
+spec:
+  manager: user2
+  owners:
+  - user1
+  - user2
+`), 0644)
+		assert.Nil(t, err)
+		g = NewGoliacLocalImpl()
+		errs, _ = g.LoadAndValidateLocal(fs, "/tmp/goliac")
+
+		assert.Equal(t, 0, len(errs))
+	})
+
+	t.Run("not happy path: empty admin team", func(t *testing.T) {
+		fs := afero.NewMemMapFs()
+		createBasicStructure(fs, "/tmp/goliac")
+		err := afero.WriteFile(fs, "/tmp/goliac/goliac.yaml", []byte(`
+admin_team: admin
+`), 0644)
+		assert.Nil(t, err)
+		g := NewGoliacLocalImpl()
+		errs, _ := g.LoadAndValidateLocal(fs, "/tmp/goliac")
+
+		assert.Equal(t, 1, len(errs))
+		assert.Contains(t, errs[0].Error(), "the admin team admin would be empty")
+
+		// unless a protected user is there
+		err = afero.WriteFile(fs, "/tmp/goliac/users/protected/breakglass.yaml", []byte(`
+apiVersion: v1
+kind: User
+name: breakglass
+spec:
+  githubID: breakglass-github
+`), 0644)
+		assert.Nil(t, err)
+		g = NewGoliacLocalImpl()
+		errs, _ = g.LoadAndValidateLocal(fs, "/tmp/goliac")
+
+		assert.Equal(t, 0, len(errs))
+	})
+}
+
+func TestGetTagCommit(t *testing.T) {
+	t.Run("happy path: commit of the goliac tag, not HEAD", func(t *testing.T) {
+		tmpDirectory, err := os.MkdirTemp("", "goliac")
+		assert.Nil(t, err)
+		defer os.RemoveAll(tmpDirectory)
+
+		r, err := git.PlainInit(tmpDirectory, false)
+		assert.Nil(t, err)
+		w, err := r.Worktree()
+		assert.Nil(t, err)
+
+		commit := func(filename string) {
+			err := os.WriteFile(filepath.Join(tmpDirectory, filename), []byte("content"), 0644)
+			assert.Nil(t, err)
+			_, err = w.Add(filename)
+			assert.Nil(t, err)
+			_, err = w.Commit("commit "+filename, &git.CommitOptions{
+				Author: &object.Signature{Name: "goliac", Email: "goliac@alayacare.com", When: time.Now()},
+			})
+			assert.Nil(t, err)
+		}
+
+		commit("applied.yaml")
+		head, err := r.Head()
+		assert.Nil(t, err)
+		_, err = r.CreateTag("goliac", head.Hash(), nil)
+		assert.Nil(t, err)
+		commit("pending.yaml")
+
+		g := &GoliacLocalImpl{repo: r}
+
+		tagCommit, err := g.GetTagCommit("goliac")
+		assert.Nil(t, err)
+		assert.Equal(t, head.Hash(), tagCommit.Hash)
+
+		headCommit, err := g.GetHeadCommit()
+		assert.Nil(t, err)
+		assert.NotEqual(t, tagCommit.Hash, headCommit.Hash)
+
+		_, err = g.GetTagCommit("unknown")
+		assert.NotNil(t, err)
+	})
+}
+
+func TestAppliedProtectedUsers(t *testing.T) {
+	t.Run("not happy path: a protected user whose file is removed fails the validation", func(t *testing.T) {
+		tmpDirectory, err := os.MkdirTemp("", "goliac")
+		assert.Nil(t, err)
+		defer os.RemoveAll(tmpDirectory)
+
+		r := createProtectedUserRepository(t, tmpDirectory)
+		w, err := r.Worktree()
+		assert.Nil(t, err)
+
+		// the protected user file is removed
+		_, err = w.Remove("users/protected/breakglass.yaml")
+		assert.Nil(t, err)
+		_, err = w.Commit("remove the protected user", &git.CommitOptions{
+			Author: &object.Signature{Name: "goliac", Email: "goliac@alayacare.com", When: time.Now()},
+		})
+		assert.Nil(t, err)
+
+		g := NewGoliacLocalImpl().(*GoliacLocalImpl)
+		g.repo = r
+		assert.Nil(t, g.LoadAppliedProtectedUsers("goliac"))
+		assert.Equal(t, 1, len(g.AppliedProtectedUsers()))
+		errs, _ := g.LoadAndValidate()
+		assert.Equal(t, 1, len(errs))
+		assert.Contains(t, errs[0].Error(), "the protected user breakglass cannot be removed")
+
+		// the reconciliator doesn't remove it either
+		remote := newGoliacRemoteMock()
+		for _, githubid := range []string{"github1", "github2", "breakglass-github", "leaver-github"} {
+			remote.users[githubid] = UserStateMember
+		}
+		recorder := NewReconciliatorListenerRecorder()
+		repoconfig := &config.RepositoryConfig{AdminTeam: "team1"}
+		repoconfig.DestructiveOperations.AllowDestructiveUsers = true
+		reconciliator := NewGoliacReconciliatorImpl(recorder, repoconfig)
+
+		err = reconciliator.Reconciliate(context.TODO(), g, remote, "teams", false)
+
+		assert.Nil(t, err)
+		assert.Equal(t, map[string]string{"leaver-github": "leaver-github"}, recorder.UsersRemoved)
+	})
+
+	t.Run("happy path: a protected user moved to users/org", func(t *testing.T) {
+		tmpDirectory, err := os.MkdirTemp("", "goliac")
+		assert.Nil(t, err)
+		defer os.RemoveAll(tmpDirectory)
+
+		r := createProtectedUserRepository(t, tmpDirectory)
+		w, err := r.Worktree()
+		assert.Nil(t, err)
+
+		err = os.Rename(filepath.Join(tmpDirectory, "users/protected/breakglass.yaml"), filepath.Join(tmpDirectory, "users/org/breakglass.yaml"))
+		assert.Nil(t, err)
+		_, err = w.Add(".")
+		assert.Nil(t, err)
+		_, err = w.Commit("unprotect the user", &git.CommitOptions{
+			Author: &object.Signature{Name: "goliac", Email: "goliac@alayacare.com", When: time.Now()},
+		})
+		assert.Nil(t, err)
+
+		// like goliac verify
+		g := NewGoliacLocalImpl().(*GoliacLocalImpl)
+		assert.Nil(t, g.LoadAppliedProtectedUsersFromDirectory(tmpDirectory, "goliac"))
+		assert.Equal(t, 1, len(g.AppliedProtectedUsers()))
+		errs, _ := g.LoadAndValidateLocal(afero.NewOsFs(), tmpDirectory)
+		assert.Equal(t, 0, len(errs))
+	})
+
+	t.Run("happy path: no tag", func(t *testing.T) {
+		tmpDirectory, err := os.MkdirTemp("", "goliac")
+		assert.Nil(t, err)
+		defer os.RemoveAll(tmpDirectory)
+
+		r, err := git.PlainInit(tmpDirectory, false)
+		assert.Nil(t, err)
+
+		g := NewGoliacLocalImpl().(*GoliacLocalImpl)
+		g.repo = r
+		assert.Nil(t, g.LoadAppliedProtectedUsers("goliac"))
+		assert.Equal(t, 0, len(g.AppliedProtectedUsers()))
+	})
+
+	t.Run("happy path: not a git repository", func(t *testing.T) {
+		tmpDirectory, err := os.MkdirTemp("", "goliac")
+		assert.Nil(t, err)
+		defer os.RemoveAll(tmpDirectory)
+
+		g := NewGoliacLocalImpl().(*GoliacLocalImpl)
+		assert.Nil(t, g.LoadAppliedProtectedUsersFromDirectory(tmpDirectory, "goliac"))
+		assert.Equal(t, 0, len(g.AppliedProtectedUsers()))
+	})
+}
+
+/*
+ * createProtectedUserRepository creates a git repository with a protected user
+ * (breakglass), and the goliac tag on its commit
+ */
+func createProtectedUserRepository(t *testing.T, tmpDirectory string) *git.Repository {
+	r, err := git.PlainInit(tmpDirectory, false)
+	assert.Nil(t, err)
+	w, err := r.Worktree()
+	assert.Nil(t, err)
+
+	fs := afero.NewOsFs()
+	createBasicStructure(fs, tmpDirectory)
+	err = fs.MkdirAll(filepath.Join(tmpDirectory, "users/protected"), 0755)
+	assert.Nil(t, err)
+	err = afero.WriteFile(fs, filepath.Join(tmpDirectory, "users/protected/breakglass.yaml"), []byte(`
+apiVersion: v1
+kind: User
+name: breakglass
+spec:
+  githubID: breakglass-github
+`), 0644)
+	assert.Nil(t, err)
+	_, err = w.Add(".")
+	assert.Nil(t, err)
+	applied, err := w.Commit("protected user", &git.CommitOptions{
+		Author: &object.Signature{Name: "goliac", Email: "goliac@alayacare.com", When: time.Now()},
+	})
+	assert.Nil(t, err)
+	_, err = r.CreateTag("goliac", applied, nil)
+	assert.Nil(t, err)
+	return r
+}
+
+func TestCodeOwners(t *testing.T) {
+	t.Run("happy path: protected users are only owned by the admin team", func(t *testing.T) {
+		config.Config.GithubAppOrganization = "myorg"
+		defer func() { config.Config.GithubAppOrganization = "" }()
+		fs := afero.NewMemMapFs()
+		createBasicStructure(fs, "/tmp/goliac")
+		g := NewGoliacLocalImpl().(*GoliacLocalImpl)
+		g.LoadAndValidateLocal(fs, "/tmp/goliac")
+
+		codeowners := g.codeowners_regenerate("admin")
+
+		assert.Contains(t, codeowners, "/teams/team1/* @myorg/team1-owners @myorg/admin\n")
+		// like everything out of the teams directories
+		assert.Contains(t, codeowners, "* @myorg/admin\n")
+		assert.NotContains(t, codeowners, "/users/")
+	})
 }
 
 type ScrambleUserSync struct {
 }
 
-func (p *ScrambleUserSync) UpdateUsers(repoconfig *config.RepositoryConfig, orguserdirrectorypath string) (map[string]*entity.User, error) {
+func (p *ScrambleUserSync) UpdateUsers(ctx context.Context, repoconfig *config.RepositoryConfig, orguserdirrectorypath string) (map[string]*entity.User, error) {
 	users := make(map[string]*entity.User)
 
 	// added
@@ -171,7 +491,7 @@ func (p *ScrambleUserSync) UpdateUsers(repoconfig *config.RepositoryConfig, orgu
 type ErroreUserSync struct {
 }
 
-func (p *ErroreUserSync) UpdateUsers(repoconfig *config.RepositoryConfig, orguserdirrectorypath string) (map[string]*entity.User, error) {
+func (p *ErroreUserSync) UpdateUsers(ctx context.Context, repoconfig *config.RepositoryConfig, orguserdirrectorypath string) (map[string]*entity.User, error) {
 	return nil, fmt.Errorf("unknown error")
 }
 
@@ -185,7 +505,7 @@ func NewUserSyncPluginNoop() UserSyncPlugin {
 	}
 }
 
-func (p *UserSyncPluginNoop) UpdateUsers(repoconfig *config.RepositoryConfig, orguserdirrectorypath string) (map[string]*entity.User, error) {
+func (p *UserSyncPluginNoop) UpdateUsers(ctx context.Context, repoconfig *config.RepositoryConfig, orguserdirrectorypath string) (map[string]*entity.User, error) {
 	users, errs, _ := entity.ReadUserDirectory(p.Fs, orguserdirrectorypath)
 	if len(errs) > 0 {
 		return nil, fmt.Errorf("cannot load org users (for example: %v)", errs[0])
@@ -200,7 +520,7 @@ func TestSyncUsersViaUserPlugin(t *testing.T) {
 		fs := afero.NewMemMapFs()
 		createBasicStructure(fs, "/tmp/goliac")
 
-		removed, added, err := syncUsersViaUserPlugin(&config.RepositoryConfig{}, fs, &UserSyncPluginNoop{
+		removed, added, err := syncUsersViaUserPlugin(context.TODO(), &config.RepositoryConfig{}, fs, &UserSyncPluginNoop{
 			Fs: fs,
 		}, "/tmp/goliac")
 
@@ -213,7 +533,7 @@ func TestSyncUsersViaUserPlugin(t *testing.T) {
 		fs := afero.NewMemMapFs()
 		createBasicStructure(fs, "/tmp/goliac")
 
-		removed, added, err := syncUsersViaUserPlugin(&config.RepositoryConfig{}, fs, &ScrambleUserSync{}, "/tmp/goliac")
+		removed, added, err := syncUsersViaUserPlugin(context.TODO(), &config.RepositoryConfig{}, fs, &ScrambleUserSync{}, "/tmp/goliac")
 
 		assert.Nil(t, err)
 		assert.Equal(t, 1, len(removed))
@@ -225,7 +545,7 @@ func TestSyncUsersViaUserPlugin(t *testing.T) {
 		fs := afero.NewMemMapFs()
 		createBasicStructure(fs, "/tmp/goliac")
 
-		_, _, err := syncUsersViaUserPlugin(&config.RepositoryConfig{}, fs, &ErroreUserSync{}, "/tmp/goliac")
+		_, _, err := syncUsersViaUserPlugin(context.TODO(), &config.RepositoryConfig{}, fs, &ErroreUserSync{}, "/tmp/goliac")
 
 		assert.NotNil(t, err)
 	})
@@ -1,6 +1,10 @@
 package engine
 
-import "github.com/gosimple/slug"
+import (
+	"context"
+
+	"github.com/gosimple/slug"
+)
 
 /*
  * MutableGoliacRemoteImpl is used by GoliacReconciliatorImpl to update
@@ -15,31 +19,52 @@ type MutableGoliacRemoteImpl struct {
 	teamSlugByName map[string]string
 	rulesets       map[string]*GithubRuleSet
 	appIds         map[string]int
+	reposActions   map[string]*GithubActions
+	orgActions     *GithubActions
+	properties     map[string]*GithubCustomProperty
+	reposProps     map[string]map[string][]string
+	orgSettings    *GithubOrganizationSettings
+}
+
+func copyGithubActions(actions *GithubActions) *GithubActions {
+	c := &GithubActions{
+		Variables: make(map[string]string),
+		Secrets:   make(map[string]string),
+	}
+	if actions != nil {
+		for k, v := range actions.Variables {
+			c.Variables[k] = v
+		}
+		for k, v := range actions.Secrets {
+			c.Secrets[k] = v
+		}
+	}
+	return c
 }
 
-func NewMutableGoliacRemoteImpl(remote GoliacRemote) *MutableGoliacRemoteImpl {
+func NewMutableGoliacRemoteImpl(ctx context.Context, remote GoliacRemote) *MutableGoliacRemoteImpl {
 	rUsers := make(map[string]string)
-	for k, v := range remote.Users() {
+	for k, v := range remote.Users(ctx) {
 		rUsers[k] = v
 	}
 	rTeamSlugByName := make(map[string]string)
-	for k, v := range remote.TeamSlugByName() {
+	for k, v := range remote.TeamSlugByName(ctx) {
 		rTeamSlugByName[k] = v
 	}
 	rTeams := make(map[string]*GithubTeam)
-	for k, v := range remote.Teams() {
+	for k, v := range remote.Teams(ctx) {
 		ght := *v
 		rTeams[k] = &ght
 	}
 
 	rRepositories := make(map[string]*GithubRepository)
-	for k, v := range remote.Repositories() {
+	for k, v := range remote.Repositories(ctx) {
 		ghr := *v
 		rRepositories[k] = &ghr
 	}
 
 	rTeamRepositories := make(map[string]map[string]*GithubTeamRepo)
-	for k1, v1 := range remote.TeamRepositories() {
+	for k1, v1 := range remote.TeamRepositories(ctx) {
 		repos := make(map[string]*GithubTeamRepo)
 		for k2, v2 := range v1 {
 			gtr := *v2
@@ -49,15 +74,36 @@ func NewMutableGoliacRemoteImpl(remote GoliacRemote) *MutableGoliacRemoteImpl {
 	}
 
 	rulesets := make(map[string]*GithubRuleSet)
-	for k, v := range remote.RuleSets() {
+	for k, v := range remote.RuleSets(ctx) {
 		rulesets[k] = v
 	}
 
 	appids := make(map[string]int)
-	for k, v := range remote.AppIds() {
+	for k, v := range remote.AppIds(ctx) {
 		appids[k] = v
 	}
 
+	properties := make(map[string]*GithubCustomProperty)
+	for k, v := range remote.CustomProperties(ctx) {
+		p := *v
+		properties[k] = &p
+	}
+
+	reposProps := make(map[string]map[string][]string)
+	for k1, v1 := range remote.RepositoriesCustomProperties(ctx) {
+		values := make(map[string][]string)
+		for k2, v2 := range v1 {
+			values[k2] = v2
+		}
+		reposProps[k1] = values
+	}
+
+	var orgSettings *GithubOrganizationSettings
+	if s := remote.OrganizationSettings(ctx); s != nil {
+		orgSettings = &GithubOrganizationSettings{}
+		*orgSettings = *s
+	}
+
 	return &MutableGoliacRemoteImpl{
 		users:          rUsers,
 		repositories:   rRepositories,
@@ -66,7 +112,27 @@ func NewMutableGoliacRemoteImpl(remote GoliacRemote) *MutableGoliacRemoteImpl {
 		teamSlugByName: rTeamSlugByName,
 		rulesets:       rulesets,
 		appIds:         appids,
+		reposActions:   make(map[string]*GithubActions),
+		orgActions:     &GithubActions{Variables: map[string]string{}, Secrets: map[string]string{}},
+		properties:     properties,
+		reposProps:     reposProps,
+		orgSettings:    orgSettings,
+	}
+}
+
+/*
+ * loadActions copies the Github Actions variables and secrets of the remote.
+ * They are only loaded (from Github) when some are configured, see reconciliateActions
+ */
+func (m *MutableGoliacRemoteImpl) loadActions(ctx context.Context, remote GoliacRemote) error {
+	if err := remote.LoadActions(ctx); err != nil {
+		return err
+	}
+	for k, v := range remote.RepositoriesActions(ctx) {
+		m.reposActions[k] = copyGithubActions(v)
 	}
+	m.orgActions = copyGithubActions(remote.OrgActions(ctx))
+	return nil
 }
 
 func (m *MutableGoliacRemoteImpl) Users() map[string]string {
@@ -92,17 +158,40 @@ func (m *MutableGoliacRemoteImpl) RuleSets() map[string]*GithubRuleSet {
 func (g *MutableGoliacRemoteImpl) AppIds() map[string]int {
 	return g.appIds
 }
+func (m *MutableGoliacRemoteImpl) RepositoriesActions() map[string]*GithubActions {
+	return m.reposActions
+}
+func (m *MutableGoliacRemoteImpl) OrgActions() *GithubActions {
+	return m.orgActions
+}
+func (m *MutableGoliacRemoteImpl) CustomProperties() map[string]*GithubCustomProperty {
+	return m.properties
+}
+func (m *MutableGoliacRemoteImpl) RepositoriesCustomProperties() map[string]map[string][]string {
+	return m.reposProps
+}
+func (m *MutableGoliacRemoteImpl) OrganizationSettings() *GithubOrganizationSettings {
+	return m.orgSettings
+}
 
 // LISTENER
 
 func (m *MutableGoliacRemoteImpl) AddUserToOrg(ghuserid string) {
-	m.users[ghuserid] = ghuserid
+	m.users[ghuserid] = UserStateInvited
 }
 
 func (m *MutableGoliacRemoteImpl) RemoveUserFromOrg(ghuserid string) {
 	delete(m.users, ghuserid)
 }
 
+func (m *MutableGoliacRemoteImpl) InviteUserByEmail(email string) {
+	m.users[email] = UserStateInvited
+}
+
+func (m *MutableGoliacRemoteImpl) CancelOrgInvitation(invitee string) {
+	delete(m.users, invitee)
+}
+
 func (m *MutableGoliacRemoteImpl) CreateTeam(teamname string, description string, members []string) {
 	teamslug := slug.Make(teamname)
 	t := GithubTeam{
@@ -198,3 +287,64 @@ func (m *MutableGoliacRemoteImpl) UpdateRuleset(ruleset *GithubRuleSet) {
 func (m *MutableGoliacRemoteImpl) DeleteRuleset(rulesetid int) {
 
 }
+
+func (m *MutableGoliacRemoteImpl) repositoryActions(reponame string) *GithubActions {
+	actions, ok := m.reposActions[reponame]
+	if !ok {
+		actions = copyGithubActions(nil)
+		m.reposActions[reponame] = actions
+	}
+	return actions
+}
+func (m *MutableGoliacRemoteImpl) UpdateRepositorySetVariable(reponame string, name string, value string) {
+	m.repositoryActions(reponame).Variables[name] = value
+}
+func (m *MutableGoliacRemoteImpl) UpdateRepositoryRemoveVariable(reponame string, name string) {
+	delete(m.repositoryActions(reponame).Variables, name)
+}
+func (m *MutableGoliacRemoteImpl) UpdateRepositorySetSecret(reponame string, name string, value string) {
+	m.repositoryActions(reponame).Secrets[name] = hashSecretValue(value)
+}
+func (m *MutableGoliacRemoteImpl) UpdateRepositoryRemoveSecret(reponame string, name string) {
+	delete(m.repositoryActions(reponame).Secrets, name)
+}
+func (m *MutableGoliacRemoteImpl) SetOrgVariable(name string, value string) {
+	m.orgActions.Variables[name] = value
+}
+func (m *MutableGoliacRemoteImpl) RemoveOrgVariable(name string) {
+	delete(m.orgActions.Variables, name)
+}
+func (m *MutableGoliacRemoteImpl) SetOrgSecret(name string, value string) {
+	m.orgActions.Secrets[name] = hashSecretValue(value)
+}
+func (m *MutableGoliacRemoteImpl) RemoveOrgSecret(name string) {
+	delete(m.orgActions.Secrets, name)
+}
+func (m *MutableGoliacRemoteImpl) UpsertCustomProperty(property *GithubCustomProperty) {
+	m.properties[property.Name] = property
+}
+func (m *MutableGoliacRemoteImpl) DeleteCustomProperty(name string) {
+	delete(m.properties, name)
+	for _, values := range m.reposProps {
+		delete(values, name)
+	}
+}
+func (m *MutableGoliacRemoteImpl) UpdateRepositorySetCustomProperty(reponame string, name string, value []string) {
+	values, ok := m.reposProps[reponame]
+	if !ok {
+		values = make(map[string][]string)
+		m.reposProps[reponame] = values
+	}
+	if len(value) > 0 {
+		values[name] = value
+	} else {
+		delete(values, name)
+	}
+}
+func (m *MutableGoliacRemoteImpl) UpdateOrganizationSettings(settings *GithubOrganizationSettingsUpdate) {
+	if m.orgSettings == nil {
+		return
+	}
+	s := settings.ApplyTo(*m.orgSettings)
+	m.orgSettings = &s
+}
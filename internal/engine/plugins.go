@@ -1,13 +1,24 @@
 package engine
 
 import (
+	"context"
+
 	"github.com/Alayacare/goliac/internal/config"
 	"github.com/Alayacare/goliac/internal/entity"
 )
 
 type UserSyncPlugin interface {
 	// Get the current user list directory path, returns the new user list
-	UpdateUsers(repoconfig *config.RepositoryConfig, orguserdirrectorypath string) (map[string]*entity.User, error)
+	UpdateUsers(ctx context.Context, repoconfig *config.RepositoryConfig, orguserdirrectorypath string) (map[string]*entity.User, error)
+}
+
+/*
+ * UserSyncPluginGroups is implemented by the plugins that also know the
+ * IdP groups, to manage the members of the teams with spec.externallyManaged
+ */
+type UserSyncPluginGroups interface {
+	// Get the members (user names) of each requested group. Unknown groups are not returned
+	UpdateGroups(ctx context.Context, repoconfig *config.RepositoryConfig, groups []string) (map[string][]string, error)
 }
 
 var plugins map[string]UserSyncPlugin
@@ -24,3 +35,33 @@ func GetUserSyncPlugin(pluginname string) (UserSyncPlugin, bool) {
 	plugin, found := plugins[pluginname]
 	return plugin, found
 }
+
+type SecretProvider interface {
+	// Get the secret value associated with the key (an env variable name, a filename, ...)
+	GetSecret(key string) (string, error)
+}
+
+/*
+ * RepositorySecretProvider is implemented by the secret providers that can be
+ * used for repository secrets. The repositories files can be approved by the
+ * team owners: CheckRepositoryKey must refuse the keys giving access to
+ * the Goliac own secrets (like its Github App private key)
+ */
+type RepositorySecretProvider interface {
+	SecretProvider
+	CheckRepositoryKey(key string) error
+}
+
+var secretProviders map[string]SecretProvider
+
+func RegisterSecretProvider(name string, provider SecretProvider) {
+	if secretProviders == nil {
+		secretProviders = make(map[string]SecretProvider)
+	}
+	secretProviders[name] = provider
+}
+
+func GetSecretProvider(providername string) (SecretProvider, bool) {
+	provider, found := secretProviders[providername]
+	return provider, found
+}
@@ -1,29 +1,91 @@
 package engine
 
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+/*
+ * ReconciliatorExecutor applies the changes computed by the reconciliator.
+ * Each operation returns an error if it was not applied, and Commit returns
+ * the (aggregated) errors of the operations applied on commit
+ */
 type ReconciliatorExecutor interface {
-	AddUserToOrg(dryrun bool, ghuserid string)
-	RemoveUserFromOrg(dryrun bool, ghuserid string)
-
-	CreateTeam(dryrun bool, teamname string, description string, members []string)
-	UpdateTeamAddMember(dryrun bool, teamslug string, username string, role string) // role can be 'member' or 'maintainer'
-	//UpdateTeamUpdateMember(dryrun bool, teamslug string, username string, role string) // role can be 'member' or 'maintainer'
-	UpdateTeamRemoveMember(dryrun bool, teamslug string, username string)
-	DeleteTeam(dryrun bool, teamslug string)
-
-	CreateRepository(dryrun bool, reponame string, descrition string, writers []string, readers []string, public bool)
-	UpdateRepositoryUpdateArchived(dryrun bool, reponame string, archived bool)
-	UpdateRepositoryUpdatePrivate(dryrun bool, reponame string, private bool)
-	UpdateRepositoryAddTeamAccess(dryrun bool, reponame string, teamslug string, permission string)    // permission can be "pull", "push", or "admin" which correspond to read, write, and admin access.
-	UpdateRepositoryUpdateTeamAccess(dryrun bool, reponame string, teamslug string, permission string) // permission can be "pull", "push", or "admin" which correspond to read, write, and admin access.
-	UpdateRepositoryRemoveTeamAccess(dryrun bool, reponame string, teamslug string)
-	AddRuleset(dryrun bool, ruleset *GithubRuleSet)
-	UpdateRuleset(dryrun bool, ruleset *GithubRuleSet)
-	DeleteRuleset(dryrun bool, rulesetid int)
-	UpdateRepositorySetExternalUser(dryrun bool, reponame string, githubid string, permission string) // permission can be "pull" or "push"
-	UpdateRepositoryRemoveExternalUser(dryrun bool, reponame string, githubid string)
-	DeleteRepository(dryrun bool, reponame string)
-
-	Begin(dryrun bool)
-	Rollback(dryrun bool, err error)
-	Commit(dryrun bool)
+	AddUserToOrg(ctx context.Context, dryrun bool, ghuserid string) error
+	RemoveUserFromOrg(ctx context.Context, dryrun bool, ghuserid string) error
+	InviteUserByEmail(ctx context.Context, dryrun bool, email string) error
+	CancelOrgInvitation(ctx context.Context, dryrun bool, invitee string) error // invitee is a Github login or an email
+
+	CreateTeam(ctx context.Context, dryrun bool, teamname string, description string, members []string) error
+	UpdateTeamAddMember(ctx context.Context, dryrun bool, teamslug string, username string, role string) error // role can be 'member' or 'maintainer'
+	//UpdateTeamUpdateMember(ctx context.Context, dryrun bool, teamslug string, username string, role string) // role can be 'member' or 'maintainer'
+	UpdateTeamRemoveMember(ctx context.Context, dryrun bool, teamslug string, username string) error
+	DeleteTeam(ctx context.Context, dryrun bool, teamslug string) error
+	UpdateTeamSetExternalGroup(ctx context.Context, dryrun bool, teamslug string, groupname string) error // link the team to an IdP group (Enterprise Managed Users)
+	UpdateTeamRemoveExternalGroup(ctx context.Context, dryrun bool, teamslug string) error
+
+	CreateRepository(ctx context.Context, dryrun bool, reponame string, descrition string, writers []string, readers []string, public bool) error
+	UpdateRepositoryUpdateArchived(ctx context.Context, dryrun bool, reponame string, archived bool) error
+	UpdateRepositoryUpdatePrivate(ctx context.Context, dryrun bool, reponame string, private bool) error
+	UpdateRepositoryAddTeamAccess(ctx context.Context, dryrun bool, reponame string, teamslug string, permission string) error    // permission can be "pull", "push", or "admin" which correspond to read, write, and admin access.
+	UpdateRepositoryUpdateTeamAccess(ctx context.Context, dryrun bool, reponame string, teamslug string, permission string) error // permission can be "pull", "push", or "admin" which correspond to read, write, and admin access.
+	UpdateRepositoryRemoveTeamAccess(ctx context.Context, dryrun bool, reponame string, teamslug string) error
+	AddRuleset(ctx context.Context, dryrun bool, ruleset *GithubRuleSet) error
+	UpdateRuleset(ctx context.Context, dryrun bool, ruleset *GithubRuleSet) error
+	DeleteRuleset(ctx context.Context, dryrun bool, rulesetid int) error
+	UpdateRepositorySetExternalUser(ctx context.Context, dryrun bool, reponame string, githubid string, permission string) error // permission can be "pull" or "push"
+	UpdateRepositoryRemoveExternalUser(ctx context.Context, dryrun bool, reponame string, githubid string) error
+	DeleteRepository(ctx context.Context, dryrun bool, reponame string) error
+	UpdateRepositorySetVariable(ctx context.Context, dryrun bool, reponame string, name string, value string) error
+	UpdateRepositoryRemoveVariable(ctx context.Context, dryrun bool, reponame string, name string) error
+	UpdateRepositorySetSecret(ctx context.Context, dryrun bool, reponame string, name string, value string) error // value is the clear value, it will be encrypted before being sent
+	UpdateRepositoryRemoveSecret(ctx context.Context, dryrun bool, reponame string, name string) error
+	SetOrgVariable(ctx context.Context, dryrun bool, name string, value string) error
+	RemoveOrgVariable(ctx context.Context, dryrun bool, name string) error
+	SetOrgSecret(ctx context.Context, dryrun bool, name string, value string) error // value is the clear value, it will be encrypted before being sent
+	RemoveOrgSecret(ctx context.Context, dryrun bool, name string) error
+	UpsertCustomProperty(ctx context.Context, dryrun bool, property *GithubCustomProperty) error
+	DeleteCustomProperty(ctx context.Context, dryrun bool, name string) error
+	UpdateRepositorySetCustomProperty(ctx context.Context, dryrun bool, reponame string, name string, value []string) error // an empty value unsets the property
+	UpdateOrganizationSettings(ctx context.Context, dryrun bool, settings *GithubOrganizationSettingsUpdate) error
+
+	Begin(ctx context.Context, dryrun bool)
+	Rollback(ctx context.Context, dryrun bool, err error)
+	Commit(ctx context.Context, dryrun bool) error
+}
+
+/*
+ * ApplyErrors aggregates the errors of the operations that were not applied
+ */
+type ApplyErrors []error
+
+func (e ApplyErrors) Error() string {
+	msgs := make([]string, 0, len(e))
+	for _, err := range e {
+		msgs = append(msgs, err.Error())
+	}
+	return fmt.Sprintf("%d operation(s) failed: %s", len(e), strings.Join(msgs, "; "))
+}
+
+/*
+ * NewApplyErrors aggregates errs (flattening nested ApplyErrors)
+ * and returns nil if there is no error
+ */
+func NewApplyErrors(errs []error) error {
+	flatten := make(ApplyErrors, 0, len(errs))
+	for _, err := range errs {
+		if err == nil {
+			continue
+		}
+		if nested, ok := err.(ApplyErrors); ok {
+			flatten = append(flatten, nested...)
+		} else {
+			flatten = append(flatten, err)
+		}
+	}
+	if len(flatten) == 0 {
+		return nil
+	}
+	return flatten
 }
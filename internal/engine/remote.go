@@ -1,8 +1,12 @@
 package engine
 
 import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
+	"net/url"
 	"strings"
 	"sync"
 	"time"
@@ -10,13 +14,29 @@ import (
 	"github.com/Alayacare/goliac/internal/config"
 	"github.com/Alayacare/goliac/internal/entity"
 	"github.com/Alayacare/goliac/internal/github"
+	"github.com/Alayacare/goliac/internal/metrics"
 	"github.com/gosimple/slug"
 	"github.com/hashicorp/go-version"
 	"github.com/sirupsen/logrus"
+	"golang.org/x/crypto/nacl/box"
 )
 
 const FORLOOP_STOP = 100
 
+// names of the Github remote caches (also used as the "cache" label of the cache metrics)
+const (
+	CacheUsers            = "users"
+	CacheRepositories     = "repositories"
+	CacheTeams            = "teams"
+	CacheTeamsRepos       = "teams_repos"
+	CacheRulesets         = "rulesets"
+	CacheAppIds           = "appids"
+	CacheActions          = "actions"
+	CacheCustomProperties = "custom_properties"
+	CacheOrgSettings      = "org_settings"
+	CacheExternalGroups   = "external_groups"
+)
+
 /*
  * GoliacRemote
  * This interface is used to load the goliac organization from a Github
@@ -24,20 +44,43 @@ const FORLOOP_STOP = 100
  */
 type GoliacRemote interface {
 	// Load from a github repository
-	Load() error
+	Load(ctx context.Context) error
 	FlushCache()
-
-	Users() map[string]string
-	TeamSlugByName() map[string]string
-	Teams() map[string]*GithubTeam                           // the key is the team slug
-	Repositories() map[string]*GithubRepository              // the key is the repository name
-	TeamRepositories() map[string]map[string]*GithubTeamRepo // key is team slug, second key is repo name
-	RuleSets() map[string]*GithubRuleSet
-	AppIds() map[string]int
+	// expire only some caches (see the Cache* constants), to reload them on the next Load
+	InvalidateCache(caches ...string)
+
+	Users(ctx context.Context) map[string]string // the key is the Github login (or the email of an invitation by email), the value the UserState*
+	TeamSlugByName(ctx context.Context) map[string]string
+	Teams(ctx context.Context) map[string]*GithubTeam                           // the key is the team slug
+	Repositories(ctx context.Context) map[string]*GithubRepository              // the key is the repository name
+	TeamRepositories(ctx context.Context) map[string]map[string]*GithubTeamRepo // key is team slug, second key is repo name
+	RuleSets(ctx context.Context) map[string]*GithubRuleSet
+	AppIds(ctx context.Context) map[string]int
+	// load the Github Actions variables and secrets.
+	// Not part of Load: they cost 2 calls per repository, and are only needed if some are configured
+	LoadActions(ctx context.Context) error
+	RepositoriesActions(ctx context.Context) map[string]*GithubActions // the key is the repository name
+	OrgActions(ctx context.Context) *GithubActions
+	CustomProperties(ctx context.Context) map[string]*GithubCustomProperty           // the key is the property name
+	RepositoriesCustomProperties(ctx context.Context) map[string]map[string][]string // key is the repository name, second key is the property name
+	OrganizationSettings(ctx context.Context) *GithubOrganizationSettings            // nil if they could not be loaded
+	// the IdP groups of an Enterprise Managed Users organization (the key is the group name).
+	// Not part of Load: they are only loaded when asked for
+	ExternalGroups(ctx context.Context) map[string]*GithubExternalGroup
 
 	IsEnterprise() bool // check if we are on an Enterprise version, or if we are on GHES 3.11+
+
+	// the Github login of githubid (with its case), or "" if this Github user doesn't exist
+	GetUserLogin(ctx context.Context, githubid string) (string, error)
 }
 
+// state of a Github user in the organization (the values of GoliacRemote.Users())
+const (
+	UserStateMember           = "member"
+	UserStateInvited          = "invited"           // pending invitation
+	UserStateInvitationFailed = "invitation_failed" // expired (or failed) invitation
+)
+
 type GoliacRemoteExecutor interface {
 	GoliacRemote
 	ReconciliatorExecutor
@@ -58,6 +101,98 @@ type GithubTeam struct {
 	Members []string // user login
 }
 
+/*
+ * GithubActions holds the Github Actions variables and secrets
+ * of a repository (or of the organization)
+ */
+type GithubActions struct {
+	Variables map[string]string // [name]value
+	Secrets   map[string]string // [name]sha256 of the value last set by Goliac ("" if unknown, since Github never returns secret values)
+}
+
+/*
+ * GithubCustomProperty is an organization custom property definition
+ */
+type GithubCustomProperty struct {
+	Name          string
+	ValueType     string // string, single_select, multi_select or true_false
+	Required      bool
+	DefaultValue  string
+	Description   string
+	AllowedValues []string
+}
+
+/*
+ * GithubOrganizationSettings are the organization settings managed by Goliac
+ */
+type GithubOrganizationSettings struct {
+	DefaultRepositoryPermission       string // read, write, admin or none
+	MembersCanCreateRepositories      bool
+	MembersCanForkPrivateRepositories bool
+	WebCommitSignoffRequired          bool
+	TwoFactorRequirementEnabled       bool // read only
+}
+
+/*
+ * GithubOrganizationSettingsUpdate are the organization settings to change
+ * (nil values are left as is)
+ */
+type GithubOrganizationSettingsUpdate struct {
+	DefaultRepositoryPermission       *string
+	MembersCanCreateRepositories      *bool
+	MembersCanForkPrivateRepositories *bool
+	WebCommitSignoffRequired          *bool
+}
+
+/*
+ * Payload returns the settings to change, keyed by their Github API name
+ */
+func (u *GithubOrganizationSettingsUpdate) Payload() map[string]interface{} {
+	payload := make(map[string]interface{})
+	if u.DefaultRepositoryPermission != nil {
+		payload["default_repository_permission"] = *u.DefaultRepositoryPermission
+	}
+	if u.MembersCanCreateRepositories != nil {
+		payload["members_can_create_repositories"] = *u.MembersCanCreateRepositories
+	}
+	if u.MembersCanForkPrivateRepositories != nil {
+		payload["members_can_fork_private_repositories"] = *u.MembersCanForkPrivateRepositories
+	}
+	if u.WebCommitSignoffRequired != nil {
+		payload["web_commit_signoff_required"] = *u.WebCommitSignoffRequired
+	}
+	return payload
+}
+
+/*
+ * ApplyTo returns the organization settings once updated
+ */
+func (u *GithubOrganizationSettingsUpdate) ApplyTo(settings GithubOrganizationSettings) GithubOrganizationSettings {
+	if u.DefaultRepositoryPermission != nil {
+		settings.DefaultRepositoryPermission = *u.DefaultRepositoryPermission
+	}
+	if u.MembersCanCreateRepositories != nil {
+		settings.MembersCanCreateRepositories = *u.MembersCanCreateRepositories
+	}
+	if u.MembersCanForkPrivateRepositories != nil {
+		settings.MembersCanForkPrivateRepositories = *u.MembersCanForkPrivateRepositories
+	}
+	if u.WebCommitSignoffRequired != nil {
+		settings.WebCommitSignoffRequired = *u.WebCommitSignoffRequired
+	}
+	return settings
+}
+
+/*
+ * GithubExternalGroup is an IdP group (Enterprise Managed Users)
+ * and the teams linked to it
+ */
+type GithubExternalGroup struct {
+	Id    int
+	Name  string
+	Teams []string // team slugs
+}
+
 type GithubTeamRepo struct {
 	Name       string // repository name
 	Permission string // possible values: ADMIN, MAINTAIN, WRITE, TRIAGE, READ
@@ -66,6 +201,7 @@ type GithubTeamRepo struct {
 type GoliacRemoteImpl struct {
 	client                github.GitHubClient
 	users                 map[string]string
+	invitations           map[string]int // [login or email]id of the pending invitations
 	repositories          map[string]*GithubRepository
 	repositoriesByRefId   map[string]*GithubRepository
 	teams                 map[string]*GithubTeam
@@ -73,12 +209,25 @@ type GoliacRemoteImpl struct {
 	teamSlugByName        map[string]string
 	rulesets              map[string]*GithubRuleSet
 	appIds                map[string]int
+	repositoriesActions   map[string]*GithubActions
+	orgActions            *GithubActions
+	secretsHashes         *secretsHashesStore // HMAC-SHA256 of the secrets pushed by Goliac
+	customProperties      map[string]*GithubCustomProperty
+	reposProperties       map[string]map[string][]string
+	orgSettings           *GithubOrganizationSettings // nil if they could not be loaded
+	externalGroups        map[string]*GithubExternalGroup
+	userLogins            map[string]string // [githubid]login ("" if not found), see GetUserLogin
 	ttlExpireUsers        time.Time
 	ttlExpireRepositories time.Time
 	ttlExpireTeams        time.Time
 	ttlExpireTeamsRepos   time.Time
 	ttlExpireRulesets     time.Time
 	ttlExpireAppIds       time.Time
+	ttlExpireActions      time.Time
+	ttlExpireProperties   time.Time
+	ttlExpireOrgSettings  time.Time
+	ttlExpireExtGroups    time.Time
+	ttlExpireUserLogins   time.Time
 	isEnterprise          bool
 }
 
@@ -86,8 +235,8 @@ type GHESInfo struct {
 	InstalledVersion string `json:"installed_version"`
 }
 
-func getGHESVersion(client github.GitHubClient) (*GHESInfo, error) {
-	body, err := client.CallRestAPI("/api/v3", "GET", nil)
+func getGHESVersion(ctx context.Context, client github.GitHubClient) (*GHESInfo, error) {
+	body, err := client.CallRestAPI(ctx, "/api/v3", "GET", nil)
 	if err != nil {
 		return nil, err
 	}
@@ -102,14 +251,18 @@ func getGHESVersion(client github.GitHubClient) (*GHESInfo, error) {
 }
 
 type OrgInfo struct {
-	TwoFactorRequirementEnabled bool `json:"two_factor_requirement_enabled"`
-	Plan                        struct {
+	TwoFactorRequirementEnabled       bool   `json:"two_factor_requirement_enabled"`
+	DefaultRepositoryPermission       string `json:"default_repository_permission"`
+	MembersCanCreateRepositories      bool   `json:"members_can_create_repositories"`
+	MembersCanForkPrivateRepositories bool   `json:"members_can_fork_private_repositories"`
+	WebCommitSignoffRequired          bool   `json:"web_commit_signoff_required"`
+	Plan                              struct {
 		Name string `json:"name"` // enterprise
 	} `json:"plan"`
 }
 
-func getOrgInfo(orgname string, client github.GitHubClient) (*OrgInfo, error) {
-	body, err := client.CallRestAPI("/orgs/"+orgname, "GET", nil)
+func getOrgInfo(ctx context.Context, orgname string, client github.GitHubClient) (*OrgInfo, error) {
+	body, err := client.CallRestAPI(ctx, "/orgs/"+orgname, "GET", nil)
 	if err != nil {
 		return nil, err
 	}
@@ -123,9 +276,9 @@ func getOrgInfo(orgname string, client github.GitHubClient) (*OrgInfo, error) {
 	return &info, nil
 }
 
-func isEnterprise(orgname string, client github.GitHubClient) bool {
+func isEnterprise(ctx context.Context, orgname string, client github.GitHubClient) bool {
 	// are we on Github Enteprise Server
-	if ghesInfo, err := getGHESVersion(client); err == nil {
+	if ghesInfo, err := getGHESVersion(ctx, client); err == nil {
 		logrus.Debugf("GHES versiob: %s", ghesInfo.InstalledVersion)
 		version3_11, err := version.NewVersion("3.11")
 		if err != nil {
@@ -138,7 +291,7 @@ func isEnterprise(orgname string, client github.GitHubClient) bool {
 		if ghesVersion.GreaterThanOrEqual(version3_11) {
 			return true
 		}
-	} else if info, err := getOrgInfo(orgname, client); err == nil {
+	} else if info, err := getOrgInfo(ctx, orgname, client); err == nil {
 		logrus.Debugf("Organization plan: %s", info.Plan.Name)
 		if info.Plan.Name == "enterprise" {
 			return true
@@ -151,6 +304,7 @@ func NewGoliacRemoteImpl(client github.GitHubClient) *GoliacRemoteImpl {
 	return &GoliacRemoteImpl{
 		client:                client,
 		users:                 make(map[string]string),
+		invitations:           make(map[string]int),
 		repositories:          make(map[string]*GithubRepository),
 		repositoriesByRefId:   make(map[string]*GithubRepository),
 		teams:                 make(map[string]*GithubTeam),
@@ -158,13 +312,26 @@ func NewGoliacRemoteImpl(client github.GitHubClient) *GoliacRemoteImpl {
 		teamSlugByName:        make(map[string]string),
 		rulesets:              make(map[string]*GithubRuleSet),
 		appIds:                make(map[string]int),
+		repositoriesActions:   make(map[string]*GithubActions),
+		orgActions:            &GithubActions{Variables: map[string]string{}, Secrets: map[string]string{}},
+		secretsHashes:         newSecretsHashesStore(config.Config.SecretsHashesFile),
+		customProperties:      make(map[string]*GithubCustomProperty),
+		reposProperties:       make(map[string]map[string][]string),
+		orgSettings:           nil,
+		externalGroups:        make(map[string]*GithubExternalGroup),
+		userLogins:            make(map[string]string),
 		ttlExpireUsers:        time.Now(),
 		ttlExpireRepositories: time.Now(),
 		ttlExpireTeams:        time.Now(),
 		ttlExpireTeamsRepos:   time.Now(),
 		ttlExpireRulesets:     time.Now(),
 		ttlExpireAppIds:       time.Now(),
-		isEnterprise:          isEnterprise(config.Config.GithubAppOrganization, client),
+		ttlExpireActions:      time.Now(),
+		ttlExpireProperties:   time.Now(),
+		ttlExpireOrgSettings:  time.Now(),
+		ttlExpireExtGroups:    time.Now(),
+		ttlExpireUserLogins:   time.Now(),
+		isEnterprise:          isEnterprise(context.Background(), config.Config.GithubAppOrganization, client),
 	}
 }
 
@@ -172,6 +339,38 @@ func (g *GoliacRemoteImpl) IsEnterprise() bool {
 	return g.isEnterprise
 }
 
+/*
+ * GetUserLogin looks for a Github user. The lookups are cached
+ * (like the organization users)
+ */
+func (g *GoliacRemoteImpl) GetUserLogin(ctx context.Context, githubid string) (string, error) {
+	if time.Now().After(g.ttlExpireUserLogins) {
+		g.userLogins = make(map[string]string)
+		g.ttlExpireUserLogins = time.Now().Add(time.Duration(config.Config.GithubCacheTTL) * time.Second)
+	}
+	if login, ok := g.userLogins[githubid]; ok {
+		return login, nil
+	}
+
+	// https://docs.github.com/en/rest/users/users?apiVersion=2022-11-28#get-a-user
+	body, err := g.client.CallRestAPI(ctx, fmt.Sprintf("/users/%s", url.PathEscape(githubid)), "GET", nil)
+	if err != nil {
+		if github.IsNotFound(err) {
+			g.userLogins[githubid] = ""
+			return "", nil
+		}
+		return "", err
+	}
+	var user struct {
+		Login string `json:"login"`
+	}
+	if err := json.Unmarshal(body, &user); err != nil {
+		return "", err
+	}
+	g.userLogins[githubid] = user.Login
+	return user.Login, nil
+}
+
 func (g *GoliacRemoteImpl) FlushCache() {
 	g.ttlExpireUsers = time.Now()
 	g.ttlExpireRepositories = time.Now()
@@ -179,11 +378,46 @@ func (g *GoliacRemoteImpl) FlushCache() {
 	g.ttlExpireTeamsRepos = time.Now()
 	g.ttlExpireRulesets = time.Now()
 	g.ttlExpireAppIds = time.Now()
-}
-
-func (g *GoliacRemoteImpl) RuleSets() map[string]*GithubRuleSet {
-	if time.Now().After(g.ttlExpireRulesets) {
-		rulesets, err := g.loadRulesets()
+	g.ttlExpireActions = time.Now()
+	g.ttlExpireProperties = time.Now()
+	g.ttlExpireOrgSettings = time.Now()
+	g.ttlExpireExtGroups = time.Now()
+	g.ttlExpireUserLogins = time.Now()
+}
+
+func (g *GoliacRemoteImpl) InvalidateCache(caches ...string) {
+	for _, cache := range caches {
+		switch cache {
+		case CacheUsers:
+			g.ttlExpireUsers = time.Now()
+			g.ttlExpireUserLogins = time.Now()
+		case CacheRepositories:
+			g.ttlExpireRepositories = time.Now()
+		case CacheTeams:
+			g.ttlExpireTeams = time.Now()
+		case CacheTeamsRepos:
+			g.ttlExpireTeamsRepos = time.Now()
+		case CacheRulesets:
+			g.ttlExpireRulesets = time.Now()
+		case CacheAppIds:
+			g.ttlExpireAppIds = time.Now()
+		case CacheActions:
+			g.ttlExpireActions = time.Now()
+		case CacheCustomProperties:
+			g.ttlExpireProperties = time.Now()
+		case CacheOrgSettings:
+			g.ttlExpireOrgSettings = time.Now()
+		case CacheExternalGroups:
+			g.ttlExpireExtGroups = time.Now()
+		default:
+			logrus.Warnf("unknown Github remote cache: %s", cache)
+		}
+	}
+}
+
+func (g *GoliacRemoteImpl) RuleSets(ctx context.Context) map[string]*GithubRuleSet {
+	if metrics.CacheExpired(CacheRulesets, time.Now().After(g.ttlExpireRulesets)) {
+		rulesets, err := g.loadRulesets(ctx)
 		if err == nil {
 			g.rulesets = rulesets
 			g.ttlExpireRulesets = time.Now().Add(time.Duration(config.Config.GithubCacheTTL) * time.Second)
@@ -192,9 +426,9 @@ func (g *GoliacRemoteImpl) RuleSets() map[string]*GithubRuleSet {
 	return g.rulesets
 }
 
-func (g *GoliacRemoteImpl) AppIds() map[string]int {
-	if time.Now().After(g.ttlExpireAppIds) {
-		appIds, err := g.loadAppIds()
+func (g *GoliacRemoteImpl) AppIds(ctx context.Context) map[string]int {
+	if metrics.CacheExpired(CacheAppIds, time.Now().After(g.ttlExpireAppIds)) {
+		appIds, err := g.loadAppIds(ctx)
 		if err == nil {
 			g.appIds = appIds
 			g.ttlExpireAppIds = time.Now().Add(time.Duration(config.Config.GithubCacheTTL) * time.Second)
@@ -203,20 +437,92 @@ func (g *GoliacRemoteImpl) AppIds() map[string]int {
 	return g.appIds
 }
 
-func (g *GoliacRemoteImpl) Users() map[string]string {
-	if time.Now().After(g.ttlExpireUsers) {
-		users, err := g.loadOrgUsers()
+func (g *GoliacRemoteImpl) LoadActions(ctx context.Context) error {
+	if metrics.CacheExpired(CacheActions, time.Now().After(g.ttlExpireActions)) {
+		repositoriesActions, orgActions, err := g.loadActions(ctx)
+		if err != nil {
+			// the Github App may not have the Actions (or Secrets) permission
+			return fmt.Errorf("not able to load Github Actions variables and secrets: %v", err)
+		}
+		g.repositoriesActions = repositoriesActions
+		g.orgActions = orgActions
+		g.ttlExpireActions = time.Now().Add(time.Duration(config.Config.GithubCacheTTL) * time.Second)
+	}
+	return nil
+}
+
+func (g *GoliacRemoteImpl) RepositoriesActions(ctx context.Context) map[string]*GithubActions {
+	return g.repositoriesActions
+}
+
+func (g *GoliacRemoteImpl) OrgActions(ctx context.Context) *GithubActions {
+	return g.orgActions
+}
+
+func (g *GoliacRemoteImpl) CustomProperties(ctx context.Context) map[string]*GithubCustomProperty {
+	if metrics.CacheExpired(CacheCustomProperties, time.Now().After(g.ttlExpireProperties)) {
+		properties, reposProperties, err := g.loadCustomProperties(ctx)
+		if err == nil {
+			g.customProperties = properties
+			g.reposProperties = reposProperties
+			g.ttlExpireProperties = time.Now().Add(time.Duration(config.Config.GithubCacheTTL) * time.Second)
+		}
+	}
+	return g.customProperties
+}
+
+func (g *GoliacRemoteImpl) RepositoriesCustomProperties(ctx context.Context) map[string]map[string][]string {
+	if metrics.CacheExpired(CacheCustomProperties, time.Now().After(g.ttlExpireProperties)) {
+		properties, reposProperties, err := g.loadCustomProperties(ctx)
+		if err == nil {
+			g.customProperties = properties
+			g.reposProperties = reposProperties
+			g.ttlExpireProperties = time.Now().Add(time.Duration(config.Config.GithubCacheTTL) * time.Second)
+		}
+	}
+	return g.reposProperties
+}
+
+func (g *GoliacRemoteImpl) OrganizationSettings(ctx context.Context) *GithubOrganizationSettings {
+	if metrics.CacheExpired(CacheOrgSettings, time.Now().After(g.ttlExpireOrgSettings)) {
+		settings, err := g.loadOrganizationSettings(ctx)
+		if err == nil {
+			g.orgSettings = settings
+			g.ttlExpireOrgSettings = time.Now().Add(time.Duration(config.Config.GithubCacheTTL) * time.Second)
+		}
+	}
+	return g.orgSettings
+}
+
+func (g *GoliacRemoteImpl) ExternalGroups(ctx context.Context) map[string]*GithubExternalGroup {
+	if metrics.CacheExpired(CacheExternalGroups, time.Now().After(g.ttlExpireExtGroups)) {
+		groups, err := g.loadExternalGroups(ctx)
+		if err != nil {
+			// only available for Enterprise Managed Users organizations
+			logrus.Warnf("not able to load the external groups: %v", err)
+		} else {
+			g.externalGroups = groups
+			g.ttlExpireExtGroups = time.Now().Add(time.Duration(config.Config.GithubCacheTTL) * time.Second)
+		}
+	}
+	return g.externalGroups
+}
+
+func (g *GoliacRemoteImpl) Users(ctx context.Context) map[string]string {
+	if metrics.CacheExpired(CacheUsers, time.Now().After(g.ttlExpireUsers)) {
+		users, invitations, err := g.loadOrgUsers(ctx)
 		if err == nil {
 			g.users = users
+			g.invitations = invitations
 			g.ttlExpireUsers = time.Now().Add(time.Duration(config.Config.GithubCacheTTL) * time.Second)
 		}
 	}
 	return g.users
 }
 
-func (g *GoliacRemoteImpl) TeamSlugByName() map[string]string {
-	if time.Now().After(g.ttlExpireTeams) {
-		teams, teamSlugByName, err := g.loadTeams()
+func (g *GoliacRemoteImpl) TeamSlugByName(ctx context.Context) map[string]string {
+	if metrics.CacheExpired(CacheTeams, time.Now().After(g.ttlExpireTeams)) {
+		teams, teamSlugByName, err := g.loadTeams(ctx)
 		if err == nil {
 			g.teams = teams
 			g.teamSlugByName = teamSlugByName
@@ -226,9 +532,9 @@ func (g *GoliacRemoteImpl) TeamSlugByName() map[string]string {
 	return g.teamSlugByName
 }
 
-func (g *GoliacRemoteImpl) Teams() map[string]*GithubTeam {
-	if time.Now().After(g.ttlExpireTeams) {
-		teams, teamSlugByName, err := g.loadTeams()
+func (g *GoliacRemoteImpl) Teams(ctx context.Context) map[string]*GithubTeam {
+	if metrics.CacheExpired(CacheTeams, time.Now().After(g.ttlExpireTeams)) {
+		teams, teamSlugByName, err := g.loadTeams(ctx)
 		if err == nil {
 			g.teams = teams
 			g.teamSlugByName = teamSlugByName
@@ -238,9 +544,9 @@ func (g *GoliacRemoteImpl) Teams() map[string]*GithubTeam {
 	return g.teams
 }
 
-func (g *GoliacRemoteImpl) Repositories() map[string]*GithubRepository {
-	if time.Now().After(g.ttlExpireRepositories) {
-		repositories, repositoriesByRefIds, err := g.loadRepositories()
+func (g *GoliacRemoteImpl) Repositories(ctx context.Context) map[string]*GithubRepository {
+	if metrics.CacheExpired(CacheRepositories, time.Now().After(g.ttlExpireRepositories)) {
+		repositories, repositoriesByRefIds, err := g.loadRepositories(ctx)
 		if err == nil {
 			g.repositories = repositories
 			g.repositoriesByRefId = repositoriesByRefIds
@@ -250,16 +556,16 @@ func (g *GoliacRemoteImpl) Repositories() map[string]*GithubRepository {
 	return g.repositories
 }
 
-func (g *GoliacRemoteImpl) TeamRepositories() map[string]map[string]*GithubTeamRepo {
-	if time.Now().After(g.ttlExpireTeamsRepos) {
+func (g *GoliacRemoteImpl) TeamRepositories(ctx context.Context) map[string]map[string]*GithubTeamRepo {
+	if metrics.CacheExpired(CacheTeamsRepos, time.Now().After(g.ttlExpireTeamsRepos)) {
 		if config.Config.GithubConcurrentThreads <= 1 {
-			teamsrepos, err := g.loadTeamReposNonConcurrently()
+			teamsrepos, err := g.loadTeamReposNonConcurrently(ctx)
 			if err == nil {
 				g.teamRepos = teamsrepos
 				g.ttlExpireTeamsRepos = time.Now().Add(time.Duration(config.Config.GithubCacheTTL) * time.Second)
 			}
 		} else {
-			teamsrepos, err := g.loadTeamReposConcurrently(config.Config.GithubConcurrentThreads)
+			teamsrepos, err := g.loadTeamReposConcurrently(ctx, config.Config.GithubConcurrentThreads)
 			if err == nil {
 				g.teamRepos = teamsrepos
 				g.ttlExpireTeamsRepos = time.Now().Add(time.Duration(config.Config.GithubCacheTTL) * time.Second)
@@ -313,8 +619,13 @@ type GraplQLUsers struct {
 	} `json:"errors"`
 }
 
-func (g *GoliacRemoteImpl) loadOrgUsers() (map[string]string, error) {
+/*
+ * loadOrgUsers returns the organization members, and the pending (and failed) invitations
+ * (see the UserState* constants), and the ids of the pending invitations
+ */
+func (g *GoliacRemoteImpl) loadOrgUsers(ctx context.Context) (map[string]string, map[string]int, error) {
 	users := make(map[string]string)
+	invitations := make(map[string]int)
 
 	variables := make(map[string]interface{})
 	variables["orgLogin"] = config.Config.GithubAppOrganization
@@ -323,23 +634,23 @@ func (g *GoliacRemoteImpl) loadOrgUsers() (map[string]string, error) {
 	hasNextPage := true
 	count := 0
 	for hasNextPage {
-		data, err := g.client.QueryGraphQLAPI(listAllOrgMembers, variables)
+		data, err := g.client.QueryGraphQLAPI(ctx, listAllOrgMembers, variables)
 		if err != nil {
-			return users, err
+			return users, invitations, err
 		}
 		var gResult GraplQLUsers
 
 		// parse first page
 		err = json.Unmarshal(data, &gResult)
 		if err != nil {
-			return users, err
+			return users, invitations, err
 		}
 		if len(gResult.Errors) > 0 {
-			return users, fmt.Errorf("Graphql error: %v", gResult.Errors[0].Message)
+			return users, invitations, fmt.Errorf("Graphql error: %v", gResult.Errors[0].Message)
 		}
 
 		for _, c := range gResult.Data.Organization.MembersWithRole.Nodes {
-			users[c.Login] = c.Login
+			users[c.Login] = UserStateMember
 		}
 
 		hasNextPage = gResult.Data.Organization.MembersWithRole.PageInfo.HasNextPage
@@ -352,7 +663,130 @@ func (g *GoliacRemoteImpl) loadOrgUsers() (map[string]string, error) {
 		}
 	}
 
-	return users, nil
+	// https://docs.github.com/en/rest/orgs/members?apiVersion=2022-11-28#list-failed-organization-invitations
+	failed, err := g.loadOrgInvitations(ctx, "failed_invitations")
+	if err != nil {
+		// without the invitations, users would be invited again (and not removed)
+		return users, invitations, fmt.Errorf("not able to load the failed organization invitations: %v", err)
+	}
+	for _, invitation := range failed {
+		if _, ok := users[invitation.invitee()]; !ok {
+			users[invitation.invitee()] = UserStateInvitationFailed
+		}
+	}
+
+	// https://docs.github.com/en/rest/orgs/members?apiVersion=2022-11-28#list-pending-organization-invitations
+	pending, err := g.loadOrgInvitations(ctx, "invitations")
+	if err != nil {
+		return users, invitations, fmt.Errorf("not able to load the pending organization invitations: %v", err)
+	}
+	for _, invitation := range pending {
+		if state, ok := users[invitation.invitee()]; !ok || state != UserStateMember {
+			users[invitation.invitee()] = UserStateInvited
+			invitations[invitation.invitee()] = invitation.Id
+		}
+	}
+
+	return users, invitations, nil
+}
+
+type GithubOrgInvitation struct {
+	Id    int    `json:"id"`
+	Login string `json:"login"` // empty for an invitation by email
+	Email string `json:"email"`
+}
+
+func (i *GithubOrgInvitation) invitee() string {
+	if i.Login != "" {
+		return i.Login
+	}
+	return i.Email
+}
+
+/*
+ * loadOrgInvitations lists the organization invitations (endpoint is "invitations" or "failed_invitations")
+ */
+func (g *GoliacRemoteImpl) loadOrgInvitations(ctx context.Context, endpoint string) ([]GithubOrgInvitation, error) {
+	invitations := []GithubOrgInvitation{}
+	for page := 1; page <= FORLOOP_STOP; page++ {
+		body, err := g.client.CallRestAPI(ctx, fmt.Sprintf("/orgs/%s/%s?per_page=100&page=%d", config.Config.GithubAppOrganization, endpoint, page), "GET", nil)
+		if err != nil {
+			return invitations, err
+		}
+		var result []GithubOrgInvitation
+		if err := json.Unmarshal(body, &result); err != nil {
+			return invitations, err
+		}
+		invitations = append(invitations, result...)
+		if len(result) < 100 {
+			break
+		}
+	}
+	return invitations, nil
+}
+
+type githubExternalGroupTeam struct {
+	TeamId   int    `json:"team_id"`
+	TeamName string `json:"team_name"`
+}
+
+type githubExternalGroup struct {
+	GroupId   int                       `json:"group_id"`
+	GroupName string                    `json:"group_name"`
+	Teams     []githubExternalGroupTeam `json:"teams"`
+}
+
+/*
+ * loadExternalGroups lists the IdP groups of the organization, and the teams linked to each of them
+ */
+func (g *GoliacRemoteImpl) loadExternalGroups(ctx context.Context) (map[string]*GithubExternalGroup, error) {
+	groups := make(map[string]*GithubExternalGroup)
+
+	// https://docs.github.com/en/enterprise-cloud@latest/rest/teams/external-groups?apiVersion=2022-11-28#list-external-groups-available-to-an-organization
+	list := []githubExternalGroup{}
+	for page := 1; page <= FORLOOP_STOP; page++ {
+		body, err := g.client.CallRestAPI(ctx, fmt.Sprintf("/orgs/%s/external-groups?per_page=100&page=%d", config.Config.GithubAppOrganization, page), "GET", nil)
+		if err != nil {
+			return groups, err
+		}
+		var result struct {
+			Groups []githubExternalGroup `json:"groups"`
+		}
+		if err := json.Unmarshal(body, &result); err != nil {
+			return groups, err
+		}
+		list = append(list, result.Groups...)
+		if len(result.Groups) < 100 {
+			break
+		}
+	}
+
+	teamSlugByName := g.TeamSlugByName(ctx)
+	for _, group := range list {
+		// https://docs.github.com/en/enterprise-cloud@latest/rest/teams/external-groups?apiVersion=2022-11-28#get-an-external-group
+		body, err := g.client.CallRestAPI(ctx, fmt.Sprintf("/orgs/%s/external-group/%d", config.Config.GithubAppOrganization, group.GroupId), "GET", nil)
+		if err != nil {
+			return groups, err
+		}
+		var details githubExternalGroup
+		if err := json.Unmarshal(body, &details); err != nil {
+			return groups, err
+		}
+		externalGroup := &GithubExternalGroup{
+			Id:    group.GroupId,
+			Name:  group.GroupName,
+			Teams: []string{},
+		}
+		for _, team := range details.Teams {
+			teamslug, ok := teamSlugByName[team.TeamName]
+			if !ok {
+				teamslug = slug.Make(team.TeamName)
+			}
+			externalGroup.Teams = append(externalGroup.Teams, teamslug)
+		}
+		groups[group.GroupName] = externalGroup
+	}
+	return groups, nil
 }
 
 const listAllReposInOrg = `
@@ -423,7 +857,7 @@ type GraplQLRepositories struct {
 	} `json:"errors"`
 }
 
-func (g *GoliacRemoteImpl) loadRepositories() (map[string]*GithubRepository, map[string]*GithubRepository, error) {
+func (g *GoliacRemoteImpl) loadRepositories(ctx context.Context) (map[string]*GithubRepository, map[string]*GithubRepository, error) {
 	repositories := make(map[string]*GithubRepository)
 	repositoriesByRefId := make(map[string]*GithubRepository)
 
@@ -434,7 +868,7 @@ func (g *GoliacRemoteImpl) loadRepositories() (map[string]*GithubRepository, map
 	hasNextPage := true
 	count := 0
 	for hasNextPage {
-		data, err := g.client.QueryGraphQLAPI(listAllReposInOrg, variables)
+		data, err := g.client.QueryGraphQLAPI(ctx, listAllReposInOrg, variables)
 		if err != nil {
 			return repositories, repositoriesByRefId, err
 		}
@@ -578,7 +1012,7 @@ type GraplQLTeamsRepos struct {
 	} `json:"errors"`
 }
 
-func (g *GoliacRemoteImpl) loadAppIds() (map[string]int, error) {
+func (g *GoliacRemoteImpl) loadAppIds(ctx context.Context) (map[string]int, error) {
 	type Installation struct {
 		TotalClount   int `json:"total_count"`
 		Installations []struct {
@@ -589,7 +1023,7 @@ func (g *GoliacRemoteImpl) loadAppIds() (map[string]int, error) {
 		} `json:"installations"`
 	}
 	// https://docs.github.com/en/enterprise-cloud@latest/rest/orgs/orgs?apiVersion=2022-11-28#list-app-installations-for-an-organization
-	body, err := g.client.CallRestAPI(fmt.Sprintf("/orgs/%s/installations", config.Config.GithubAppOrganization),
+	body, err := g.client.CallRestAPI(ctx, fmt.Sprintf("/orgs/%s/installations", config.Config.GithubAppOrganization),
 		"GET",
 		nil)
 
@@ -611,18 +1045,19 @@ func (g *GoliacRemoteImpl) loadAppIds() (map[string]int, error) {
 	return appIds, nil
 }
 
-func (g *GoliacRemoteImpl) Load() error {
-	if time.Now().After(g.ttlExpireUsers) {
-		users, err := g.loadOrgUsers()
+func (g *GoliacRemoteImpl) Load(ctx context.Context) error {
+	if metrics.CacheExpired(CacheUsers, time.Now().After(g.ttlExpireUsers)) {
+		users, invitations, err := g.loadOrgUsers(ctx)
 		if err != nil {
 			return err
 		}
 		g.users = users
+		g.invitations = invitations
 		g.ttlExpireUsers = time.Now().Add(time.Duration(config.Config.GithubCacheTTL) * time.Second)
 	}
 
-	if time.Now().After(g.ttlExpireRepositories) {
-		repositories, repositoriesByRefId, err := g.loadRepositories()
+	if metrics.CacheExpired(CacheRepositories, time.Now().After(g.ttlExpireRepositories)) {
+		repositories, repositoriesByRefId, err := g.loadRepositories(ctx)
 		if err != nil {
 			return err
 		}
@@ -631,8 +1066,8 @@ func (g *GoliacRemoteImpl) Load() error {
 		g.ttlExpireRepositories = time.Now().Add(time.Duration(config.Config.GithubCacheTTL) * time.Second)
 	}
 
-	if time.Now().After(g.ttlExpireTeams) {
-		teams, teamSlugByName, err := g.loadTeams()
+	if metrics.CacheExpired(CacheTeams, time.Now().After(g.ttlExpireTeams)) {
+		teams, teamSlugByName, err := g.loadTeams(ctx)
 		if err != nil {
 			return err
 		}
@@ -641,8 +1076,8 @@ func (g *GoliacRemoteImpl) Load() error {
 		g.ttlExpireTeams = time.Now().Add(time.Duration(config.Config.GithubCacheTTL) * time.Second)
 	}
 
-	if time.Now().After(g.ttlExpireAppIds) {
-		appIds, err := g.loadAppIds()
+	if metrics.CacheExpired(CacheAppIds, time.Now().After(g.ttlExpireAppIds)) {
+		appIds, err := g.loadAppIds(ctx)
 		if err != nil {
 			return err
 		}
@@ -650,8 +1085,8 @@ func (g *GoliacRemoteImpl) Load() error {
 		g.ttlExpireAppIds = time.Now().Add(time.Duration(config.Config.GithubCacheTTL) * time.Second)
 	}
 
-	if time.Now().After(g.ttlExpireRulesets) {
-		rulesets, err := g.loadRulesets()
+	if metrics.CacheExpired(CacheRulesets, time.Now().After(g.ttlExpireRulesets)) {
+		rulesets, err := g.loadRulesets(ctx)
 		if err != nil {
 			return err
 		}
@@ -659,15 +1094,43 @@ func (g *GoliacRemoteImpl) Load() error {
 		g.ttlExpireRulesets = time.Now().Add(time.Duration(config.Config.GithubCacheTTL) * time.Second)
 	}
 
-	if time.Now().After(g.ttlExpireTeamsRepos) {
+	// the Github Actions variables and secrets are loaded on demand (see LoadActions)
+	// as they cost 2 calls per repository, and are only needed if some are configured
+
+	if metrics.CacheExpired(CacheOrgSettings, time.Now().After(g.ttlExpireOrgSettings)) {
+		settings, err := g.loadOrganizationSettings(ctx)
+		if err != nil {
+			// the Github App may not have the Administration (organization) permission:
+			// the organization settings are not reconciliated (see OrganizationSettings)
+			logrus.Warnf("not able to load organization settings: %v", err)
+			g.orgSettings = nil
+		} else {
+			g.orgSettings = settings
+			g.ttlExpireOrgSettings = time.Now().Add(time.Duration(config.Config.GithubCacheTTL) * time.Second)
+		}
+	}
+
+	if metrics.CacheExpired(CacheCustomProperties, time.Now().After(g.ttlExpireProperties)) {
+		properties, reposProperties, err := g.loadCustomProperties(ctx)
+		if err != nil {
+			// custom properties are not available on every Github plan/version
+			logrus.Warnf("not able to load custom properties: %v", err)
+		} else {
+			g.customProperties = properties
+			g.reposProperties = reposProperties
+		}
+		g.ttlExpireProperties = time.Now().Add(time.Duration(config.Config.GithubCacheTTL) * time.Second)
+	}
+
+	if metrics.CacheExpired(CacheTeamsRepos, time.Now().After(g.ttlExpireTeamsRepos)) {
 		if config.Config.GithubConcurrentThreads <= 1 {
-			teamsrepos, err := g.loadTeamReposNonConcurrently()
+			teamsrepos, err := g.loadTeamReposNonConcurrently(ctx)
 			if err != nil {
 				return err
 			}
 			g.teamRepos = teamsrepos
 		} else {
-			teamsrepos, err := g.loadTeamReposConcurrently(config.Config.GithubConcurrentThreads)
+			teamsrepos, err := g.loadTeamReposConcurrently(ctx, config.Config.GithubConcurrentThreads)
 			if err != nil {
 				return err
 			}
@@ -683,11 +1146,14 @@ func (g *GoliacRemoteImpl) Load() error {
 	return nil
 }
 
-func (g *GoliacRemoteImpl) loadTeamReposNonConcurrently() (map[string]map[string]*GithubTeamRepo, error) {
+func (g *GoliacRemoteImpl) loadTeamReposNonConcurrently(ctx context.Context) (map[string]map[string]*GithubTeamRepo, error) {
 	teamRepos := make(map[string]map[string]*GithubTeamRepo)
 
 	for teamSlug := range g.teams {
-		repos, err := g.loadTeamRepos(teamSlug)
+		if err := ctx.Err(); err != nil {
+			return teamRepos, err
+		}
+		repos, err := g.loadTeamRepos(ctx, teamSlug)
 		if err != nil {
 			return teamRepos, err
 		}
@@ -696,9 +1162,13 @@ func (g *GoliacRemoteImpl) loadTeamReposNonConcurrently() (map[string]map[string
 	return teamRepos, nil
 }
 
-func (g *GoliacRemoteImpl) loadTeamReposConcurrently(maxGoroutines int64) (map[string]map[string]*GithubTeamRepo, error) {
+func (g *GoliacRemoteImpl) loadTeamReposConcurrently(ctx context.Context, maxGoroutines int64) (map[string]map[string]*GithubTeamRepo, error) {
 	teamRepos := make(map[string]map[string]*GithubTeamRepo)
 
+	// stop the other workers on the first error (or when the caller cancels)
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
 	var wg sync.WaitGroup
 
 	// Create buffered channels
@@ -715,13 +1185,21 @@ func (g *GoliacRemoteImpl) loadTeamReposConcurrently(maxGoroutines int64) (map[s
 		go func() {
 			defer wg.Done()
 			for slug := range teamsChan {
-				repos, err := g.loadTeamRepos(slug)
+				if err := ctx.Err(); err != nil {
+					select {
+					case errChan <- err:
+					default:
+					}
+					return
+				}
+				repos, err := g.loadTeamRepos(ctx, slug)
 				if err != nil {
 					// Try to report the error
 					select {
 					case errChan <- err:
 					default:
 					}
+					cancel()
 					return
 				}
 				reposChan <- struct {
@@ -756,7 +1234,7 @@ func (g *GoliacRemoteImpl) loadTeamReposConcurrently(maxGoroutines int64) (map[s
 	return teamRepos, nil
 }
 
-func (g *GoliacRemoteImpl) loadTeamRepos(teamSlug string) (map[string]*GithubTeamRepo, error) {
+func (g *GoliacRemoteImpl) loadTeamRepos(ctx context.Context, teamSlug string) (map[string]*GithubTeamRepo, error) {
 	variables := make(map[string]interface{})
 	variables["orgLogin"] = config.Config.GithubAppOrganization
 	variables["teamSlug"] = teamSlug
@@ -767,7 +1245,7 @@ func (g *GoliacRemoteImpl) loadTeamRepos(teamSlug string) (map[string]*GithubTea
 	hasNextPage := true
 	count := 0
 	for hasNextPage {
-		data, err := g.client.QueryGraphQLAPI(listAllTeamsReposInOrg, variables)
+		data, err := g.client.QueryGraphQLAPI(ctx, listAllTeamsReposInOrg, variables)
 		if err != nil {
 			return nil, err
 		}
@@ -853,7 +1331,7 @@ type GraplQLTeamMembers struct {
 	} `json:"errors"`
 }
 
-func (g *GoliacRemoteImpl) loadTeams() (map[string]*GithubTeam, map[string]string, error) {
+func (g *GoliacRemoteImpl) loadTeams(ctx context.Context) (map[string]*GithubTeam, map[string]string, error) {
 	teams := make(map[string]*GithubTeam)
 	teamSlugByName := make(map[string]string)
 
@@ -864,7 +1342,7 @@ func (g *GoliacRemoteImpl) loadTeams() (map[string]*GithubTeam, map[string]strin
 	hasNextPage := true
 	count := 0
 	for hasNextPage {
-		data, err := g.client.QueryGraphQLAPI(listAllTeamsInOrg, variables)
+		data, err := g.client.QueryGraphQLAPI(ctx, listAllTeamsInOrg, variables)
 		if err != nil {
 			return teams, teamSlugByName, err
 		}
@@ -906,7 +1384,7 @@ func (g *GoliacRemoteImpl) loadTeams() (map[string]*GithubTeam, map[string]strin
 		hasNextPage := true
 		count := 0
 		for hasNextPage {
-			data, err := g.client.QueryGraphQLAPI(listAllTeamMembersInOrg, variables)
+			data, err := g.client.QueryGraphQLAPI(ctx, listAllTeamMembersInOrg, variables)
 			if err != nil {
 				return teams, teamSlugByName, err
 			}
@@ -1130,7 +1608,7 @@ func (g *GoliacRemoteImpl) fromGraphQLToGithubRulset(src *GraphQLGithubRuleSet)
 	return &ruleset
 }
 
-func (g *GoliacRemoteImpl) loadRulesets() (map[string]*GithubRuleSet, error) {
+func (g *GoliacRemoteImpl) loadRulesets(ctx context.Context) (map[string]*GithubRuleSet, error) {
 	variables := make(map[string]interface{})
 	variables["orgLogin"] = config.Config.GithubAppOrganization
 	variables["endCursor"] = nil
@@ -1140,7 +1618,7 @@ func (g *GoliacRemoteImpl) loadRulesets() (map[string]*GithubRuleSet, error) {
 	hasNextPage := true
 	count := 0
 	for hasNextPage {
-		data, err := g.client.QueryGraphQLAPI(listRulesets, variables)
+		data, err := g.client.QueryGraphQLAPI(ctx, listRulesets, variables)
 		if err != nil {
 			return rulesets, err
 		}
@@ -1172,7 +1650,7 @@ func (g *GoliacRemoteImpl) loadRulesets() (map[string]*GithubRuleSet, error) {
 	return rulesets, nil
 }
 
-func (g *GoliacRemoteImpl) prepareRuleset(ruleset *GithubRuleSet) map[string]interface{} {
+func (g *GoliacRemoteImpl) prepareRuleset(ctx context.Context, ruleset *GithubRuleSet) map[string]interface{} {
 	bypassActors := make([]map[string]interface{}, 0)
 
 	for appname, mode := range ruleset.BypassApps {
@@ -1243,54 +1721,56 @@ func (g *GoliacRemoteImpl) prepareRuleset(ruleset *GithubRuleSet) map[string]int
 	return payload
 }
 
-func (g *GoliacRemoteImpl) AddRuleset(dryrun bool, ruleset *GithubRuleSet) {
+func (g *GoliacRemoteImpl) AddRuleset(ctx context.Context, dryrun bool, ruleset *GithubRuleSet) error {
 	// add ruleset
 	// https://docs.github.com/en/enterprise-cloud@latest/rest/orgs/rules?apiVersion=2022-11-28#create-an-organization-repository-ruleset
 
 	if !dryrun {
-		body, err := g.client.CallRestAPI(
+		body, err := g.client.CallRestAPI(ctx,
 			fmt.Sprintf("/orgs/%s/rulesets", config.Config.GithubAppOrganization),
 			"POST",
-			g.prepareRuleset(ruleset),
+			g.prepareRuleset(ctx, ruleset),
 		)
 		if err != nil {
-			logrus.Errorf("failed to add ruleset to org: %v. %s", err, string(body))
+			return fmt.Errorf("failed to add ruleset to org: %v. %s", err, string(body))
 		}
 	}
 
 	g.rulesets[ruleset.Name] = ruleset
+	return nil
 }
 
-func (g *GoliacRemoteImpl) UpdateRuleset(dryrun bool, ruleset *GithubRuleSet) {
+func (g *GoliacRemoteImpl) UpdateRuleset(ctx context.Context, dryrun bool, ruleset *GithubRuleSet) error {
 	// add ruleset
 	// https://docs.github.com/en/enterprise-cloud@latest/rest/orgs/rules?apiVersion=2022-11-28#update-an-organization-repository-ruleset
 
 	if !dryrun {
-		body, err := g.client.CallRestAPI(
+		body, err := g.client.CallRestAPI(ctx,
 			fmt.Sprintf("/orgs/%s/rulesets/%d", config.Config.GithubAppOrganization, ruleset.Id),
 			"PUT",
-			g.prepareRuleset(ruleset),
+			g.prepareRuleset(ctx, ruleset),
 		)
 		if err != nil {
-			logrus.Errorf("failed to update ruleset %d to org: %v. %s", ruleset.Id, err, string(body))
+			return fmt.Errorf("failed to update ruleset %d to org: %v. %s", ruleset.Id, err, string(body))
 		}
 	}
 
 	g.rulesets[ruleset.Name] = ruleset
+	return nil
 }
 
-func (g *GoliacRemoteImpl) DeleteRuleset(dryrun bool, rulesetid int) {
+func (g *GoliacRemoteImpl) DeleteRuleset(ctx context.Context, dryrun bool, rulesetid int) error {
 	// remove ruleset
 	// https://docs.github.com/en/enterprise-cloud@latest/rest/orgs/rules?apiVersion=2022-11-28#delete-an-organization-repository-ruleset
 
 	if !dryrun {
-		_, err := g.client.CallRestAPI(
+		_, err := g.client.CallRestAPI(ctx,
 			fmt.Sprintf("/orgs/%s/rulesets/%d", config.Config.GithubAppOrganization, rulesetid),
 			"DELETE",
 			nil,
 		)
 		if err != nil {
-			logrus.Errorf("failed to remove ruleset to org: %v", err)
+			return fmt.Errorf("failed to remove ruleset to org: %v", err)
 		}
 	}
 
@@ -1300,40 +1780,87 @@ func (g *GoliacRemoteImpl) DeleteRuleset(dryrun bool, rulesetid int) {
 			break
 		}
 	}
+	return nil
 }
 
-func (g *GoliacRemoteImpl) AddUserToOrg(dryrun bool, ghuserid string) {
+func (g *GoliacRemoteImpl) AddUserToOrg(ctx context.Context, dryrun bool, ghuserid string) error {
 	// add member
 	// https://docs.github.com/en/rest/teams/teams?apiVersion=2022-11-28#create-a-team
 	if !dryrun {
-		body, err := g.client.CallRestAPI(
+		body, err := g.client.CallRestAPI(ctx,
 			fmt.Sprintf("/orgs/%s/memberships/%s", config.Config.GithubAppOrganization, ghuserid),
 			"PUT",
 			map[string]interface{}{"role": "member"},
 		)
 		if err != nil {
-			logrus.Errorf("failed to add user to org: %v. %s", err, string(body))
+			return fmt.Errorf("failed to add user to org: %v. %s", err, string(body))
+		}
+	}
+
+	// the user must accept the invitation
+	g.users[ghuserid] = UserStateInvited
+	return nil
+}
+
+func (g *GoliacRemoteImpl) InviteUserByEmail(ctx context.Context, dryrun bool, email string) error {
+	// https://docs.github.com/en/rest/orgs/members?apiVersion=2022-11-28#create-an-organization-invitation
+	if !dryrun {
+		body, err := g.client.CallRestAPI(ctx,
+			fmt.Sprintf("/orgs/%s/invitations", config.Config.GithubAppOrganization),
+			"POST",
+			map[string]interface{}{"email": email, "role": "direct_member"},
+		)
+		if err != nil {
+			return fmt.Errorf("failed to invite %s to org: %v. %s", email, err, string(body))
+		}
+		var invitation GithubOrgInvitation
+		if err := json.Unmarshal(body, &invitation); err == nil {
+			g.invitations[email] = invitation.Id
+		}
+	}
+
+	g.users[email] = UserStateInvited
+	return nil
+}
+
+func (g *GoliacRemoteImpl) CancelOrgInvitation(ctx context.Context, dryrun bool, invitee string) error {
+	// https://docs.github.com/en/rest/orgs/members?apiVersion=2022-11-28#cancel-an-organization-invitation
+	if !dryrun {
+		id, ok := g.invitations[invitee]
+		if !ok {
+			return fmt.Errorf("failed to cancel the invitation of %s: invitation not found", invitee)
+		}
+		body, err := g.client.CallRestAPI(ctx,
+			fmt.Sprintf("/orgs/%s/invitations/%d", config.Config.GithubAppOrganization, id),
+			"DELETE",
+			nil,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to cancel the invitation of %s: %v. %s", invitee, err, string(body))
 		}
 	}
 
-	g.users[ghuserid] = ghuserid
+	delete(g.invitations, invitee)
+	delete(g.users, invitee)
+	return nil
 }
 
-func (g *GoliacRemoteImpl) RemoveUserFromOrg(dryrun bool, ghuserid string) {
+func (g *GoliacRemoteImpl) RemoveUserFromOrg(ctx context.Context, dryrun bool, ghuserid string) error {
 	// remove member
 	// https://docs.github.com/en/rest/orgs/members?apiVersion=2022-11-28#remove-organization-membership-for-a-user
 	if !dryrun {
-		body, err := g.client.CallRestAPI(
+		body, err := g.client.CallRestAPI(ctx,
 			fmt.Sprintf("/orgs/%s/memberships/%s", config.Config.GithubAppOrganization, ghuserid),
 			"DELETE",
 			nil,
 		)
 		if err != nil {
-			logrus.Errorf("failed to remove user from org: %v. %s", err, string(body))
+			return fmt.Errorf("failed to remove user from org: %v. %s", err, string(body))
 		}
 	}
 
 	delete(g.users, ghuserid)
+	return nil
 }
 
 type CreateTeamResponse struct {
@@ -1341,38 +1868,35 @@ type CreateTeamResponse struct {
 	Slug string
 }
 
-func (g *GoliacRemoteImpl) CreateTeam(dryrun bool, teamname string, description string, members []string) {
+func (g *GoliacRemoteImpl) CreateTeam(ctx context.Context, dryrun bool, teamname string, description string, members []string) error {
 	slugname := slug.Make(teamname)
 	// create team
 	// https://docs.github.com/en/rest/teams/teams?apiVersion=2022-11-28#create-a-team
 	if !dryrun {
-		body, err := g.client.CallRestAPI(
+		body, err := g.client.CallRestAPI(ctx,
 			fmt.Sprintf("/orgs/%s/teams", config.Config.GithubAppOrganization),
 			"POST",
 			map[string]interface{}{"name": teamname, "description": description, "privacy": "closed"},
 		)
 		if err != nil {
-			logrus.Errorf("failed to create team: %v. %s", err, string(body))
-			return
+			return fmt.Errorf("failed to create team: %v. %s", err, string(body))
 		}
 		var res CreateTeamResponse
 		err = json.Unmarshal(body, &res)
 		if err != nil {
-			logrus.Errorf("failed to create team: %v", err)
-			return
+			return fmt.Errorf("failed to create team: %v", err)
 		}
 
 		// add members
 		for _, member := range members {
 			// https://docs.github.com/en/rest/teams/members?apiVersion=2022-11-28#add-or-update-team-membership-for-a-user
-			body, err := g.client.CallRestAPI(
+			body, err := g.client.CallRestAPI(ctx,
 				fmt.Sprintf("orgs/%s/teams/%s/memberships/%s", config.Config.GithubAppOrganization, res.Slug, member),
 				"PUT",
 				map[string]interface{}{"role": "member"},
 			)
 			if err != nil {
-				logrus.Errorf("failed to create team: %v. %s", err, string(body))
-				return
+				return fmt.Errorf("failed to create team: %v. %s", err, string(body))
 			}
 		}
 		slugname = res.Slug
@@ -1384,19 +1908,20 @@ func (g *GoliacRemoteImpl) CreateTeam(dryrun bool, teamname string, description
 		Members: members,
 	}
 	g.teamSlugByName[teamname] = slugname
+	return nil
 }
 
 // role = member or maintainer (usually we use member)
-func (g *GoliacRemoteImpl) UpdateTeamAddMember(dryrun bool, teamslug string, username string, role string) {
+func (g *GoliacRemoteImpl) UpdateTeamAddMember(ctx context.Context, dryrun bool, teamslug string, username string, role string) error {
 	// https://docs.github.com/en/rest/teams/members?apiVersion=2022-11-28#add-or-update-team-membership-for-a-user
 	if !dryrun {
-		body, err := g.client.CallRestAPI(
+		body, err := g.client.CallRestAPI(ctx,
 			fmt.Sprintf("/orgs/%s/teams/%s/memberships/%s", config.Config.GithubAppOrganization, teamslug, username),
 			"PUT",
 			map[string]interface{}{"role": role},
 		)
 		if err != nil {
-			logrus.Errorf("failed to add team member: %v. %s", err, string(body))
+			return fmt.Errorf("failed to add team member: %v. %s", err, string(body))
 		}
 	}
 
@@ -1413,18 +1938,19 @@ func (g *GoliacRemoteImpl) UpdateTeamAddMember(dryrun bool, teamslug string, use
 			g.teams[teamslug].Members = members
 		}
 	}
+	return nil
 }
 
-func (g *GoliacRemoteImpl) UpdateTeamRemoveMember(dryrun bool, teamslug string, username string) {
+func (g *GoliacRemoteImpl) UpdateTeamRemoveMember(ctx context.Context, dryrun bool, teamslug string, username string) error {
 	// https://docs.github.com/en/rest/teams/members?apiVersion=2022-11-28#add-or-update-team-membership-for-a-user
 	if !dryrun {
-		body, err := g.client.CallRestAPI(
+		body, err := g.client.CallRestAPI(ctx,
 			fmt.Sprintf("orgs/%s/teams/%s/memberships/%s", config.Config.GithubAppOrganization, teamslug, username),
 			"DELETE",
 			nil,
 		)
 		if err != nil {
-			logrus.Errorf("failed to remove team member: %v. %s", err, string(body))
+			return fmt.Errorf("failed to remove team member: %v. %s", err, string(body))
 		}
 	}
 
@@ -1441,19 +1967,20 @@ func (g *GoliacRemoteImpl) UpdateTeamRemoveMember(dryrun bool, teamslug string,
 			g.teams[teamslug].Members = members
 		}
 	}
+	return nil
 }
 
-func (g *GoliacRemoteImpl) DeleteTeam(dryrun bool, teamslug string) {
+func (g *GoliacRemoteImpl) DeleteTeam(ctx context.Context, dryrun bool, teamslug string) error {
 	// delete team
 	// https://docs.github.com/en/rest/teams/teams?apiVersion=2022-11-28#delete-a-team
 	if !dryrun {
-		body, err := g.client.CallRestAPI(
+		body, err := g.client.CallRestAPI(ctx,
 			fmt.Sprintf("/orgs/%s/teams/%s", config.Config.GithubAppOrganization, teamslug),
 			"DELETE",
 			nil,
 		)
 		if err != nil {
-			logrus.Errorf("failed to delete a team: %v. %s", err, string(body))
+			return fmt.Errorf("failed to delete a team: %v. %s", err, string(body))
 		}
 	}
 
@@ -1463,6 +1990,61 @@ func (g *GoliacRemoteImpl) DeleteTeam(dryrun bool, teamslug string) {
 			delete(g.teamSlugByName, name)
 		}
 	}
+	return nil
+}
+
+/*
+ * UpdateTeamSetExternalGroup links a team to an IdP group: Github then manages its members
+ */
+func (g *GoliacRemoteImpl) UpdateTeamSetExternalGroup(ctx context.Context, dryrun bool, teamslug string, groupname string) error {
+	group, ok := g.externalGroups[groupname]
+	if !ok {
+		return fmt.Errorf("failed to link team %s: external group %s not found", teamslug, groupname)
+	}
+	// https://docs.github.com/en/enterprise-cloud@latest/rest/teams/external-groups?apiVersion=2022-11-28#update-the-connection-between-an-external-group-and-a-team
+	if !dryrun {
+		body, err := g.client.CallRestAPI(ctx,
+			fmt.Sprintf("/orgs/%s/teams/%s/external-groups", config.Config.GithubAppOrganization, teamslug),
+			"PATCH",
+			map[string]interface{}{"group_id": group.Id},
+		)
+		if err != nil {
+			return fmt.Errorf("failed to link team %s to external group %s: %v. %s", teamslug, groupname, err, string(body))
+		}
+	}
+
+	g.unlinkExternalGroup(teamslug)
+	group.Teams = append(group.Teams, teamslug)
+	return nil
+}
+
+func (g *GoliacRemoteImpl) UpdateTeamRemoveExternalGroup(ctx context.Context, dryrun bool, teamslug string) error {
+	// https://docs.github.com/en/enterprise-cloud@latest/rest/teams/external-groups?apiVersion=2022-11-28#remove-the-connection-between-an-external-group-and-a-team
+	if !dryrun {
+		body, err := g.client.CallRestAPI(ctx,
+			fmt.Sprintf("/orgs/%s/teams/%s/external-groups", config.Config.GithubAppOrganization, teamslug),
+			"DELETE",
+			nil,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to unlink team %s from its external group: %v. %s", teamslug, err, string(body))
+		}
+	}
+
+	g.unlinkExternalGroup(teamslug)
+	return nil
+}
+
+func (g *GoliacRemoteImpl) unlinkExternalGroup(teamslug string) {
+	for _, group := range g.externalGroups {
+		teams := []string{}
+		for _, t := range group.Teams {
+			if t != teamslug {
+				teams = append(teams, t)
+			}
+		}
+		group.Teams = teams
+	}
 }
 
 type CreateRepositoryResponse struct {
@@ -1470,28 +2052,26 @@ type CreateRepositoryResponse struct {
 	NodeId string `json:"node_id"`
 }
 
-func (g *GoliacRemoteImpl) CreateRepository(dryrun bool, reponame string, description string, writers []string, readers []string, public bool) {
+func (g *GoliacRemoteImpl) CreateRepository(ctx context.Context, dryrun bool, reponame string, description string, writers []string, readers []string, public bool) error {
 	repoId := 0
 	repoRefId := reponame
 	// create repository
 	// https://docs.github.com/en/rest/repos/repos?apiVersion=2022-11-28#create-an-organization-repository
 	if !dryrun {
-		body, err := g.client.CallRestAPI(
+		body, err := g.client.CallRestAPI(ctx,
 			fmt.Sprintf("/orgs/%s/repos", config.Config.GithubAppOrganization),
 			"POST",
 			map[string]interface{}{"name": reponame, "description": description, "private": !public},
 		)
 		if err != nil {
-			logrus.Errorf("failed to create repository: %v. %s", err, string(body))
-			return
+			return fmt.Errorf("failed to create repository: %v. %s", err, string(body))
 		}
 
 		// get the repo id
 		var resp CreateRepositoryResponse
 		err = json.Unmarshal(body, &resp)
 		if err != nil {
-			logrus.Errorf("failed to read the create repository action response: %v", err)
-			return
+			return fmt.Errorf("failed to read the create repository action response: %v", err)
 		}
 		repoId = resp.Id
 		repoRefId = resp.NodeId
@@ -1512,14 +2092,13 @@ func (g *GoliacRemoteImpl) CreateRepository(dryrun bool, reponame string, descri
 	for _, reader := range readers {
 		// https://docs.github.com/en/rest/teams/teams?apiVersion=2022-11-28#add-or-update-team-repository-permissions
 		if !dryrun {
-			body, err := g.client.CallRestAPI(
+			body, err := g.client.CallRestAPI(ctx,
 				fmt.Sprintf("orgs/%s/teams/%s/repos/%s/%s", config.Config.GithubAppOrganization, reader, config.Config.GithubAppOrganization, reponame),
 				"PUT",
 				map[string]interface{}{"permission": "pull"},
 			)
 			if err != nil {
-				logrus.Errorf("failed to create repository (and add members): %v. %s", err, string(body))
-				return
+				return fmt.Errorf("failed to create repository (and add members): %v. %s", err, string(body))
 			}
 		}
 
@@ -1536,13 +2115,13 @@ func (g *GoliacRemoteImpl) CreateRepository(dryrun bool, reponame string, descri
 	for _, writer := range writers {
 		// https://docs.github.com/en/rest/teams/teams?apiVersion=2022-11-28#add-or-update-team-repository-permissions
 		if !dryrun {
-			body, err := g.client.CallRestAPI(
+			body, err := g.client.CallRestAPI(ctx,
 				fmt.Sprintf("orgs/%s/teams/%s/repos/%s/%s", config.Config.GithubAppOrganization, writer, config.Config.GithubAppOrganization, reponame),
 				"PUT",
 				map[string]interface{}{"permission": "push"},
 			)
 			if err != nil {
-				logrus.Errorf("failed to create repository (and add members): %v. %s", err, string(body))
+				return fmt.Errorf("failed to create repository (and add members): %v. %s", err, string(body))
 			}
 		}
 
@@ -1556,19 +2135,20 @@ func (g *GoliacRemoteImpl) CreateRepository(dryrun bool, reponame string, descri
 		}
 		g.teamRepos[writer] = teamsRepos
 	}
+	return nil
 }
 
-func (g *GoliacRemoteImpl) UpdateRepositoryAddTeamAccess(dryrun bool, reponame string, teamslug string, permission string) {
+func (g *GoliacRemoteImpl) UpdateRepositoryAddTeamAccess(ctx context.Context, dryrun bool, reponame string, teamslug string, permission string) error {
 	// update member
 	// https://docs.github.com/en/rest/teams/teams?apiVersion=2022-11-28#add-or-update-team-repository-permissions
 	if !dryrun {
-		body, err := g.client.CallRestAPI(
+		body, err := g.client.CallRestAPI(ctx,
 			fmt.Sprintf("/orgs/%s/teams/%s/repos/%s/%s", config.Config.GithubAppOrganization, teamslug, config.Config.GithubAppOrganization, reponame),
 			"PUT",
 			map[string]interface{}{"permission": permission},
 		)
 		if err != nil {
-			logrus.Errorf("failed to add team access: %v. %s", err, string(body))
+			return fmt.Errorf("failed to add team access: %v. %s", err, string(body))
 		}
 	}
 
@@ -1585,19 +2165,20 @@ func (g *GoliacRemoteImpl) UpdateRepositoryAddTeamAccess(dryrun bool, reponame s
 		Permission: rPermission,
 	}
 	g.teamRepos[teamslug] = teamsRepos
+	return nil
 }
 
-func (g *GoliacRemoteImpl) UpdateRepositoryUpdateTeamAccess(dryrun bool, reponame string, teamslug string, permission string) {
+func (g *GoliacRemoteImpl) UpdateRepositoryUpdateTeamAccess(ctx context.Context, dryrun bool, reponame string, teamslug string, permission string) error {
 	// update member
 	// https://docs.github.com/en/rest/teams/teams?apiVersion=2022-11-28#add-or-update-team-repository-permissions
 	if !dryrun {
-		body, err := g.client.CallRestAPI(
+		body, err := g.client.CallRestAPI(ctx,
 			fmt.Sprintf("/orgs/%s/teams/%s/repos/%s/%s", config.Config.GithubAppOrganization, teamslug, config.Config.GithubAppOrganization, reponame),
 			"PUT",
 			map[string]interface{}{"permission": permission},
 		)
 		if err != nil {
-			logrus.Errorf("failed to add team access: %v. %s", err, string(body))
+			return fmt.Errorf("failed to add team access: %v. %s", err, string(body))
 		}
 	}
 
@@ -1614,19 +2195,20 @@ func (g *GoliacRemoteImpl) UpdateRepositoryUpdateTeamAccess(dryrun bool, reponam
 		Permission: rPermission,
 	}
 	g.teamRepos[teamslug] = teamsRepos
+	return nil
 }
 
-func (g *GoliacRemoteImpl) UpdateRepositoryRemoveTeamAccess(dryrun bool, reponame string, teamslug string) {
+func (g *GoliacRemoteImpl) UpdateRepositoryRemoveTeamAccess(ctx context.Context, dryrun bool, reponame string, teamslug string) error {
 	// delete member
 	// https://docs.github.com/en/rest/teams/teams?apiVersion=2022-11-28#remove-a-repository-from-a-team
 	if !dryrun {
-		body, err := g.client.CallRestAPI(
+		body, err := g.client.CallRestAPI(ctx,
 			fmt.Sprintf("orgs/%s/teams/%s/repos/%s/%s", config.Config.GithubAppOrganization, teamslug, config.Config.GithubAppOrganization, reponame),
 			"DELETE",
 			nil,
 		)
 		if err != nil {
-			logrus.Errorf("failed to remove team access: %. %s", err, string(body))
+			return fmt.Errorf("failed to remove team access: %v. %s", err, string(body))
 		}
 	}
 
@@ -1634,53 +2216,56 @@ func (g *GoliacRemoteImpl) UpdateRepositoryRemoveTeamAccess(dryrun bool, reponam
 	if teamsRepos != nil {
 		delete(g.teamRepos[teamslug], reponame)
 	}
+	return nil
 }
 
-func (g *GoliacRemoteImpl) UpdateRepositoryUpdatePrivate(dryrun bool, reponame string, private bool) {
+func (g *GoliacRemoteImpl) UpdateRepositoryUpdatePrivate(ctx context.Context, dryrun bool, reponame string, private bool) error {
 	// https://docs.github.com/en/rest/repos/repos?apiVersion=2022-11-28#update-a-repository
 	if !dryrun {
-		body, err := g.client.CallRestAPI(
+		body, err := g.client.CallRestAPI(ctx,
 			fmt.Sprintf("repos/%s/%s", config.Config.GithubAppOrganization, reponame),
 			"PATCH",
 			map[string]interface{}{"private": private},
 		)
 		if err != nil {
-			logrus.Errorf("failed to update repository private setting: %v. %s", err, string(body))
+			return fmt.Errorf("failed to update repository private setting: %v. %s", err, string(body))
 		}
 	}
 
 	if repo, ok := g.repositories[reponame]; ok {
 		repo.IsPrivate = private
 	}
+	return nil
 }
-func (g *GoliacRemoteImpl) UpdateRepositoryUpdateArchived(dryrun bool, reponame string, archived bool) {
+func (g *GoliacRemoteImpl) UpdateRepositoryUpdateArchived(ctx context.Context, dryrun bool, reponame string, archived bool) error {
 	// https://docs.github.com/en/rest/repos/repos?apiVersion=2022-11-28#update-a-repository
 	if !dryrun {
-		body, err := g.client.CallRestAPI(
+		body, err := g.client.CallRestAPI(ctx,
 			fmt.Sprintf("repos/%s/%s", config.Config.GithubAppOrganization, reponame),
 			"PATCH",
 			map[string]interface{}{"archived": archived},
 		)
 		if err != nil {
-			logrus.Errorf("failed to update repository archive setting: %v. %s", err, string(body))
+			return fmt.Errorf("failed to update repository archive setting: %v. %s", err, string(body))
 		}
 	}
 
 	if repo, ok := g.repositories[reponame]; ok {
 		repo.IsArchived = archived
 	}
+	return nil
 }
 
-func (g *GoliacRemoteImpl) UpdateRepositorySetExternalUser(dryrun bool, reponame string, githubid string, permission string) {
+func (g *GoliacRemoteImpl) UpdateRepositorySetExternalUser(ctx context.Context, dryrun bool, reponame string, githubid string, permission string) error {
 	// https://docs.github.com/en/rest/collaborators/collaborators?apiVersion=2022-11-28#add-a-repository-collaborator
 	if !dryrun {
-		body, err := g.client.CallRestAPI(
+		body, err := g.client.CallRestAPI(ctx,
 			fmt.Sprintf("repos/%s/%s/collaborators/%s", config.Config.GithubAppOrganization, reponame, githubid),
 			"PUT",
 			map[string]interface{}{"permission": permission},
 		)
 		if err != nil {
-			logrus.Errorf("failed to set repository collaborator: %v. %s", err, string(body))
+			return fmt.Errorf("failed to set repository collaborator: %v. %s", err, string(body))
 		}
 	}
 
@@ -1691,37 +2276,39 @@ func (g *GoliacRemoteImpl) UpdateRepositorySetExternalUser(dryrun bool, reponame
 			repo.ExternalUsers[githubid] = "READ"
 		}
 	}
+	return nil
 }
 
-func (g *GoliacRemoteImpl) UpdateRepositoryRemoveExternalUser(dryrun bool, reponame string, githubid string) {
+func (g *GoliacRemoteImpl) UpdateRepositoryRemoveExternalUser(ctx context.Context, dryrun bool, reponame string, githubid string) error {
 	// https://docs.github.com/en/rest/collaborators/collaborators?apiVersion=2022-11-28#remove-a-repository-collaborator
 	if !dryrun {
-		body, err := g.client.CallRestAPI(
+		body, err := g.client.CallRestAPI(ctx,
 			fmt.Sprintf("repos/%s/%s/collaborators/%s", config.Config.GithubAppOrganization, reponame, githubid),
 			"DELETE",
 			nil,
 		)
 		if err != nil {
-			logrus.Errorf("failed to remove repository collaborator: %v. %s", err, string(body))
+			return fmt.Errorf("failed to remove repository collaborator: %v. %s", err, string(body))
 		}
 	}
 
 	if repo, ok := g.repositories[reponame]; ok {
 		delete(repo.ExternalUsers, githubid)
 	}
+	return nil
 }
 
-func (g *GoliacRemoteImpl) DeleteRepository(dryrun bool, reponame string) {
+func (g *GoliacRemoteImpl) DeleteRepository(ctx context.Context, dryrun bool, reponame string) error {
 	// delete repo
 	// https://docs.github.com/en/rest/repos/repos?apiVersion=2022-11-28#delete-a-repository
 	if !dryrun {
-		body, err := g.client.CallRestAPI(
+		body, err := g.client.CallRestAPI(ctx,
 			fmt.Sprintf("/repos/%s/%s", config.Config.GithubAppOrganization, reponame),
 			"DELETE",
 			nil,
 		)
 		if err != nil {
-			logrus.Errorf("failed to delete repository: %v. %s", err, string(body))
+			return fmt.Errorf("failed to delete repository: %v. %s", err, string(body))
 		}
 	}
 
@@ -1730,11 +2317,590 @@ func (g *GoliacRemoteImpl) DeleteRepository(dryrun bool, reponame string) {
 		delete(g.repositoriesByRefId, r.RefId)
 		delete(g.repositories, reponame)
 	}
+	return nil
+}
+func (g *GoliacRemoteImpl) Begin(ctx context.Context, dryrun bool) {
+}
+func (g *GoliacRemoteImpl) Rollback(ctx context.Context, dryrun bool, err error) {
+}
+func (g *GoliacRemoteImpl) Commit(ctx context.Context, dryrun bool) error {
+	return nil
+}
+
+type ActionsVariables struct {
+	TotalCount int `json:"total_count"`
+	Variables  []struct {
+		Name  string `json:"name"`
+		Value string `json:"value"`
+	} `json:"variables"`
+}
+
+type ActionsSecrets struct {
+	TotalCount int `json:"total_count"`
+	Secrets    []struct {
+		Name      string `json:"name"`
+		UpdatedAt string `json:"updated_at"`
+	} `json:"secrets"`
+}
+
+type ActionsPublicKey struct {
+	KeyId string `json:"key_id"`
+	Key   string `json:"key"`
+}
+
+/*
+ * loadScopeActions loads the Github Actions variables and secrets (names only) for a scope.
+ * The scope is either "orgs/<org>" or "repos/<org>/<repo>"
+ */
+func (g *GoliacRemoteImpl) loadScopeActions(ctx context.Context, scope string) (*GithubActions, error) {
+	actions := &GithubActions{
+		Variables: make(map[string]string),
+		Secrets:   make(map[string]string),
+	}
+
+	// https://docs.github.com/en/rest/actions/variables?apiVersion=2022-11-28#list-repository-variables
+	page := 1
+	for page < FORLOOP_STOP {
+		body, err := g.client.CallRestAPI(ctx, fmt.Sprintf("/%s/actions/variables?per_page=30&page=%d", scope, page), "GET", nil)
+		if err != nil {
+			return nil, fmt.Errorf("not able to list actions variables for %s: %v", scope, err)
+		}
+		var variables ActionsVariables
+		err = json.Unmarshal(body, &variables)
+		if err != nil {
+			return nil, fmt.Errorf("not able to list actions variables for %s: %v", scope, err)
+		}
+		for _, v := range variables.Variables {
+			actions.Variables[v.Name] = v.Value
+		}
+		if len(variables.Variables) == 0 || len(actions.Variables) >= variables.TotalCount {
+			break
+		}
+		page++
+	}
+
+	// https://docs.github.com/en/rest/actions/secrets?apiVersion=2022-11-28#list-repository-secrets
+	page = 1
+	for page < FORLOOP_STOP {
+		body, err := g.client.CallRestAPI(ctx, fmt.Sprintf("/%s/actions/secrets?per_page=100&page=%d", scope, page), "GET", nil)
+		if err != nil {
+			return nil, fmt.Errorf("not able to list actions secrets for %s: %v", scope, err)
+		}
+		var secrets ActionsSecrets
+		err = json.Unmarshal(body, &secrets)
+		if err != nil {
+			return nil, fmt.Errorf("not able to list actions secrets for %s: %v", scope, err)
+		}
+		for _, s := range secrets.Secrets {
+			// we cannot read back a secret value, so we rely on what we pushed previously (if any)
+			actions.Secrets[s.Name] = g.secretsHashes.Get(scope+"/"+s.Name, s.UpdatedAt)
+		}
+		if len(secrets.Secrets) == 0 || len(actions.Secrets) >= secrets.TotalCount {
+			break
+		}
+		page++
+	}
+
+	return actions, nil
+}
+
+/*
+ * loadActions loads the organization Github Actions variables and secrets
+ * and the ones of every (non archived) repository
+ */
+func (g *GoliacRemoteImpl) loadActions(ctx context.Context) (map[string]*GithubActions, *GithubActions, error) {
+	orgActions, err := g.loadScopeActions(ctx, "orgs/"+config.Config.GithubAppOrganization)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	reponames := make([]string, 0)
+	for reponame, repo := range g.Repositories(ctx) {
+		if !repo.IsArchived {
+			reponames = append(reponames, reponame)
+		}
+	}
+
+	maxGoroutines := config.Config.GithubConcurrentThreads
+	if maxGoroutines < 1 {
+		maxGoroutines = 1
+	}
+
+	var wg sync.WaitGroup
+	var mutex sync.Mutex
+	repositoriesActions := make(map[string]*GithubActions)
+	reposChan := make(chan string, len(reponames))
+	errChan := make(chan error, 1) // will hold the first error
+
+	for i := int64(0); i < maxGoroutines; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for reponame := range reposChan {
+				actions, err := g.loadScopeActions(ctx, fmt.Sprintf("repos/%s/%s", config.Config.GithubAppOrganization, reponame))
+				if err != nil {
+					// Try to report the error
+					select {
+					case errChan <- err:
+					default:
+					}
+					return
+				}
+				mutex.Lock()
+				repositoriesActions[reponame] = actions
+				mutex.Unlock()
+			}
+		}()
+	}
+
+	for _, reponame := range reponames {
+		reposChan <- reponame
+	}
+	close(reposChan)
+	wg.Wait()
+
+	select {
+	case err := <-errChan:
+		return nil, nil, err
+	default:
+	}
+
+	return repositoriesActions, orgActions, nil
+}
+
+/*
+ * encryptSecret encrypts the value with the (base64) public key of the scope,
+ * using a libsodium sealed box, as expected by the Github Actions secrets API
+ */
+func encryptSecret(publicKey string, value string) (string, error) {
+	decodedKey, err := base64.StdEncoding.DecodeString(publicKey)
+	if err != nil {
+		return "", fmt.Errorf("not able to decode the public key: %v", err)
+	}
+	if len(decodedKey) != 32 {
+		return "", fmt.Errorf("invalid public key size: %d", len(decodedKey))
+	}
+	var recipient [32]byte
+	copy(recipient[:], decodedKey)
+
+	encrypted, err := box.SealAnonymous(nil, []byte(value), &recipient, rand.Reader)
+	if err != nil {
+		return "", fmt.Errorf("not able to encrypt the secret: %v", err)
+	}
+	return base64.StdEncoding.EncodeToString(encrypted), nil
+}
+
+func (g *GoliacRemoteImpl) setScopeVariable(ctx context.Context, scope string, actions *GithubActions, name string, value string) error {
+	// https://docs.github.com/en/rest/actions/variables?apiVersion=2022-11-28#create-a-repository-variable
+	// https://docs.github.com/en/rest/actions/variables?apiVersion=2022-11-28#update-a-repository-variable
+	payload := map[string]interface{}{"name": name, "value": value}
+	if strings.HasPrefix(scope, "orgs/") {
+		payload["visibility"] = "private"
+	}
+	if _, ok := actions.Variables[name]; ok {
+		body, err := g.client.CallRestAPI(ctx, fmt.Sprintf("/%s/actions/variables/%s", scope, name), "PATCH", payload)
+		if err != nil {
+			return fmt.Errorf("%v. %s", err, string(body))
+		}
+	} else {
+		body, err := g.client.CallRestAPI(ctx, fmt.Sprintf("/%s/actions/variables", scope), "POST", payload)
+		if err != nil {
+			return fmt.Errorf("%v. %s", err, string(body))
+		}
+	}
+	return nil
+}
+
+/*
+ * setScopeSecret pushes the secret and remembers its hash, with the
+ * updated_at timestamp returned by Github for it
+ */
+func (g *GoliacRemoteImpl) setScopeSecret(ctx context.Context, scope string, name string, value string) error {
+	// https://docs.github.com/en/rest/actions/secrets?apiVersion=2022-11-28#get-a-repository-public-key
+	body, err := g.client.CallRestAPI(ctx, fmt.Sprintf("/%s/actions/secrets/public-key", scope), "GET", nil)
+	if err != nil {
+		return fmt.Errorf("not able to get the public key: %v. %s", err, string(body))
+	}
+	var publicKey ActionsPublicKey
+	err = json.Unmarshal(body, &publicKey)
+	if err != nil {
+		return fmt.Errorf("not able to get the public key: %v", err)
+	}
+
+	encrypted, err := encryptSecret(publicKey.Key, value)
+	if err != nil {
+		return err
+	}
+
+	// https://docs.github.com/en/rest/actions/secrets?apiVersion=2022-11-28#create-or-update-a-repository-secret
+	payload := map[string]interface{}{"encrypted_value": encrypted, "key_id": publicKey.KeyId}
+	if strings.HasPrefix(scope, "orgs/") {
+		payload["visibility"] = "private"
+	}
+	body, err = g.client.CallRestAPI(ctx, fmt.Sprintf("/%s/actions/secrets/%s", scope, name), "PUT", payload)
+	if err != nil {
+		return fmt.Errorf("%v. %s", err, string(body))
+	}
+
+	// https://docs.github.com/en/rest/actions/secrets?apiVersion=2022-11-28#get-a-repository-secret
+	var secret struct {
+		UpdatedAt string `json:"updated_at"`
+	}
+	body, err = g.client.CallRestAPI(ctx, fmt.Sprintf("/%s/actions/secrets/%s", scope, name), "GET", nil)
+	if err == nil {
+		err = json.Unmarshal(body, &secret)
+	}
+	if err != nil {
+		// the secret is pushed, but we will not be able to tell next time if it changed
+		logrus.Warnf("not able to get the secret %s/%s: %v", scope, name, err)
+		return nil
+	}
+	g.secretsHashes.Set(scope+"/"+name, hashSecretValue(value), secret.UpdatedAt)
+	return nil
+}
+
+func (g *GoliacRemoteImpl) repositoryActions(ctx context.Context, reponame string) *GithubActions {
+	actions, ok := g.repositoriesActions[reponame]
+	if !ok {
+		actions = &GithubActions{
+			Variables: make(map[string]string),
+			Secrets:   make(map[string]string),
+		}
+		g.repositoriesActions[reponame] = actions
+	}
+	return actions
+}
+
+func (g *GoliacRemoteImpl) UpdateRepositorySetVariable(ctx context.Context, dryrun bool, reponame string, name string, value string) error {
+	actions := g.repositoryActions(ctx, reponame)
+	if !dryrun {
+		err := g.setScopeVariable(ctx, fmt.Sprintf("repos/%s/%s", config.Config.GithubAppOrganization, reponame), actions, name, value)
+		if err != nil {
+			return fmt.Errorf("failed to set repository variable: %v", err)
+		}
+	}
+	actions.Variables[name] = value
+	return nil
+}
+
+func (g *GoliacRemoteImpl) UpdateRepositoryRemoveVariable(ctx context.Context, dryrun bool, reponame string, name string) error {
+	// https://docs.github.com/en/rest/actions/variables?apiVersion=2022-11-28#delete-a-repository-variable
+	if !dryrun {
+		body, err := g.client.CallRestAPI(ctx,
+			fmt.Sprintf("/repos/%s/%s/actions/variables/%s", config.Config.GithubAppOrganization, reponame, name),
+			"DELETE",
+			nil,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to remove repository variable: %v. %s", err, string(body))
+		}
+	}
+	delete(g.repositoryActions(ctx, reponame).Variables, name)
+	return nil
+}
+
+func (g *GoliacRemoteImpl) UpdateRepositorySetSecret(ctx context.Context, dryrun bool, reponame string, name string, value string) error {
+	scope := fmt.Sprintf("repos/%s/%s", config.Config.GithubAppOrganization, reponame)
+	hash := hashSecretValue(value)
+	if !dryrun {
+		err := g.setScopeSecret(ctx, scope, name, value)
+		if err != nil {
+			return fmt.Errorf("failed to set repository secret %s: %v", name, err)
+		}
+	}
+	g.repositoryActions(ctx, reponame).Secrets[name] = hash
+	return nil
+}
+
+func (g *GoliacRemoteImpl) UpdateRepositoryRemoveSecret(ctx context.Context, dryrun bool, reponame string, name string) error {
+	scope := fmt.Sprintf("repos/%s/%s", config.Config.GithubAppOrganization, reponame)
+	// https://docs.github.com/en/rest/actions/secrets?apiVersion=2022-11-28#delete-a-repository-secret
+	if !dryrun {
+		body, err := g.client.CallRestAPI(ctx, fmt.Sprintf("/%s/actions/secrets/%s", scope, name), "DELETE", nil)
+		if err != nil {
+			return fmt.Errorf("failed to remove repository secret: %v. %s", err, string(body))
+		}
+		g.secretsHashes.Delete(scope + "/" + name)
+	}
+	delete(g.repositoryActions(ctx, reponame).Secrets, name)
+	return nil
+}
+
+func (g *GoliacRemoteImpl) SetOrgVariable(ctx context.Context, dryrun bool, name string, value string) error {
+	if !dryrun {
+		err := g.setScopeVariable(ctx, "orgs/"+config.Config.GithubAppOrganization, g.orgActions, name, value)
+		if err != nil {
+			return fmt.Errorf("failed to set organization variable: %v", err)
+		}
+	}
+	g.orgActions.Variables[name] = value
+	return nil
+}
+
+func (g *GoliacRemoteImpl) RemoveOrgVariable(ctx context.Context, dryrun bool, name string) error {
+	// https://docs.github.com/en/rest/actions/variables?apiVersion=2022-11-28#delete-an-organization-variable
+	if !dryrun {
+		body, err := g.client.CallRestAPI(ctx,
+			fmt.Sprintf("/orgs/%s/actions/variables/%s", config.Config.GithubAppOrganization, name),
+			"DELETE",
+			nil,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to remove organization variable: %v. %s", err, string(body))
+		}
+	}
+	delete(g.orgActions.Variables, name)
+	return nil
+}
+
+func (g *GoliacRemoteImpl) SetOrgSecret(ctx context.Context, dryrun bool, name string, value string) error {
+	scope := "orgs/" + config.Config.GithubAppOrganization
+	hash := hashSecretValue(value)
+	if !dryrun {
+		err := g.setScopeSecret(ctx, scope, name, value)
+		if err != nil {
+			return fmt.Errorf("failed to set organization secret %s: %v", name, err)
+		}
+	}
+	g.orgActions.Secrets[name] = hash
+	return nil
+}
+
+func (g *GoliacRemoteImpl) RemoveOrgSecret(ctx context.Context, dryrun bool, name string) error {
+	scope := "orgs/" + config.Config.GithubAppOrganization
+	// https://docs.github.com/en/rest/actions/secrets?apiVersion=2022-11-28#delete-an-organization-secret
+	if !dryrun {
+		body, err := g.client.CallRestAPI(ctx, fmt.Sprintf("/%s/actions/secrets/%s", scope, name), "DELETE", nil)
+		if err != nil {
+			return fmt.Errorf("failed to remove organization secret: %v. %s", err, string(body))
+		}
+		g.secretsHashes.Delete(scope + "/" + name)
+	}
+	delete(g.orgActions.Secrets, name)
+	return nil
+}
+
+type CustomPropertyDefinition struct {
+	PropertyName  string   `json:"property_name"`
+	ValueType     string   `json:"value_type"`
+	Required      bool     `json:"required"`
+	DefaultValue  *string  `json:"default_value"`
+	Description   *string  `json:"description"`
+	AllowedValues []string `json:"allowed_values"`
+}
+
+type CustomPropertyValue struct {
+	PropertyName string      `json:"property_name"`
+	Value        interface{} `json:"value"` // null, string or array of strings
+}
+
+type RepositoryCustomPropertyValues struct {
+	RepositoryName string                `json:"repository_name"`
+	Properties     []CustomPropertyValue `json:"properties"`
+}
+
+/*
+ * loadCustomProperties loads the organization custom properties definitions
+ * and the custom properties values of every repository
+ */
+func (g *GoliacRemoteImpl) loadCustomProperties(ctx context.Context) (map[string]*GithubCustomProperty, map[string]map[string][]string, error) {
+	properties := make(map[string]*GithubCustomProperty)
+	reposProperties := make(map[string]map[string][]string)
+
+	// https://docs.github.com/en/rest/orgs/custom-properties?apiVersion=2022-11-28#get-all-custom-properties-for-an-organization
+	body, err := g.client.CallRestAPI(ctx, fmt.Sprintf("/orgs/%s/properties/schema", config.Config.GithubAppOrganization), "GET", nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("not able to list custom properties: %v", err)
+	}
+	var definitions []CustomPropertyDefinition
+	if err := json.Unmarshal(body, &definitions); err != nil {
+		return nil, nil, fmt.Errorf("not able to list custom properties: %v", err)
+	}
+	for _, d := range definitions {
+		p := &GithubCustomProperty{
+			Name:          d.PropertyName,
+			ValueType:     d.ValueType,
+			Required:      d.Required,
+			AllowedValues: d.AllowedValues,
+		}
+		if d.DefaultValue != nil {
+			p.DefaultValue = *d.DefaultValue
+		}
+		if d.Description != nil {
+			p.Description = *d.Description
+		}
+		properties[p.Name] = p
+	}
+
+	// https://docs.github.com/en/rest/orgs/custom-properties?apiVersion=2022-11-28#list-custom-property-values-for-organization-repositories
+	page := 1
+	for page < FORLOOP_STOP {
+		body, err := g.client.CallRestAPI(ctx, fmt.Sprintf("/orgs/%s/properties/values?per_page=100&page=%d", config.Config.GithubAppOrganization, page), "GET", nil)
+		if err != nil {
+			return nil, nil, fmt.Errorf("not able to list custom properties values: %v", err)
+		}
+		var values []RepositoryCustomPropertyValues
+		if err := json.Unmarshal(body, &values); err != nil {
+			return nil, nil, fmt.Errorf("not able to list custom properties values: %v", err)
+		}
+		for _, repo := range values {
+			repoValues := make(map[string][]string)
+			for _, p := range repo.Properties {
+				if v := customPropertyValueToStrings(p.Value); len(v) > 0 {
+					repoValues[p.PropertyName] = v
+				}
+			}
+			reposProperties[repo.RepositoryName] = repoValues
+		}
+		if len(values) < 100 {
+			break
+		}
+		page++
+	}
+
+	return properties, reposProperties, nil
+}
+
+func customPropertyValueToStrings(value interface{}) []string {
+	switch v := value.(type) {
+	case string:
+		return []string{v}
+	case []interface{}:
+		values := []string{}
+		for _, e := range v {
+			if s, ok := e.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	}
+	return nil
+}
 
+func (g *GoliacRemoteImpl) UpsertCustomProperty(ctx context.Context, dryrun bool, property *GithubCustomProperty) error {
+	// https://docs.github.com/en/rest/orgs/custom-properties?apiVersion=2022-11-28#create-or-update-a-custom-property-for-an-organization
+	if !dryrun {
+		params := map[string]interface{}{
+			"value_type":  property.ValueType,
+			"required":    property.Required,
+			"description": property.Description,
+		}
+		if property.DefaultValue != "" {
+			params["default_value"] = property.DefaultValue
+		} else {
+			params["default_value"] = nil
+		}
+		if len(property.AllowedValues) > 0 {
+			params["allowed_values"] = property.AllowedValues
+		} else {
+			params["allowed_values"] = nil
+		}
+		body, err := g.client.CallRestAPI(ctx,
+			fmt.Sprintf("/orgs/%s/properties/schema/%s", config.Config.GithubAppOrganization, property.Name),
+			"PUT",
+			params,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to create or update custom property: %v. %s", err, string(body))
+		}
+	}
+	g.customProperties[property.Name] = property
+	return nil
+}
+
+func (g *GoliacRemoteImpl) DeleteCustomProperty(ctx context.Context, dryrun bool, name string) error {
+	// https://docs.github.com/en/rest/orgs/custom-properties?apiVersion=2022-11-28#remove-a-custom-property-for-an-organization
+	if !dryrun {
+		body, err := g.client.CallRestAPI(ctx,
+			fmt.Sprintf("/orgs/%s/properties/schema/%s", config.Config.GithubAppOrganization, name),
+			"DELETE",
+			nil,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to delete custom property: %v. %s", err, string(body))
+		}
+	}
+	delete(g.customProperties, name)
+	for _, values := range g.reposProperties {
+		delete(values, name)
+	}
+	return nil
 }
-func (g *GoliacRemoteImpl) Begin(dryrun bool) {
+
+/*
+ * UpdateRepositorySetCustomProperty sets a repository custom property value
+ * (an empty value removes the property value from the repository)
+ */
+func (g *GoliacRemoteImpl) UpdateRepositorySetCustomProperty(ctx context.Context, dryrun bool, reponame string, name string, value []string) error {
+	// https://docs.github.com/en/rest/orgs/custom-properties?apiVersion=2022-11-28#create-or-update-custom-property-values-for-organization-repositories
+	if !dryrun {
+		var v interface{}
+		if len(value) > 0 {
+			if p, ok := g.customProperties[name]; ok && p.ValueType == "multi_select" {
+				v = value
+			} else {
+				v = value[0]
+			}
+		}
+		body, err := g.client.CallRestAPI(ctx,
+			fmt.Sprintf("/orgs/%s/properties/values", config.Config.GithubAppOrganization),
+			"PATCH",
+			map[string]interface{}{
+				"repository_names": []string{reponame},
+				"properties": []map[string]interface{}{
+					{
+						"property_name": name,
+						"value":         v,
+					},
+				},
+			},
+		)
+		if err != nil {
+			return fmt.Errorf("failed to set repository custom property: %v. %s", err, string(body))
+		}
+	}
+	values, ok := g.reposProperties[reponame]
+	if !ok {
+		values = make(map[string][]string)
+		g.reposProperties[reponame] = values
+	}
+	if len(value) > 0 {
+		values[name] = value
+	} else {
+		delete(values, name)
+	}
+	return nil
 }
-func (g *GoliacRemoteImpl) Rollback(dryrun bool, err error) {
+
+func (g *GoliacRemoteImpl) loadOrganizationSettings(ctx context.Context) (*GithubOrganizationSettings, error) {
+	info, err := getOrgInfo(ctx, config.Config.GithubAppOrganization, g.client)
+	if err != nil {
+		return nil, fmt.Errorf("not able to load organization settings: %v", err)
+	}
+	return &GithubOrganizationSettings{
+		DefaultRepositoryPermission:       info.DefaultRepositoryPermission,
+		MembersCanCreateRepositories:      info.MembersCanCreateRepositories,
+		MembersCanForkPrivateRepositories: info.MembersCanForkPrivateRepositories,
+		WebCommitSignoffRequired:          info.WebCommitSignoffRequired,
+		TwoFactorRequirementEnabled:       info.TwoFactorRequirementEnabled,
+	}, nil
 }
-func (g *GoliacRemoteImpl) Commit(dryrun bool) {
+
+func (g *GoliacRemoteImpl) UpdateOrganizationSettings(ctx context.Context, dryrun bool, settings *GithubOrganizationSettingsUpdate) error {
+	// https://docs.github.com/en/rest/orgs/orgs?apiVersion=2022-11-28#update-an-organization
+	if !dryrun {
+		body, err := g.client.CallRestAPI(ctx,
+			fmt.Sprintf("/orgs/%s", config.Config.GithubAppOrganization),
+			"PATCH",
+			settings.Payload(),
+		)
+		if err != nil {
+			return fmt.Errorf("failed to update organization settings: %v. %s", err, string(body))
+		}
+	}
+	if g.orgSettings != nil {
+		s := settings.ApplyTo(*g.orgSettings)
+		g.orgSettings = &s
+	}
+	return nil
 }
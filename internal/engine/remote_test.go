@@ -1,17 +1,28 @@
 package engine
 
 import (
+	"context"
+	crand "crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
 	"math/rand"
 	"net/http"
+	"os"
+	"path/filepath"
 	"strconv"
+	"strings"
 	"testing"
 
+	"github.com/Alayacare/goliac/internal/config"
 	"github.com/Alayacare/goliac/internal/github"
 	"github.com/stretchr/testify/assert"
 
+	"golang.org/x/crypto/nacl/box"
+
 	"github.com/vektah/gqlparser/v2/ast"
 	"github.com/vektah/gqlparser/v2/parser"
 )
@@ -355,11 +366,11 @@ func (m *MockGithubClient) organization(args ast.ArgumentList, children ast.Sele
 	return data
 }
 
-func (m *MockGithubClient) GetAppSlug() string {
-	return "mock-github-client"
+func (m *MockGithubClient) GetActor() string {
+	return "mock-github-client[bot]"
 }
 
-func (m *MockGithubClient) QueryGraphQLAPI(query string, variables map[string]interface{}) ([]byte, error) {
+func (m *MockGithubClient) QueryGraphQLAPI(ctx context.Context, query string, variables map[string]interface{}) ([]byte, error) {
 
 	doc, err := parser.ParseQuery(&ast.Source{Input: query})
 
@@ -389,10 +400,14 @@ func (m *MockGithubClient) QueryGraphQLAPI(query string, variables map[string]in
 	return j, nil
 }
 
-func (m *MockGithubClient) CallRestAPI(endpoint, method string, body map[string]interface{}) ([]byte, error) {
-	return nil, nil
+func (m *MockGithubClient) CallRestAPI(ctx context.Context, endpoint, method string, body map[string]interface{}) ([]byte, error) {
+	// no organization invitation
+	if strings.Contains(endpoint, "invitations?") {
+		return []byte("[]"), nil
+	}
+	return []byte("{}"), nil
 }
-func (m *MockGithubClient) GetAccessToken() (string, error) {
+func (m *MockGithubClient) GetAccessToken(ctx context.Context) (string, error) {
 	return "", nil
 }
 
@@ -405,7 +420,7 @@ func TestRemoteRepository(t *testing.T) {
 
 		remoteImpl := NewGoliacRemoteImpl(&client)
 
-		repositories, _, err := remoteImpl.loadRepositories()
+		repositories, _, err := remoteImpl.loadRepositories(context.TODO())
 		assert.Nil(t, err)
 		assert.Equal(t, 133, len(repositories))
 		assert.Equal(t, false, repositories["repo_1"].IsArchived)
@@ -419,7 +434,7 @@ func TestRemoteRepository(t *testing.T) {
 
 		remoteImpl := NewGoliacRemoteImpl(&client)
 
-		teams, _, err := remoteImpl.loadTeams()
+		teams, _, err := remoteImpl.loadTeams(context.TODO())
 		assert.Nil(t, err)
 		assert.Equal(t, 122, len(teams))
 		assert.Equal(t, "team_1", teams["slug-1"].Name)
@@ -431,7 +446,7 @@ func TestRemoteRepository(t *testing.T) {
 
 		remoteImpl := NewGoliacRemoteImpl(&client)
 
-		repos, err := remoteImpl.loadTeamRepos("team-1")
+		repos, err := remoteImpl.loadTeamRepos(context.TODO(), "team-1")
 		assert.Nil(t, err)
 		assert.Equal(t, 2, len(repos))
 		assert.Equal(t, "push", repos["repo_0"].Permission)
@@ -443,11 +458,29 @@ func TestRemoteRepository(t *testing.T) {
 
 		remoteImpl := NewGoliacRemoteImpl(&client)
 
-		err := remoteImpl.Load()
+		err := remoteImpl.Load(context.TODO())
 		assert.Nil(t, err)
 		assert.Equal(t, 122, len(remoteImpl.teams))
 		assert.Equal(t, 2, len(remoteImpl.teamRepos["slug-1"]))
 	})
+
+	t.Run("not happy path: a cancelled context stops loading team's repos", func(t *testing.T) {
+		client := MockGithubClient{}
+
+		remoteImpl := NewGoliacRemoteImpl(&client)
+		teams, _, err := remoteImpl.loadTeams(context.TODO())
+		assert.Nil(t, err)
+		remoteImpl.teams = teams
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		_, err = remoteImpl.loadTeamReposConcurrently(ctx, 4)
+		assert.Equal(t, context.Canceled, err)
+
+		_, err = remoteImpl.loadTeamReposNonConcurrently(ctx)
+		assert.Equal(t, context.Canceled, err)
+	})
 }
 
 type GitHubClientIsEnterpriseMock struct {
@@ -455,16 +488,16 @@ type GitHubClientIsEnterpriseMock struct {
 	err     error
 }
 
-func (g *GitHubClientIsEnterpriseMock) QueryGraphQLAPI(query string, variables map[string]interface{}) ([]byte, error) {
+func (g *GitHubClientIsEnterpriseMock) QueryGraphQLAPI(ctx context.Context, query string, variables map[string]interface{}) ([]byte, error) {
 	return []byte(""), nil
 }
-func (g *GitHubClientIsEnterpriseMock) CallRestAPI(endpoint, method string, body map[string]interface{}) ([]byte, error) {
+func (g *GitHubClientIsEnterpriseMock) CallRestAPI(ctx context.Context, endpoint, method string, body map[string]interface{}) ([]byte, error) {
 	return g.results[endpoint], g.err
 }
-func (g *GitHubClientIsEnterpriseMock) GetAccessToken() (string, error) {
+func (g *GitHubClientIsEnterpriseMock) GetAccessToken(ctx context.Context) (string, error) {
 	return "", nil
 }
-func (g *GitHubClientIsEnterpriseMock) GetAppSlug() string {
+func (g *GitHubClientIsEnterpriseMock) GetActor() string {
 	return ""
 }
 
@@ -520,7 +553,7 @@ func TestIsEnterprise(t *testing.T) {
 		}
 
 		for _, set := range tests {
-			res := isEnterprise("foobar", set.mock)
+			res := isEnterprise(context.TODO(), "foobar", set.mock)
 
 			assert.Equal(t, set.expected, res)
 
@@ -567,10 +600,238 @@ func TestIsEnterprise(t *testing.T) {
 		}
 
 		for _, set := range tests {
-			res := isEnterprise("foobar", set.mock)
+			res := isEnterprise(context.TODO(), "foobar", set.mock)
 
 			assert.Equal(t, set.expected, res)
 
 		}
 	})
 }
+
+func TestEncryptSecret(t *testing.T) {
+	t.Run("happy path: sealed box encryption", func(t *testing.T) {
+		publicKey, privateKey, err := box.GenerateKey(crand.Reader)
+		assert.Nil(t, err)
+
+		encrypted, err := encryptSecret(base64.StdEncoding.EncodeToString(publicKey[:]), "mysecret")
+		assert.Nil(t, err)
+
+		decoded, err := base64.StdEncoding.DecodeString(encrypted)
+		assert.Nil(t, err)
+		decrypted, ok := box.OpenAnonymous(nil, decoded, publicKey, privateKey)
+		assert.True(t, ok)
+		assert.Equal(t, "mysecret", string(decrypted))
+	})
+
+	t.Run("not happy path: invalid public key", func(t *testing.T) {
+		_, err := encryptSecret(base64.StdEncoding.EncodeToString([]byte("tooshort")), "mysecret")
+		assert.NotNil(t, err)
+	})
+}
+
+func TestSecretsHashesStore(t *testing.T) {
+	t.Run("happy path: hashes survive a restart", func(t *testing.T) {
+		filename := filepath.Join(t.TempDir(), "hashes.json")
+
+		store := newSecretsHashesStore(filename)
+		store.Set("orgs/myorg/SECRET1", hashSecretValue("value1"), "2024-01-01T00:00:00Z")
+		store.Set("orgs/myorg/SECRET2", hashSecretValue("value2"), "2024-01-01T00:00:00Z")
+		store.Delete("orgs/myorg/SECRET2")
+
+		reloaded := newSecretsHashesStore(filename)
+		assert.Equal(t, hashSecretValue("value1"), reloaded.Get("orgs/myorg/SECRET1", "2024-01-01T00:00:00Z"))
+		assert.Equal(t, "", reloaded.Get("orgs/myorg/SECRET2", "2024-01-01T00:00:00Z"))
+	})
+
+	t.Run("happy path: a secret updated outside of Goliac is unknown", func(t *testing.T) {
+		store := newSecretsHashesStore("")
+		store.Set("orgs/myorg/SECRET1", hashSecretValue("value1"), "2024-01-01T00:00:00Z")
+
+		assert.Equal(t, "", store.Get("orgs/myorg/SECRET1", "2024-02-01T00:00:00Z"))
+	})
+
+	t.Run("happy path: the hashes are keyed by the file", func(t *testing.T) {
+		newSecretsHashesStore("")
+		hash1 := hashSecretValue("value1")
+		newSecretsHashesStore("")
+		hash2 := hashSecretValue("value1")
+
+		assert.NotEqual(t, hash1, hash2)
+		plain := sha256.Sum256([]byte("value1"))
+		assert.NotEqual(t, hex.EncodeToString(plain[:]), hash2)
+	})
+
+	t.Run("not happy path: unsalted hashes are not trusted", func(t *testing.T) {
+		filename := filepath.Join(t.TempDir(), "hashes.json")
+		plain := sha256.Sum256([]byte("value1"))
+		err := os.WriteFile(filename, []byte(`{"orgs/myorg/SECRET1":{"hash":"`+hex.EncodeToString(plain[:])+`","updated_at":"2024-01-01T00:00:00Z"}}`), 0600)
+		assert.Nil(t, err)
+
+		store := newSecretsHashesStore(filename)
+		assert.Equal(t, "", store.Get("orgs/myorg/SECRET1", "2024-01-01T00:00:00Z"))
+	})
+}
+
+func TestRemoteMutationErrors(t *testing.T) {
+	t.Run("not happy path: Github errors are returned", func(t *testing.T) {
+		client := GitHubClientIsEnterpriseMock{
+			err: fmt.Errorf("403 Forbidden"),
+		}
+		remoteImpl := NewGoliacRemoteImpl(&client)
+
+		err := remoteImpl.CreateTeam(context.TODO(), false, "team1", "team1", []string{})
+		assert.NotNil(t, err)
+		assert.Contains(t, err.Error(), "403 Forbidden")
+		// the cache is not updated
+		_, found := remoteImpl.teams["team1"]
+		assert.False(t, found)
+
+		err = remoteImpl.UpdateRepositoryAddTeamAccess(context.TODO(), false, "repo1", "team1", "push")
+		assert.NotNil(t, err)
+	})
+
+	t.Run("happy path: nothing is called in dryrun", func(t *testing.T) {
+		client := GitHubClientIsEnterpriseMock{
+			err: fmt.Errorf("403 Forbidden"),
+		}
+		remoteImpl := NewGoliacRemoteImpl(&client)
+
+		err := remoteImpl.CreateTeam(context.TODO(), true, "team1", "team1", []string{})
+		assert.Nil(t, err)
+		_, found := remoteImpl.teams["team1"]
+		assert.True(t, found)
+	})
+}
+
+/*
+ * InvitationsGithubClientMock serves one member, and the organization invitations
+ */
+type InvitationsGithubClientMock struct {
+	GitHubClientIsEnterpriseMock
+	deleted []string
+}
+
+func (g *InvitationsGithubClientMock) QueryGraphQLAPI(ctx context.Context, query string, variables map[string]interface{}) ([]byte, error) {
+	return []byte(`{"data":{"organization":{"membersWithRole":{"nodes":[{"login":"member"}],"pageInfo":{"hasNextPage":false}}}}}`), nil
+}
+func (g *InvitationsGithubClientMock) CallRestAPI(ctx context.Context, endpoint, method string, body map[string]interface{}) ([]byte, error) {
+	if method == "DELETE" {
+		g.deleted = append(g.deleted, endpoint)
+		return nil, nil
+	}
+	return g.GitHubClientIsEnterpriseMock.CallRestAPI(ctx, endpoint, method, body)
+}
+
+func TestRemoteInvitations(t *testing.T) {
+	t.Run("happy path: pending and failed invitations", func(t *testing.T) {
+		config.Config.GithubAppOrganization = "myorg"
+		defer func() { config.Config.GithubAppOrganization = "" }()
+		client := &InvitationsGithubClientMock{
+			GitHubClientIsEnterpriseMock: GitHubClientIsEnterpriseMock{
+				results: map[string][]byte{
+					"/orgs/myorg/invitations?per_page=100&page=1":        []byte(`[{"id": 1, "login": "newcomer"}, {"id": 2, "login": null, "email": "alice@mycompany.com"}]`),
+					"/orgs/myorg/failed_invitations?per_page=100&page=1": []byte(`[{"id": 3, "login": "expired"}, {"id": 4, "login": "newcomer"}]`),
+				},
+			},
+		}
+		remoteImpl := NewGoliacRemoteImpl(client)
+
+		users := remoteImpl.Users(context.TODO())
+		assert.Equal(t, map[string]string{
+			"member":              UserStateMember,
+			"newcomer":            UserStateInvited,
+			"alice@mycompany.com": UserStateInvited,
+			"expired":             UserStateInvitationFailed,
+		}, users)
+
+		err := remoteImpl.CancelOrgInvitation(context.TODO(), false, "alice@mycompany.com")
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"/orgs/myorg/invitations/2"}, client.deleted)
+		_, found := remoteImpl.Users(context.TODO())["alice@mycompany.com"]
+		assert.False(t, found)
+
+		// a failed invitation cannot be cancelled
+		err = remoteImpl.CancelOrgInvitation(context.TODO(), false, "expired")
+		assert.NotNil(t, err)
+	})
+
+	t.Run("not happy path: the invitations cannot be loaded", func(t *testing.T) {
+		config.Config.GithubAppOrganization = "myorg"
+		defer func() { config.Config.GithubAppOrganization = "" }()
+		client := &InvitationsGithubClientMock{
+			GitHubClientIsEnterpriseMock: GitHubClientIsEnterpriseMock{
+				err: fmt.Errorf("403 Forbidden"),
+			},
+		}
+		remoteImpl := NewGoliacRemoteImpl(client)
+
+		_, _, err := remoteImpl.loadOrgUsers(context.TODO())
+		assert.NotNil(t, err)
+		assert.Contains(t, err.Error(), "not able to load the failed organization invitations")
+	})
+}
+
+/*
+ * CountingGithubClientMock counts the REST calls
+ */
+type CountingGithubClientMock struct {
+	GitHubClientIsEnterpriseMock
+	calls map[string]int
+}
+
+func (g *CountingGithubClientMock) CallRestAPI(ctx context.Context, endpoint, method string, body map[string]interface{}) ([]byte, error) {
+	g.calls[endpoint]++
+	return g.GitHubClientIsEnterpriseMock.CallRestAPI(ctx, endpoint, method, body)
+}
+
+func TestGetUserLogin(t *testing.T) {
+	t.Run("happy path: the lookups are cached", func(t *testing.T) {
+		config.Config.GithubCacheTTL = 60
+		defer func() { config.Config.GithubCacheTTL = 0 }()
+		client := &CountingGithubClientMock{
+			GitHubClientIsEnterpriseMock: GitHubClientIsEnterpriseMock{
+				results: map[string][]byte{"/users/camelcase": []byte(`{"login": "CamelCase"}`)},
+			},
+			calls: make(map[string]int),
+		}
+		remoteImpl := NewGoliacRemoteImpl(client)
+
+		for i := 0; i < 2; i++ {
+			login, err := remoteImpl.GetUserLogin(context.TODO(), "camelcase")
+			assert.Nil(t, err)
+			assert.Equal(t, "CamelCase", login)
+		}
+		assert.Equal(t, 1, client.calls["/users/camelcase"])
+
+		// until the users cache is invalidated
+		remoteImpl.InvalidateCache(CacheUsers)
+		_, err := remoteImpl.GetUserLogin(context.TODO(), "camelcase")
+		assert.Nil(t, err)
+		assert.Equal(t, 2, client.calls["/users/camelcase"])
+	})
+}
+
+func TestLoadActions(t *testing.T) {
+	t.Run("not happy path: the actions are loaded again after an error", func(t *testing.T) {
+		config.Config.GithubAppOrganization = "myorg"
+		config.Config.GithubCacheTTL = 60
+		defer func() {
+			config.Config.GithubAppOrganization = ""
+			config.Config.GithubCacheTTL = 0
+		}()
+		client := &CountingGithubClientMock{
+			GitHubClientIsEnterpriseMock: GitHubClientIsEnterpriseMock{
+				err: fmt.Errorf("403 Forbidden"),
+			},
+			calls: make(map[string]int),
+		}
+		remoteImpl := NewGoliacRemoteImpl(client)
+
+		for i := 0; i < 2; i++ {
+			err := remoteImpl.LoadActions(context.TODO())
+			assert.NotNil(t, err)
+			assert.Contains(t, err.Error(), "403 Forbidden")
+		}
+		assert.Equal(t, 2, client.calls["/orgs/myorg/actions/variables?per_page=30&page=1"])
+	})
+}
@@ -0,0 +1,150 @@
+package engine
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"os"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+type secretHash struct {
+	Hash      string `json:"hash"`
+	UpdatedAt string `json:"updated_at"`
+}
+
+type secretsHashesFile struct {
+	Key    string                `json:"key"` // hex encoded HMAC key
+	Hashes map[string]secretHash `json:"hashes"`
+}
+
+var (
+	secretsHashKeyMutex sync.RWMutex
+	secretsHashKey      []byte // the HMAC key of the secrets hashes file
+)
+
+/*
+ * hashSecretValue returns the HMAC-SHA256 of a secret value, with the random
+ * key of the secrets hashes file (a plain hash could be brute forced)
+ */
+func hashSecretValue(value string) string {
+	secretsHashKeyMutex.RLock()
+	defer secretsHashKeyMutex.RUnlock()
+	mac := hmac.New(sha256.New, secretsHashKey)
+	mac.Write([]byte(value))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+/*
+ * secretsHashesStore keeps the HMAC-SHA256 of the secrets pushed by Goliac (Github
+ * never gives back a secret value), with the Github updated_at timestamp of the
+ * secret at the time it was pushed.
+ * The hashes (and their key) are saved in a JSON file (if a filename is given) to
+ * survive a restart, and a hash is only trusted if the secret was not updated since
+ */
+type secretsHashesStore struct {
+	filename string
+	mu       sync.Mutex
+	key      []byte
+	hashes   map[string]secretHash // [scope/name]
+}
+
+func newSecretsHashesStore(filename string) *secretsHashesStore {
+	s := &secretsHashesStore{
+		filename: filename,
+		hashes:   make(map[string]secretHash),
+	}
+	defer s.useKey()
+	if filename == "" {
+		return s
+	}
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			logrus.Warnf("not able to read the secrets hashes file %s: %v", filename, err)
+		}
+		return s
+	}
+	var file secretsHashesFile
+	if err := json.Unmarshal(content, &file); err != nil {
+		logrus.Warnf("not able to parse the secrets hashes file %s: %v", filename, err)
+		return s
+	}
+	key, err := hex.DecodeString(file.Key)
+	if err != nil || len(key) == 0 || file.Hashes == nil {
+		// without key (like a file of unsalted hashes), the hashes are not trusted
+		return s
+	}
+	s.key = key
+	s.hashes = file.Hashes
+	return s
+}
+
+/*
+ * useKey sets the key used by hashSecretValue (a new random key if none was loaded)
+ */
+func (s *secretsHashesStore) useKey() {
+	if s.key == nil {
+		s.key = make([]byte, 32)
+		if _, err := rand.Read(s.key); err != nil {
+			logrus.Warnf("not able to generate the secrets hashes key: %v", err)
+		}
+	}
+	secretsHashKeyMutex.Lock()
+	secretsHashKey = s.key
+	secretsHashKeyMutex.Unlock()
+}
+
+/*
+ * Get returns the hash of the secret pushed by Goliac, or an empty string
+ * if unknown or if the secret was updated (outside of Goliac) since
+ */
+func (s *secretsHashesStore) Get(key string, updatedAt string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	h, ok := s.hashes[key]
+	if !ok || h.UpdatedAt != updatedAt {
+		return ""
+	}
+	return h.Hash
+}
+
+func (s *secretsHashesStore) Set(key string, hash string, updatedAt string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.hashes[key] = secretHash{Hash: hash, UpdatedAt: updatedAt}
+	s.save()
+}
+
+func (s *secretsHashesStore) Delete(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.hashes[key]; !ok {
+		return
+	}
+	delete(s.hashes, key)
+	s.save()
+}
+
+// save must be called with the lock held
+func (s *secretsHashesStore) save() {
+	if s.filename == "" {
+		return
+	}
+	content, err := json.Marshal(secretsHashesFile{
+		Key:    hex.EncodeToString(s.key),
+		Hashes: s.hashes,
+	})
+	if err != nil {
+		logrus.Warnf("not able to save the secrets hashes: %v", err)
+		return
+	}
+	if err := os.WriteFile(s.filename, content, 0600); err != nil {
+		logrus.Warnf("not able to save the secrets hashes file %s: %v", s.filename, err)
+	}
+}
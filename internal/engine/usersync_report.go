@@ -0,0 +1,111 @@
+package engine
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/Alayacare/goliac/internal/entity"
+)
+
+// branch used to propose the user sync changes via a pull request (usersync.pull_request)
+const UserSyncBranch = "goliac/usersync"
+
+/*
+ * UserSyncReport summarizes what a user sync changed in the teams repository
+ */
+type UserSyncReport struct {
+	AddedUsers   []string          // usernames
+	RemovedUsers []string          // usernames
+	RenamedUsers map[string]string // old username -> new username (same Github login)
+	UpdatedUsers []string          // usernames
+	Teams        []string          // teamnames
+	// teams left without owner after the sync
+	OrphanedTeams []string
+}
+
+/*
+ * NewUserSyncReport compares the org users before and after the sync.
+ * A removed user and an added user sharing the same Github login is a renamed user.
+ * teamschanged are the team files rewritten by the sync
+ */
+func NewUserSyncReport(before map[string]*entity.User, after map[string]*entity.User, teamschanged []string) *UserSyncReport {
+	report := &UserSyncReport{
+		AddedUsers:    []string{},
+		RemovedUsers:  []string{},
+		RenamedUsers:  map[string]string{},
+		UpdatedUsers:  []string{},
+		Teams:         []string{},
+		OrphanedTeams: []string{},
+	}
+
+	added := map[string]string{} // githubid -> username
+	for username, user := range after {
+		if old, ok := before[username]; !ok {
+			added[user.Spec.GithubID] = username
+		} else if !old.Equals(user) {
+			report.UpdatedUsers = append(report.UpdatedUsers, username)
+		}
+	}
+	for username, user := range before {
+		if _, ok := after[username]; ok {
+			continue
+		}
+		if newname, ok := added[user.Spec.GithubID]; ok {
+			report.RenamedUsers[username] = newname
+			delete(added, user.Spec.GithubID)
+			continue
+		}
+		report.RemovedUsers = append(report.RemovedUsers, username)
+	}
+	for _, username := range added {
+		report.AddedUsers = append(report.AddedUsers, username)
+	}
+
+	for _, t := range teamschanged {
+		report.Teams = append(report.Teams, filepath.Base(filepath.Dir(t)))
+	}
+
+	sort.Strings(report.AddedUsers)
+	sort.Strings(report.RemovedUsers)
+	sort.Strings(report.UpdatedUsers)
+	sort.Strings(report.Teams)
+	return report
+}
+
+func (r *UserSyncReport) IsEmpty() bool {
+	return len(r.AddedUsers) == 0 && len(r.RemovedUsers) == 0 && len(r.RenamedUsers) == 0 && len(r.UpdatedUsers) == 0 && len(r.Teams) == 0
+}
+
+/*
+ * Summary returns a markdown description of the changes (used as the pull request body)
+ */
+func (r *UserSyncReport) Summary() string {
+	var sb strings.Builder
+	sb.WriteString("Users and teams changes found by the Goliac user sync. Please review them (especially the removed users) before merging.\n")
+
+	section := func(title string, items []string) {
+		if len(items) == 0 {
+			return
+		}
+		sb.WriteString(fmt.Sprintf("\n### %s (%d)\n\n", title, len(items)))
+		for _, item := range items {
+			sb.WriteString(fmt.Sprintf("- %s\n", item))
+		}
+	}
+
+	renamed := []string{}
+	for oldname, newname := range r.RenamedUsers {
+		renamed = append(renamed, fmt.Sprintf("%s -> %s", oldname, newname))
+	}
+	sort.Strings(renamed)
+
+	section("Added users", r.AddedUsers)
+	section("Removed users", r.RemovedUsers)
+	section("Renamed users", renamed)
+	section("Updated users", r.UpdatedUsers)
+	section("Teams affected", r.Teams)
+	section("Teams without owner", r.OrphanedTeams)
+	return sb.String()
+}
@@ -0,0 +1,59 @@
+package engine
+
+import (
+	"testing"
+
+	"github.com/Alayacare/goliac/internal/entity"
+	"github.com/stretchr/testify/assert"
+)
+
+func newReportUser(username, githubid string) *entity.User {
+	user := &entity.User{}
+	user.ApiVersion = "v1"
+	user.Kind = "User"
+	user.Name = username
+	user.Spec.GithubID = githubid
+	return user
+}
+
+func TestUserSyncReport(t *testing.T) {
+	t.Run("happy path: added, removed, renamed and updated users", func(t *testing.T) {
+		before := map[string]*entity.User{
+			"alice": newReportUser("alice", "alice-gh"),
+			"bob":   newReportUser("bob", "bob-gh"),
+			"carol": newReportUser("carol", "carol-gh"),
+			"dave":  newReportUser("dave", "dave-gh"),
+		}
+		after := map[string]*entity.User{
+			"alice":    newReportUser("alice", "alice-gh"),
+			"caroline": newReportUser("caroline", "carol-gh"),
+			"dave":     newReportUser("dave", "dave-new-gh"),
+			"eve":      newReportUser("eve", "eve-gh"),
+		}
+
+		report := NewUserSyncReport(before, after, []string{"/tmp/goliac/teams/ops/team.yaml", "/tmp/goliac/teams/dev/front/team.yaml"})
+
+		assert.Equal(t, []string{"eve"}, report.AddedUsers)
+		assert.Equal(t, []string{"bob"}, report.RemovedUsers)
+		assert.Equal(t, map[string]string{"carol": "caroline"}, report.RenamedUsers)
+		assert.Equal(t, []string{"dave"}, report.UpdatedUsers)
+		assert.Equal(t, []string{"front", "ops"}, report.Teams)
+		assert.False(t, report.IsEmpty())
+
+		summary := report.Summary()
+		assert.Contains(t, summary, "### Removed users (1)\n\n- bob\n")
+		assert.Contains(t, summary, "- carol -> caroline\n")
+		assert.Contains(t, summary, "### Teams affected (2)")
+	})
+
+	t.Run("happy path: no change", func(t *testing.T) {
+		users := map[string]*entity.User{
+			"alice": newReportUser("alice", "alice-gh"),
+		}
+
+		report := NewUserSyncReport(users, users, []string{})
+
+		assert.True(t, report.IsEmpty())
+		assert.NotContains(t, report.Summary(), "###")
+	})
+}
@@ -0,0 +1,189 @@
+package entity
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/afero"
+	"gopkg.in/yaml.v3"
+)
+
+// maximum duration of a temporary access request
+const MaxAccessRequestDuration = 7 * 24 * time.Hour
+
+/*
+ * AccessRequest is a time-boxed (just-in-time) access to a repository,
+ * granted to a team, or to an external user
+ */
+type AccessRequest struct {
+	Entity `yaml:",inline"`
+	Spec   struct {
+		Repository    string `yaml:"repository"`
+		Team          string `yaml:"team,omitempty"`         // a team defined in the teams directory
+		ExternalUser  string `yaml:"externalUser,omitempty"` // an external user defined in users/external
+		Permission    string `yaml:"permission"`             // read or write
+		StartsAt      string `yaml:"startsAt"`               // RFC3339 (like 2026-10-19T14:00:00Z)
+		Duration      string `yaml:"duration"`               // like 4h or 90m
+		Justification string `yaml:"justification"`
+	} `yaml:"spec"`
+}
+
+/*
+ * NewAccessRequest reads a file and returns an AccessRequest object
+ * The next step is to validate the AccessRequest object using the Validate method
+ */
+func NewAccessRequest(fs afero.Fs, filename string) (*AccessRequest, error) {
+	filecontent, err := afero.ReadFile(fs, filename)
+	if err != nil {
+		return nil, err
+	}
+
+	request := &AccessRequest{}
+	err = yaml.Unmarshal(filecontent, request)
+	if err != nil {
+		return nil, err
+	}
+
+	return request, nil
+}
+
+/**
+ * ReadAccessRequestDirectory reads all the files in the dirname directory and returns
+ * - a map of AccessRequest objects
+ * - a slice of errors that must stop the validation process
+ * - a slice of warning that must not stop the validation process
+ */
+func ReadAccessRequestDirectory(fs afero.Fs, dirname string, repos map[string]*Repository, teams map[string]*Team, externalUsers map[string]*User) (map[string]*AccessRequest, []error, []Warning) {
+	errors := []error{}
+	warning := []Warning{}
+	requests := make(map[string]*AccessRequest)
+
+	exist, err := afero.Exists(fs, dirname)
+	if err != nil {
+		errors = append(errors, err)
+		return requests, errors, warning
+	}
+	if !exist {
+		return requests, errors, warning
+	}
+
+	entries, err := afero.ReadDir(fs, dirname)
+	if err != nil {
+		errors = append(errors, err)
+		return requests, errors, warning
+	}
+
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		// skipping files starting with '.'
+		if e.Name()[0] == '.' {
+			continue
+		}
+		if !strings.HasSuffix(e.Name(), ".yaml") {
+			warning = append(warning, fmt.Errorf("File %s doesn't have a .yaml extension", e.Name()))
+			continue
+		}
+		request, err := NewAccessRequest(fs, filepath.Join(dirname, e.Name()))
+		if err != nil {
+			errors = append(errors, err)
+			continue
+		}
+		if err := request.Validate(filepath.Join(dirname, e.Name()), repos, teams, externalUsers); err != nil {
+			errors = append(errors, err)
+			continue
+		}
+		if request.IsExpired(time.Now()) {
+			warning = append(warning, fmt.Errorf("access request %s has expired, the file %s can be removed", request.Name, filepath.Join(dirname, e.Name())))
+		}
+		requests[request.Name] = request
+	}
+	return requests, errors, warning
+}
+
+func (a *AccessRequest) Validate(filename string, repos map[string]*Repository, teams map[string]*Team, externalUsers map[string]*User) error {
+
+	if a.ApiVersion != "v1" {
+		return fmt.Errorf("invalid apiVersion: %s for access request filename %s", a.ApiVersion, filename)
+	}
+
+	if a.Kind != "AccessRequest" {
+		return fmt.Errorf("invalid kind: %s for access request filename %s", a.Kind, filename)
+	}
+
+	if a.Name == "" {
+		return fmt.Errorf("metadata.name is empty for access request filename %s", filename)
+	}
+
+	filename = filepath.Base(filename)
+	if a.Name != filename[:len(filename)-len(filepath.Ext(filename))] {
+		return fmt.Errorf("invalid metadata.name: %s for access request filename %s", a.Name, filename)
+	}
+
+	repo, ok := repos[a.Spec.Repository]
+	if !ok {
+		return fmt.Errorf("invalid repository: %s doesn't exist (check access request filename %s)", a.Spec.Repository, filename)
+	}
+	if repo.Archived {
+		return fmt.Errorf("invalid repository: %s is archived (check access request filename %s)", a.Spec.Repository, filename)
+	}
+
+	if (a.Spec.Team == "") == (a.Spec.ExternalUser == "") {
+		return fmt.Errorf("either team or externalUser must be set (check access request filename %s)", filename)
+	}
+	if a.Spec.Team != "" {
+		if _, ok := teams[a.Spec.Team]; !ok {
+			return fmt.Errorf("invalid team: %s doesn't exist (check access request filename %s)", a.Spec.Team, filename)
+		}
+	}
+	if a.Spec.ExternalUser != "" {
+		if _, ok := externalUsers[a.Spec.ExternalUser]; !ok {
+			return fmt.Errorf("invalid externalUser: %s doesn't exist (check access request filename %s)", a.Spec.ExternalUser, filename)
+		}
+	}
+
+	if a.Spec.Permission != "read" && a.Spec.Permission != "write" {
+		return fmt.Errorf("invalid permission: %s must be read or write (check access request filename %s)", a.Spec.Permission, filename)
+	}
+
+	if _, err := time.Parse(time.RFC3339, a.Spec.StartsAt); err != nil {
+		return fmt.Errorf("invalid startsAt: %s must be a RFC3339 date (check access request filename %s)", a.Spec.StartsAt, filename)
+	}
+
+	duration, err := time.ParseDuration(a.Spec.Duration)
+	if err != nil || duration <= 0 {
+		return fmt.Errorf("invalid duration: %s (check access request filename %s)", a.Spec.Duration, filename)
+	}
+	if duration > MaxAccessRequestDuration {
+		return fmt.Errorf("invalid duration: %s is longer than %s (check access request filename %s)", a.Spec.Duration, MaxAccessRequestDuration, filename)
+	}
+
+	if strings.TrimSpace(a.Spec.Justification) == "" {
+		return fmt.Errorf("justification is empty (check access request filename %s)", filename)
+	}
+
+	return nil
+}
+
+/*
+ * Window returns when the access starts and ends
+ * (the AccessRequest must have been validated)
+ */
+func (a *AccessRequest) Window() (time.Time, time.Time) {
+	start, _ := time.Parse(time.RFC3339, a.Spec.StartsAt)
+	duration, _ := time.ParseDuration(a.Spec.Duration)
+	return start, start.Add(duration)
+}
+
+func (a *AccessRequest) IsActive(now time.Time) bool {
+	start, end := a.Window()
+	return !now.Before(start) && now.Before(end)
+}
+
+func (a *AccessRequest) IsExpired(now time.Time) bool {
+	_, end := a.Window()
+	return !now.Before(end)
+}
@@ -0,0 +1,126 @@
+package entity
+
+import (
+	"testing"
+	"time"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAccessRequest(t *testing.T) {
+	loadFixture := func(t *testing.T, fs afero.Fs) (map[string]*Repository, map[string]*Team) {
+		fixtureCreateUserTeam(t, fs)
+		err := afero.WriteFile(fs, "teams/team1/repo1.yaml", []byte(`
+apiVersion: v1
+kind: Repository
+name: repo1
+`), 0644)
+		assert.Nil(t, err)
+		users, _, _ := ReadUserDirectory(fs, "users")
+		teams, _, _ := ReadTeamDirectory(fs, "teams", users)
+		repos, _, _ := ReadRepositories(fs, "archived", "teams", teams, map[string]*User{})
+		return repos, teams
+	}
+
+	t.Run("happy path", func(t *testing.T) {
+		fs := afero.NewMemMapFs()
+		repos, teams := loadFixture(t, fs)
+
+		startsAt := time.Now().Add(-time.Hour).UTC().Format(time.RFC3339)
+		err := afero.WriteFile(fs, "requests/incident-1.yaml", []byte(`
+apiVersion: v1
+kind: AccessRequest
+name: incident-1
+spec:
+  repository: repo1
+  team: team1
+  permission: write
+  startsAt: `+startsAt+`
+  duration: 4h
+  justification: incident 1
+`), 0644)
+		assert.Nil(t, err)
+
+		requests, errs, warns := ReadAccessRequestDirectory(fs, "requests", repos, teams, map[string]*User{})
+		assert.Equal(t, 0, len(errs))
+		assert.Equal(t, 0, len(warns))
+		assert.Equal(t, 1, len(requests))
+		assert.True(t, requests["incident-1"].IsActive(time.Now()))
+		assert.False(t, requests["incident-1"].IsExpired(time.Now()))
+	})
+
+	t.Run("happy path: expired request", func(t *testing.T) {
+		fs := afero.NewMemMapFs()
+		repos, teams := loadFixture(t, fs)
+
+		err := afero.WriteFile(fs, "requests/incident-1.yaml", []byte(`
+apiVersion: v1
+kind: AccessRequest
+name: incident-1
+spec:
+  repository: repo1
+  team: team1
+  permission: read
+  startsAt: 2020-01-01T10:00:00Z
+  duration: 4h
+  justification: incident 1
+`), 0644)
+		assert.Nil(t, err)
+
+		requests, errs, warns := ReadAccessRequestDirectory(fs, "requests", repos, teams, map[string]*User{})
+		assert.Equal(t, 0, len(errs))
+		assert.Equal(t, 1, len(warns))
+		assert.True(t, requests["incident-1"].IsExpired(time.Now()))
+	})
+
+	t.Run("not happy path: invalid requests", func(t *testing.T) {
+		fs := afero.NewMemMapFs()
+		repos, teams := loadFixture(t, fs)
+
+		// duration too long
+		err := afero.WriteFile(fs, "requests/request1.yaml", []byte(`
+apiVersion: v1
+kind: AccessRequest
+name: request1
+spec:
+  repository: repo1
+  team: team1
+  permission: write
+  startsAt: 2020-01-01T10:00:00Z
+  duration: 720h
+  justification: too long
+`), 0644)
+		assert.Nil(t, err)
+		// unknown repository
+		err = afero.WriteFile(fs, "requests/request2.yaml", []byte(`
+apiVersion: v1
+kind: AccessRequest
+name: request2
+spec:
+  repository: unknown
+  team: team1
+  permission: write
+  startsAt: 2020-01-01T10:00:00Z
+  duration: 1h
+  justification: unknown repository
+`), 0644)
+		assert.Nil(t, err)
+		// missing justification and grantee
+		err = afero.WriteFile(fs, "requests/request3.yaml", []byte(`
+apiVersion: v1
+kind: AccessRequest
+name: request3
+spec:
+  repository: repo1
+  permission: admin
+  startsAt: 2020-01-01T10:00:00Z
+  duration: 1h
+`), 0644)
+		assert.Nil(t, err)
+
+		requests, errs, _ := ReadAccessRequestDirectory(fs, "requests", repos, teams, map[string]*User{})
+		assert.Equal(t, 3, len(errs))
+		assert.Equal(t, 0, len(requests))
+	})
+}
@@ -0,0 +1,105 @@
+package entity
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// number of days before an expiration to start warning about it
+const ExpirationWarningDays = 14
+
+/*
+ * ParseExpirationDate parses an expiration date (YYYY-MM-DD).
+ * The access is granted until the end of this day (UTC)
+ */
+func ParseExpirationDate(expires string) (time.Time, error) {
+	date, err := time.Parse("2006-01-02", expires)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%s is not a valid date (expected format: YYYY-MM-DD)", expires)
+	}
+	return date.AddDate(0, 0, 1), nil
+}
+
+/*
+ * ExternalUserExpiration returns when the access of an external user
+ * to a repository expires (the earliest between the user expiration and
+ * the repository grant expiration), or nil if it never expires
+ */
+func ExternalUserExpiration(repo *Repository, username string, externalUsers map[string]*User) *time.Time {
+	var expiration *time.Time
+	candidates := []string{}
+	if user, ok := externalUsers[username]; ok && user.Spec.Expires != "" {
+		candidates = append(candidates, user.Spec.Expires)
+	}
+	if expires, ok := repo.Spec.ExternalUserExpirations[username]; ok {
+		candidates = append(candidates, expires)
+	}
+	for _, c := range candidates {
+		date, err := ParseExpirationDate(c)
+		if err != nil {
+			continue
+		}
+		if expiration == nil || date.Before(*expiration) {
+			expiration = &date
+		}
+	}
+	return expiration
+}
+
+/*
+ * IsExternalUserExpired checks if the access of an external user to a repository has expired
+ */
+func IsExternalUserExpired(repo *Repository, username string, externalUsers map[string]*User, now time.Time) bool {
+	expiration := ExternalUserExpiration(repo, username, externalUsers)
+	return expiration != nil && !now.Before(*expiration)
+}
+
+type Expiration struct {
+	Username   string
+	GithubID   string
+	Repository string
+	Date       string    // last day of access (YYYY-MM-DD)
+	Expires    time.Time // end of the access
+}
+
+/*
+ * ListExpirations returns the external users repository accesses
+ * that expire before now+within (including the ones already expired),
+ * sorted by expiration date
+ */
+func ListExpirations(repos map[string]*Repository, externalUsers map[string]*User, now time.Time, within time.Duration) []Expiration {
+	expirations := []Expiration{}
+	for reponame, repo := range repos {
+		if repo.Archived {
+			continue
+		}
+		for _, username := range append(repo.Spec.ExternalUserReaders, repo.Spec.ExternalUserWriters...) {
+			user, ok := externalUsers[username]
+			if !ok {
+				continue
+			}
+			expiration := ExternalUserExpiration(repo, username, externalUsers)
+			if expiration == nil || expiration.After(now.Add(within)) {
+				continue
+			}
+			expirations = append(expirations, Expiration{
+				Username:   username,
+				GithubID:   user.Spec.GithubID,
+				Repository: reponame,
+				Date:       expiration.AddDate(0, 0, -1).Format("2006-01-02"),
+				Expires:    *expiration,
+			})
+		}
+	}
+	sort.Slice(expirations, func(i, j int) bool {
+		if expirations[i].Expires.Equal(expirations[j].Expires) {
+			if expirations[i].Repository == expirations[j].Repository {
+				return expirations[i].Username < expirations[j].Username
+			}
+			return expirations[i].Repository < expirations[j].Repository
+		}
+		return expirations[i].Expires.Before(expirations[j].Expires)
+	})
+	return expirations
+}
@@ -0,0 +1,54 @@
+package entity
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExpiration(t *testing.T) {
+	now := time.Date(2026, 12, 20, 10, 0, 0, 0, time.UTC)
+	within := ExpirationWarningDays * 24 * time.Hour
+
+	newFixture := func() (map[string]*Repository, map[string]*User) {
+		user1 := &User{}
+		user1.Name = "user1"
+		user1.Spec.GithubID = "github1"
+		user1.Spec.Expires = "2026-12-31"
+
+		user2 := &User{}
+		user2.Name = "user2"
+		user2.Spec.GithubID = "github2"
+
+		repo1 := &Repository{}
+		repo1.Name = "repo1"
+		repo1.Spec.ExternalUserReaders = []string{"user1"}
+		repo1.Spec.ExternalUserWriters = []string{"user2"}
+
+		return map[string]*Repository{"repo1": repo1}, map[string]*User{"user1": user1, "user2": user2}
+	}
+
+	t.Run("happy path: access valid until the end of the day", func(t *testing.T) {
+		repos, users := newFixture()
+		assert.False(t, IsExternalUserExpired(repos["repo1"], "user1", users, time.Date(2026, 12, 31, 23, 59, 0, 0, time.UTC)))
+		assert.True(t, IsExternalUserExpired(repos["repo1"], "user1", users, time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC)))
+		assert.False(t, IsExternalUserExpired(repos["repo1"], "user2", users, now))
+	})
+
+	t.Run("happy path: the earliest expiration wins", func(t *testing.T) {
+		repos, users := newFixture()
+		repos["repo1"].Spec.ExternalUserExpirations = map[string]string{"user1": "2026-12-24", "user2": "2027-06-30"}
+
+		expirations := ListExpirations(repos, users, now, within)
+		assert.Equal(t, 1, len(expirations))
+		assert.Equal(t, "user1", expirations[0].Username)
+		assert.Equal(t, "2026-12-24", expirations[0].Date)
+	})
+
+	t.Run("happy path: no upcoming expiration", func(t *testing.T) {
+		repos, users := newFixture()
+		expirations := ListExpirations(repos, users, now.AddDate(0, -1, 0), within)
+		assert.Equal(t, 0, len(expirations))
+	})
+}
@@ -0,0 +1,45 @@
+package entity
+
+import (
+	"fmt"
+
+	"github.com/Alayacare/goliac/internal/config"
+)
+
+/*
+ * ValidateOrganizationSettings checks the organization settings defined in goliac.yaml
+ */
+func ValidateOrganizationSettings(settings config.OrganizationSettings) error {
+	if settings.DefaultRepositoryPermission != nil {
+		switch *settings.DefaultRepositoryPermission {
+		case "read", "write", "admin", "none":
+		default:
+			return fmt.Errorf("invalid default_repository_permission: %s (must be read, write, admin or none)", *settings.DefaultRepositoryPermission)
+		}
+	}
+	return nil
+}
+
+/*
+ * ValidateDriftPolicy checks the drift_policy defined in goliac.yaml
+ */
+func ValidateDriftPolicy(policy config.DriftPolicy) error {
+	values := map[string]string{
+		"default":           policy.Default,
+		"organization":      policy.Organization,
+		"users":             policy.Users,
+		"teams":             policy.Teams,
+		"repositories":      policy.Repositories,
+		"rulesets":          policy.Rulesets,
+		"actions":           policy.Actions,
+		"custom_properties": policy.CustomProperties,
+	}
+	for resource, value := range values {
+		switch value {
+		case "", "enforce", "report", "ignore":
+		default:
+			return fmt.Errorf("invalid %s policy: %s (must be enforce, report or ignore)", resource, value)
+		}
+	}
+	return nil
+}
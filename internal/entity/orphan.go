@@ -0,0 +1,49 @@
+package entity
+
+import (
+	"sort"
+)
+
+/*
+ * Orphan is a team without any owner: nobody but the admin team can approve
+ * the changes of its directory (teams/<team>/), including its repositories
+ */
+type Orphan struct {
+	Team         string
+	Repositories []string // the (non archived) repositories owned by the team
+}
+
+/*
+ * ListOrphans returns the teams without any (existing) owner, sorted by name
+ */
+func ListOrphans(teams map[string]*Team, repos map[string]*Repository, users map[string]*User) []Orphan {
+	orphans := []Orphan{}
+	for teamname, team := range teams {
+		orphaned := true
+		for _, owner := range team.Spec.Owners {
+			if _, ok := users[owner]; ok {
+				orphaned = false
+				break
+			}
+		}
+		if !orphaned {
+			continue
+		}
+
+		orphan := Orphan{
+			Team:         teamname,
+			Repositories: []string{},
+		}
+		for reponame, repo := range repos {
+			if !repo.Archived && repo.Owner != nil && *repo.Owner == teamname {
+				orphan.Repositories = append(orphan.Repositories, reponame)
+			}
+		}
+		sort.Strings(orphan.Repositories)
+		orphans = append(orphans, orphan)
+	}
+	sort.Slice(orphans, func(i, j int) bool {
+		return orphans[i].Team < orphans[j].Team
+	})
+	return orphans
+}
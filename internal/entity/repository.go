@@ -3,8 +3,10 @@ package entity
 import (
 	"fmt"
 	"path/filepath"
+	"regexp"
 	"strings"
 
+	"github.com/Alayacare/goliac/internal/config"
 	"github.com/spf13/afero"
 	"gopkg.in/yaml.v3"
 )
@@ -16,7 +18,14 @@ type Repository struct {
 		Readers             []string `yaml:"readers,omitempty"`
 		ExternalUserReaders []string `yaml:"externalUserReaders,omitempty"`
 		ExternalUserWriters []string `yaml:"externalUserWriters,omitempty"`
-		IsPublic            bool     `yaml:"public,omitempty"`
+		// optional per repository expiration date (YYYY-MM-DD) of an external user access
+		ExternalUserExpirations map[string]string `yaml:"externalUserExpirations,omitempty"`
+		IsPublic                bool              `yaml:"public,omitempty"`
+		// Github Actions variables and secrets
+		Variables map[string]string        `yaml:"variables,omitempty"`
+		Secrets   []config.SecretReference `yaml:"secrets,omitempty"`
+		// organization custom properties values
+		CustomProperties map[string]PropertyValue `yaml:"customProperties,omitempty"`
 	} `yaml:"spec,omitempty"`
 	Archived bool    `yaml:"archived,omitempty"` // implicit: will be set by Goliac
 	Owner    *string `yaml:"owner,omitempty"`    // implicit. team name owning the repo (if any)
@@ -189,5 +198,193 @@ func (r *Repository) Validate(filename string, teams map[string]*Team, externalU
 		}
 	}
 
+	for externalUser, expires := range r.Spec.ExternalUserExpirations {
+		found := false
+		for _, e := range append(r.Spec.ExternalUserReaders, r.Spec.ExternalUserWriters...) {
+			if e == externalUser {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("invalid externalUserExpirations: %s is not an externalUserReader or externalUserWriter (check repository filename %s)", externalUser, filename)
+		}
+		if _, err := ParseExpirationDate(expires); err != nil {
+			return fmt.Errorf("invalid externalUserExpirations for %s: %v (check repository filename %s)", externalUser, err, filename)
+		}
+	}
+
+	for name := range r.Spec.Variables {
+		if err := ValidateActionsName(name); err != nil {
+			return fmt.Errorf("invalid variable: %v (check repository filename %s)", err, filename)
+		}
+	}
+
+	if err := ValidateSecretReferences(r.Spec.Secrets); err != nil {
+		return fmt.Errorf("invalid secret: %v (check repository filename %s)", err, filename)
+	}
+
+	return nil
+}
+
+var actionsNameRegexp = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+/*
+ * ValidateActionsName checks that a Github Actions variable or secret name follows
+ * the Github naming rules
+ */
+func ValidateActionsName(name string) error {
+	if !actionsNameRegexp.MatchString(name) {
+		return fmt.Errorf("%s must only contain alphanumeric characters or underscores, and must not start with a number", name)
+	}
+	if strings.HasPrefix(strings.ToUpper(name), "GITHUB_") {
+		return fmt.Errorf("%s must not start with the GITHUB_ prefix", name)
+	}
+	return nil
+}
+
+/*
+ * ValidateSecretReferences checks a list of secret references (name, provider and key are mandatory)
+ */
+func ValidateSecretReferences(secrets []config.SecretReference) error {
+	names := make(map[string]bool)
+	for _, s := range secrets {
+		if err := ValidateActionsName(s.Name); err != nil {
+			return err
+		}
+		if s.Provider == "" {
+			return fmt.Errorf("provider is empty for secret %s", s.Name)
+		}
+		if s.Key == "" {
+			return fmt.Errorf("key is empty for secret %s", s.Name)
+		}
+		if names[s.Name] {
+			return fmt.Errorf("secret %s is defined twice", s.Name)
+		}
+		names[s.Name] = true
+	}
+	return nil
+}
+
+/*
+ * PropertyValue is a custom property value. It can be written
+ * either as a scalar (string, single_select, true_false)
+ * or as a list (multi_select)
+ */
+type PropertyValue []string
+
+func (pv *PropertyValue) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.SequenceNode {
+		values := []string{}
+		if err := value.Decode(&values); err != nil {
+			return err
+		}
+		*pv = values
+		return nil
+	}
+	var v string
+	if err := value.Decode(&v); err != nil {
+		return err
+	}
+	*pv = []string{v}
+	return nil
+}
+
+func (pv PropertyValue) MarshalYAML() (interface{}, error) {
+	if len(pv) == 1 {
+		return pv[0], nil
+	}
+	return []string(pv), nil
+}
+
+var customPropertyNameRegexp = regexp.MustCompile(`^[a-zA-Z0-9_#$-]+$`)
+
+/*
+ * ValidateCustomPropertiesSchema checks the organization custom properties
+ * definitions (from goliac.yaml)
+ */
+func ValidateCustomPropertiesSchema(schema []config.CustomProperty) error {
+	names := make(map[string]bool)
+	for _, p := range schema {
+		if !customPropertyNameRegexp.MatchString(p.Name) || len(p.Name) > 75 {
+			return fmt.Errorf("invalid custom property name: %s", p.Name)
+		}
+		if names[p.Name] {
+			return fmt.Errorf("custom property %s is defined twice", p.Name)
+		}
+		names[p.Name] = true
+
+		switch p.ValueType {
+		case "string", "true_false":
+			if len(p.AllowedValues) > 0 {
+				return fmt.Errorf("custom property %s: allowed_values can only be used with single_select or multi_select", p.Name)
+			}
+		case "single_select", "multi_select":
+			if len(p.AllowedValues) == 0 {
+				return fmt.Errorf("custom property %s: allowed_values is mandatory for %s", p.Name, p.ValueType)
+			}
+		default:
+			return fmt.Errorf("custom property %s: invalid value_type %s (must be string, single_select, multi_select or true_false)", p.Name, p.ValueType)
+		}
+
+		if p.DefaultValue != "" {
+			if err := validatePropertyValue(p, []string{p.DefaultValue}); err != nil {
+				return fmt.Errorf("invalid default_value: %v", err)
+			}
+		}
+		if p.Required && p.DefaultValue == "" {
+			return fmt.Errorf("custom property %s is required and must have a default_value", p.Name)
+		}
+	}
+	return nil
+}
+
+/*
+ * ValidateCustomProperties checks a repository custom properties values
+ * against the organization custom properties schema
+ */
+func ValidateCustomProperties(values map[string]PropertyValue, schema []config.CustomProperty) error {
+	definitions := make(map[string]config.CustomProperty)
+	for _, p := range schema {
+		definitions[p.Name] = p
+	}
+	for name, value := range values {
+		p, ok := definitions[name]
+		if !ok {
+			return fmt.Errorf("custom property %s is not defined in goliac.yaml", name)
+		}
+		if err := validatePropertyValue(p, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validatePropertyValue(p config.CustomProperty, value []string) error {
+	if len(value) == 0 {
+		return fmt.Errorf("custom property %s: empty value", p.Name)
+	}
+	if p.ValueType != "multi_select" && len(value) > 1 {
+		return fmt.Errorf("custom property %s: only one value is allowed for %s", p.Name, p.ValueType)
+	}
+	for _, v := range value {
+		switch p.ValueType {
+		case "true_false":
+			if v != "true" && v != "false" {
+				return fmt.Errorf("custom property %s: %s must be true or false", p.Name, v)
+			}
+		case "single_select", "multi_select":
+			found := false
+			for _, a := range p.AllowedValues {
+				if a == v {
+					found = true
+					break
+				}
+			}
+			if !found {
+				return fmt.Errorf("custom property %s: %s is not an allowed value", p.Name, v)
+			}
+		}
+	}
 	return nil
 }
@@ -3,6 +3,7 @@ package entity
 import (
 	"testing"
 
+	"github.com/Alayacare/goliac/internal/config"
 	"github.com/spf13/afero"
 	"github.com/stretchr/testify/assert"
 )
@@ -212,4 +213,98 @@ name: repo1
 		assert.NotNil(t, repos)
 		assert.Equal(t, len(repos), 1)
 	})
+	t.Run("happy path: repo with actions variables and secrets", func(t *testing.T) {
+		fs := afero.NewMemMapFs()
+		fixtureCreateUserTeam(t, fs)
+
+		err := afero.WriteFile(fs, "teams/team1/repo1.yaml", []byte(`
+apiVersion: v1
+kind: Repository
+name: repo1
+spec:
+  variables:
+    ENVIRONMENT: production
+  secrets:
+    - name: NPM_TOKEN
+      provider: env
+      key: NPM_TOKEN
+`), 0644)
+		assert.Nil(t, err)
+		users, _, _ := ReadUserDirectory(fs, "users")
+		teams, _, _ := ReadTeamDirectory(fs, "teams", users)
+
+		repos, errs, warns := ReadRepositories(fs, "archived", "teams", teams, map[string]*User{})
+		assert.Equal(t, len(errs), 0)
+		assert.Equal(t, len(warns), 0)
+		assert.Equal(t, "production", repos["repo1"].Spec.Variables["ENVIRONMENT"])
+		assert.Equal(t, "env", repos["repo1"].Spec.Secrets[0].Provider)
+	})
+
+	t.Run("not happy path: invalid actions secrets", func(t *testing.T) {
+		fs := afero.NewMemMapFs()
+		fixtureCreateUserTeam(t, fs)
+
+		err := afero.WriteFile(fs, "teams/team1/repo1.yaml", []byte(`
+apiVersion: v1
+kind: Repository
+name: repo1
+spec:
+  secrets:
+    - name: GITHUB_TOKEN
+      provider: env
+      key: TOKEN
+`), 0644)
+		assert.Nil(t, err)
+		users, _, _ := ReadUserDirectory(fs, "users")
+		teams, _, _ := ReadTeamDirectory(fs, "teams", users)
+
+		_, errs, _ := ReadRepositories(fs, "archived", "teams", teams, map[string]*User{})
+		assert.Equal(t, len(errs), 1)
+	})
+
+	t.Run("happy path: repo with custom properties", func(t *testing.T) {
+		fs := afero.NewMemMapFs()
+		fixtureCreateUserTeam(t, fs)
+
+		err := afero.WriteFile(fs, "teams/team1/repo1.yaml", []byte(`
+apiVersion: v1
+kind: Repository
+name: repo1
+spec:
+  customProperties:
+    tier: critical
+    languages:
+      - go
+      - python
+    internal: true
+`), 0644)
+		assert.Nil(t, err)
+		users, _, _ := ReadUserDirectory(fs, "users")
+		teams, _, _ := ReadTeamDirectory(fs, "teams", users)
+
+		repos, errs, _ := ReadRepositories(fs, "archived", "teams", teams, map[string]*User{})
+		assert.Equal(t, len(errs), 0)
+
+		schema := []config.CustomProperty{
+			{Name: "tier", ValueType: "single_select", AllowedValues: []string{"critical", "standard"}},
+			{Name: "languages", ValueType: "multi_select", AllowedValues: []string{"go", "python", "java"}},
+			{Name: "internal", ValueType: "true_false"},
+		}
+		assert.Nil(t, ValidateCustomPropertiesSchema(schema))
+		assert.Equal(t, PropertyValue{"go", "python"}, repos["repo1"].Spec.CustomProperties["languages"])
+		assert.Nil(t, ValidateCustomProperties(repos["repo1"].Spec.CustomProperties, schema))
+	})
+
+	t.Run("not happy path: invalid custom properties", func(t *testing.T) {
+		schema := []config.CustomProperty{
+			{Name: "tier", ValueType: "single_select", AllowedValues: []string{"critical", "standard"}},
+		}
+		assert.NotNil(t, ValidateCustomProperties(map[string]PropertyValue{"tier": {"unknown"}}, schema))
+		assert.NotNil(t, ValidateCustomProperties(map[string]PropertyValue{"tier": {"critical", "standard"}}, schema))
+		assert.NotNil(t, ValidateCustomProperties(map[string]PropertyValue{"undefined": {"value"}}, schema))
+
+		assert.NotNil(t, ValidateCustomPropertiesSchema([]config.CustomProperty{{Name: "tier", ValueType: "single_select"}}))
+		assert.NotNil(t, ValidateCustomPropertiesSchema([]config.CustomProperty{{Name: "tier", ValueType: "string", Required: true}}))
+		assert.NotNil(t, ValidateCustomPropertiesSchema([]config.CustomProperty{{Name: "tier", ValueType: "number"}}))
+	})
 }
@@ -3,6 +3,7 @@ package entity
 import (
 	"fmt"
 	"path/filepath"
+	"sort"
 	"strings"
 
 	"github.com/spf13/afero"
@@ -14,9 +15,17 @@ type Team struct {
 	Spec   struct {
 		Owners  []string `yaml:"owners,omitempty"`
 		Members []string `yaml:"members,omitempty"`
+		// adopts the team if a user sync removes all its owners
+		Manager string `yaml:"manager,omitempty"`
+		// if set, the members are rewritten from an IdP group by the usersync plugin (owners stay manual)
+		ExternallyManaged *TeamExternallyManaged `yaml:"externallyManaged,omitempty"`
 	} `yaml:"spec"`
 }
 
+type TeamExternallyManaged struct {
+	Group string `yaml:"group"`
+}
+
 /*
  * NewTeam reads a file and returns a Team object
  * The next step is to validate the Team object using the Validate method
@@ -122,6 +131,16 @@ func (t *Team) Validate(dirname string, users map[string]*User) (error, []Warnin
 		}
 	}
 
+	if t.Spec.Manager != "" {
+		if _, ok := users[t.Spec.Manager]; !ok {
+			return fmt.Errorf("invalid manager: %s doesn't exist in team filename %s/team.yaml", t.Spec.Manager, dirname), warnings
+		}
+	}
+
+	if t.Spec.ExternallyManaged != nil && t.Spec.ExternallyManaged.Group == "" {
+		return fmt.Errorf("spec.externallyManaged.group is empty for team filename %s/team.yaml", dirname), warnings
+	}
+
 	for _, member := range t.Spec.Members {
 		if _, ok := users[member]; !ok {
 			return fmt.Errorf("invalid member: %s doesn't exist in team filename %s/team.yaml", member, dirname), warnings
@@ -137,13 +156,48 @@ func (t *Team) Validate(dirname string, users map[string]*User) (error, []Warnin
 	return nil, warnings
 }
 
+/**
+ * ReadTeamsExternalGroups returns the IdP groups used by the
+ * (externally managed) teams of the dirname directory
+ */
+func ReadTeamsExternalGroups(fs afero.Fs, dirname string) ([]string, error) {
+	groups := []string{}
+
+	exist, err := afero.Exists(fs, dirname)
+	if err != nil || !exist {
+		return groups, err
+	}
+
+	entries, err := afero.ReadDir(fs, dirname)
+	if err != nil {
+		return groups, err
+	}
+
+	for _, e := range entries {
+		if !e.IsDir() || e.Name()[0] == '.' {
+			continue
+		}
+		team, err := NewTeam(fs, filepath.Join(dirname, e.Name(), "team.yaml"))
+		if err != nil {
+			return groups, err
+		}
+		if team.Spec.ExternallyManaged != nil && team.Spec.ExternallyManaged.Group != "" {
+			groups = append(groups, team.Spec.ExternallyManaged.Group)
+		}
+	}
+	return groups, nil
+}
+
 /**
  * AdjustTeamDirectory adjust team's defintion depending on user availability.
  * The goal is that if a user has been removed, we must update the team definition.
+ * The members of externally managed teams are rewritten from their group
+ * (groups is the members list of each group, nil if the usersync plugin doesn't know the groups)
+ * A team losing all its owners is adopted by its manager, else by the fallbackOwners
  * Returns:
  * - a list of (team's) file changes (to commit to Github)
  */
-func ReadAndAdjustTeamDirectory(fs afero.Fs, dirname string, users map[string]*User) ([]string, error) {
+func ReadAndAdjustTeamDirectory(fs afero.Fs, dirname string, users map[string]*User, groups map[string][]string, fallbackOwners []string) ([]string, error) {
 	teamschanged := []string{}
 
 	exist, err := afero.Exists(fs, dirname)
@@ -166,11 +220,13 @@ func ReadAndAdjustTeamDirectory(fs afero.Fs, dirname string, users map[string]*U
 			if err != nil {
 				return teamschanged, err
 			} else {
+				groupChanged := team.UpdateMembersFromGroup(groups)
+				adopted := team.AdoptIfOrphaned(users, fallbackOwners)
 				changed, err := team.Update(fs, filepath.Join(dirname, e.Name(), "team.yaml"), users)
 				if err != nil {
 					return teamschanged, err
 				}
-				if changed {
+				if changed || groupChanged || adopted {
 					teamschanged = append(teamschanged, filepath.Join(dirname, e.Name(), "team.yaml"))
 				}
 			}
@@ -179,6 +235,85 @@ func ReadAndAdjustTeamDirectory(fs afero.Fs, dirname string, users map[string]*U
 	return teamschanged, nil
 }
 
+/*
+ * UpdateMembersFromGroup replaces the members of an externally managed team
+ * by the members of its group (the owners are not changed, and not repeated as members).
+ * Returns true if the members changed
+ */
+func (t *Team) UpdateMembersFromGroup(groups map[string][]string) bool {
+	if t.Spec.ExternallyManaged == nil || groups == nil {
+		return false
+	}
+	groupMembers, ok := groups[t.Spec.ExternallyManaged.Group]
+	if !ok {
+		// unknown group: better keep the current members
+		return false
+	}
+
+	owners := make(map[string]bool)
+	for _, owner := range t.Spec.Owners {
+		owners[owner] = true
+	}
+	members := make([]string, 0, len(groupMembers))
+	seen := make(map[string]bool)
+	for _, member := range groupMembers {
+		if !owners[member] && !seen[member] {
+			members = append(members, member)
+			seen[member] = true
+		}
+	}
+	sort.Strings(members)
+
+	current := append([]string{}, t.Spec.Members...)
+	sort.Strings(current)
+	t.Spec.Members = members
+	return strings.Join(current, ",") != strings.Join(members, ",")
+}
+
+/*
+ * AdoptIfOrphaned gives new owners to a team whose owners have all been removed:
+ * its manager if still there, else the fallbackOwners (still there).
+ * A team that never had owners is left as is.
+ * Returns true if the owners changed
+ */
+func (t *Team) AdoptIfOrphaned(users map[string]*User, fallbackOwners []string) bool {
+	if len(t.Spec.Owners) == 0 {
+		return false
+	}
+	for _, owner := range t.Spec.Owners {
+		if _, ok := users[owner]; ok {
+			return false
+		}
+	}
+
+	candidates := fallbackOwners
+	if _, ok := users[t.Spec.Manager]; ok && t.Spec.Manager != "" {
+		candidates = []string{t.Spec.Manager}
+	}
+	owners := []string{}
+	isOwner := make(map[string]bool)
+	for _, candidate := range candidates {
+		if _, ok := users[candidate]; ok && !isOwner[candidate] {
+			owners = append(owners, candidate)
+			isOwner[candidate] = true
+		}
+	}
+	if len(owners) == 0 {
+		return false
+	}
+
+	// owners are not repeated as members
+	members := []string{}
+	for _, member := range t.Spec.Members {
+		if !isOwner[member] {
+			members = append(members, member)
+		}
+	}
+	t.Spec.Owners = owners
+	t.Spec.Members = members
+	return true
+}
+
 // Update is telling if the team needs to be adjust (and the team's definition was changed on disk),
 // based on the list of (still) existing users
 func (t *Team) Update(fs afero.Fs, filename string, users map[string]*User) (bool, error) {
@@ -203,6 +338,13 @@ func (t *Team) Update(fs afero.Fs, filename string, users map[string]*User) (boo
 	}
 	t.Spec.Members = members
 
+	if t.Spec.Manager != "" {
+		if _, ok := users[t.Spec.Manager]; !ok {
+			t.Spec.Manager = ""
+			changed = true
+		}
+	}
+
 	yamlTeam, err := yaml.Marshal(t)
 	if err != nil {
 		return changed, err
@@ -199,7 +199,7 @@ func TestReadAndAdjustTeam(t *testing.T) {
 		fs := afero.NewMemMapFs()
 		users := make(map[string]*User)
 
-		changed, err := ReadAndAdjustTeamDirectory(fs, "/teams", users)
+		changed, err := ReadAndAdjustTeamDirectory(fs, "/teams", users, nil, nil)
 		assert.Nil(t, err)
 		assert.Equal(t, 0, len(changed))
 	})
@@ -227,7 +227,7 @@ spec:
     - member3
 `), 0644)
 		assert.Nil(t, err)
-		changed, err := ReadAndAdjustTeamDirectory(fs, "/teams", users)
+		changed, err := ReadAndAdjustTeamDirectory(fs, "/teams", users, nil, nil)
 		assert.Nil(t, err)
 		assert.Equal(t, 0, len(changed))
 	})
@@ -254,8 +254,155 @@ spec:
     - member3
 `), 0644)
 		assert.Nil(t, err)
-		changed, err := ReadAndAdjustTeamDirectory(fs, "/teams", users)
+		changed, err := ReadAndAdjustTeamDirectory(fs, "/teams", users, nil, nil)
 		assert.Nil(t, err)
 		assert.Equal(t, 1, len(changed))
 	})
 }
+
+func TestExternallyManagedTeam(t *testing.T) {
+	users := make(map[string]*User)
+	for _, username := range []string{"owner1", "member1", "member2", "member3"} {
+		u := User{}
+		u.Name = username
+		u.Spec.GithubID = username
+		users[username] = &u
+	}
+	teamyaml := []byte(`
+apiVersion: v1
+kind: Team
+name: ateam
+spec:
+  owners:
+    - owner1
+  members:
+    - member1
+  externallyManaged:
+    group: eng-payments
+`)
+
+	t.Run("happy path: members are rewritten from the group", func(t *testing.T) {
+		fs := afero.NewMemMapFs()
+		afero.WriteFile(fs, "/teams/ateam/team.yaml", teamyaml, 0644)
+
+		groups := map[string][]string{"eng-payments": {"member3", "owner1", "member2", "unknown"}}
+		changed, err := ReadAndAdjustTeamDirectory(fs, "/teams", users, groups, nil)
+		assert.Nil(t, err)
+		assert.Equal(t, 1, len(changed))
+
+		team, err := NewTeam(fs, "/teams/ateam/team.yaml")
+		assert.Nil(t, err)
+		// owners stay manual (and are not repeated as members), unknown users are removed
+		assert.Equal(t, []string{"owner1"}, team.Spec.Owners)
+		assert.Equal(t, []string{"member2", "member3"}, team.Spec.Members)
+		assert.Equal(t, "eng-payments", team.Spec.ExternallyManaged.Group)
+	})
+
+	t.Run("happy path: unknown group or no group support", func(t *testing.T) {
+		fs := afero.NewMemMapFs()
+		afero.WriteFile(fs, "/teams/ateam/team.yaml", teamyaml, 0644)
+
+		changed, err := ReadAndAdjustTeamDirectory(fs, "/teams", users, map[string][]string{}, nil)
+		assert.Nil(t, err)
+		assert.Equal(t, 0, len(changed))
+
+		changed, err = ReadAndAdjustTeamDirectory(fs, "/teams", users, nil, nil)
+		assert.Nil(t, err)
+		assert.Equal(t, 0, len(changed))
+	})
+
+	t.Run("happy path: groups used by the teams", func(t *testing.T) {
+		fs := afero.NewMemMapFs()
+		afero.WriteFile(fs, "/teams/ateam/team.yaml", teamyaml, 0644)
+		afero.WriteFile(fs, "/teams/bteam/team.yaml", []byte("apiVersion: v1\nkind: Team\nname: bteam\n"), 0644)
+
+		groups, err := ReadTeamsExternalGroups(fs, "/teams")
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"eng-payments"}, groups)
+	})
+
+	t.Run("not happy path: empty group", func(t *testing.T) {
+		team := Team{}
+		team.ApiVersion = "v1"
+		team.Kind = "Team"
+		team.Name = "ateam"
+		team.Spec.ExternallyManaged = &TeamExternallyManaged{}
+
+		err, _ := team.Validate("/teams/ateam", users)
+		assert.NotNil(t, err)
+	})
+}
+
+func TestOrphanTeam(t *testing.T) {
+	users := make(map[string]*User)
+	for _, username := range []string{"manager1", "admin1", "member1"} {
+		u := User{}
+		u.Name = username
+		u.Spec.GithubID = username
+		users[username] = &u
+	}
+
+	t.Run("happy path: the manager adopts the team", func(t *testing.T) {
+		team := Team{}
+		team.Spec.Owners = []string{"gone1", "gone2"}
+		team.Spec.Members = []string{"member1", "manager1"}
+		team.Spec.Manager = "manager1"
+
+		assert.True(t, team.AdoptIfOrphaned(users, []string{"admin1"}))
+		assert.Equal(t, []string{"manager1"}, team.Spec.Owners)
+		assert.Equal(t, []string{"member1"}, team.Spec.Members)
+	})
+
+	t.Run("happy path: the fallback owners adopt the team", func(t *testing.T) {
+		fs := afero.NewMemMapFs()
+		afero.WriteFile(fs, "/teams/ateam/team.yaml", []byte(`
+apiVersion: v1
+kind: Team
+name: ateam
+spec:
+  owners:
+    - gone1
+  members:
+    - member1
+  manager: gone2
+`), 0644)
+
+		changed, err := ReadAndAdjustTeamDirectory(fs, "/teams", users, nil, []string{"admin1", "gone3"})
+		assert.Nil(t, err)
+		assert.Equal(t, 1, len(changed))
+
+		team, err := NewTeam(fs, "/teams/ateam/team.yaml")
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"admin1"}, team.Spec.Owners)
+		assert.Equal(t, []string{"member1"}, team.Spec.Members)
+		assert.Equal(t, "", team.Spec.Manager)
+	})
+
+	t.Run("happy path: a team without owners is left as is", func(t *testing.T) {
+		team := Team{}
+		team.Spec.Members = []string{"member1"}
+
+		assert.False(t, team.AdoptIfOrphaned(users, []string{"admin1"}))
+		assert.Equal(t, 0, len(team.Spec.Owners))
+	})
+
+	t.Run("happy path: list the orphans", func(t *testing.T) {
+		owned := Team{}
+		owned.Spec.Owners = []string{"admin1"}
+		orphan := Team{}
+		orphan.Spec.Owners = []string{"gone1"}
+		teamname := "orphan"
+		repo := Repository{}
+		repo.Owner = &teamname
+		archived := Repository{}
+		archived.Owner = &teamname
+		archived.Archived = true
+
+		orphans := ListOrphans(
+			map[string]*Team{"owned": &owned, "orphan": &orphan},
+			map[string]*Repository{"repo": &repo, "archived": &archived},
+			users)
+
+		assert.Equal(t, []Orphan{{Team: "orphan", Repositories: []string{"repo"}}}, orphans)
+	})
+}
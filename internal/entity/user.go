@@ -2,6 +2,7 @@ package entity
 
 import (
 	"fmt"
+	"net/mail"
 	"path/filepath"
 	"strings"
 
@@ -13,6 +14,11 @@ type User struct {
 	Entity `yaml:",inline"`
 	Spec   struct {
 		GithubID string `yaml:"githubID"`
+		Expires  string `yaml:"expires,omitempty"` // optional (YYYY-MM-DD), only used for external users
+		// optional metadata
+		Email     string   `yaml:"email,omitempty"`
+		FullName  string   `yaml:"fullName,omitempty"`
+		ManagerOf []string `yaml:"managerOf,omitempty"` // teamnames
 	} `yaml:"spec"`
 }
 
@@ -112,6 +118,18 @@ func (u *User) Validate(filename string) error {
 		return fmt.Errorf("data.githubID is empty for user filename %s", filename)
 	}
 
+	if u.Spec.Expires != "" {
+		if _, err := ParseExpirationDate(u.Spec.Expires); err != nil {
+			return fmt.Errorf("invalid spec.expires: %v for user filename %s", err, filename)
+		}
+	}
+
+	if u.Spec.Email != "" {
+		if address, err := mail.ParseAddress(u.Spec.Email); err != nil || address.Address != u.Spec.Email {
+			return fmt.Errorf("invalid spec.email: %s for user filename %s", u.Spec.Email, filename)
+		}
+	}
+
 	return nil
 }
 
@@ -128,6 +146,18 @@ func (u *User) Equals(a *User) bool {
 	if u.Spec.GithubID != a.Spec.GithubID {
 		return false
 	}
+	if u.Spec.Expires != a.Spec.Expires {
+		return false
+	}
+	if u.Spec.Email != a.Spec.Email {
+		return false
+	}
+	if u.Spec.FullName != a.Spec.FullName {
+		return false
+	}
+	if strings.Join(u.Spec.ManagerOf, ",") != strings.Join(a.Spec.ManagerOf, ",") {
+		return false
+	}
 
 	return true
 }
@@ -115,4 +115,78 @@ func TestEqualUser(t *testing.T) {
 
 		assert.False(t, res)
 	})
+	t.Run("happy path: external user with expiration date", func(t *testing.T) {
+		fs := afero.NewMemMapFs()
+		fs.Mkdir("users", 0755)
+		err := afero.WriteFile(fs, "users/user1.yaml", []byte(`
+apiVersion: v1
+kind: User
+name: user1
+spec:
+  githubID: github1
+  expires: 2026-12-31
+`), 0644)
+		assert.Nil(t, err)
+		users, errs, _ := ReadUserDirectory(fs, "users")
+		assert.Equal(t, len(errs), 0)
+		assert.Equal(t, "2026-12-31", users["user1"].Spec.Expires)
+	})
+
+	t.Run("not happy path: invalid expiration date", func(t *testing.T) {
+		fs := afero.NewMemMapFs()
+		fs.Mkdir("users", 0755)
+		err := afero.WriteFile(fs, "users/user1.yaml", []byte(`
+apiVersion: v1
+kind: User
+name: user1
+spec:
+  githubID: github1
+  expires: 31/12/2026
+`), 0644)
+		assert.Nil(t, err)
+		_, errs, _ := ReadUserDirectory(fs, "users")
+		assert.Equal(t, len(errs), 1)
+	})
+
+	t.Run("happy path: user metadata", func(t *testing.T) {
+		fs := afero.NewMemMapFs()
+		fs.Mkdir("users", 0755)
+		err := afero.WriteFile(fs, "users/user1.yaml", []byte(`
+apiVersion: v1
+kind: User
+name: user1
+spec:
+  githubID: github1
+  email: user1@mycompany.com
+  fullName: User One
+  managerOf:
+    - ateam
+`), 0644)
+		assert.Nil(t, err)
+		users, errs, _ := ReadUserDirectory(fs, "users")
+		assert.Equal(t, len(errs), 0)
+		assert.Equal(t, "user1@mycompany.com", users["user1"].Spec.Email)
+		assert.Equal(t, "User One", users["user1"].Spec.FullName)
+		assert.Equal(t, []string{"ateam"}, users["user1"].Spec.ManagerOf)
+
+		other := *users["user1"]
+		other.Spec.FullName = "User 1"
+		assert.False(t, users["user1"].Equals(&other))
+	})
+
+	t.Run("not happy path: invalid email", func(t *testing.T) {
+		fs := afero.NewMemMapFs()
+		fs.Mkdir("users", 0755)
+		err := afero.WriteFile(fs, "users/user1.yaml", []byte(`
+apiVersion: v1
+kind: User
+name: user1
+spec:
+  githubID: github1
+  email: User One <user1@mycompany.com>
+`), 0644)
+		assert.Nil(t, err)
+		_, errs, _ := ReadUserDirectory(fs, "users")
+		assert.Equal(t, len(errs), 1)
+	})
 }
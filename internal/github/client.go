@@ -2,37 +2,45 @@ package github
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
-	"os"
-	"strconv"
+	"strings"
 	"sync"
 	"time"
 
+	"github.com/Alayacare/goliac/internal/metrics"
 	jwt "github.com/dgrijalva/jwt-go"
 	"github.com/sirupsen/logrus"
 )
 
 type GitHubClient interface {
-	QueryGraphQLAPI(query string, variables map[string]interface{}) ([]byte, error)
-	CallRestAPI(endpoint, method string, body map[string]interface{}) ([]byte, error)
-	GetAccessToken() (string, error)
-	GetAppSlug() string
+	QueryGraphQLAPI(ctx context.Context, query string, variables map[string]interface{}) ([]byte, error)
+	CallRestAPI(ctx context.Context, endpoint, method string, body map[string]interface{}) ([]byte, error)
+	GetAccessToken(ctx context.Context) (string, error)
+	// the Github login of the identity used by the client: <app slug>[bot]
+	// for a Github App, the token owner login for a token
+	GetActor() string
 }
 
 type GitHubClientImpl struct {
 	gitHubServer    string
 	appID           int64
 	installationID  int64
-	appSlug         string
+	actor           string
 	privateKey      []byte
+	loadPrivateKey  PrivateKeyLoader // nil if authenticated with a static token
+	token           string           // static token (personal access token or GITHUB_TOKEN), instead of the Github App
 	accessToken     string
 	httpClient      *http.Client
 	tokenExpiration time.Time
 	mu              sync.Mutex
+	retryPolicy     RetryPolicy
+	etags           *etagCache // nil to disable conditional requests
 }
 
 type AuthorizedTransport struct {
@@ -45,12 +53,16 @@ func (t *AuthorizedTransport) RoundTrip(req *http.Request) (*http.Response, erro
 
 	// Refresh the access token if necessary
 	if t.client.accessToken == "" || time.Until(t.client.tokenExpiration) < 5*time.Minute {
+		// the private key may have been rotated since the last refresh
+		if err := t.client.reloadPrivateKey(); err != nil {
+			return nil, err
+		}
 		token, err := t.client.createJWT()
 		if err != nil {
 			return nil, err
 		}
 
-		accessToken, expiresAt, err := t.client.getAccessTokenForInstallation(token)
+		accessToken, expiresAt, err := t.client.getAccessTokenForInstallation(req.Context(), token)
 		if err != nil {
 			return nil, err
 		}
@@ -69,7 +81,7 @@ func (t *AuthorizedTransport) RoundTrip(req *http.Request) (*http.Response, erro
  * @param {string} githubServer usually https://api.github.com
  * @param {string} organizationName
  * @param {string} appID
- * @param {string} privateKeyFile
+ * @param {PrivateKeyLoader} loadPrivateKey
  * @return {GitHubClient} client
  * @return {error} error
  *
@@ -78,19 +90,19 @@ func (t *AuthorizedTransport) RoundTrip(req *http.Request) (*http.Response, erro
  * 	"https://api.github.com",
  * 	"my-org",
  * 	"12345",
- * 	"private-key.pem",
+ * 	PrivateKeyFromFile("private-key.pem"),
  * )
  */
-func NewGitHubClientImpl(githubServer, organizationName string, appID int64, privateKeyFile string) (GitHubClient, error) {
-	privateKey, err := os.ReadFile(privateKeyFile)
-	if err != nil {
-		return nil, err
-	}
-
+func NewGitHubClientImpl(githubServer, organizationName string, appID int64, loadPrivateKey PrivateKeyLoader) (GitHubClient, error) {
 	client := &GitHubClientImpl{
-		gitHubServer: githubServer,
-		appID:        appID,
-		privateKey:   privateKey,
+		gitHubServer:   githubServer,
+		appID:          appID,
+		loadPrivateKey: loadPrivateKey,
+		retryPolicy:    DefaultRetryPolicy(),
+		etags:          newEtagCache(),
+	}
+	if err := client.reloadPrivateKey(); err != nil {
+		return nil, err
 	}
 
 	// create JWT
@@ -109,7 +121,7 @@ func NewGitHubClientImpl(githubServer, organizationName string, appID int64, pri
 	for _, installation := range installations {
 		if installation.Account.Login == organizationName && installation.AppId == appID {
 			client.installationID = installation.ID
-			client.appSlug = installation.AppSlug
+			client.actor = installation.AppSlug + "[bot]"
 			break
 		}
 	}
@@ -129,29 +141,146 @@ func NewGitHubClientImpl(githubServer, organizationName string, appID int64, pri
 	return client, nil
 }
 
-// waitRateLimit helps dealing with rate limits
-// cf https://docs.github.com/en/rest/guides/best-practices-for-integrators?apiVersion=2022-11-28#dealing-with-rate-limits
-func waitRateLimit(resetTimeStr string) error {
-	if resetTimeStr == "" {
-		return fmt.Errorf("X-RateLimit-Reset header not found")
+/*
+ * TokenTransport authenticates the requests with a static token
+ * (a personal access token, or the GITHUB_TOKEN of a Github action)
+ */
+type TokenTransport struct {
+	token string
+}
+
+func (t *TokenTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req.Header.Set("Authorization", "Bearer "+t.token)
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+/**
+ * NewGitHubClientWithToken
+ * Same as NewGitHubClientImpl, but authenticated with a (fine-grained) personal
+ * access token or the GITHUB_TOKEN of a Github action, instead of a Github App.
+ * It checks that the token can access the organization, and (for classic personal
+ * access tokens) that it has the required scopes, and resolves the token login
+ * @param {string} githubServer usually https://api.github.com
+ * @param {string} organizationName
+ * @param {string} token
+ * @return {GitHubClient} client
+ * @return {error} error
+ */
+func NewGitHubClientWithToken(githubServer, organizationName string, token string) (GitHubClient, error) {
+	if token == "" {
+		return nil, fmt.Errorf("empty Github token")
+	}
+	client := &GitHubClientImpl{
+		gitHubServer: githubServer,
+		token:        token,
+		httpClient:   &http.Client{Transport: &TokenTransport{token: token}},
+		retryPolicy:  DefaultRetryPolicy(),
+		etags:        newEtagCache(),
 	}
 
-	logrus.Infof("Rate limit exceeded, waiting for %s", resetTimeStr)
+	if err := client.checkTokenAccess(context.Background(), organizationName); err != nil {
+		return nil, err
+	}
+	client.actor = client.getTokenLogin(context.Background())
+	return client, nil
+}
 
-	// Parse the reset time.
-	resetTimeUnix, err := strconv.ParseInt(resetTimeStr, 10, 64)
+/*
+ * getTokenLogin returns the login of the token owner (GET /user), used to
+ * recognize what Goliac did itself.
+ * The GITHUB_TOKEN of a Github action cannot query /user, but its actions
+ * are done as github-actions[bot]
+ */
+func (client *GitHubClientImpl) getTokenLogin(ctx context.Context) string {
+	resp, body, err := client.doWithRetry(ctx, "rest", true, func() (*http.Request, error) {
+		req, err := http.NewRequest("GET", fmt.Sprintf("%s/user", client.gitHubServer), nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Accept", "application/vnd.github+json")
+		return req, nil
+	})
 	if err != nil {
-		return fmt.Errorf("failed to parse X-RateLimit-Reset header: %w", err)
+		logrus.Warnf("not able to get the Github token login: %v", err)
+		return ""
 	}
+	if resp.StatusCode == http.StatusForbidden {
+		return "github-actions[bot]"
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		logrus.Warnf("not able to get the Github token login: unexpected status: %s", resp.Status)
+		return ""
+	}
+	var user struct {
+		Login string `json:"login"`
+	}
+	if err := json.Unmarshal(body, &user); err != nil {
+		logrus.Warnf("not able to get the Github token login: %v", err)
+		return ""
+	}
+	return user.Login
+}
 
-	resetTime := time.Unix(resetTimeUnix, 0)
-
-	// Calculate how long we need to wait.
-	waitDuration := time.Until(resetTime)
+// classic personal access token scopes needed to load the organization
+// (and the scopes that include them)
+var requiredTokenScopes = []struct {
+	scope      string
+	includedIn []string
+}{
+	{"repo", []string{"repo"}},
+	{"read:org", []string{"read:org", "write:org", "admin:org"}},
+}
 
-	// Wait until the reset time.
-	time.Sleep(waitDuration)
+/*
+ * checkTokenAccess fetches the organization with the token, to report
+ * an invalid token, or a token without the required scopes, right away
+ */
+func (client *GitHubClientImpl) checkTokenAccess(ctx context.Context, organizationName string) error {
+	resp, _, err := client.doWithRetry(ctx, "rest", true, func() (*http.Request, error) {
+		req, err := http.NewRequest("GET", fmt.Sprintf("%s/orgs/%s", client.gitHubServer, organizationName), nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Accept", "application/vnd.github+json")
+		return req, nil
+	})
+	if err != nil {
+		return fmt.Errorf("not able to check the Github token: %v", err)
+	}
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized:
+		return fmt.Errorf("the Github token is invalid or expired")
+	case resp.StatusCode == http.StatusForbidden || resp.StatusCode == http.StatusNotFound:
+		return fmt.Errorf("the Github token has no access to the %s organization (%s)", organizationName, resp.Status)
+	case resp.StatusCode < 200 || resp.StatusCode >= 300:
+		return fmt.Errorf("not able to check the Github token: unexpected status: %s", resp.Status)
+	}
 
+	// only classic personal access tokens have scopes (fine-grained tokens
+	// and GITHUB_TOKEN have permissions, checked by Github on each call)
+	if _, ok := resp.Header["X-Oauth-Scopes"]; !ok {
+		return nil
+	}
+	scopes := make(map[string]bool)
+	for _, scope := range strings.Split(resp.Header.Get("X-OAuth-Scopes"), ",") {
+		scopes[strings.TrimSpace(scope)] = true
+	}
+	missing := []string{}
+	for _, required := range requiredTokenScopes {
+		found := false
+		for _, scope := range required.includedIn {
+			found = found || scopes[scope]
+		}
+		if !found {
+			missing = append(missing, required.scope)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("the Github token lacks the required scopes: %s (token scopes: %s)", strings.Join(missing, ", "), resp.Header.Get("X-OAuth-Scopes"))
+	}
+	if !scopes["admin:org"] {
+		logrus.Warnf("the Github token doesn't have the admin:org scope: it can be used to plan, but not to apply")
+	}
 	return nil
 }
 
@@ -179,9 +308,9 @@ type GraphQLRequest struct {
  * variables := map[string]interface{}{
  *	"name": "octocat",
  * }
- * responseBody, err := client.QueryGraphQLAPI(query, variables)
+ * responseBody, err := client.QueryGraphQLAPI(ctx, query, variables)
  */
-func (client *GitHubClientImpl) QueryGraphQLAPI(query string, variables map[string]interface{}) ([]byte, error) {
+func (client *GitHubClientImpl) QueryGraphQLAPI(ctx context.Context, query string, variables map[string]interface{}) ([]byte, error) {
 	body, err := json.Marshal(GraphQLRequest{
 		Query:     query,
 		Variables: variables,
@@ -190,35 +319,21 @@ func (client *GitHubClientImpl) QueryGraphQLAPI(query string, variables map[stri
 		return nil, err
 	}
 
-	req, err := http.NewRequest("POST", client.gitHubServer+"/graphql", bytes.NewBuffer(body))
-	if err != nil {
-		return nil, err
-	}
-
-	req.Header.Set("Content-Type", "application/json")
-
-	resp, err := client.httpClient.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode == http.StatusTooManyRequests {
-		// We're being rate limited. Get the reset time from the headers.
-		if err := waitRateLimit(resp.Header.Get("X-RateLimit-Reset")); err != nil {
-			return nil, err
-		}
-
-		// Retry the request.
-		return client.QueryGraphQLAPI(query, variables)
-	} else {
-		responseBody, err := io.ReadAll(resp.Body)
+	// queries can be sent again, mutations cannot
+	idempotent := !strings.HasPrefix(strings.TrimSpace(query), "mutation")
+	_, responseBody, err := client.doWithRetry(ctx, "graphql", idempotent, func() (*http.Request, error) {
+		req, err := http.NewRequest("POST", client.gitHubServer+"/graphql", bytes.NewBuffer(body))
 		if err != nil {
 			return nil, err
 		}
-
-		return responseBody, nil
+		req.Header.Set("Content-Type", "application/json")
+		return req, nil
+	})
+	if err != nil {
+		return nil, err
 	}
+
+	return responseBody, nil
 }
 
 /*
@@ -232,53 +347,87 @@ func (client *GitHubClientImpl) QueryGraphQLAPI(query string, variables map[stri
  *	"name": "my-repo",
  *	"private": true,
  * }
- * responseBody, err := client.CallRestAPIWithBody("orgs/my-org/repos", "POST", body)
+ * responseBody, err := client.CallRestAPI(ctx, "orgs/my-org/repos", "POST", body)
  */
-func (client *GitHubClientImpl) CallRestAPI(endpoint, method string, body map[string]interface{}) ([]byte, error) {
-	var bodyReader io.Reader
+func (client *GitHubClientImpl) CallRestAPI(ctx context.Context, endpoint, method string, body map[string]interface{}) ([]byte, error) {
+	var jsonBody []byte
 	if body != nil {
-		jsonBody, err := json.Marshal(body)
+		var err error
+		jsonBody, err = json.Marshal(body)
 		if err != nil {
 			return nil, err
 		}
-		bodyReader = bytes.NewBuffer(jsonBody)
-	}
-	urlpath, err := url.JoinPath(client.gitHubServer, endpoint)
-	if err != nil {
-		return nil, err
 	}
-	req, err := http.NewRequest(method, urlpath, bodyReader)
+	// url.JoinPath would escape the query string
+	path, query, _ := strings.Cut(endpoint, "?")
+	urlpath, err := url.JoinPath(client.gitHubServer, path)
 	if err != nil {
 		return nil, err
 	}
-	req.Header.Set("Accept", "application/vnd.github+json")
-	//	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
-	resp, err := client.httpClient.Do(req)
-	if err != nil {
-		return nil, err
+	if query != "" {
+		urlpath += "?" + query
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode == http.StatusTooManyRequests {
-		// We're being rate limited. Get the reset time from the headers.
-		if err := waitRateLimit(resp.Header.Get("X-RateLimit-Reset")); err != nil {
-			return nil, err
+	resp, responseBody, err := client.doWithRetry(ctx, "rest", isIdempotentMethod(method), func() (*http.Request, error) {
+		var bodyReader io.Reader
+		if jsonBody != nil {
+			bodyReader = bytes.NewReader(jsonBody)
 		}
-
-		// Retry the request.
-		return client.CallRestAPI(endpoint, method, body)
-	} else {
-		responseBody, err := io.ReadAll(resp.Body)
+		req, err := http.NewRequest(method, urlpath, bodyReader)
 		if err != nil {
 			return nil, err
 		}
-		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-			return responseBody, fmt.Errorf("unexpected status: %s", resp.Status)
+		req.Header.Set("Accept", "application/vnd.github+json")
+		//	req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+		client.etags.setIfNoneMatch(req, urlpath)
+		return req, nil
+	})
+	if err != nil {
+		return responseBody, err
+	}
+	if resp.StatusCode == http.StatusNotModified {
+		if cached, ok := client.etags.get(urlpath); ok {
+			metrics.GithubNotModified.Inc()
+			return cached, nil
+		}
+	}
+	if resp.StatusCode == http.StatusForbidden {
+		// https://docs.github.com/en/rest/authentication/permissions-required-for-fine-grained-personal-access-tokens
+		if permissions := resp.Header.Get("X-Accepted-GitHub-Permissions"); permissions != "" {
+			return responseBody, fmt.Errorf("unexpected status: %s (the token lacks the required permissions: %s)", resp.Status, permissions)
+		}
+		if scopes := resp.Header.Get("X-Accepted-OAuth-Scopes"); scopes != "" {
+			return responseBody, fmt.Errorf("unexpected status: %s (the token lacks the required scopes: %s)", resp.Status, scopes)
 		}
-
-		return responseBody, nil
 	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return responseBody, &StatusError{StatusCode: resp.StatusCode, Status: resp.Status}
+	}
+	if method == http.MethodGet {
+		client.etags.store(urlpath, resp, responseBody)
+	}
+
+	return responseBody, nil
+}
+
+/*
+ * StatusError is returned by CallRestAPI when Github answers with an unexpected status
+ */
+type StatusError struct {
+	StatusCode int
+	Status     string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("unexpected status: %s", e.Status)
+}
+
+/*
+ * IsNotFound checks if a CallRestAPI error is a 404
+ */
+func IsNotFound(err error) bool {
+	var statusErr *StatusError
+	return errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusNotFound
 }
 
 func (client *GitHubClientImpl) createJWT() (string, error) {
@@ -307,8 +456,8 @@ type AccessTokenResponse struct {
 	Token string `json:"token"`
 }
 
-func (client *GitHubClientImpl) getAccessTokenForInstallation(jwt string) (string, time.Time, error) {
-	req, err := http.NewRequest("POST", fmt.Sprintf("%s/app/installations/%d/access_tokens", client.gitHubServer, client.installationID), nil)
+func (client *GitHubClientImpl) getAccessTokenForInstallation(ctx context.Context, jwt string) (string, time.Time, error) {
+	req, err := http.NewRequestWithContext(ctx, "POST", fmt.Sprintf("%s/app/installations/%d/access_tokens", client.gitHubServer, client.installationID), nil)
 	if err != nil {
 		return "", time.Now(), err
 	}
@@ -342,7 +491,7 @@ func (client *GitHubClientImpl) getAccessTokenForInstallation(jwt string) (strin
  * @return {error} error
  *
  * Example:
- * accessToken, err := client.GetAccessToken()
+ * accessToken, err := client.GetAccessToken(ctx)
  * if err != nil {
  *	log.Fatal(err)
  * }
@@ -353,19 +502,27 @@ func (client *GitHubClientImpl) getAccessTokenForInstallation(jwt string) (strin
  *		Password: accessToken,
  *	},
  */
-func (client *GitHubClientImpl) GetAccessToken() (string, error) {
+func (client *GitHubClientImpl) GetAccessToken(ctx context.Context) (string, error) {
+	if client.token != "" {
+		return client.token, nil
+	}
+	client.mu.Lock()
+	defer client.mu.Unlock()
 	logrus.Debugf("GetAccessToken(): client.tokenExpiration: %v", client.tokenExpiration)
 
 	if client.accessToken != "" && client.tokenExpiration.After(time.Now()) {
 		return client.accessToken, nil
 	}
 
+	if err := client.reloadPrivateKey(); err != nil {
+		return "", err
+	}
 	jwt, err := client.createJWT()
 	if err != nil {
 		return "", err
 	}
 
-	accessToken, expiration, err := client.getAccessTokenForInstallation(jwt)
+	accessToken, expiration, err := client.getAccessTokenForInstallation(ctx, jwt)
 	if err != nil {
 		return "", err
 	}
@@ -378,6 +535,6 @@ func (client *GitHubClientImpl) GetAccessToken() (string, error) {
 	return accessToken, nil
 }
 
-func (client *GitHubClientImpl) GetAppSlug() string {
-	return client.appSlug
+func (client *GitHubClientImpl) GetActor() string {
+	return client.actor
 }
@@ -1,6 +1,7 @@
 package github
 
 import (
+	"context"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -36,7 +37,7 @@ func TestQueryGraphQLAPI(t *testing.T) {
 
 	// Call the function and check the result
 	query := `query { user(login: "octocat") { name } }`
-	result, err := client.QueryGraphQLAPI(query, nil)
+	result, err := client.QueryGraphQLAPI(context.TODO(), query, nil)
 	if err != nil {
 		t.Errorf("unexpected error: %v", err)
 	}
@@ -45,3 +46,219 @@ func TestQueryGraphQLAPI(t *testing.T) {
 		t.Errorf("expected 'octocat' in the result, got %s", result)
 	}
 }
+
+func TestCallRestAPI(t *testing.T) {
+	t.Run("happy path: query string is kept", func(t *testing.T) {
+		var requestURI string
+		testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requestURI = r.RequestURI
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte(`[]`))
+		}))
+		defer testServer.Close()
+
+		client := &GitHubClientImpl{
+			gitHubServer: testServer.URL,
+			httpClient:   http.DefaultClient,
+		}
+
+		_, err := client.CallRestAPI(context.TODO(), "/orgs/myorg/actions/variables?per_page=30&page=2", "GET", nil)
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		if requestURI != "/orgs/myorg/actions/variables?per_page=30&page=2" {
+			t.Errorf("unexpected request uri: %s", requestURI)
+		}
+	})
+
+	t.Run("happy path: unchanged resources are served from the ETag cache", func(t *testing.T) {
+		var ifNoneMatch []string
+		testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ifNoneMatch = append(ifNoneMatch, r.Header.Get("If-None-Match"))
+			if r.Header.Get("If-None-Match") == `"v1"` {
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+			w.Header().Set("ETag", `"v1"`)
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte(`{"total_count":1}`))
+		}))
+		defer testServer.Close()
+
+		client := &GitHubClientImpl{
+			gitHubServer: testServer.URL,
+			httpClient:   http.DefaultClient,
+			etags:        newEtagCache(),
+		}
+
+		for i := 0; i < 2; i++ {
+			body, err := client.CallRestAPI(context.TODO(), "/repos/myorg/repo1/actions/variables", "GET", nil)
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if string(body) != `{"total_count":1}` {
+				t.Errorf("unexpected body: %s", body)
+			}
+		}
+		if len(ifNoneMatch) != 2 || ifNoneMatch[0] != "" || ifNoneMatch[1] != `"v1"` {
+			t.Errorf("unexpected If-None-Match headers: %v", ifNoneMatch)
+		}
+
+		// mutations are never conditional
+		ifNoneMatch = nil
+		if _, err := client.CallRestAPI(context.TODO(), "/repos/myorg/repo1/actions/variables", "POST", map[string]interface{}{"name": "VAR"}); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		if ifNoneMatch[0] != "" {
+			t.Errorf("unexpected If-None-Match header on a POST: %s", ifNoneMatch[0])
+		}
+	})
+
+	t.Run("not happy path: not found", func(t *testing.T) {
+		testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"message":"Not Found"}`))
+		}))
+		defer testServer.Close()
+
+		client := &GitHubClientImpl{
+			gitHubServer: testServer.URL,
+			httpClient:   http.DefaultClient,
+		}
+
+		_, err := client.CallRestAPI(context.TODO(), "/users/unknown", "GET", nil)
+		if !IsNotFound(err) {
+			t.Errorf("expected a not found error, got: %v", err)
+		}
+		if IsNotFound(&StatusError{StatusCode: http.StatusInternalServerError, Status: "500 Internal Server Error"}) {
+			t.Errorf("unexpected not found error")
+		}
+	})
+}
+
+func TestTokenAuthentication(t *testing.T) {
+	t.Run("happy path: the token is sent and returned as access token", func(t *testing.T) {
+		var authorization string
+		testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			authorization = r.Header.Get("Authorization")
+			w.Header().Set("X-OAuth-Scopes", "repo, admin:org")
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte(`{"login":"myorg"}`))
+		}))
+		defer testServer.Close()
+
+		client, err := NewGitHubClientWithToken(testServer.URL, "myorg", "mytoken")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if authorization != "Bearer mytoken" {
+			t.Errorf("unexpected authorization header: %s", authorization)
+		}
+
+		authorization = ""
+		if _, err := client.CallRestAPI(context.TODO(), "/orgs/myorg/teams", "GET", nil); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		if authorization != "Bearer mytoken" {
+			t.Errorf("unexpected authorization header: %s", authorization)
+		}
+
+		token, err := client.GetAccessToken(context.TODO())
+		if err != nil || token != "mytoken" {
+			t.Errorf("unexpected access token: %s (%v)", token, err)
+		}
+	})
+
+	t.Run("happy path: fine-grained tokens have no scopes", func(t *testing.T) {
+		testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer testServer.Close()
+
+		_, err := NewGitHubClientWithToken(testServer.URL, "myorg", "github_pat_xxx")
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("not happy path: missing scopes", func(t *testing.T) {
+		testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("X-OAuth-Scopes", "repo")
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer testServer.Close()
+
+		_, err := NewGitHubClientWithToken(testServer.URL, "myorg", "mytoken")
+		if err == nil || !strings.Contains(err.Error(), "lacks the required scopes: read:org") {
+			t.Errorf("expected a missing scope error, got %v", err)
+		}
+	})
+
+	t.Run("not happy path: invalid token", func(t *testing.T) {
+		testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusUnauthorized)
+		}))
+		defer testServer.Close()
+
+		_, err := NewGitHubClientWithToken(testServer.URL, "myorg", "mytoken")
+		if err == nil || !strings.Contains(err.Error(), "invalid or expired") {
+			t.Errorf("expected an invalid token error, got %v", err)
+		}
+	})
+
+	t.Run("not happy path: missing fine-grained permission", func(t *testing.T) {
+		testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("X-Accepted-GitHub-Permissions", "administration=write")
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"message":"Resource not accessible by personal access token"}`))
+		}))
+		defer testServer.Close()
+
+		client := &GitHubClientImpl{
+			gitHubServer: testServer.URL,
+			httpClient:   http.DefaultClient,
+		}
+		_, err := client.CallRestAPI(context.TODO(), "/orgs/myorg/repos", "POST", map[string]interface{}{"name": "repo1"})
+		if err == nil || !strings.Contains(err.Error(), "lacks the required permissions: administration=write") {
+			t.Errorf("expected a missing permission error, got %v", err)
+		}
+	})
+
+	t.Run("happy path: the token login is the actor", func(t *testing.T) {
+		testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+			if r.URL.Path == "/user" {
+				w.Write([]byte(`{"login":"goliac-bot"}`))
+			}
+		}))
+		defer testServer.Close()
+
+		client, err := NewGitHubClientWithToken(testServer.URL, "myorg", "github_pat_xxx")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if client.GetActor() != "goliac-bot" {
+			t.Errorf("unexpected actor: %s", client.GetActor())
+		}
+	})
+
+	t.Run("happy path: the GITHUB_TOKEN acts as github-actions[bot]", func(t *testing.T) {
+		testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/user" {
+				w.WriteHeader(http.StatusForbidden)
+				w.Write([]byte(`{"message":"Resource not accessible by integration"}`))
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer testServer.Close()
+
+		client, err := NewGitHubClientWithToken(testServer.URL, "myorg", "ghs_xxx")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if client.GetActor() != "github-actions[bot]" {
+			t.Errorf("unexpected actor: %s", client.GetActor())
+		}
+	})
+}
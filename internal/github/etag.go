@@ -0,0 +1,78 @@
+package github
+
+import (
+	"net/http"
+	"sync"
+)
+
+// above this number of entries, the cache is emptied (to bound the memory used)
+const etagCacheMaxEntries = 10000
+
+type etagCacheEntry struct {
+	etag string
+	body []byte
+}
+
+/*
+ * etagCache keeps the last ETag (and body) returned by Github for each REST GET url.
+ * The next GET is sent with If-None-Match: if nothing changed, Github answers
+ * 304 Not Modified, which doesn't count against the rate limit, and the cached
+ * body is returned instead.
+ * (GraphQL queries are POST requests and cannot be conditional)
+ */
+type etagCache struct {
+	mu      sync.Mutex
+	entries map[string]etagCacheEntry
+}
+
+func newEtagCache() *etagCache {
+	return &etagCache{
+		entries: make(map[string]etagCacheEntry),
+	}
+}
+
+/*
+ * setIfNoneMatch adds the If-None-Match header if the url was already fetched
+ */
+func (c *etagCache) setIfNoneMatch(req *http.Request, url string) {
+	if c == nil || req.Method != http.MethodGet {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if entry, ok := c.entries[url]; ok {
+		req.Header.Set("If-None-Match", entry.etag)
+	}
+}
+
+/*
+ * get returns the cached body of a 304 Not Modified response
+ */
+func (c *etagCache) get(url string) ([]byte, bool) {
+	if c == nil {
+		return nil, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[url]
+	return entry.body, ok
+}
+
+/*
+ * store keeps the body of a successful GET response, if Github sent an ETag
+ */
+func (c *etagCache) store(url string, resp *http.Response, body []byte) {
+	if c == nil {
+		return
+	}
+	etag := resp.Header.Get("ETag")
+	if etag == "" {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.entries) >= etagCacheMaxEntries {
+		c.entries = make(map[string]etagCacheEntry)
+	}
+	c.entries[url] = etagCacheEntry{etag: etag, body: body}
+}
@@ -0,0 +1,69 @@
+package github
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	jwt "github.com/dgrijalva/jwt-go"
+	"github.com/sirupsen/logrus"
+)
+
+/*
+ * PrivateKeyLoader returns the Github App private key (PEM).
+ * It is called each time a new installation token is needed (about every hour),
+ * so a rotated key is picked up without restarting Goliac
+ */
+type PrivateKeyLoader func() ([]byte, error)
+
+/*
+ * PrivateKeyFromFile reads the private key from a PEM file
+ */
+func PrivateKeyFromFile(filename string) PrivateKeyLoader {
+	return func() ([]byte, error) {
+		return os.ReadFile(filename)
+	}
+}
+
+/*
+ * PrivateKeyFromString returns a private key given directly (like from an
+ * environment variable). Escaped newlines ("\n") are accepted, since a
+ * multiline value is not always easy to inject
+ */
+func PrivateKeyFromString(key string) PrivateKeyLoader {
+	if !strings.Contains(key, "\n") {
+		key = strings.ReplaceAll(key, `\n`, "\n")
+	}
+	return func() ([]byte, error) {
+		return []byte(key), nil
+	}
+}
+
+/*
+ * reloadPrivateKey fetches the private key again. If it fails (or the key
+ * cannot be parsed), the previous key (if any) is kept
+ */
+func (client *GitHubClientImpl) reloadPrivateKey() error {
+	if client.loadPrivateKey == nil {
+		return nil
+	}
+	key, err := client.loadPrivateKey()
+	if err == nil && len(key) == 0 {
+		err = fmt.Errorf("empty private key")
+	}
+	if err == nil {
+		_, err = jwt.ParseRSAPrivateKeyFromPEM(key)
+	}
+	if err != nil {
+		if client.privateKey != nil {
+			logrus.Warnf("not able to reload the Github App private key, keeping the previous one: %v", err)
+			return nil
+		}
+		return fmt.Errorf("not able to load the Github App private key: %v", err)
+	}
+	if client.privateKey != nil && string(key) != string(client.privateKey) {
+		logrus.Info("Github App private key has been rotated")
+	}
+	client.privateKey = key
+	return nil
+}
@@ -0,0 +1,245 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Alayacare/goliac/internal/metrics"
+	"github.com/sirupsen/logrus"
+)
+
+/*
+ * RetryPolicy defines how the Github client retries a failed call:
+ * - network errors and 5xx responses are retried with an exponential backoff (and jitter),
+ *   only for idempotent calls (a POST may have been applied even if it failed)
+ * - rate limited responses (429, or 403 secondary rate limits) are retried
+ *   after Retry-After (or X-RateLimit-Reset)
+ * - when X-RateLimit-Remaining goes below MinRemaining, the calls are slowed down
+ *   to spread the remaining calls until the rate limit reset
+ */
+type RetryPolicy struct {
+	MaxAttempts  int           // including the first call
+	BaseDelay    time.Duration // first backoff delay (doubled at each attempt)
+	MaxDelay     time.Duration // maximum backoff delay
+	MinRemaining int           // X-RateLimit-Remaining threshold to start throttling
+}
+
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:  5,
+		BaseDelay:    1 * time.Second,
+		MaxDelay:     30 * time.Second,
+		MinRemaining: 100,
+	}
+}
+
+/*
+ * backoff returns the delay before the attempt n (starting at 1)
+ * a random jitter (between half and the full delay) avoids to retry all
+ * the concurrent calls at the same time
+ */
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	delay := p.BaseDelay
+	for i := 1; i < attempt && delay < p.MaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	if delay <= 0 {
+		return 0
+	}
+	half := delay / 2
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
+
+/*
+ * sleepContext waits for d, or until the context is cancelled
+ */
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+/*
+ * rateLimitDelay returns how long to wait before retrying a rate limited response,
+ * and false if the response is not rate limited
+ */
+func rateLimitDelay(resp *http.Response, body []byte) (time.Duration, bool) {
+	rateLimited := resp.StatusCode == http.StatusTooManyRequests
+	if resp.StatusCode == http.StatusForbidden {
+		// secondary rate limit, or primary rate limit exhausted
+		// https://docs.github.com/en/rest/using-the-rest-api/rate-limits-for-the-rest-api?apiVersion=2022-11-28#exceeding-the-rate-limit
+		rateLimited = resp.Header.Get("Retry-After") != "" ||
+			resp.Header.Get("X-RateLimit-Remaining") == "0" ||
+			strings.Contains(strings.ToLower(string(body)), "secondary rate limit")
+	}
+	if !rateLimited {
+		return 0, false
+	}
+
+	if retryAfter := resp.Header.Get("Retry-After"); retryAfter != "" {
+		if seconds, err := strconv.Atoi(retryAfter); err == nil {
+			return time.Duration(seconds) * time.Second, true
+		}
+	}
+	if reset := resp.Header.Get("X-RateLimit-Reset"); reset != "" {
+		if resetUnix, err := strconv.ParseInt(reset, 10, 64); err == nil {
+			return time.Until(time.Unix(resetUnix, 0)), true
+		}
+	}
+	// no hint: wait at least a minute for secondary rate limits
+	return time.Minute, true
+}
+
+/*
+ * throttleDelay returns how long to wait after a response, to spread the remaining
+ * calls (X-RateLimit-Remaining) until the rate limit reset (X-RateLimit-Reset)
+ */
+func (p RetryPolicy) throttleDelay(resp *http.Response) time.Duration {
+	remaining, err := strconv.Atoi(resp.Header.Get("X-RateLimit-Remaining"))
+	if err != nil || remaining >= p.MinRemaining {
+		return 0
+	}
+	resetUnix, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64)
+	if err != nil {
+		return 0
+	}
+	untilReset := time.Until(time.Unix(resetUnix, 0))
+	if untilReset <= 0 {
+		return 0
+	}
+	return untilReset / time.Duration(remaining+1)
+}
+
+/*
+ * isIdempotentMethod returns true if a REST call can be sent again safely
+ */
+func isIdempotentMethod(method string) bool {
+	switch strings.ToUpper(method) {
+	case http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete, http.MethodPatch:
+		return true
+	}
+	return false
+}
+
+func isRetryableStatus(status int) bool {
+	switch status {
+	case http.StatusInternalServerError, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+/*
+ * doWithRetry sends the request built by newRequest (called for each attempt)
+ * following the client retry policy, and returns the last response status and body.
+ * Network errors and 5xx responses are only retried if the call is idempotent,
+ * rate limited calls are always retried
+ */
+func (client *GitHubClientImpl) doWithRetry(ctx context.Context, api string, idempotent bool, newRequest func() (*http.Request, error)) (*http.Response, []byte, error) {
+	policy := client.retryPolicy
+	if policy.MaxAttempts <= 0 {
+		policy = DefaultRetryPolicy()
+	}
+
+	var lastErr error
+	for attempt := 1; attempt <= policy.MaxAttempts; attempt++ {
+		if attempt > 1 {
+			metrics.GithubRetries.WithLabelValues(api).Inc()
+		}
+		req, err := newRequest()
+		if err != nil {
+			return nil, nil, err
+		}
+		req = req.WithContext(ctx)
+
+		metrics.GithubApiCalls.WithLabelValues(api).Inc()
+		resp, err := client.httpClient.Do(req)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, nil, ctx.Err()
+			}
+			lastErr = err
+			if !idempotent {
+				return nil, nil, err
+			}
+			delay := policy.backoff(attempt)
+			logrus.Debugf("Github %s call failed (attempt %d/%d): %v, retrying in %v", api, attempt, policy.MaxAttempts, err, delay)
+			if attempt < policy.MaxAttempts {
+				if err := sleepContext(ctx, delay); err != nil {
+					return nil, nil, err
+				}
+			}
+			continue
+		}
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			lastErr = err
+			if !idempotent {
+				return nil, nil, err
+			}
+			if attempt < policy.MaxAttempts {
+				if err := sleepContext(ctx, policy.backoff(attempt)); err != nil {
+					return nil, nil, err
+				}
+			}
+			continue
+		}
+
+		if delay, limited := rateLimitDelay(resp, body); limited {
+			lastErr = fmt.Errorf("rate limited: %s", resp.Status)
+			if attempt == policy.MaxAttempts {
+				return resp, body, lastErr
+			}
+			logrus.Infof("Github rate limit exceeded, waiting for %v", delay)
+			metrics.GithubRateLimitWaits.Inc()
+			if delay > 0 {
+				metrics.GithubRateLimitWaitSeconds.Add(delay.Seconds())
+			}
+			if err := sleepContext(ctx, delay); err != nil {
+				return nil, nil, err
+			}
+			continue
+		}
+
+		if idempotent && isRetryableStatus(resp.StatusCode) {
+			lastErr = fmt.Errorf("unexpected status: %s", resp.Status)
+			if attempt == policy.MaxAttempts {
+				return resp, body, lastErr
+			}
+			delay := policy.backoff(attempt)
+			logrus.Debugf("Github %s call failed (attempt %d/%d): %s, retrying in %v", api, attempt, policy.MaxAttempts, resp.Status, delay)
+			if err := sleepContext(ctx, delay); err != nil {
+				return nil, nil, err
+			}
+			continue
+		}
+
+		if delay := policy.throttleDelay(resp); delay > 0 {
+			logrus.Debugf("Github rate limit almost exhausted (%s remaining), slowing down for %v", resp.Header.Get("X-RateLimit-Remaining"), delay)
+			metrics.GithubRateLimitWaitSeconds.Add(delay.Seconds())
+			if err := sleepContext(ctx, delay); err != nil {
+				return nil, nil, err
+			}
+		}
+		return resp, body, nil
+	}
+	return nil, nil, fmt.Errorf("giving up after %d attempts: %v", policy.MaxAttempts, lastErr)
+}
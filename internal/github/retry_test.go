@@ -0,0 +1,260 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newRetryTestClient(serverURL string) *GitHubClientImpl {
+	return &GitHubClientImpl{
+		gitHubServer: serverURL,
+		httpClient:   http.DefaultClient,
+		retryPolicy: RetryPolicy{
+			MaxAttempts:  3,
+			BaseDelay:    time.Millisecond,
+			MaxDelay:     5 * time.Millisecond,
+			MinRemaining: 0,
+		},
+	}
+}
+
+func TestRetryPolicy(t *testing.T) {
+	t.Run("happy path: 5xx are retried", func(t *testing.T) {
+		var calls int32
+		testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if atomic.AddInt32(&calls, 1) == 1 {
+				w.WriteHeader(http.StatusBadGateway)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte(`{"name":"repo1"}`))
+		}))
+		defer testServer.Close()
+
+		client := newRetryTestClient(testServer.URL)
+		body, err := client.CallRestAPI(context.TODO(), "/repos/myorg/repo1", "GET", nil)
+		assert.Nil(t, err)
+		assert.Equal(t, `{"name":"repo1"}`, string(body))
+		assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+	})
+
+	t.Run("happy path: the body is sent again on retry", func(t *testing.T) {
+		var calls int32
+		var lastBody string
+		testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			buf := make([]byte, 100)
+			n, _ := r.Body.Read(buf)
+			lastBody = string(buf[:n])
+			if atomic.AddInt32(&calls, 1) == 1 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			w.WriteHeader(http.StatusCreated)
+		}))
+		defer testServer.Close()
+
+		client := newRetryTestClient(testServer.URL)
+		_, err := client.CallRestAPI(context.TODO(), "/orgs/myorg/teams/team1", "PATCH", map[string]interface{}{"name": "team1"})
+		assert.Nil(t, err)
+		assert.Equal(t, `{"name":"team1"}`, lastBody)
+	})
+
+	t.Run("not happy path: POST are not retried on 5xx", func(t *testing.T) {
+		var calls int32
+		testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&calls, 1)
+			w.WriteHeader(http.StatusBadGateway)
+		}))
+		defer testServer.Close()
+
+		client := newRetryTestClient(testServer.URL)
+		_, err := client.CallRestAPI(context.TODO(), "/orgs/myorg/teams", "POST", map[string]interface{}{"name": "team1"})
+		assert.NotNil(t, err)
+		assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+	})
+
+	t.Run("happy path: rate limited POST are retried", func(t *testing.T) {
+		var calls int32
+		testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if atomic.AddInt32(&calls, 1) == 1 {
+				w.Header().Set("Retry-After", "0")
+				w.WriteHeader(http.StatusTooManyRequests)
+				return
+			}
+			w.WriteHeader(http.StatusCreated)
+		}))
+		defer testServer.Close()
+
+		client := newRetryTestClient(testServer.URL)
+		_, err := client.CallRestAPI(context.TODO(), "/orgs/myorg/teams", "POST", map[string]interface{}{"name": "team1"})
+		assert.Nil(t, err)
+		assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+	})
+
+	t.Run("not happy path: attempts are bounded", func(t *testing.T) {
+		var calls int32
+		testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&calls, 1)
+			w.WriteHeader(http.StatusInternalServerError)
+		}))
+		defer testServer.Close()
+
+		client := newRetryTestClient(testServer.URL)
+		_, err := client.CallRestAPI(context.TODO(), "/repos/myorg/repo1", "GET", nil)
+		assert.NotNil(t, err)
+		assert.Equal(t, int32(3), atomic.LoadInt32(&calls))
+	})
+
+	t.Run("not happy path: 4xx are not retried", func(t *testing.T) {
+		var calls int32
+		testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&calls, 1)
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"message":"Not Found"}`))
+		}))
+		defer testServer.Close()
+
+		client := newRetryTestClient(testServer.URL)
+		body, err := client.CallRestAPI(context.TODO(), "/repos/myorg/repo1", "GET", nil)
+		assert.NotNil(t, err)
+		assert.Equal(t, `{"message":"Not Found"}`, string(body))
+		assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+	})
+
+	t.Run("happy path: secondary rate limit with Retry-After", func(t *testing.T) {
+		var calls int32
+		testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if atomic.AddInt32(&calls, 1) == 1 {
+				w.Header().Set("Retry-After", "0")
+				w.WriteHeader(http.StatusForbidden)
+				w.Write([]byte(`{"message":"You have exceeded a secondary rate limit"}`))
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte(`{"data":{}}`))
+		}))
+		defer testServer.Close()
+
+		client := newRetryTestClient(testServer.URL)
+		body, err := client.QueryGraphQLAPI(context.TODO(), "query { viewer { login } }", nil)
+		assert.Nil(t, err)
+		assert.Equal(t, `{"data":{}}`, string(body))
+		assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+	})
+
+	t.Run("happy path: 429 waits for the rate limit reset", func(t *testing.T) {
+		var calls int32
+		testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if atomic.AddInt32(&calls, 1) == 1 {
+				w.Header().Set("X-RateLimit-Remaining", "0")
+				w.Header().Set("X-RateLimit-Reset", fmt.Sprintf("%d", time.Now().Unix()))
+				w.WriteHeader(http.StatusTooManyRequests)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer testServer.Close()
+
+		client := newRetryTestClient(testServer.URL)
+		_, err := client.CallRestAPI(context.TODO(), "/repos/myorg/repo1", "GET", nil)
+		assert.Nil(t, err)
+		assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+	})
+
+	t.Run("happy path: network errors are retried", func(t *testing.T) {
+		var calls int32
+		testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if atomic.AddInt32(&calls, 1) == 1 {
+				// close the connection without any response
+				conn, _, _ := w.(http.Hijacker).Hijack()
+				conn.Close()
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer testServer.Close()
+
+		client := newRetryTestClient(testServer.URL)
+		_, err := client.CallRestAPI(context.TODO(), "/repos/myorg/repo1", "GET", nil)
+		assert.Nil(t, err)
+		assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+	})
+
+	t.Run("not happy path: context cancellation stops the retries", func(t *testing.T) {
+		var calls int32
+		testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&calls, 1)
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}))
+		defer testServer.Close()
+
+		client := newRetryTestClient(testServer.URL)
+		client.retryPolicy.BaseDelay = time.Minute
+		client.retryPolicy.MaxDelay = time.Minute
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		start := time.Now()
+		_, _, err := client.doWithRetry(ctx, "rest", true, func() (*http.Request, error) {
+			return http.NewRequest("GET", testServer.URL+"/repos/myorg/repo1", nil)
+		})
+		assert.Equal(t, context.DeadlineExceeded, err)
+		assert.Less(t, time.Since(start), 10*time.Second)
+		assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+	})
+}
+
+func TestRetryPolicyDelays(t *testing.T) {
+	t.Run("happy path: exponential backoff with jitter", func(t *testing.T) {
+		policy := RetryPolicy{BaseDelay: time.Second, MaxDelay: 10 * time.Second}
+		for i := 0; i < 20; i++ {
+			d := policy.backoff(1)
+			assert.GreaterOrEqual(t, d, 500*time.Millisecond)
+			assert.LessOrEqual(t, d, time.Second)
+
+			d = policy.backoff(3)
+			assert.GreaterOrEqual(t, d, 2*time.Second)
+			assert.LessOrEqual(t, d, 4*time.Second)
+
+			// capped
+			d = policy.backoff(10)
+			assert.GreaterOrEqual(t, d, 5*time.Second)
+			assert.LessOrEqual(t, d, 10*time.Second)
+		}
+	})
+
+	t.Run("happy path: Retry-After is used for secondary rate limits", func(t *testing.T) {
+		resp := &http.Response{StatusCode: http.StatusForbidden, Header: http.Header{}}
+		resp.Header.Set("Retry-After", "42")
+		delay, limited := rateLimitDelay(resp, []byte(`{"message":"You have exceeded a secondary rate limit"}`))
+		assert.True(t, limited)
+		assert.Equal(t, 42*time.Second, delay)
+	})
+
+	t.Run("happy path: a 403 without rate limit is not retried", func(t *testing.T) {
+		resp := &http.Response{StatusCode: http.StatusForbidden, Header: http.Header{}}
+		_, limited := rateLimitDelay(resp, []byte(`{"message":"Resource not accessible by integration"}`))
+		assert.False(t, limited)
+	})
+
+	t.Run("happy path: proactive throttle when the rate limit is almost exhausted", func(t *testing.T) {
+		policy := RetryPolicy{MinRemaining: 100}
+		resp := &http.Response{StatusCode: http.StatusOK, Header: http.Header{}}
+		resp.Header.Set("X-RateLimit-Remaining", "9")
+		resp.Header.Set("X-RateLimit-Reset", fmt.Sprintf("%d", time.Now().Add(100*time.Second).Unix()))
+		delay := policy.throttleDelay(resp)
+		// 100s for 10 calls
+		assert.Greater(t, delay, 8*time.Second)
+		assert.LessOrEqual(t, delay, 10*time.Second)
+
+		resp.Header.Set("X-RateLimit-Remaining", "4000")
+		assert.Equal(t, time.Duration(0), policy.throttleDelay(resp))
+	})
+}
@@ -1,7 +1,14 @@
 package internal
 
 import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"unicode"
+
 	"github.com/Alayacare/goliac/internal/engine"
+	"github.com/Alayacare/goliac/internal/metrics"
 	"github.com/sirupsen/logrus"
 )
 
@@ -10,7 +17,7 @@ import (
  * object, so we can regroup all of them to apply (or cancel) them in batch
  */
 type GithubCommand interface {
-	Apply()
+	Apply(ctx context.Context) error
 }
 
 /*
@@ -39,23 +46,43 @@ func NewGithubBatchExecutor(client engine.ReconciliatorExecutor, maxChangesets i
 	return &gal
 }
 
-func (g *GithubBatchExecutor) AddUserToOrg(dryrun bool, ghuserid string) {
+func (g *GithubBatchExecutor) AddUserToOrg(ctx context.Context, dryrun bool, ghuserid string) error {
 	g.commands = append(g.commands, &GithubCommandAddUserToOrg{
 		client:   g.client,
 		dryrun:   dryrun,
 		ghuserid: ghuserid,
 	})
+	return nil
 }
 
-func (g *GithubBatchExecutor) RemoveUserFromOrg(dryrun bool, ghuserid string) {
-	g.commands = append(g.commands, &GithubCommandAddUserToOrg{
+func (g *GithubBatchExecutor) RemoveUserFromOrg(ctx context.Context, dryrun bool, ghuserid string) error {
+	g.commands = append(g.commands, &GithubCommandRemoveUserFromOrg{
 		client:   g.client,
 		dryrun:   dryrun,
 		ghuserid: ghuserid,
 	})
+	return nil
+}
+
+func (g *GithubBatchExecutor) InviteUserByEmail(ctx context.Context, dryrun bool, email string) error {
+	g.commands = append(g.commands, &GithubCommandInviteUserByEmail{
+		client: g.client,
+		dryrun: dryrun,
+		email:  email,
+	})
+	return nil
 }
 
-func (g *GithubBatchExecutor) CreateTeam(dryrun bool, teamname string, description string, members []string) {
+func (g *GithubBatchExecutor) CancelOrgInvitation(ctx context.Context, dryrun bool, invitee string) error {
+	g.commands = append(g.commands, &GithubCommandCancelOrgInvitation{
+		client:  g.client,
+		dryrun:  dryrun,
+		invitee: invitee,
+	})
+	return nil
+}
+
+func (g *GithubBatchExecutor) CreateTeam(ctx context.Context, dryrun bool, teamname string, description string, members []string) error {
 	g.commands = append(g.commands, &GithubCommandCreateTeam{
 		client:      g.client,
 		dryrun:      dryrun,
@@ -63,10 +90,11 @@ func (g *GithubBatchExecutor) CreateTeam(dryrun bool, teamname string, descripti
 		description: description,
 		members:     members,
 	})
+	return nil
 }
 
 // role = member or maintainer (usually we use member)
-func (g *GithubBatchExecutor) UpdateTeamAddMember(dryrun bool, teamslug string, username string, role string) {
+func (g *GithubBatchExecutor) UpdateTeamAddMember(ctx context.Context, dryrun bool, teamslug string, username string, role string) error {
 	g.commands = append(g.commands, &GithubCommandUpdateTeamAddMember{
 		client:   g.client,
 		dryrun:   dryrun,
@@ -74,26 +102,48 @@ func (g *GithubBatchExecutor) UpdateTeamAddMember(dryrun bool, teamslug string,
 		member:   username,
 		role:     role,
 	})
+	return nil
 }
 
-func (g *GithubBatchExecutor) UpdateTeamRemoveMember(dryrun bool, teamslug string, username string) {
+func (g *GithubBatchExecutor) UpdateTeamRemoveMember(ctx context.Context, dryrun bool, teamslug string, username string) error {
 	g.commands = append(g.commands, &GithubCommandUpdateTeamRemoveMember{
 		client:   g.client,
 		dryrun:   dryrun,
 		teamslug: teamslug,
 		member:   username,
 	})
+	return nil
 }
 
-func (g *GithubBatchExecutor) DeleteTeam(dryrun bool, teamslug string) {
+func (g *GithubBatchExecutor) DeleteTeam(ctx context.Context, dryrun bool, teamslug string) error {
 	g.commands = append(g.commands, &GithubCommandDeleteTeam{
 		client:   g.client,
 		dryrun:   dryrun,
 		teamslug: teamslug,
 	})
+	return nil
+}
+
+func (g *GithubBatchExecutor) UpdateTeamSetExternalGroup(ctx context.Context, dryrun bool, teamslug string, groupname string) error {
+	g.commands = append(g.commands, &GithubCommandUpdateTeamSetExternalGroup{
+		client:    g.client,
+		dryrun:    dryrun,
+		teamslug:  teamslug,
+		groupname: groupname,
+	})
+	return nil
+}
+
+func (g *GithubBatchExecutor) UpdateTeamRemoveExternalGroup(ctx context.Context, dryrun bool, teamslug string) error {
+	g.commands = append(g.commands, &GithubCommandUpdateTeamRemoveExternalGroup{
+		client:   g.client,
+		dryrun:   dryrun,
+		teamslug: teamslug,
+	})
+	return nil
 }
 
-func (g *GithubBatchExecutor) CreateRepository(dryrun bool, reponame string, description string, writers []string, readers []string, public bool) {
+func (g *GithubBatchExecutor) CreateRepository(ctx context.Context, dryrun bool, reponame string, description string, writers []string, readers []string, public bool) error {
 	g.commands = append(g.commands, &GithubCommandCreateRepository{
 		client:      g.client,
 		dryrun:      dryrun,
@@ -103,9 +153,10 @@ func (g *GithubBatchExecutor) CreateRepository(dryrun bool, reponame string, des
 		writers:     writers,
 		public:      public,
 	})
+	return nil
 }
 
-func (g *GithubBatchExecutor) UpdateRepositoryAddTeamAccess(dryrun bool, reponame string, teamslug string, permission string) {
+func (g *GithubBatchExecutor) UpdateRepositoryAddTeamAccess(ctx context.Context, dryrun bool, reponame string, teamslug string, permission string) error {
 	g.commands = append(g.commands, &GithubCommandUpdateRepositoryAddTeamAccess{
 		client:     g.client,
 		dryrun:     dryrun,
@@ -113,9 +164,10 @@ func (g *GithubBatchExecutor) UpdateRepositoryAddTeamAccess(dryrun bool, reponam
 		teamslug:   teamslug,
 		permission: permission,
 	})
+	return nil
 }
 
-func (g *GithubBatchExecutor) UpdateRepositoryUpdateTeamAccess(dryrun bool, reponame string, teamslug string, permission string) {
+func (g *GithubBatchExecutor) UpdateRepositoryUpdateTeamAccess(ctx context.Context, dryrun bool, reponame string, teamslug string, permission string) error {
 	g.commands = append(g.commands, &GithubCommandUpdateRepositoryUpdateTeamAccess{
 		client:     g.client,
 		dryrun:     dryrun,
@@ -123,36 +175,40 @@ func (g *GithubBatchExecutor) UpdateRepositoryUpdateTeamAccess(dryrun bool, repo
 		teamslug:   teamslug,
 		permission: permission,
 	})
+	return nil
 }
 
-func (g *GithubBatchExecutor) UpdateRepositoryRemoveTeamAccess(dryrun bool, reponame string, teamslug string) {
+func (g *GithubBatchExecutor) UpdateRepositoryRemoveTeamAccess(ctx context.Context, dryrun bool, reponame string, teamslug string) error {
 	g.commands = append(g.commands, &GithubCommandUpdateRepositoryRemoveTeamAccess{
 		client:   g.client,
 		dryrun:   dryrun,
 		reponame: reponame,
 		teamslug: teamslug,
 	})
+	return nil
 }
 
-func (g *GithubBatchExecutor) UpdateRepositoryUpdatePrivate(dryrun bool, reponame string, private bool) {
+func (g *GithubBatchExecutor) UpdateRepositoryUpdatePrivate(ctx context.Context, dryrun bool, reponame string, private bool) error {
 	g.commands = append(g.commands, &GithubCommandUpdateRepositoryUpdatePrivate{
 		client:   g.client,
 		dryrun:   dryrun,
 		reponame: reponame,
 		private:  private,
 	})
+	return nil
 }
 
-func (g *GithubBatchExecutor) UpdateRepositoryUpdateArchived(dryrun bool, reponame string, archived bool) {
+func (g *GithubBatchExecutor) UpdateRepositoryUpdateArchived(ctx context.Context, dryrun bool, reponame string, archived bool) error {
 	g.commands = append(g.commands, &GithubCommandUpdateRepositoryUpdateArchived{
 		client:   g.client,
 		dryrun:   dryrun,
 		reponame: reponame,
 		archived: archived,
 	})
+	return nil
 }
 
-func (g *GithubBatchExecutor) UpdateRepositorySetExternalUser(dryrun bool, reponame string, githubid string, permission string) {
+func (g *GithubBatchExecutor) UpdateRepositorySetExternalUser(ctx context.Context, dryrun bool, reponame string, githubid string, permission string) error {
 	g.commands = append(g.commands, &GithubCommandUpdateRepositorySetExternalUser{
 		client:     g.client,
 		dryrun:     dryrun,
@@ -160,64 +216,229 @@ func (g *GithubBatchExecutor) UpdateRepositorySetExternalUser(dryrun bool, repon
 		githubid:   githubid,
 		permission: permission,
 	})
+	return nil
 }
 
-func (g *GithubBatchExecutor) UpdateRepositoryRemoveExternalUser(dryrun bool, reponame string, githubid string) {
+func (g *GithubBatchExecutor) UpdateRepositoryRemoveExternalUser(ctx context.Context, dryrun bool, reponame string, githubid string) error {
 	g.commands = append(g.commands, &GithubCommandUpdateRepositoryRemoveExternalUser{
 		client:   g.client,
 		dryrun:   dryrun,
 		reponame: reponame,
 		githubid: githubid,
 	})
+	return nil
 }
 
-func (g *GithubBatchExecutor) DeleteRepository(dryrun bool, reponame string) {
+func (g *GithubBatchExecutor) DeleteRepository(ctx context.Context, dryrun bool, reponame string) error {
 	g.commands = append(g.commands, &GithubCommandDeleteRepository{
 		client:   g.client,
 		dryrun:   dryrun,
 		reponame: reponame,
 	})
+	return nil
 }
 
-func (g *GithubBatchExecutor) AddRuleset(dryrun bool, ruleset *engine.GithubRuleSet) {
+func (g *GithubBatchExecutor) AddRuleset(ctx context.Context, dryrun bool, ruleset *engine.GithubRuleSet) error {
 	g.commands = append(g.commands, &GithubCommandAddRuletset{
 		client:  g.client,
 		dryrun:  dryrun,
 		ruleset: ruleset,
 	})
+	return nil
 }
 
-func (g *GithubBatchExecutor) UpdateRuleset(dryrun bool, ruleset *engine.GithubRuleSet) {
+func (g *GithubBatchExecutor) UpdateRuleset(ctx context.Context, dryrun bool, ruleset *engine.GithubRuleSet) error {
 	g.commands = append(g.commands, &GithubCommandUpdateRuletset{
 		client:  g.client,
 		dryrun:  dryrun,
 		ruleset: ruleset,
 	})
+	return nil
 }
 
-func (g *GithubBatchExecutor) DeleteRuleset(dryrun bool, rulesetid int) {
+func (g *GithubBatchExecutor) DeleteRuleset(ctx context.Context, dryrun bool, rulesetid int) error {
 	g.commands = append(g.commands, &GithubCommandDeleteRuletset{
 		client:    g.client,
 		dryrun:    dryrun,
 		rulesetid: rulesetid,
 	})
+	return nil
+}
+
+func (g *GithubBatchExecutor) UpdateRepositorySetVariable(ctx context.Context, dryrun bool, reponame string, name string, value string) error {
+	g.commands = append(g.commands, &GithubCommandUpdateRepositorySetVariable{
+		client:   g.client,
+		dryrun:   dryrun,
+		reponame: reponame,
+		name:     name,
+		value:    value,
+	})
+	return nil
+}
+
+func (g *GithubBatchExecutor) UpdateRepositoryRemoveVariable(ctx context.Context, dryrun bool, reponame string, name string) error {
+	g.commands = append(g.commands, &GithubCommandUpdateRepositoryRemoveVariable{
+		client:   g.client,
+		dryrun:   dryrun,
+		reponame: reponame,
+		name:     name,
+	})
+	return nil
+}
+
+func (g *GithubBatchExecutor) UpdateRepositorySetSecret(ctx context.Context, dryrun bool, reponame string, name string, value string) error {
+	g.commands = append(g.commands, &GithubCommandUpdateRepositorySetSecret{
+		client:   g.client,
+		dryrun:   dryrun,
+		reponame: reponame,
+		name:     name,
+		value:    value,
+	})
+	return nil
+}
+
+func (g *GithubBatchExecutor) UpdateRepositoryRemoveSecret(ctx context.Context, dryrun bool, reponame string, name string) error {
+	g.commands = append(g.commands, &GithubCommandUpdateRepositoryRemoveSecret{
+		client:   g.client,
+		dryrun:   dryrun,
+		reponame: reponame,
+		name:     name,
+	})
+	return nil
+}
+
+func (g *GithubBatchExecutor) SetOrgVariable(ctx context.Context, dryrun bool, name string, value string) error {
+	g.commands = append(g.commands, &GithubCommandSetOrgVariable{
+		client: g.client,
+		dryrun: dryrun,
+		name:   name,
+		value:  value,
+	})
+	return nil
+}
+
+func (g *GithubBatchExecutor) RemoveOrgVariable(ctx context.Context, dryrun bool, name string) error {
+	g.commands = append(g.commands, &GithubCommandRemoveOrgVariable{
+		client: g.client,
+		dryrun: dryrun,
+		name:   name,
+	})
+	return nil
+}
+
+func (g *GithubBatchExecutor) SetOrgSecret(ctx context.Context, dryrun bool, name string, value string) error {
+	g.commands = append(g.commands, &GithubCommandSetOrgSecret{
+		client: g.client,
+		dryrun: dryrun,
+		name:   name,
+		value:  value,
+	})
+	return nil
+}
+
+func (g *GithubBatchExecutor) RemoveOrgSecret(ctx context.Context, dryrun bool, name string) error {
+	g.commands = append(g.commands, &GithubCommandRemoveOrgSecret{
+		client: g.client,
+		dryrun: dryrun,
+		name:   name,
+	})
+	return nil
+}
+
+func (g *GithubBatchExecutor) UpsertCustomProperty(ctx context.Context, dryrun bool, property *engine.GithubCustomProperty) error {
+	g.commands = append(g.commands, &GithubCommandUpsertCustomProperty{
+		client:   g.client,
+		dryrun:   dryrun,
+		property: property,
+	})
+	return nil
+}
+
+func (g *GithubBatchExecutor) DeleteCustomProperty(ctx context.Context, dryrun bool, name string) error {
+	g.commands = append(g.commands, &GithubCommandDeleteCustomProperty{
+		client: g.client,
+		dryrun: dryrun,
+		name:   name,
+	})
+	return nil
 }
 
-func (g *GithubBatchExecutor) Begin(dryrun bool) {
+func (g *GithubBatchExecutor) UpdateRepositorySetCustomProperty(ctx context.Context, dryrun bool, reponame string, name string, value []string) error {
+	g.commands = append(g.commands, &GithubCommandUpdateRepositorySetCustomProperty{
+		client:   g.client,
+		dryrun:   dryrun,
+		reponame: reponame,
+		name:     name,
+		value:    value,
+	})
+	return nil
+}
+
+func (g *GithubBatchExecutor) UpdateOrganizationSettings(ctx context.Context, dryrun bool, settings *engine.GithubOrganizationSettingsUpdate) error {
+	g.commands = append(g.commands, &GithubCommandUpdateOrganizationSettings{
+		client:   g.client,
+		dryrun:   dryrun,
+		settings: settings,
+	})
+	return nil
+}
+
+func (g *GithubBatchExecutor) Begin(ctx context.Context, dryrun bool) {
 	g.commands = make([]GithubCommand, 0)
 }
-func (g *GithubBatchExecutor) Rollback(dryrun bool, err error) {
+func (g *GithubBatchExecutor) Rollback(ctx context.Context, dryrun bool, err error) {
 	g.commands = make([]GithubCommand, 0)
 }
-func (g *GithubBatchExecutor) Commit(dryrun bool) {
+func (g *GithubBatchExecutor) Commit(ctx context.Context, dryrun bool) error {
 	if len(g.commands) > g.maxChangesets {
-		logrus.Errorf("More than %d changesets to apply (total of %d), this is suspicious. Aborting", g.maxChangesets, len(g.commands))
-		return
+		metrics.ChangesetsRejected.Inc()
+		err := fmt.Errorf("more than %d changesets to apply (total of %d), this is suspicious. Aborting", g.maxChangesets, len(g.commands))
+		g.commands = make([]GithubCommand, 0)
+		return err
 	}
-	for _, c := range g.commands {
-		c.Apply()
+	errs := make([]error, 0)
+	for i, c := range g.commands {
+		// stop applying when the run is cancelled (SIGTERM or timeout)
+		if err := ctx.Err(); err != nil {
+			logrus.Warnf("apply interrupted, %d command(s) not applied: %v", len(g.commands)-i, err)
+			errs = append(errs, err)
+			break
+		}
+		if err := c.Apply(ctx); err != nil {
+			logrus.WithField("command", commandName(c)).Error(err)
+			errs = append(errs, err)
+			continue
+		}
+		if !dryrun {
+			metrics.Operations.WithLabelValues(commandName(c)).Inc()
+		}
 	}
 	g.commands = make([]GithubCommand, 0)
+	return engine.NewApplyErrors(errs)
+}
+
+/*
+ * commandName returns the snake_case name of a command
+ * (GithubCommandCreateTeam -> create_team)
+ */
+func commandName(c GithubCommand) string {
+	t := reflect.TypeOf(c)
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	name := strings.TrimPrefix(t.Name(), "GithubCommand")
+
+	var sb strings.Builder
+	for i, r := range name {
+		if unicode.IsUpper(r) {
+			if i > 0 {
+				sb.WriteRune('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String()
 }
 
 type GithubCommandAddUserToOrg struct {
@@ -226,8 +447,28 @@ type GithubCommandAddUserToOrg struct {
 	ghuserid string
 }
 
-func (g *GithubCommandAddUserToOrg) Apply() {
-	g.client.AddUserToOrg(g.dryrun, g.ghuserid)
+func (g *GithubCommandAddUserToOrg) Apply(ctx context.Context) error {
+	return g.client.AddUserToOrg(ctx, g.dryrun, g.ghuserid)
+}
+
+type GithubCommandInviteUserByEmail struct {
+	client engine.ReconciliatorExecutor
+	dryrun bool
+	email  string
+}
+
+func (g *GithubCommandInviteUserByEmail) Apply(ctx context.Context) error {
+	return g.client.InviteUserByEmail(ctx, g.dryrun, g.email)
+}
+
+type GithubCommandCancelOrgInvitation struct {
+	client  engine.ReconciliatorExecutor
+	dryrun  bool
+	invitee string
+}
+
+func (g *GithubCommandCancelOrgInvitation) Apply(ctx context.Context) error {
+	return g.client.CancelOrgInvitation(ctx, g.dryrun, g.invitee)
 }
 
 type GithubCommandCreateRepository struct {
@@ -240,8 +481,8 @@ type GithubCommandCreateRepository struct {
 	public      bool
 }
 
-func (g *GithubCommandCreateRepository) Apply() {
-	g.client.CreateRepository(g.dryrun, g.reponame, g.description, g.writers, g.readers, g.public)
+func (g *GithubCommandCreateRepository) Apply(ctx context.Context) error {
+	return g.client.CreateRepository(ctx, g.dryrun, g.reponame, g.description, g.writers, g.readers, g.public)
 }
 
 type GithubCommandCreateTeam struct {
@@ -252,8 +493,8 @@ type GithubCommandCreateTeam struct {
 	members     []string
 }
 
-func (g *GithubCommandCreateTeam) Apply() {
-	g.client.CreateTeam(g.dryrun, g.teamname, g.description, g.members)
+func (g *GithubCommandCreateTeam) Apply(ctx context.Context) error {
+	return g.client.CreateTeam(ctx, g.dryrun, g.teamname, g.description, g.members)
 }
 
 type GithubCommandDeleteRepository struct {
@@ -262,8 +503,8 @@ type GithubCommandDeleteRepository struct {
 	reponame string
 }
 
-func (g *GithubCommandDeleteRepository) Apply() {
-	g.client.DeleteRepository(g.dryrun, g.reponame)
+func (g *GithubCommandDeleteRepository) Apply(ctx context.Context) error {
+	return g.client.DeleteRepository(ctx, g.dryrun, g.reponame)
 }
 
 type GithubCommandDeleteTeam struct {
@@ -272,8 +513,29 @@ type GithubCommandDeleteTeam struct {
 	teamslug string
 }
 
-func (g *GithubCommandDeleteTeam) Apply() {
-	g.client.DeleteTeam(g.dryrun, g.teamslug)
+func (g *GithubCommandDeleteTeam) Apply(ctx context.Context) error {
+	return g.client.DeleteTeam(ctx, g.dryrun, g.teamslug)
+}
+
+type GithubCommandUpdateTeamSetExternalGroup struct {
+	client    engine.ReconciliatorExecutor
+	dryrun    bool
+	teamslug  string
+	groupname string
+}
+
+func (g *GithubCommandUpdateTeamSetExternalGroup) Apply(ctx context.Context) error {
+	return g.client.UpdateTeamSetExternalGroup(ctx, g.dryrun, g.teamslug, g.groupname)
+}
+
+type GithubCommandUpdateTeamRemoveExternalGroup struct {
+	client   engine.ReconciliatorExecutor
+	dryrun   bool
+	teamslug string
+}
+
+func (g *GithubCommandUpdateTeamRemoveExternalGroup) Apply(ctx context.Context) error {
+	return g.client.UpdateTeamRemoveExternalGroup(ctx, g.dryrun, g.teamslug)
 }
 
 type GithubCommandRemoveUserFromOrg struct {
@@ -282,8 +544,8 @@ type GithubCommandRemoveUserFromOrg struct {
 	ghuserid string
 }
 
-func (g *GithubCommandRemoveUserFromOrg) Apply() {
-	g.client.RemoveUserFromOrg(g.dryrun, g.ghuserid)
+func (g *GithubCommandRemoveUserFromOrg) Apply(ctx context.Context) error {
+	return g.client.RemoveUserFromOrg(ctx, g.dryrun, g.ghuserid)
 }
 
 type GithubCommandUpdateRepositoryRemoveTeamAccess struct {
@@ -293,8 +555,8 @@ type GithubCommandUpdateRepositoryRemoveTeamAccess struct {
 	teamslug string
 }
 
-func (g *GithubCommandUpdateRepositoryRemoveTeamAccess) Apply() {
-	g.client.UpdateRepositoryRemoveTeamAccess(g.dryrun, g.reponame, g.teamslug)
+func (g *GithubCommandUpdateRepositoryRemoveTeamAccess) Apply(ctx context.Context) error {
+	return g.client.UpdateRepositoryRemoveTeamAccess(ctx, g.dryrun, g.reponame, g.teamslug)
 }
 
 type GithubCommandUpdateRepositoryAddTeamAccess struct {
@@ -305,8 +567,8 @@ type GithubCommandUpdateRepositoryAddTeamAccess struct {
 	permission string
 }
 
-func (g *GithubCommandUpdateRepositoryAddTeamAccess) Apply() {
-	g.client.UpdateRepositoryAddTeamAccess(g.dryrun, g.reponame, g.teamslug, g.permission)
+func (g *GithubCommandUpdateRepositoryAddTeamAccess) Apply(ctx context.Context) error {
+	return g.client.UpdateRepositoryAddTeamAccess(ctx, g.dryrun, g.reponame, g.teamslug, g.permission)
 }
 
 type GithubCommandUpdateRepositoryUpdateTeamAccess struct {
@@ -317,8 +579,8 @@ type GithubCommandUpdateRepositoryUpdateTeamAccess struct {
 	permission string
 }
 
-func (g *GithubCommandUpdateRepositoryUpdateTeamAccess) Apply() {
-	g.client.UpdateRepositoryUpdateTeamAccess(g.dryrun, g.reponame, g.teamslug, g.permission)
+func (g *GithubCommandUpdateRepositoryUpdateTeamAccess) Apply(ctx context.Context) error {
+	return g.client.UpdateRepositoryUpdateTeamAccess(ctx, g.dryrun, g.reponame, g.teamslug, g.permission)
 }
 
 type GithubCommandUpdateRepositoryUpdateArchived struct {
@@ -328,8 +590,8 @@ type GithubCommandUpdateRepositoryUpdateArchived struct {
 	archived bool
 }
 
-func (g *GithubCommandUpdateRepositoryUpdateArchived) Apply() {
-	g.client.UpdateRepositoryUpdateArchived(g.dryrun, g.reponame, g.archived)
+func (g *GithubCommandUpdateRepositoryUpdateArchived) Apply(ctx context.Context) error {
+	return g.client.UpdateRepositoryUpdateArchived(ctx, g.dryrun, g.reponame, g.archived)
 }
 
 type GithubCommandUpdateRepositorySetExternalUser struct {
@@ -340,8 +602,8 @@ type GithubCommandUpdateRepositorySetExternalUser struct {
 	permission string
 }
 
-func (g *GithubCommandUpdateRepositorySetExternalUser) Apply() {
-	g.client.UpdateRepositorySetExternalUser(g.dryrun, g.reponame, g.githubid, g.permission)
+func (g *GithubCommandUpdateRepositorySetExternalUser) Apply(ctx context.Context) error {
+	return g.client.UpdateRepositorySetExternalUser(ctx, g.dryrun, g.reponame, g.githubid, g.permission)
 }
 
 type GithubCommandUpdateRepositoryRemoveExternalUser struct {
@@ -351,8 +613,8 @@ type GithubCommandUpdateRepositoryRemoveExternalUser struct {
 	githubid string
 }
 
-func (g *GithubCommandUpdateRepositoryRemoveExternalUser) Apply() {
-	g.client.UpdateRepositoryRemoveExternalUser(g.dryrun, g.reponame, g.githubid)
+func (g *GithubCommandUpdateRepositoryRemoveExternalUser) Apply(ctx context.Context) error {
+	return g.client.UpdateRepositoryRemoveExternalUser(ctx, g.dryrun, g.reponame, g.githubid)
 }
 
 type GithubCommandUpdateRepositoryUpdatePrivate struct {
@@ -362,8 +624,8 @@ type GithubCommandUpdateRepositoryUpdatePrivate struct {
 	private  bool
 }
 
-func (g *GithubCommandUpdateRepositoryUpdatePrivate) Apply() {
-	g.client.UpdateRepositoryUpdatePrivate(g.dryrun, g.reponame, g.private)
+func (g *GithubCommandUpdateRepositoryUpdatePrivate) Apply(ctx context.Context) error {
+	return g.client.UpdateRepositoryUpdatePrivate(ctx, g.dryrun, g.reponame, g.private)
 }
 
 type GithubCommandUpdateTeamAddMember struct {
@@ -374,8 +636,8 @@ type GithubCommandUpdateTeamAddMember struct {
 	role     string
 }
 
-func (g *GithubCommandUpdateTeamAddMember) Apply() {
-	g.client.UpdateTeamAddMember(g.dryrun, g.teamslug, g.member, g.role)
+func (g *GithubCommandUpdateTeamAddMember) Apply(ctx context.Context) error {
+	return g.client.UpdateTeamAddMember(ctx, g.dryrun, g.teamslug, g.member, g.role)
 }
 
 type GithubCommandUpdateTeamRemoveMember struct {
@@ -385,8 +647,8 @@ type GithubCommandUpdateTeamRemoveMember struct {
 	member   string
 }
 
-func (g *GithubCommandUpdateTeamRemoveMember) Apply() {
-	g.client.UpdateTeamRemoveMember(g.dryrun, g.teamslug, g.member)
+func (g *GithubCommandUpdateTeamRemoveMember) Apply(ctx context.Context) error {
+	return g.client.UpdateTeamRemoveMember(ctx, g.dryrun, g.teamslug, g.member)
 }
 
 type GithubCommandAddRuletset struct {
@@ -395,8 +657,8 @@ type GithubCommandAddRuletset struct {
 	ruleset *engine.GithubRuleSet
 }
 
-func (g *GithubCommandAddRuletset) Apply() {
-	g.client.AddRuleset(g.dryrun, g.ruleset)
+func (g *GithubCommandAddRuletset) Apply(ctx context.Context) error {
+	return g.client.AddRuleset(ctx, g.dryrun, g.ruleset)
 }
 
 type GithubCommandUpdateRuletset struct {
@@ -405,8 +667,8 @@ type GithubCommandUpdateRuletset struct {
 	ruleset *engine.GithubRuleSet
 }
 
-func (g *GithubCommandUpdateRuletset) Apply() {
-	g.client.UpdateRuleset(g.dryrun, g.ruleset)
+func (g *GithubCommandUpdateRuletset) Apply(ctx context.Context) error {
+	return g.client.UpdateRuleset(ctx, g.dryrun, g.ruleset)
 }
 
 type GithubCommandDeleteRuletset struct {
@@ -415,6 +677,136 @@ type GithubCommandDeleteRuletset struct {
 	rulesetid int
 }
 
-func (g *GithubCommandDeleteRuletset) Apply() {
-	g.client.DeleteRuleset(g.dryrun, g.rulesetid)
+func (g *GithubCommandDeleteRuletset) Apply(ctx context.Context) error {
+	return g.client.DeleteRuleset(ctx, g.dryrun, g.rulesetid)
+}
+
+type GithubCommandUpdateRepositorySetVariable struct {
+	client   engine.ReconciliatorExecutor
+	dryrun   bool
+	reponame string
+	name     string
+	value    string
+}
+
+func (g *GithubCommandUpdateRepositorySetVariable) Apply(ctx context.Context) error {
+	return g.client.UpdateRepositorySetVariable(ctx, g.dryrun, g.reponame, g.name, g.value)
+}
+
+type GithubCommandUpdateRepositoryRemoveVariable struct {
+	client   engine.ReconciliatorExecutor
+	dryrun   bool
+	reponame string
+	name     string
+}
+
+func (g *GithubCommandUpdateRepositoryRemoveVariable) Apply(ctx context.Context) error {
+	return g.client.UpdateRepositoryRemoveVariable(ctx, g.dryrun, g.reponame, g.name)
+}
+
+type GithubCommandUpdateRepositorySetSecret struct {
+	client   engine.ReconciliatorExecutor
+	dryrun   bool
+	reponame string
+	name     string
+	value    string
+}
+
+func (g *GithubCommandUpdateRepositorySetSecret) Apply(ctx context.Context) error {
+	return g.client.UpdateRepositorySetSecret(ctx, g.dryrun, g.reponame, g.name, g.value)
+}
+
+type GithubCommandUpdateRepositoryRemoveSecret struct {
+	client   engine.ReconciliatorExecutor
+	dryrun   bool
+	reponame string
+	name     string
+}
+
+func (g *GithubCommandUpdateRepositoryRemoveSecret) Apply(ctx context.Context) error {
+	return g.client.UpdateRepositoryRemoveSecret(ctx, g.dryrun, g.reponame, g.name)
+}
+
+type GithubCommandSetOrgVariable struct {
+	client engine.ReconciliatorExecutor
+	dryrun bool
+	name   string
+	value  string
+}
+
+func (g *GithubCommandSetOrgVariable) Apply(ctx context.Context) error {
+	return g.client.SetOrgVariable(ctx, g.dryrun, g.name, g.value)
+}
+
+type GithubCommandRemoveOrgVariable struct {
+	client engine.ReconciliatorExecutor
+	dryrun bool
+	name   string
+}
+
+func (g *GithubCommandRemoveOrgVariable) Apply(ctx context.Context) error {
+	return g.client.RemoveOrgVariable(ctx, g.dryrun, g.name)
+}
+
+type GithubCommandSetOrgSecret struct {
+	client engine.ReconciliatorExecutor
+	dryrun bool
+	name   string
+	value  string
+}
+
+func (g *GithubCommandSetOrgSecret) Apply(ctx context.Context) error {
+	return g.client.SetOrgSecret(ctx, g.dryrun, g.name, g.value)
+}
+
+type GithubCommandRemoveOrgSecret struct {
+	client engine.ReconciliatorExecutor
+	dryrun bool
+	name   string
+}
+
+func (g *GithubCommandRemoveOrgSecret) Apply(ctx context.Context) error {
+	return g.client.RemoveOrgSecret(ctx, g.dryrun, g.name)
+}
+
+type GithubCommandUpsertCustomProperty struct {
+	client   engine.ReconciliatorExecutor
+	dryrun   bool
+	property *engine.GithubCustomProperty
+}
+
+func (g *GithubCommandUpsertCustomProperty) Apply(ctx context.Context) error {
+	return g.client.UpsertCustomProperty(ctx, g.dryrun, g.property)
+}
+
+type GithubCommandDeleteCustomProperty struct {
+	client engine.ReconciliatorExecutor
+	dryrun bool
+	name   string
+}
+
+func (g *GithubCommandDeleteCustomProperty) Apply(ctx context.Context) error {
+	return g.client.DeleteCustomProperty(ctx, g.dryrun, g.name)
+}
+
+type GithubCommandUpdateRepositorySetCustomProperty struct {
+	client   engine.ReconciliatorExecutor
+	dryrun   bool
+	reponame string
+	name     string
+	value    []string
+}
+
+func (g *GithubCommandUpdateRepositorySetCustomProperty) Apply(ctx context.Context) error {
+	return g.client.UpdateRepositorySetCustomProperty(ctx, g.dryrun, g.reponame, g.name, g.value)
+}
+
+type GithubCommandUpdateOrganizationSettings struct {
+	client   engine.ReconciliatorExecutor
+	dryrun   bool
+	settings *engine.GithubOrganizationSettingsUpdate
+}
+
+func (g *GithubCommandUpdateOrganizationSettings) Apply(ctx context.Context) error {
+	return g.client.UpdateOrganizationSettings(ctx, g.dryrun, g.settings)
 }
@@ -0,0 +1,104 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/Alayacare/goliac/internal/engine"
+	"github.com/Alayacare/goliac/internal/metrics"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+type ReconciliatorExecutorMock struct {
+	engine.ReconciliatorExecutor // only the methods used by the tests are implemented
+	teamsCreated                 []string
+	failingTeams                 map[string]bool
+	cancel                       context.CancelFunc // if set, called after the first team created
+}
+
+func (e *ReconciliatorExecutorMock) CreateTeam(ctx context.Context, dryrun bool, teamname string, description string, members []string) error {
+	if e.failingTeams[teamname] {
+		return fmt.Errorf("failed to create team %s", teamname)
+	}
+	e.teamsCreated = append(e.teamsCreated, teamname)
+	if e.cancel != nil {
+		e.cancel()
+	}
+	return nil
+}
+
+func TestGithubBatchExecutor(t *testing.T) {
+	t.Run("happy path: command names", func(t *testing.T) {
+		assert.Equal(t, "create_team", commandName(&GithubCommandCreateTeam{}))
+		assert.Equal(t, "update_repository_add_team_access", commandName(&GithubCommandUpdateRepositoryAddTeamAccess{}))
+	})
+
+	t.Run("happy path: operations are counted", func(t *testing.T) {
+		before := testutil.ToFloat64(metrics.Operations.WithLabelValues("create_team"))
+
+		executor := &ReconciliatorExecutorMock{}
+		gal := NewGithubBatchExecutor(executor, 10)
+		gal.Begin(context.TODO(), false)
+		gal.CreateTeam(context.TODO(), false, "team1", "team1", []string{})
+		err := gal.Commit(context.TODO(), false)
+
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"team1"}, executor.teamsCreated)
+		assert.Equal(t, before+1, testutil.ToFloat64(metrics.Operations.WithLabelValues("create_team")))
+	})
+
+	t.Run("not happy path: too many changesets", func(t *testing.T) {
+		before := testutil.ToFloat64(metrics.ChangesetsRejected)
+
+		executor := &ReconciliatorExecutorMock{}
+		gal := NewGithubBatchExecutor(executor, 1)
+		gal.Begin(context.TODO(), false)
+		gal.CreateTeam(context.TODO(), false, "team1", "team1", []string{})
+		gal.CreateTeam(context.TODO(), false, "team2", "team2", []string{})
+		err := gal.Commit(context.TODO(), false)
+
+		assert.NotNil(t, err)
+		assert.Equal(t, 0, len(executor.teamsCreated))
+		assert.Equal(t, before+1, testutil.ToFloat64(metrics.ChangesetsRejected))
+	})
+
+	t.Run("not happy path: failed operations are reported", func(t *testing.T) {
+		executor := &ReconciliatorExecutorMock{
+			failingTeams: map[string]bool{"team1": true, "team3": true},
+		}
+		gal := NewGithubBatchExecutor(executor, 10)
+		gal.Begin(context.TODO(), false)
+		gal.CreateTeam(context.TODO(), false, "team1", "team1", []string{})
+		gal.CreateTeam(context.TODO(), false, "team2", "team2", []string{})
+		gal.CreateTeam(context.TODO(), false, "team3", "team3", []string{})
+		err := gal.Commit(context.TODO(), false)
+
+		// the other operations are still applied
+		assert.Equal(t, []string{"team2"}, executor.teamsCreated)
+		assert.NotNil(t, err)
+		applyErrors, ok := err.(engine.ApplyErrors)
+		assert.True(t, ok)
+		assert.Equal(t, 2, len(applyErrors))
+		assert.Contains(t, err.Error(), "failed to create team team1")
+		assert.Contains(t, err.Error(), "failed to create team team3")
+	})
+
+	t.Run("not happy path: a cancelled context stops the commit", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		executor := &ReconciliatorExecutorMock{cancel: cancel}
+		gal := NewGithubBatchExecutor(executor, 10)
+		gal.Begin(ctx, false)
+		gal.CreateTeam(ctx, false, "team1", "team1", []string{})
+		gal.CreateTeam(ctx, false, "team2", "team2", []string{})
+		gal.CreateTeam(ctx, false, "team3", "team3", []string{})
+		err := gal.Commit(ctx, false)
+
+		assert.Equal(t, []string{"team1"}, executor.teamsCreated)
+		assert.NotNil(t, err)
+		assert.Contains(t, err.Error(), context.Canceled.Error())
+	})
+}
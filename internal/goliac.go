@@ -2,16 +2,20 @@ package internal
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"net/url"
 	"path"
 	"path/filepath"
 	"strings"
+	"sync"
 
+	"github.com/Alayacare/goliac/internal/audit"
 	"github.com/Alayacare/goliac/internal/config"
 	"github.com/Alayacare/goliac/internal/engine"
 	"github.com/Alayacare/goliac/internal/entity"
 	"github.com/Alayacare/goliac/internal/github"
+	"github.com/Alayacare/goliac/internal/secrets"
 	"github.com/Alayacare/goliac/internal/usersync"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/afero"
@@ -27,15 +31,33 @@ const (
  */
 type Goliac interface {
 	// will run and apply the reconciliation
-	Apply(dryrun bool, repositoryUrl, branch string, forcesync bool) error
+	Apply(ctx context.Context, dryrun bool, repositoryUrl, branch string, forcesync bool) error
 
 	// will clone run the user-plugin to sync users, and will commit to the team repository
-	UsersUpdate(repositoryUrl, branch string) error
+	UsersUpdate(ctx context.Context, repositoryUrl, branch string) error
+
+	// same as UsersUpdate, but with the given plugin (nil for the goliac.yaml usersync plugin)
+	UsersUpdateWithPlugin(ctx context.Context, repositoryUrl, branch string, userplugin engine.UserSyncPlugin) error
+
+	// will compute the drift between the repository and Github (without applying it)
+	Drift(ctx context.Context, repositoryUrl, branch string) ([]*engine.Drift, error)
+
+	// the drift found during the last resync
+	GetLastDrift() []*engine.Drift
 
 	// flush remote cache
 	FlushCache()
 
+	// expire only some remote caches (engine.Cache*), reloaded on the next sync
+	InvalidateCache(caches ...string)
+
 	GetLocal() engine.GoliacLocalResources
+
+	// the Github login used by Goliac (to recognize its own actions)
+	GetActor() string
+
+	// the audit trail of an entity (like repo/foo or team/bar), the most recent first
+	GetAudit(entity string) ([]*audit.Record, error)
 }
 
 type GoliacImpl struct {
@@ -43,16 +65,74 @@ type GoliacImpl struct {
 	remote       engine.GoliacRemoteExecutor
 	githubClient github.GitHubClient
 	repoconfig   *config.RepositoryConfig
+	lastDrift    []*engine.Drift
+	driftMutex   sync.Mutex
+	auditStore   audit.AuditStore // nil if the audit trail is disabled
 }
 
-func NewGoliacImpl() (Goliac, error) {
-	githubClient, err := github.NewGitHubClientImpl(
+/*
+ * newGithubClient authenticates with the Github App if GOLIAC_GITHUB_APP_ID is set,
+ * else with GOLIAC_GITHUB_TOKEN (or GITHUB_TOKEN), useful to plan locally or in a CI
+ */
+func newGithubClient() (github.GitHubClient, error) {
+	if config.Config.GithubAppID != 0 {
+		loadPrivateKey, err := githubAppPrivateKeyLoader()
+		if err != nil {
+			return nil, err
+		}
+		return github.NewGitHubClientImpl(
+			config.Config.GithubServer,
+			config.Config.GithubAppOrganization,
+			config.Config.GithubAppID,
+			loadPrivateKey,
+		)
+	}
+
+	token := config.Config.GithubToken
+	if token == "" {
+		token = config.Config.GithubActionsToken
+	}
+	if token == "" {
+		return nil, fmt.Errorf("no Github credentials: set GOLIAC_GITHUB_APP_ID (and GOLIAC_GITHUB_APP_PRIVATE_KEY_FILE), or GOLIAC_GITHUB_TOKEN")
+	}
+	logrus.Debug("GOLIAC_GITHUB_APP_ID not set, using a Github token")
+	return github.NewGitHubClientWithToken(
 		config.Config.GithubServer,
 		config.Config.GithubAppOrganization,
-		config.Config.GithubAppID,
-		config.Config.GithubAppPrivateKeyFile,
+		token,
 	)
+}
 
+/*
+ * githubAppPrivateKeyLoader returns where to (re)load the Github App private key from:
+ * GOLIAC_GITHUB_APP_PRIVATE_KEY, a secret provider (GOLIAC_GITHUB_APP_PRIVATE_KEY_SECRET),
+ * or GOLIAC_GITHUB_APP_PRIVATE_KEY_FILE
+ */
+func githubAppPrivateKeyLoader() (github.PrivateKeyLoader, error) {
+	if config.Config.GithubAppPrivateKey != "" {
+		return github.PrivateKeyFromString(config.Config.GithubAppPrivateKey), nil
+	}
+	if config.Config.GithubAppPrivateKeySecret != "" {
+		providername, key, found := strings.Cut(config.Config.GithubAppPrivateKeySecret, ":")
+		if !found {
+			return nil, fmt.Errorf("GOLIAC_GITHUB_APP_PRIVATE_KEY_SECRET must be in the form <provider>:<key>")
+		}
+		provider, ok := engine.GetSecretProvider(providername)
+		if !ok {
+			return nil, fmt.Errorf("secret provider %s not found (for the Github App private key)", providername)
+		}
+		return func() ([]byte, error) {
+			value, err := provider.GetSecret(key)
+			return []byte(value), err
+		}, nil
+	}
+	return github.PrivateKeyFromFile(config.Config.GithubAppPrivateKeyFile), nil
+}
+
+func NewGoliacImpl() (Goliac, error) {
+	secrets.InitProviders()
+
+	githubClient, err := newGithubClient()
 	if err != nil {
 		return nil, err
 	}
@@ -61,11 +141,17 @@ func NewGoliacImpl() (Goliac, error) {
 
 	usersync.InitPlugins(githubClient)
 
+	var auditStore audit.AuditStore
+	if config.Config.AuditFile != "" {
+		auditStore = audit.NewFileAuditStore(config.Config.AuditFile)
+	}
+
 	return &GoliacImpl{
 		local:        engine.NewGoliacLocalImpl(),
 		githubClient: githubClient,
 		remote:       remote,
 		repoconfig:   &config.RepositoryConfig{},
+		auditStore:   auditStore,
 	}, nil
 }
 
@@ -73,12 +159,39 @@ func (g *GoliacImpl) GetLocal() engine.GoliacLocalResources {
 	return g.local
 }
 
+func (g *GoliacImpl) GetActor() string {
+	return g.githubClient.GetActor()
+}
+
 func (g *GoliacImpl) FlushCache() {
 	g.remote.FlushCache()
 }
 
-func (g *GoliacImpl) Apply(dryrun bool, repositoryUrl, branch string, forcesync bool) error {
-	err := g.loadAndValidateGoliacOrganization(repositoryUrl, branch)
+func (g *GoliacImpl) InvalidateCache(caches ...string) {
+	g.remote.InvalidateCache(caches...)
+}
+
+func (g *GoliacImpl) GetAudit(entity string) ([]*audit.Record, error) {
+	if g.auditStore == nil {
+		// the audit trail is not enabled
+		return []*audit.Record{}, nil
+	}
+	return g.auditStore.Query(entity)
+}
+
+/*
+ * executor returns the executor applying the changes to Github
+ * (recording them in the audit trail, if enabled)
+ */
+func (g *GoliacImpl) executor(commitSha string, author string) engine.ReconciliatorExecutor {
+	if g.auditStore == nil {
+		return g.remote
+	}
+	return engine.NewAuditExecutor(g.remote, g.auditStore, commitSha, author)
+}
+
+func (g *GoliacImpl) Apply(ctx context.Context, dryrun bool, repositoryUrl, branch string, forcesync bool) error {
+	err := g.loadAndValidateGoliacOrganization(ctx, repositoryUrl, branch)
 	defer g.local.Close()
 	if err != nil {
 		return fmt.Errorf("failed to load and validate: %s", err)
@@ -90,35 +203,42 @@ func (g *GoliacImpl) Apply(dryrun bool, repositoryUrl, branch string, forcesync
 
 	teamsreponame := strings.TrimSuffix(path.Base(u.Path), filepath.Ext(path.Base(u.Path)))
 
-	err = g.applyToGithub(dryrun, teamsreponame, branch, forcesync)
+	err = g.applyToGithub(ctx, dryrun, teamsreponame, branch, forcesync)
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
-func (g *GoliacImpl) loadAndValidateGoliacOrganization(repositoryUrl, branch string) error {
+func (g *GoliacImpl) loadAndValidateGoliacOrganization(ctx context.Context, repositoryUrl, branch string) error {
 	var errs []error
 	var warns []entity.Warning
 	if strings.HasPrefix(repositoryUrl, "https://") || strings.HasPrefix(repositoryUrl, "git@") {
-		accessToken, err := g.githubClient.GetAccessToken()
+		accessToken, err := g.githubClient.GetAccessToken(ctx)
 		if err != nil {
 			return err
 		}
 
-		err = g.local.Clone(accessToken, repositoryUrl, branch)
+		err = g.local.Clone(ctx, accessToken, repositoryUrl, branch)
 		if err != nil {
 			return fmt.Errorf("unable to clone: %v", err)
 		}
+		if err := g.loadAppliedProtectedUsers(); err != nil {
+			return err
+		}
+		errs, warns = g.local.LoadAndValidate()
+
+		// goliac.yaml was parsed (and validated) by LoadAndValidate
 		err, repoconfig := g.local.LoadRepoConfig()
 		if err != nil {
 			return fmt.Errorf("unable to read goliac.yaml config file: %v", err)
 		}
 		g.repoconfig = repoconfig
-
-		errs, warns = g.local.LoadAndValidate()
 	} else {
 		// Local
+		if err := g.local.LoadAppliedProtectedUsersFromDirectory(repositoryUrl, GOLIAC_GIT_TAG); err != nil {
+			return fmt.Errorf("not able to load the protected users of the %s tag: %v", GOLIAC_GIT_TAG, err)
+		}
 		fs := afero.NewOsFs()
 		errs, warns = g.local.LoadAndValidateLocal(fs, repositoryUrl)
 	}
@@ -141,8 +261,8 @@ func (g *GoliacImpl) loadAndValidateGoliacOrganization(repositoryUrl, branch str
  * we must ensure that the "squqsh and merge" option is the only option.
  * Else we may append to apply commits that are part of a PR, but wasn't the final PR commit state
  */
-func (g *GoliacImpl) forceSquashMergeOnTeamsRepo(teamreponame string, branchname string) error {
-	_, err := g.githubClient.CallRestAPI(fmt.Sprintf("/repos/%s/%s", config.Config.GithubAppOrganization, teamreponame), "PATCH",
+func (g *GoliacImpl) forceSquashMergeOnTeamsRepo(ctx context.Context, teamreponame string, branchname string) error {
+	_, err := g.githubClient.CallRestAPI(ctx, fmt.Sprintf("/repos/%s/%s", config.Config.GithubAppOrganization, teamreponame), "PATCH",
 		map[string]interface{}{
 			"allow_merge_commit": false,
 			"allow_rebase_merge": false,
@@ -158,7 +278,7 @@ func (g *GoliacImpl) forceSquashMergeOnTeamsRepo(teamreponame string, branchname
 	if config.Config.ServerGitBranchProtectionRequiredCheck != "" {
 		contexts = append(contexts, config.Config.ServerGitBranchProtectionRequiredCheck)
 	}
-	_, err = g.githubClient.CallRestAPI(fmt.Sprintf("/repos/%s/%s/branches/%s/protection", config.Config.GithubAppOrganization, teamreponame, branchname), "PUT",
+	_, err = g.githubClient.CallRestAPI(ctx, fmt.Sprintf("/repos/%s/%s/branches/%s/protection", config.Config.GithubAppOrganization, teamreponame, branchname), "PUT",
 		map[string]interface{}{
 			"required_status_checks": map[string]interface{}{
 				"strict":   true,     // // This ensures branches are up to date before merging
@@ -177,14 +297,14 @@ func (g *GoliacImpl) forceSquashMergeOnTeamsRepo(teamreponame string, branchname
 	return err
 }
 
-func (g *GoliacImpl) applyToGithub(dryrun bool, teamreponame string, branch string, forceresync bool) error {
-	err := g.remote.Load()
+func (g *GoliacImpl) applyToGithub(ctx context.Context, dryrun bool, teamreponame string, branch string, forceresync bool) error {
+	err := g.remote.Load(ctx)
 	if err != nil {
 		return fmt.Errorf("Error when fetching data from Github: %v", err)
 	}
 
 	if !dryrun {
-		err := g.forceSquashMergeOnTeamsRepo(teamreponame, branch)
+		err := g.forceSquashMergeOnTeamsRepo(ctx, teamreponame, branch)
 		if err != nil {
 			logrus.Errorf("Error when ensuring PR on %s repo can only be done via squash and merge: %v", teamreponame, err)
 		}
@@ -193,11 +313,13 @@ func (g *GoliacImpl) applyToGithub(dryrun bool, teamreponame string, branch stri
 	commits, err := g.local.ListCommitsFromTag(GOLIAC_GIT_TAG)
 	// if we can get commits
 	if err != nil {
-		ga := NewGithubBatchExecutor(g.remote, g.repoconfig.MaxChangesets)
+		commitSha := ""
+		if commit, err := g.local.GetHeadCommit(); err == nil {
+			commitSha = commit.Hash.String()
+		}
+		ga := NewGithubBatchExecutor(g.executor(commitSha, ""), g.repoconfig.MaxChangesets)
 		reconciliator := engine.NewGoliacReconciliatorImpl(ga, g.repoconfig)
 
-		ctx := context.TODO()
-
 		err = reconciliator.Reconciliate(ctx, g.local, g.remote, teamreponame, dryrun)
 		if err != nil {
 			return fmt.Errorf("Error when reconciliating: %v", err)
@@ -206,24 +328,43 @@ func (g *GoliacImpl) applyToGithub(dryrun bool, teamreponame string, branch stri
 		// or if are not in enterprise mode and cannot guarrantee that PR commits are squashed
 	} else if (len(commits) == 0 && forceresync) || !g.remote.IsEnterprise() {
 
-		ga := NewGithubBatchExecutor(g.remote, g.repoconfig.MaxChangesets)
-		reconciliator := engine.NewGoliacReconciliatorImpl(ga, g.repoconfig)
-		commit, err := g.local.GetHeadCommit()
-
-		ctx := context.TODO()
-
-		if err == nil {
-			ctx = context.WithValue(context.TODO(), engine.KeyAuthor, fmt.Sprintf("%s <%s>", commit.Author.Name, commit.Author.Email))
+		commit, headErr := g.local.GetHeadCommit()
+		commitSha := ""
+		if headErr == nil {
+			commitSha = commit.Hash.String()
 		}
+		if len(commits) == 0 {
+			// no new commit to apply: every change is a drift
+			ga := NewGithubBatchExecutor(g.executor(commitSha, "goliac (drift)"), g.repoconfig.MaxChangesets)
+			drifts, err := g.reconciliateDrift(ctx, ga, teamreponame, dryrun)
+			if err != nil {
+				return fmt.Errorf("Error when reconciliating: %v", err)
+			}
+			g.driftMutex.Lock()
+			g.lastDrift = drifts
+			g.driftMutex.Unlock()
+		} else {
+			author := ""
+			if headErr == nil {
+				author = fmt.Sprintf("%s <%s>", commit.Author.Name, commit.Author.Email)
+				ctx = context.WithValue(ctx, engine.KeyAuthor, author)
+			}
 
-		err = reconciliator.Reconciliate(ctx, g.local, g.remote, teamreponame, dryrun)
-		if err != nil {
-			return fmt.Errorf("Error when reconciliating: %v", err)
+			ga := NewGithubBatchExecutor(g.executor(commitSha, author), g.repoconfig.MaxChangesets)
+			reconciliator := engine.NewGoliacReconciliatorImpl(ga, g.repoconfig)
+			err = reconciliator.Reconciliate(ctx, g.local, g.remote, teamreponame, dryrun)
+			if err != nil {
+				return fmt.Errorf("Error when reconciliating: %v", err)
+			}
 		}
 	} else {
 		// we have 1 or more commits to apply
 		for _, commit := range commits {
 			if err := g.local.CheckoutCommit(commit); err == nil {
+				// the tag is moved after each applied commit
+				if err := g.loadAppliedProtectedUsers(); err != nil {
+					return err
+				}
 				errs, _ := g.local.LoadAndValidate()
 				if len(errs) > 0 {
 					for _, err := range errs {
@@ -231,44 +372,205 @@ func (g *GoliacImpl) applyToGithub(dryrun bool, teamreponame string, branch stri
 					}
 					continue
 				}
-				ga := NewGithubBatchExecutor(g.remote, g.repoconfig.MaxChangesets)
+				author := fmt.Sprintf("%s <%s>", commit.Author.Name, commit.Author.Email)
+				ga := NewGithubBatchExecutor(g.executor(commit.Hash.String(), author), g.repoconfig.MaxChangesets)
 				reconciliator := engine.NewGoliacReconciliatorImpl(ga, g.repoconfig)
 
-				ctx := context.WithValue(context.TODO(), engine.KeyAuthor, fmt.Sprintf("%s <%s>", commit.Author.Name, commit.Author.Email))
-				err = reconciliator.Reconciliate(ctx, g.local, g.remote, teamreponame, dryrun)
+				commitCtx := context.WithValue(ctx, engine.KeyAuthor, author)
+				err = reconciliator.Reconciliate(commitCtx, g.local, g.remote, teamreponame, dryrun)
 				if err != nil {
-					return fmt.Errorf("Error when reconciliating: %v", err)
+					// the goliac tag is not moved past this commit: it will be applied again on the next sync
+					return fmt.Errorf("Error when reconciliating commit %s: %v", commit.Hash.String(), err)
 				}
 				if !dryrun {
-					accessToken, err := g.githubClient.GetAccessToken()
+					accessToken, err := g.githubClient.GetAccessToken(ctx)
 					if err != nil {
 						return err
 					}
-					g.local.PushTag(GOLIAC_GIT_TAG, commit.Hash, accessToken)
+					if err := g.local.PushTag(ctx, GOLIAC_GIT_TAG, commit.Hash, accessToken); err != nil {
+						return fmt.Errorf("Error when moving the %s tag to commit %s: %v", GOLIAC_GIT_TAG, commit.Hash.String(), err)
+					}
 				}
 			} else {
 				logrus.Errorf("Not able to checkout commit %s", commit.Hash.String())
 			}
 		}
 	}
-	accessToken, err := g.githubClient.GetAccessToken()
+	accessToken, err := g.githubClient.GetAccessToken(ctx)
 	if err != nil {
 		return err
 	}
-	err = g.local.UpdateAndCommitCodeOwners(g.repoconfig, dryrun, accessToken, branch, GOLIAC_GIT_TAG)
+	err = g.local.UpdateAndCommitCodeOwners(ctx, g.repoconfig, dryrun, accessToken, branch, GOLIAC_GIT_TAG)
 	if err != nil {
 		return fmt.Errorf("Error when updating and commiting: %v", err)
 	}
 	return nil
 }
 
-func (g *GoliacImpl) UsersUpdate(repositoryUrl, branch string) error {
-	accessToken, err := g.githubClient.GetAccessToken()
+/*
+ * loadAppliedProtectedUsers loads the protected users of the goliac tag,
+ * that cannot be removed from the organization (to be called before LoadAndValidate)
+ */
+func (g *GoliacImpl) loadAppliedProtectedUsers() error {
+	if err := g.local.LoadAppliedProtectedUsers(GOLIAC_GIT_TAG); err != nil {
+		return fmt.Errorf("not able to load the protected users of the %s tag: %v", GOLIAC_GIT_TAG, err)
+	}
+	return nil
+}
+
+func (g *GoliacImpl) Drift(ctx context.Context, repositoryUrl, branch string) ([]*engine.Drift, error) {
+	err := g.loadAndValidateGoliacOrganization(ctx, repositoryUrl, branch)
+	defer g.local.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load and validate: %s", err)
+	}
+	u, err := url.Parse(repositoryUrl)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %v", repositoryUrl, err)
+	}
+	teamsreponame := strings.TrimSuffix(path.Base(u.Path), filepath.Ext(path.Base(u.Path)))
+
+	// the drift is computed against the last applied commit (the goliac tag):
+	// the commits not applied yet are not drifts
+	if err := g.checkoutAppliedCommit(); err != nil {
+		return nil, err
+	}
+
+	err = g.remote.Load(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("Error when fetching data from Github: %v", err)
+	}
+
+	// nothing is applied: the drift is computed in dryrun mode
+	ga := NewGithubBatchExecutor(g.remote, g.repoconfig.MaxChangesets)
+	return g.reconciliateDrift(ctx, ga, teamsreponame, true)
+}
+
+/*
+ * checkoutAppliedCommit checks out (and loads) the commit pointed by the goliac tag,
+ * if HEAD is not already there
+ */
+func (g *GoliacImpl) checkoutAppliedCommit() error {
+	tagCommit, err := g.local.GetTagCommit(GOLIAC_GIT_TAG)
+	if err != nil {
+		logrus.Warnf("no %s tag (nothing was applied yet?): the drift is computed from the HEAD commit", GOLIAC_GIT_TAG)
+		return nil
+	}
+	headCommit, err := g.local.GetHeadCommit()
+	if err != nil {
+		return err
+	}
+	if headCommit.Hash == tagCommit.Hash {
+		return nil
+	}
+
+	logrus.Infof("some commits are not applied yet: the drift is computed from the %s tag (commit %s)", GOLIAC_GIT_TAG, tagCommit.Hash.String())
+	if err := g.local.CheckoutCommit(tagCommit); err != nil {
+		return fmt.Errorf("not able to checkout the %s tag: %v", GOLIAC_GIT_TAG, err)
+	}
+	errs, _ := g.local.LoadAndValidate()
+	if len(errs) > 0 {
+		return fmt.Errorf("not able to load the %s tag commit: %v", GOLIAC_GIT_TAG, errs[0])
+	}
+	err, repoconfig := g.local.LoadRepoConfig()
+	if err != nil {
+		return fmt.Errorf("unable to read goliac.yaml config file: %v", err)
+	}
+	g.repoconfig = repoconfig
+	return nil
+}
+
+func (g *GoliacImpl) GetLastDrift() []*engine.Drift {
+	g.driftMutex.Lock()
+	defer g.driftMutex.Unlock()
+	return g.lastDrift
+}
+
+/*
+ * reconciliateDrift reconciliates the HEAD commit (when there is no new commit to apply)
+ * following the drift policy, and returns the drift found (with who made it, if known)
+ */
+func (g *GoliacImpl) reconciliateDrift(ctx context.Context, executor engine.ReconciliatorExecutor, teamreponame string, dryrun bool) ([]*engine.Drift, error) {
+	driftExecutor := engine.NewDriftExecutor(executor, g.repoconfig.DriftPolicy)
+	reconciliator := engine.NewGoliacReconciliatorImpl(driftExecutor, g.repoconfig)
+
+	driftCtx := context.WithValue(ctx, engine.KeyAuthor, "goliac (drift)")
+	err := reconciliator.Reconciliate(driftCtx, g.local, g.remote, teamreponame, dryrun)
+	if err != nil {
+		return nil, err
+	}
+
+	drifts := driftExecutor.Drifts()
+	for _, d := range drifts {
+		logrus.WithFields(map[string]interface{}{"resource": d.Resource, "name": d.Name, "policy": d.Policy}).Warnf("drift detected: %s (%s)", d.Details, d.Command)
+	}
+	if g.remote.IsEnterprise() {
+		g.findDriftAuthors(ctx, drifts)
+	}
+	return drifts, nil
+}
+
+type AuditLogEntry struct {
+	Actor  string `json:"actor"`
+	Action string `json:"action"`
+}
+
+/*
+ * findDriftAuthors looks into the organization audit log (Github Enterprise only)
+ * who made the last change on each drifted resource
+ */
+func (g *GoliacImpl) findDriftAuthors(ctx context.Context, drifts []*engine.Drift) {
+	authors := make(map[string]string)
+	for _, d := range drifts {
+		phrase := ""
+		switch d.Resource {
+		case engine.DriftResourceRepositories, engine.DriftResourceActions, engine.DriftResourceCustomProperties:
+			phrase = fmt.Sprintf("repo:%s/%s", config.Config.GithubAppOrganization, d.Name)
+		case engine.DriftResourceTeams:
+			phrase = fmt.Sprintf("team:%s/%s", config.Config.GithubAppOrganization, d.Name)
+		case engine.DriftResourceUsers:
+			phrase = fmt.Sprintf("user:%s", d.Name)
+		default:
+			continue
+		}
+		if author, ok := authors[phrase]; ok {
+			d.Author = author
+			continue
+		}
+
+		body, err := g.githubClient.CallRestAPI(ctx, fmt.Sprintf("/orgs/%s/audit-log?phrase=%s&per_page=10", config.Config.GithubAppOrganization, url.QueryEscape(phrase)), "GET", nil)
+		if err != nil {
+			logrus.Debugf("not able to query the audit log for %s: %v", phrase, err)
+			authors[phrase] = d.Author
+			continue
+		}
+		var entries []AuditLogEntry
+		if err := json.Unmarshal(body, &entries); err != nil {
+			authors[phrase] = d.Author
+			continue
+		}
+		// skip what Goliac did itself
+		for _, e := range entries {
+			if e.Actor != "" && e.Actor != g.githubClient.GetActor() {
+				d.Author = e.Actor
+				break
+			}
+		}
+		authors[phrase] = d.Author
+	}
+}
+
+func (g *GoliacImpl) UsersUpdate(ctx context.Context, repositoryUrl, branch string) error {
+	return g.UsersUpdateWithPlugin(ctx, repositoryUrl, branch, nil)
+}
+
+func (g *GoliacImpl) UsersUpdateWithPlugin(ctx context.Context, repositoryUrl, branch string, userplugin engine.UserSyncPlugin) error {
+	accessToken, err := g.githubClient.GetAccessToken(ctx)
 	if err != nil {
 		return err
 	}
 
-	err = g.local.Clone(accessToken, repositoryUrl, branch)
+	err = g.local.Clone(ctx, accessToken, repositoryUrl, branch)
 	if err != nil {
 		return err
 	}
@@ -279,11 +581,21 @@ func (g *GoliacImpl) UsersUpdate(repositoryUrl, branch string) error {
 		return fmt.Errorf("unable to read goliac.yaml config file: %v", err)
 	}
 
-	userplugin, found := engine.GetUserSyncPlugin(g.repoconfig.UserSync.Plugin)
-	if !found {
-		return fmt.Errorf("User Sync Plugin %s not found", g.repoconfig.UserSync.Plugin)
+	if userplugin == nil {
+		var found bool
+		userplugin, found = engine.GetUserSyncPlugin(repoconfig.UserSync.Plugin)
+		if !found {
+			return fmt.Errorf("User Sync Plugin %s not found", repoconfig.UserSync.Plugin)
+		}
 	}
 
-	err = g.local.SyncUsersAndTeams(repoconfig, userplugin, false)
-	return err
+	report, err := g.local.SyncUsersAndTeams(ctx, repoconfig, userplugin, accessToken, branch, false)
+	if err != nil {
+		return err
+	}
+
+	if repoconfig.UserSync.PullRequest {
+		return syncUsersPullRequest(ctx, g.githubClient, repositoryUrl, branch, report)
+	}
+	return nil
 }
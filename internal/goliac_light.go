@@ -32,6 +32,10 @@ func NewGoliacLightImpl() (GoliacLight, error) {
 }
 
 func (g *GoliacLightImpl) Validate(path string) error {
+	// if path is a git clone (with tags), the removal of a protected user is checked
+	if err := g.local.LoadAppliedProtectedUsersFromDirectory(path, GOLIAC_GIT_TAG); err != nil {
+		return fmt.Errorf("not able to load the protected users of the %s tag: %v", GOLIAC_GIT_TAG, err)
+	}
 	fs := afero.NewOsFs()
 	errs, warns := g.local.LoadAndValidateLocal(fs, path)
 
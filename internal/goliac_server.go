@@ -1,15 +1,19 @@
 package internal
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"os/signal"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 
 	"github.com/Alayacare/goliac/internal/config"
 	"github.com/Alayacare/goliac/internal/entity"
+	"github.com/Alayacare/goliac/internal/metrics"
 	"github.com/Alayacare/goliac/swagger_gen/models"
 	"github.com/Alayacare/goliac/swagger_gen/restapi"
 	"github.com/Alayacare/goliac/swagger_gen/restapi/operations"
@@ -17,6 +21,7 @@ import (
 	"github.com/Alayacare/goliac/swagger_gen/restapi/operations/health"
 	"github.com/go-openapi/loads"
 	"github.com/go-openapi/runtime/middleware"
+	"github.com/gosimple/slug"
 	"github.com/sirupsen/logrus"
 )
 
@@ -32,6 +37,9 @@ type GoliacServer interface {
 	PostFlushCache(app.PostFlushCacheParams) middleware.Responder
 	PostResync(app.PostResyncParams) middleware.Responder
 	GetStatus(app.GetStatusParams) middleware.Responder
+	GetDrift(app.GetDriftParams) middleware.Responder
+	GetAudit(app.GetAuditParams) middleware.Responder
+	GetOrphans(app.GetOrphansParams) middleware.Responder
 
 	GetUsers(app.GetUsersParams) middleware.Responder
 	GetUser(app.GetUserParams) middleware.Responder
@@ -49,16 +57,25 @@ type GoliacServerImpl struct {
 	applyLobbyCond  *sync.Cond
 	applyCurrent    bool
 	applyLobby      bool
-	ready           bool // when the server has finished to load the local configuration
+	ready           bool       // when the server has finished to load the local configuration
+	syncStatusMutex sync.Mutex // protects lastSyncTime and lastSyncError
 	lastSyncTime    *time.Time
 	lastSyncError   error
-	syncInterval    int64 // in seconds time remaining between 2 sync
+	syncInterval    atomic.Int64    // in seconds time remaining between 2 sync
+	driftScheduled  atomic.Bool     // when the next sync must be a full resync (see webhook)
+	ctx             context.Context // cancelled when the server is stopping
+	cancel          context.CancelFunc
+	scimMutex       sync.Mutex
+	scimPending     map[string]*entity.User // users changes received on the SCIM endpoint, not committed yet (nil for a deleted user)
 }
 
 func NewGoliacServer(goliac Goliac) GoliacServer {
+	ctx, cancel := context.WithCancel(context.Background())
 	server := GoliacServerImpl{
 		goliac: goliac,
 		ready:  false,
+		ctx:    ctx,
+		cancel: cancel,
 	}
 	server.applyLobbyCond = sync.NewCond(&server.applyLobbyMutex)
 
@@ -416,15 +433,83 @@ func (g *GoliacServerImpl) GetStatus(app.GetStatusParams) middleware.Responder {
 		NbUsers:         int64(len(g.goliac.GetLocal().Users())),
 		NbUsersExternal: int64(len(g.goliac.GetLocal().ExternalUsers())),
 	}
+	g.syncStatusMutex.Lock()
 	if g.lastSyncError != nil {
 		s.LastSyncError = g.lastSyncError.Error()
 	}
 	if g.lastSyncTime != nil {
 		s.LastSyncTime = g.lastSyncTime.UTC().Format("2006-01-02T15:04:05")
 	}
+	g.syncStatusMutex.Unlock()
+	local := g.goliac.GetLocal()
+	for _, e := range entity.ListExpirations(local.Repositories(), local.ExternalUsers(), time.Now(), entity.ExpirationWarningDays*24*time.Hour) {
+		s.UpcomingExpirations = append(s.UpcomingExpirations, &models.Expiration{
+			Username:   e.Username,
+			Githubid:   e.GithubID,
+			Repository: e.Repository,
+			Expires:    e.Date,
+		})
+	}
 	return app.NewGetStatusOK().WithPayload(&s)
 }
 
+func (g *GoliacServerImpl) GetDrift(app.GetDriftParams) middleware.Responder {
+	drifts := make(models.Drifts, 0)
+	for _, d := range g.goliac.GetLastDrift() {
+		drifts = append(drifts, &models.Drift{
+			Resource:   d.Resource,
+			Name:       d.Name,
+			Command:    d.Command,
+			Details:    d.Details,
+			Policy:     d.Policy,
+			Author:     d.Author,
+			DetectedAt: d.DetectedAt.UTC().Format("2006-01-02T15:04:05"),
+		})
+	}
+	return app.NewGetDriftOK().WithPayload(drifts)
+}
+
+func (g *GoliacServerImpl) GetAudit(params app.GetAuditParams) middleware.Responder {
+	entity := params.Entity
+	// teams are recorded by their Github slug
+	if strings.HasPrefix(entity, "team/") {
+		entity = "team/" + slug.Make(strings.TrimPrefix(entity, "team/"))
+	}
+
+	records, err := g.goliac.GetAudit(entity)
+	if err != nil {
+		message := fmt.Sprintf("Not able to get the audit trail of %s: %v", params.Entity, err)
+		return app.NewGetAuditDefault(500).WithPayload(&models.Error{Message: &message})
+	}
+
+	auditRecords := make(models.AuditRecords, 0, len(records))
+	for _, r := range records {
+		auditRecords = append(auditRecords, &models.AuditRecord{
+			Timestamp:  r.Timestamp.UTC().Format("2006-01-02T15:04:05"),
+			CommitSha:  r.CommitSha,
+			Author:     r.Author,
+			Operation:  r.Operation,
+			Entities:   r.Entities,
+			Parameters: r.Parameters,
+			Success:    r.Success,
+			Error:      r.Error,
+		})
+	}
+	return app.NewGetAuditOK().WithPayload(auditRecords)
+}
+
+func (g *GoliacServerImpl) GetOrphans(app.GetOrphansParams) middleware.Responder {
+	local := g.goliac.GetLocal()
+	orphans := make(models.Orphans, 0)
+	for _, o := range entity.ListOrphans(local.Teams(), local.Repositories(), local.Users()) {
+		orphans = append(orphans, &models.Orphan{
+			Team:         o.Team,
+			Repositories: o.Repositories,
+		})
+	}
+	return app.NewGetOrphansOK().WithPayload(orphans)
+}
+
 func (g *GoliacServerImpl) GetLiveness(params health.GetLivenessParams) middleware.Responder {
 	return health.NewGetLivenessOK().WithPayload(&models.Health{Status: "OK"})
 }
@@ -444,24 +529,44 @@ func (g *GoliacServerImpl) PostFlushCache(app.PostFlushCacheParams) middleware.R
 }
 
 func (g *GoliacServerImpl) PostResync(app.PostResyncParams) middleware.Responder {
-	go func() {
-		err, applied := g.serveApply(true)
-		if !applied && err == nil {
-			// the run was skipped
-			g.syncInterval = config.Config.ServerApplyInterval
-		} else {
-			now := time.Now()
-			g.lastSyncTime = &now
-			g.lastSyncError = err
-			if err != nil {
-				logrus.Error(err)
-			}
-			g.syncInterval = config.Config.ServerApplyInterval
-		}
-	}()
+	go g.sync(true)
 	return app.NewPostResyncOK()
 }
 
+/*
+ * sync runs serveApply, records the result,
+ * and resets the interval until the next periodic sync
+ */
+func (g *GoliacServerImpl) sync(forceresync bool) {
+	err, applied := g.serveApply(g.ctx, forceresync)
+	if !applied && err == nil {
+		// the run was skipped
+		g.syncInterval.Store(config.Config.ServerApplyInterval)
+	} else {
+		now := time.Now()
+		g.syncStatusMutex.Lock()
+		g.lastSyncTime = &now
+		g.lastSyncError = err
+		g.syncStatusMutex.Unlock()
+		if err != nil {
+			logrus.Error(err)
+		}
+		g.syncInterval.Store(config.Config.ServerApplyInterval)
+	}
+}
+
+/*
+ * scheduleSync asks the Serve loop to sync in (at most) delay seconds
+ */
+func (g *GoliacServerImpl) scheduleSync(delay int64) {
+	for {
+		current := g.syncInterval.Load()
+		if current <= delay || g.syncInterval.CompareAndSwap(current, delay) {
+			return
+		}
+	}
+}
+
 func (g *GoliacServerImpl) Serve() {
 	var wg sync.WaitGroup
 	stopCh := make(chan struct{})
@@ -484,30 +589,18 @@ func (g *GoliacServerImpl) Serve() {
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
-		g.syncInterval = 0
+		g.syncInterval.Store(0)
 		for {
 			select {
 			case <-stopCh:
 				restserver.Shutdown()
 				return
 			default:
-				g.syncInterval--
+				g.syncInterval.Add(-1)
 				time.Sleep(1 * time.Second)
-				if g.syncInterval <= 0 {
+				if g.syncInterval.Load() <= 0 {
 					// Do some work here
-					err, applied := g.serveApply(false)
-					if !applied && err == nil {
-						// the run was skipped
-						g.syncInterval = config.Config.ServerApplyInterval
-					} else {
-						now := time.Now()
-						g.lastSyncTime = &now
-						g.lastSyncError = err
-						if err != nil {
-							logrus.Error(err)
-						}
-						g.syncInterval = config.Config.ServerApplyInterval
-					}
+					g.sync(g.driftScheduled.Swap(false))
 				}
 			}
 		}
@@ -519,6 +612,8 @@ func (g *GoliacServerImpl) Serve() {
 	<-signalCh
 	logrus.Info("Received OS signal, stopping Goliac...")
 
+	// stop the sync in progress (if any)
+	g.cancel()
 	close(stopCh)
 	wg.Wait()
 }
@@ -540,6 +635,9 @@ func (g *GoliacServerImpl) StartRESTApi() (*restapi.Server, error) {
 	api.AppPostFlushCacheHandler = app.PostFlushCacheHandlerFunc(g.PostFlushCache)
 	api.AppPostResyncHandler = app.PostResyncHandlerFunc(g.PostResync)
 	api.AppGetStatusHandler = app.GetStatusHandlerFunc(g.GetStatus)
+	api.AppGetDriftHandler = app.GetDriftHandlerFunc(g.GetDrift)
+	api.AppGetAuditHandler = app.GetAuditHandlerFunc(g.GetAudit)
+	api.AppGetOrphansHandler = app.GetOrphansHandlerFunc(g.GetOrphans)
 
 	api.AppGetUsersHandler = app.GetUsersHandlerFunc(g.GetUsers)
 	api.AppGetUserHandler = app.GetUserHandlerFunc(g.GetUser)
@@ -557,10 +655,14 @@ func (g *GoliacServerImpl) StartRESTApi() (*restapi.Server, error) {
 
 	server.ConfigureAPI()
 
+	// the Github webhook needs the raw body (to check the signature)
+	// and SCIM has its own schemas, so they are served outside of the swagger API
+	server.SetHandler(g.webhookMiddleware(g.scimMiddleware(server.GetHandler())))
+
 	return server, nil
 }
 
-func (g *GoliacServerImpl) serveApply(forceresync bool) (error, bool) {
+func (g *GoliacServerImpl) serveApply(ctx context.Context, forceresync bool) (error, bool) {
 	// we want to run ApplyToGithub
 	// and queue one new run (the lobby) if a new run is asked
 	g.applyLobbyMutex.Lock()
@@ -598,9 +700,24 @@ func (g *GoliacServerImpl) serveApply(forceresync bool) (error, bool) {
 	// we are ready (to give local state, and to sync with remote)
 	g.ready = true
 
-	err := g.goliac.Apply(false, repo, branch, forceresync)
+	if config.Config.ServerApplyTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(config.Config.ServerApplyTimeout)*time.Second)
+		defer cancel()
+	}
+
+	// commit the users received on the SCIM endpoint first, to apply them right away
+	if err := g.commitScimChanges(ctx, repo, branch); err != nil {
+		logrus.Error(err)
+	}
+
+	startTime := time.Now()
+	err := g.goliac.Apply(ctx, false, repo, branch, forceresync)
 	if err != nil {
+		metrics.ApplyDuration.WithLabelValues("failure").Observe(time.Since(startTime).Seconds())
 		return fmt.Errorf("failed to apply on branch %s: %s", branch, err), false
 	}
+	metrics.ApplyDuration.WithLabelValues("success").Observe(time.Since(startTime).Seconds())
+	metrics.LastApplySuccess.SetToCurrentTime()
 	return nil, true
 }
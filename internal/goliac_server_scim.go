@@ -0,0 +1,373 @@
+package internal
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Alayacare/goliac/internal/config"
+	"github.com/Alayacare/goliac/internal/entity"
+	"github.com/Alayacare/goliac/internal/usersync"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/afero"
+)
+
+// how long (in seconds) we wait before committing the SCIM changes,
+// to regroup a burst of provisioning requests into one commit
+const scimCommitDelay = 30
+
+const scimMaxPayloadSize = 1024 * 1024
+
+const (
+	scimSchemaUser         = "urn:ietf:params:scim:schemas:core:2.0:User"
+	scimSchemaListResponse = "urn:ietf:params:scim:api:messages:2.0:ListResponse"
+	scimSchemaError        = "urn:ietf:params:scim:api:messages:2.0:Error"
+)
+
+// the only filter the IdPs use to look for an existing user
+var scimUserNameFilter = regexp.MustCompile(`^userName eq "([^"]*)"$`)
+
+type scimPatchRequest struct {
+	Operations []struct {
+		Op    string      `json:"op"`
+		Path  string      `json:"path"`
+		Value interface{} `json:"value"`
+	} `json:"Operations"`
+}
+
+func (g *GoliacServerImpl) scimMiddleware(next http.Handler) http.Handler {
+	prefix := config.Config.WebPrefix + "/scim/v2/"
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.URL.Path, prefix) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		g.ServeScim(w, r, strings.TrimPrefix(r.URL.Path, prefix))
+	})
+}
+
+/*
+ * ServeScim receives the SCIM 2.0 provisioning requests of an IdP (Okta, Entra ID, ...)
+ * on /scim/v2/Users. The changes are queued, and committed into users/org
+ * (like the syncusers command does) before the next sync
+ */
+func (g *GoliacServerImpl) ServeScim(w http.ResponseWriter, r *http.Request, resource string) {
+	if config.Config.ServerScimToken == "" {
+		http.Error(w, "SCIM endpoint not configured", http.StatusNotFound)
+		return
+	}
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if subtle.ConstantTimeCompare([]byte(token), []byte(config.Config.ServerScimToken)) != 1 {
+		scimError(w, http.StatusUnauthorized, "invalid token")
+		return
+	}
+
+	endpoint, id, _ := strings.Cut(resource, "/")
+	if endpoint != "Users" {
+		scimError(w, http.StatusNotFound, fmt.Sprintf("%s is not supported", endpoint))
+		return
+	}
+
+	var body map[string]interface{}
+	if r.Method == http.MethodPost || r.Method == http.MethodPut || r.Method == http.MethodPatch {
+		payload, err := io.ReadAll(io.LimitReader(r.Body, scimMaxPayloadSize))
+		if err != nil || json.Unmarshal(payload, &body) != nil {
+			scimError(w, http.StatusBadRequest, "invalid payload")
+			return
+		}
+	}
+
+	g.scimMutex.Lock()
+	defer g.scimMutex.Unlock()
+	users := g.scimUsers()
+
+	switch {
+	case r.Method == http.MethodGet && id == "":
+		g.scimListUsers(w, r, users)
+
+	case r.Method == http.MethodGet:
+		user, ok := users[id]
+		if !ok {
+			scimError(w, http.StatusNotFound, fmt.Sprintf("user %s not found", id))
+			return
+		}
+		scimWrite(w, http.StatusOK, userToScim(user))
+
+	case r.Method == http.MethodPost && id == "":
+		user, err := scimToUser(body)
+		if err != nil {
+			scimError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if _, ok := users[user.Name]; ok {
+			scimError(w, http.StatusConflict, fmt.Sprintf("user %s already exists", user.Name))
+			return
+		}
+		logrus.Infof("scim: user %s created", user.Name)
+		g.scimQueue(user.Name, user)
+		scimWrite(w, http.StatusCreated, userToScim(user))
+
+	case (r.Method == http.MethodPut || r.Method == http.MethodPatch) && id != "":
+		current, ok := users[id]
+		if !ok {
+			scimError(w, http.StatusNotFound, fmt.Sprintf("user %s not found", id))
+			return
+		}
+		if r.Method == http.MethodPatch {
+			patched, err := applyScimPatch(userToScim(current), body)
+			if err != nil {
+				scimError(w, http.StatusBadRequest, fmt.Sprintf("invalid patch: %v", err))
+				return
+			}
+			body = patched
+		}
+		if active, ok := body["active"].(bool); ok && !active {
+			logrus.Infof("scim: user %s deactivated", id)
+			g.scimQueue(id, nil)
+			body["id"] = id
+			scimWrite(w, http.StatusOK, body)
+			return
+		}
+		user, err := scimToUser(body)
+		if err != nil {
+			scimError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if user.Name != id {
+			// a renamed user is a new user file
+			g.scimQueue(id, nil)
+		}
+		logrus.Infof("scim: user %s updated", user.Name)
+		g.scimQueue(user.Name, user)
+		scimWrite(w, http.StatusOK, userToScim(user))
+
+	case r.Method == http.MethodDelete && id != "":
+		if _, ok := users[id]; !ok {
+			scimError(w, http.StatusNotFound, fmt.Sprintf("user %s not found", id))
+			return
+		}
+		logrus.Infof("scim: user %s deleted", id)
+		g.scimQueue(id, nil)
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		scimError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+func (g *GoliacServerImpl) scimListUsers(w http.ResponseWriter, r *http.Request, users map[string]*entity.User) {
+	names := make([]string, 0, len(users))
+	if filter := r.URL.Query().Get("filter"); filter != "" {
+		match := scimUserNameFilter.FindStringSubmatch(filter)
+		if match == nil {
+			scimError(w, http.StatusBadRequest, fmt.Sprintf("unsupported filter: %s", filter))
+			return
+		}
+		if _, ok := users[match[1]]; ok {
+			names = append(names, match[1])
+		}
+	} else {
+		for name := range users {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	startIndex, err := strconv.Atoi(r.URL.Query().Get("startIndex"))
+	if err != nil || startIndex < 1 {
+		startIndex = 1
+	}
+	count, err := strconv.Atoi(r.URL.Query().Get("count"))
+	if err != nil || count < 0 {
+		count = len(names)
+	}
+	resources := []map[string]interface{}{}
+	for i := startIndex - 1; i < len(names) && len(resources) < count; i++ {
+		resources = append(resources, userToScim(users[names[i]]))
+	}
+
+	scimWrite(w, http.StatusOK, map[string]interface{}{
+		"schemas":      []string{scimSchemaListResponse},
+		"totalResults": len(names),
+		"startIndex":   startIndex,
+		"itemsPerPage": len(resources),
+		"Resources":    resources,
+	})
+}
+
+/*
+ * scimUsers returns (a copy of) the users of the teams repository, with the
+ * SCIM changes not merged yet (scimMutex must be held)
+ */
+func (g *GoliacServerImpl) scimUsers() map[string]*entity.User {
+	users := make(map[string]*entity.User)
+	for name, user := range g.goliac.GetLocal().Users() {
+		users[name] = user
+	}
+	for name, user := range g.scimPending {
+		if user == nil {
+			delete(users, name)
+		} else {
+			users[name] = user
+		}
+	}
+	return users
+}
+
+/*
+ * scimQueue records a user change (nil to delete the user) and
+ * schedules a sync to commit it (scimMutex must be held)
+ */
+func (g *GoliacServerImpl) scimQueue(name string, user *entity.User) {
+	if g.scimPending == nil {
+		g.scimPending = make(map[string]*entity.User)
+	}
+	g.scimPending[name] = user
+	g.scheduleSync(scimCommitDelay)
+}
+
+/*
+ * commitScimChanges commits the queued SCIM changes into the teams repository.
+ * The changes are kept until they are found in the branch loaded by the last
+ * sync: with usersync.pull_request, the goliac/usersync branch is rebuilt from
+ * the branch at each sync, so it must contain all the changes not merged yet
+ */
+func (g *GoliacServerImpl) commitScimChanges(ctx context.Context, repositoryUrl, branch string) error {
+	g.scimMutex.Lock()
+	g.scimForgetMerged()
+	pending := make(map[string]*entity.User, len(g.scimPending))
+	for name, user := range g.scimPending {
+		pending[name] = user
+	}
+	g.scimMutex.Unlock()
+
+	if len(pending) == 0 {
+		return nil
+	}
+
+	err := g.goliac.UsersUpdateWithPlugin(ctx, repositoryUrl, branch, &UserSyncPluginScimInbound{
+		Fs:      afero.NewOsFs(),
+		pending: pending,
+	})
+	if err != nil {
+		return fmt.Errorf("not able to commit the SCIM changes: %v", err)
+	}
+	return nil
+}
+
+/*
+ * scimForgetMerged forgets the SCIM changes already in the teams
+ * repository (scimMutex must be held)
+ */
+func (g *GoliacServerImpl) scimForgetMerged() {
+	users := g.goliac.GetLocal().Users()
+	for name, user := range g.scimPending {
+		current, ok := users[name]
+		if (user == nil && !ok) || (user != nil && ok && user.Equals(current)) {
+			delete(g.scimPending, name)
+		}
+	}
+}
+
+/*
+ * UserSyncPluginScimInbound applies the changes received on the
+ * SCIM endpoint to the current users
+ */
+type UserSyncPluginScimInbound struct {
+	Fs      afero.Fs
+	pending map[string]*entity.User // nil for a deleted user
+}
+
+func (p *UserSyncPluginScimInbound) UpdateUsers(ctx context.Context, repoconfig *config.RepositoryConfig, orguserdirrectorypath string) (map[string]*entity.User, error) {
+	users, errs, _ := entity.ReadUserDirectory(p.Fs, orguserdirrectorypath)
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("cannot load org users (for example: %v)", errs[0])
+	}
+	for name, user := range p.pending {
+		if user == nil {
+			delete(users, name)
+		} else {
+			users[name] = user
+		}
+	}
+	return users, nil
+}
+
+func scimToUser(resource map[string]interface{}) (*entity.User, error) {
+	return usersync.ScimResourceToUser(resource, config.Config.ServerScimUsernameAttribute, config.Config.ServerScimGithubIDAttribute)
+}
+
+func userToScim(user *entity.User) map[string]interface{} {
+	resource := map[string]interface{}{
+		"schemas":  []string{scimSchemaUser},
+		"id":       user.Name,
+		"userName": user.Name,
+		"active":   true,
+	}
+	// return the Github login where the IdP sends it
+	attribute := config.Config.ServerScimGithubIDAttribute
+	if i := strings.LastIndex(attribute, ":"); i >= 0 {
+		resource[attribute[:i]] = map[string]interface{}{attribute[i+1:]: user.Spec.GithubID}
+	} else if attribute != "userName" && !strings.Contains(attribute, ".") {
+		resource[attribute] = user.Spec.GithubID
+	}
+	return resource
+}
+
+/*
+ * applyScimPatch applies the (add, replace, remove) operations of a
+ * PatchOp request on the top level attributes of a resource
+ */
+func applyScimPatch(resource map[string]interface{}, patch map[string]interface{}) (map[string]interface{}, error) {
+	payload, err := json.Marshal(patch)
+	if err != nil {
+		return nil, err
+	}
+	var request scimPatchRequest
+	if err := json.Unmarshal(payload, &request); err != nil {
+		return nil, err
+	}
+
+	for _, op := range request.Operations {
+		switch strings.ToLower(op.Op) {
+		case "add", "replace":
+			if op.Path == "" {
+				if values, ok := op.Value.(map[string]interface{}); ok {
+					for k, v := range values {
+						resource[k] = v
+					}
+				}
+			} else {
+				resource[op.Path] = op.Value
+			}
+		case "remove":
+			delete(resource, op.Path)
+		}
+	}
+	// some IdPs send booleans as strings
+	if active, ok := resource["active"].(string); ok {
+		resource["active"] = strings.EqualFold(active, "true")
+	}
+	return resource, nil
+}
+
+func scimWrite(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/scim+json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+func scimError(w http.ResponseWriter, status int, detail string) {
+	scimWrite(w, status, map[string]interface{}{
+		"schemas": []string{scimSchemaError},
+		"status":  strconv.Itoa(status),
+		"detail":  detail,
+	})
+}
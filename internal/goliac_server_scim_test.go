@@ -0,0 +1,185 @@
+package internal
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Alayacare/goliac/internal/config"
+	"github.com/Alayacare/goliac/internal/engine"
+	"github.com/Alayacare/goliac/internal/entity"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+)
+
+func newScimRequest(method string, resource string, body string) *http.Request {
+	req := httptest.NewRequest(method, "/scim/v2/"+resource, bytes.NewBufferString(body))
+	req.Header.Set("Authorization", "Bearer scimtoken")
+	req.Header.Set("Content-Type", "application/scim+json")
+	return req
+}
+
+func TestScimEndpoint(t *testing.T) {
+	config.Config.ServerScimToken = "scimtoken"
+	config.Config.ServerScimUsernameAttribute = "userName"
+	config.Config.ServerScimGithubIDAttribute = "nickName"
+	defer func() {
+		config.Config.ServerScimToken = ""
+		config.Config.ServerScimGithubIDAttribute = "userName"
+	}()
+
+	newServer := func() (*GoliacServerImpl, *GoliacMock) {
+		goliac := NewGoliacMock(fixtureGoliacLocal()).(*GoliacMock)
+		server := NewGoliacServer(goliac).(*GoliacServerImpl)
+		server.syncInterval.Store(600)
+		return server, goliac
+	}
+	serve := func(server *GoliacServerImpl, req *http.Request) (*httptest.ResponseRecorder, map[string]interface{}) {
+		rec := httptest.NewRecorder()
+		server.ServeScim(rec, req, strings.TrimPrefix(req.URL.Path, "/scim/v2/"))
+		var body map[string]interface{}
+		json.Unmarshal(rec.Body.Bytes(), &body)
+		return rec, body
+	}
+
+	t.Run("happy path: look for an existing user", func(t *testing.T) {
+		server, _ := newServer()
+
+		req := newScimRequest("GET", "Users", "")
+		q := req.URL.Query()
+		q.Set("filter", `userName eq "user1"`)
+		req.URL.RawQuery = q.Encode()
+		rec, body := serve(server, req)
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, float64(1), body["totalResults"])
+		user := body["Resources"].([]interface{})[0].(map[string]interface{})
+		assert.Equal(t, "user1", user["id"])
+		assert.Equal(t, "github1", user["nickName"])
+	})
+
+	t.Run("happy path: create, deactivate and commit users", func(t *testing.T) {
+		server, goliac := newServer()
+
+		rec, body := serve(server, newScimRequest("POST", "Users", `{"userName":"alice","nickName":"alice-gh","active":true}`))
+		assert.Equal(t, http.StatusCreated, rec.Code)
+		assert.Equal(t, "alice", body["id"])
+		assert.Equal(t, int64(scimCommitDelay), server.syncInterval.Load())
+
+		rec, _ = serve(server, newScimRequest("PATCH", "Users/user2", `{"schemas":["urn:ietf:params:scim:api:messages:2.0:PatchOp"],"Operations":[{"op":"Replace","path":"active","value":"False"}]}`))
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		rec, _ = serve(server, newScimRequest("GET", "Users/user2", ""))
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+
+		// commit
+		err := server.commitScimChanges(context.TODO(), "https://github.com/myorg/teams.git", "main")
+		assert.Nil(t, err)
+		assert.NotNil(t, goliac.userplugin)
+		// kept until merged
+		assert.Equal(t, 2, len(server.scimPending))
+
+		rec, _ = serve(server, newScimRequest("GET", "Users/alice", ""))
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		// the plugin applies the changes on the users directory
+		fs := afero.NewMemMapFs()
+		for _, name := range []string{"user1", "user2"} {
+			afero.WriteFile(fs, "users/org/"+name+".yaml", []byte("apiVersion: v1\nkind: User\nname: "+name+"\nspec:\n  githubID: github-"+name+"\n"), 0644)
+		}
+		plugin := goliac.userplugin.(*UserSyncPluginScimInbound)
+		plugin.Fs = fs
+		users, err := plugin.UpdateUsers(context.TODO(), nil, "users/org")
+		assert.Nil(t, err)
+		assert.Equal(t, 2, len(users))
+		assert.Equal(t, "alice-gh", users["alice"].Spec.GithubID)
+		assert.NotNil(t, users["user1"])
+		assert.Nil(t, users["user2"])
+	})
+
+	t.Run("happy path: pending changes are committed again until merged", func(t *testing.T) {
+		server, goliac := newServer()
+
+		serve(server, newScimRequest("POST", "Users", `{"userName":"alice","nickName":"alice-gh","active":true}`))
+		serve(server, newScimRequest("DELETE", "Users/user2", ""))
+
+		err := server.commitScimChanges(context.TODO(), "https://github.com/myorg/teams.git", "main")
+		assert.Nil(t, err)
+
+		// a new change (while the pull request is not merged yet)
+		serve(server, newScimRequest("POST", "Users", `{"userName":"bob","nickName":"bob-gh","active":true}`))
+		goliac.userplugin = nil
+		err = server.commitScimChanges(context.TODO(), "https://github.com/myorg/teams.git", "main")
+		assert.Nil(t, err)
+		plugin := goliac.userplugin.(*UserSyncPluginScimInbound)
+		assert.Equal(t, 3, len(plugin.pending))
+
+		// alice and the user2 removal are merged
+		users := goliac.local.(*GoliacLocalMock).users
+		alice := &entity.User{}
+		alice.ApiVersion = "v1"
+		alice.Kind = "User"
+		alice.Name = "alice"
+		alice.Spec.GithubID = "alice-gh"
+		users["alice"] = alice
+		delete(users, "user2")
+
+		goliac.userplugin = nil
+		err = server.commitScimChanges(context.TODO(), "https://github.com/myorg/teams.git", "main")
+		assert.Nil(t, err)
+		plugin = goliac.userplugin.(*UserSyncPluginScimInbound)
+		assert.Equal(t, 1, len(plugin.pending))
+		assert.NotNil(t, plugin.pending["bob"])
+	})
+
+	t.Run("not happy path: user already exists", func(t *testing.T) {
+		server, _ := newServer()
+
+		rec, _ := serve(server, newScimRequest("POST", "Users", `{"userName":"user1","nickName":"github1"}`))
+		assert.Equal(t, http.StatusConflict, rec.Code)
+		assert.Equal(t, 0, len(server.scimPending))
+	})
+
+	t.Run("not happy path: invalid patch", func(t *testing.T) {
+		server, _ := newServer()
+
+		rec, _ := serve(server, newScimRequest("PATCH", "Users/user2", `{"schemas":["urn:ietf:params:scim:api:messages:2.0:PatchOp"],"Operations":"active"}`))
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Equal(t, 0, len(server.scimPending))
+	})
+
+	t.Run("not happy path: invalid token", func(t *testing.T) {
+		server, _ := newServer()
+
+		req := newScimRequest("DELETE", "Users/user1", "")
+		req.Header.Set("Authorization", "Bearer wrong")
+		rec, _ := serve(server, req)
+
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+		assert.Equal(t, 0, len(server.scimPending))
+	})
+
+	t.Run("not happy path: pending changes are kept if the commit fails", func(t *testing.T) {
+		server, _ := newServer()
+		server.scimPending = map[string]*entity.User{"user1": nil}
+		server.goliac = &failingUsersUpdateGoliacMock{GoliacMock: server.goliac.(*GoliacMock)}
+
+		err := server.commitScimChanges(context.TODO(), "https://github.com/myorg/teams.git", "main")
+
+		assert.NotNil(t, err)
+		assert.Equal(t, 1, len(server.scimPending))
+	})
+}
+
+type failingUsersUpdateGoliacMock struct {
+	*GoliacMock
+}
+
+func (g *failingUsersUpdateGoliacMock) UsersUpdateWithPlugin(ctx context.Context, repositoryUrl, branch string, userplugin engine.UserSyncPlugin) error {
+	return fmt.Errorf("push failed")
+}
@@ -1,11 +1,13 @@
 package internal
 
 import (
+	"context"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
 
+	"github.com/Alayacare/goliac/internal/audit"
 	"github.com/Alayacare/goliac/internal/engine"
 	"github.com/Alayacare/goliac/internal/entity"
 	"github.com/Alayacare/goliac/swagger_gen/restapi/operations/app"
@@ -17,6 +19,7 @@ type GoliacLocalMock struct {
 	users         map[string]*entity.User
 	externalUsers map[string]*entity.User
 	rulesets      map[string]*entity.RuleSet
+	requests      map[string]*entity.AccessRequest
 }
 
 func (g *GoliacLocalMock) Teams() map[string]*entity.Team {
@@ -31,9 +34,18 @@ func (g *GoliacLocalMock) Users() map[string]*entity.User {
 func (g *GoliacLocalMock) ExternalUsers() map[string]*entity.User {
 	return g.externalUsers
 }
+func (g *GoliacLocalMock) ProtectedUsers() map[string]*entity.User {
+	return map[string]*entity.User{}
+}
+func (g *GoliacLocalMock) AppliedProtectedUsers() map[string]*entity.User {
+	return map[string]*entity.User{}
+}
 func (g *GoliacLocalMock) RuleSets() map[string]*entity.RuleSet {
 	return g.rulesets
 }
+func (g *GoliacLocalMock) AccessRequests() map[string]*entity.AccessRequest {
+	return g.requests
+}
 
 func fixtureGoliacLocal() *GoliacLocalMock {
 	l := GoliacLocalMock{
@@ -104,21 +116,57 @@ func fixtureGoliacLocal() *GoliacLocalMock {
 }
 
 type GoliacMock struct {
-	local engine.GoliacLocalResources
+	local             engine.GoliacLocalResources
+	drifts            []*engine.Drift
+	records           []*audit.Record
+	nbApply           int
+	lastForceResync   bool
+	invalidatedCaches []string
+	userplugin        engine.UserSyncPlugin // the last plugin given to UsersUpdateWithPlugin
 }
 
-func (g *GoliacMock) Apply(dryrun bool, repo string, branch string, forceresync bool) error {
+func (g *GoliacMock) Apply(ctx context.Context, dryrun bool, repo string, branch string, forceresync bool) error {
+	g.nbApply++
+	g.lastForceResync = forceresync
+	return nil
+}
+func (g *GoliacMock) UsersUpdate(ctx context.Context, repositoryUrl, branch string) error {
 	return nil
 }
-func (g *GoliacMock) UsersUpdate(repositoryUrl, branch string) error {
+func (g *GoliacMock) UsersUpdateWithPlugin(ctx context.Context, repositoryUrl, branch string, userplugin engine.UserSyncPlugin) error {
+	g.userplugin = userplugin
 	return nil
 }
+func (g *GoliacMock) Drift(ctx context.Context, repositoryUrl, branch string) ([]*engine.Drift, error) {
+	return g.drifts, nil
+}
+func (g *GoliacMock) GetLastDrift() []*engine.Drift {
+	return g.drifts
+}
 func (g *GoliacMock) FlushCache() {
 }
+func (g *GoliacMock) InvalidateCache(caches ...string) {
+	g.invalidatedCaches = append(g.invalidatedCaches, caches...)
+}
 
 func (g *GoliacMock) GetLocal() engine.GoliacLocalResources {
 	return g.local
 }
+func (g *GoliacMock) GetActor() string {
+	return "goliac-app[bot]"
+}
+func (g *GoliacMock) GetAudit(entity string) ([]*audit.Record, error) {
+	records := []*audit.Record{}
+	for _, r := range g.records {
+		for _, e := range r.Entities {
+			if e == entity {
+				records = append(records, r)
+				break
+			}
+		}
+	}
+	return records, nil
+}
 func NewGoliacMock(local engine.GoliacLocalResources) Goliac {
 	mock := GoliacMock{
 		local: local,
@@ -219,3 +267,107 @@ func TestAppGetRepositories(t *testing.T) {
 		assert.NotZero(t, res.(*app.GetRepositoryDefault))
 	})
 }
+
+func TestAppGetDrift(t *testing.T) {
+	fixture := fixtureGoliacLocal()
+	goliac := NewGoliacMock(fixture).(*GoliacMock)
+	server := GoliacServerImpl{
+		goliac: goliac,
+		ready:  true,
+	}
+
+	t.Run("happy path: no drift", func(t *testing.T) {
+		res := server.GetDrift(app.GetDriftParams{})
+		payload := res.(*app.GetDriftOK)
+		assert.Equal(t, 0, len(payload.Payload))
+	})
+
+	t.Run("happy path: get drift", func(t *testing.T) {
+		goliac.drifts = []*engine.Drift{
+			{
+				Resource:   engine.DriftResourceTeams,
+				Name:       "ateam",
+				Command:    "update_team_remove_member",
+				Details:    "member github4 was added",
+				Policy:     engine.DriftPolicyReport,
+				Author:     "github4",
+				DetectedAt: time.Date(2026, 10, 19, 10, 0, 0, 0, time.UTC),
+			},
+		}
+		res := server.GetDrift(app.GetDriftParams{})
+		payload := res.(*app.GetDriftOK)
+		assert.Equal(t, 1, len(payload.Payload))
+		assert.Equal(t, "ateam", payload.Payload[0].Name)
+		assert.Equal(t, "github4", payload.Payload[0].Author)
+		assert.Equal(t, "2026-10-19T10:00:00", payload.Payload[0].DetectedAt)
+	})
+}
+
+func TestAppGetOrphans(t *testing.T) {
+	t.Run("happy path: no orphan", func(t *testing.T) {
+		server := GoliacServerImpl{
+			goliac: NewGoliacMock(fixtureGoliacLocal()),
+			ready:  true,
+		}
+
+		res := server.GetOrphans(app.GetOrphansParams{})
+		payload := res.(*app.GetOrphansOK)
+		assert.Equal(t, 0, len(payload.Payload))
+	})
+
+	t.Run("happy path: team without owner", func(t *testing.T) {
+		fixture := fixtureGoliacLocal()
+		fixture.teams["ateam"].Spec.Owners = []string{}
+		server := GoliacServerImpl{
+			goliac: NewGoliacMock(fixture),
+			ready:  true,
+		}
+
+		res := server.GetOrphans(app.GetOrphansParams{})
+		payload := res.(*app.GetOrphansOK)
+		assert.Equal(t, 1, len(payload.Payload))
+		assert.Equal(t, "ateam", payload.Payload[0].Team)
+		assert.Equal(t, []string{"repoA"}, payload.Payload[0].Repositories)
+	})
+}
+
+func TestAppGetAudit(t *testing.T) {
+	fixture := fixtureGoliacLocal()
+	goliac := NewGoliacMock(fixture).(*GoliacMock)
+	server := GoliacServerImpl{
+		goliac: goliac,
+		ready:  true,
+	}
+	goliac.records = []*audit.Record{
+		{
+			Timestamp:  time.Date(2026, 10, 19, 10, 0, 0, 0, time.UTC),
+			CommitSha:  "abcdef",
+			Author:     "John Doe <john@doe.com>",
+			Operation:  "update_repository_add_team",
+			Entities:   []string{"repo/repoA", "team/team-a"},
+			Parameters: map[string]string{"repository": "repoA", "team": "team-a", "permission": "push"},
+			Success:    true,
+		},
+	}
+
+	t.Run("happy path: get the audit trail of a repository", func(t *testing.T) {
+		res := server.GetAudit(app.GetAuditParams{Entity: "repo/repoA"})
+		payload := res.(*app.GetAuditOK)
+		assert.Equal(t, 1, len(payload.Payload))
+		assert.Equal(t, "update_repository_add_team", payload.Payload[0].Operation)
+		assert.Equal(t, "push", payload.Payload[0].Parameters["permission"])
+		assert.Equal(t, "2026-10-19T10:00:00", payload.Payload[0].Timestamp)
+	})
+
+	t.Run("happy path: teams are found by their name", func(t *testing.T) {
+		res := server.GetAudit(app.GetAuditParams{Entity: "team/team A"})
+		payload := res.(*app.GetAuditOK)
+		assert.Equal(t, 1, len(payload.Payload))
+	})
+
+	t.Run("happy path: no audit trail", func(t *testing.T) {
+		res := server.GetAudit(app.GetAuditParams{Entity: "repo/repoB"})
+		payload := res.(*app.GetAuditOK)
+		assert.Equal(t, 0, len(payload.Payload))
+	})
+}
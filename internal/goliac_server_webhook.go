@@ -0,0 +1,182 @@
+package internal
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/Alayacare/goliac/internal/config"
+	"github.com/Alayacare/goliac/internal/engine"
+	"github.com/sirupsen/logrus"
+)
+
+// how long (in seconds) we wait before a drift-correcting sync,
+// to regroup a burst of organization events into one sync
+const webhookDriftDelay = 30
+
+// Github webhook payloads are capped to 25MB
+const webhookMaxPayloadSize = 25 * 1024 * 1024
+
+/*
+ * Organization events (and actions) that can introduce a drift
+ * between the teams repository and Github
+ */
+var webhookDriftEvents = map[string][]string{
+	"organization": {"member_added", "member_removed", "member_invited"},
+	"membership":   {"added", "removed"},
+	"member":       {"added", "edited", "removed"},
+	"team":         {"created", "deleted", "edited", "added_to_repository", "removed_from_repository"},
+	"repository":   {"created", "deleted", "renamed", "archived", "unarchived", "publicized", "privatized", "transferred"},
+}
+
+/*
+ * Github remote caches to reload after an organization event,
+ * so the drift-correcting sync only refreshes what may have changed
+ */
+var webhookInvalidatedCaches = map[string][]string{
+	"organization": {engine.CacheUsers},
+	"membership":   {engine.CacheTeams},
+	"member":       {engine.CacheRepositories},
+	"team":         {engine.CacheTeams, engine.CacheTeamsRepos},
+	"repository":   {engine.CacheRepositories, engine.CacheTeamsRepos},
+}
+
+type webhookSender struct {
+	Login string `json:"login"`
+}
+
+type webhookEvent struct {
+	Action     string `json:"action"`
+	Ref        string `json:"ref"` // for push events
+	Repository *struct {
+		Name string `json:"name"`
+	} `json:"repository"`
+	Sender webhookSender `json:"sender"`
+}
+
+func (g *GoliacServerImpl) webhookMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != config.Config.WebPrefix+"/webhook" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		g.ServeWebhook(w, r)
+	})
+}
+
+/*
+ * ServeWebhook receives the Github (organization) webhook events
+ * - a push on the teams repository branch triggers a sync
+ * - an organization event (member, team, repository) invalidates the related
+ *   Github remote caches and schedules a drift-correcting sync
+ */
+func (g *GoliacServerImpl) ServeWebhook(w http.ResponseWriter, r *http.Request) {
+	if config.Config.ServerGitWebhookSecret == "" {
+		http.Error(w, "webhook not configured", http.StatusNotFound)
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, webhookMaxPayloadSize))
+	if err != nil {
+		http.Error(w, "not able to read the payload", http.StatusBadRequest)
+		return
+	}
+
+	if !verifyWebhookSignature(config.Config.ServerGitWebhookSecret, r.Header.Get("X-Hub-Signature-256"), body) {
+		logrus.Warnf("webhook: invalid signature (delivery %s)", r.Header.Get("X-GitHub-Delivery"))
+		http.Error(w, "invalid signature", http.StatusUnauthorized)
+		return
+	}
+
+	eventType := r.Header.Get("X-GitHub-Event")
+	if eventType == "ping" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	var event webhookEvent
+	if err := json.Unmarshal(body, &event); err != nil {
+		http.Error(w, "invalid payload", http.StatusBadRequest)
+		return
+	}
+
+	// ignore what Goliac did itself
+	if actor := g.goliac.GetActor(); actor != "" && event.Sender.Login == actor {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	if eventType == "push" {
+		if event.Repository != nil &&
+			event.Repository.Name == teamsRepositoryName(config.Config.ServerGitRepository) &&
+			event.Ref == "refs/heads/"+config.Config.ServerGitBranch {
+			logrus.Infof("webhook: push on %s by %s, starting a sync", config.Config.ServerGitBranch, event.Sender.Login)
+			go g.sync(false)
+			w.WriteHeader(http.StatusAccepted)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	for _, action := range webhookDriftEvents[eventType] {
+		if action == event.Action {
+			logrus.Infof("webhook: %s %s by %s, scheduling a sync in %ds", eventType, event.Action, event.Sender.Login, webhookDriftDelay)
+			g.goliac.InvalidateCache(webhookInvalidatedCaches[eventType]...)
+			g.scheduleDriftSync()
+			w.WriteHeader(http.StatusAccepted)
+			return
+		}
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+/*
+ * scheduleDriftSync asks the Serve loop to do a full resync soon
+ */
+func (g *GoliacServerImpl) scheduleDriftSync() {
+	g.driftScheduled.Store(true)
+	g.scheduleSync(webhookDriftDelay)
+}
+
+/*
+ * verifyWebhookSignature checks the X-Hub-Signature-256 header
+ * cf https://docs.github.com/en/webhooks/using-webhooks/validating-webhook-deliveries
+ */
+func verifyWebhookSignature(secret string, signature string, body []byte) bool {
+	if !strings.HasPrefix(signature, "sha256=") {
+		return false
+	}
+	expected, err := hex.DecodeString(strings.TrimPrefix(signature, "sha256="))
+	if err != nil {
+		return false
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hmac.Equal(mac.Sum(nil), expected)
+}
+
+/*
+ * teamsRepositoryName returns the repository name from its url
+ * (https://github.com/myorg/teams.git -> teams)
+ */
+func teamsRepositoryName(repositoryUrl string) string {
+	p := repositoryUrl
+	if u, err := url.Parse(repositoryUrl); err == nil && u.Path != "" {
+		p = u.Path
+	}
+	return strings.TrimSuffix(path.Base(p), filepath.Ext(path.Base(p)))
+}
@@ -0,0 +1,131 @@
+package internal
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Alayacare/goliac/internal/config"
+	"github.com/Alayacare/goliac/internal/engine"
+	"github.com/stretchr/testify/assert"
+)
+
+func signWebhookPayload(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func newWebhookRequest(event string, body []byte, signature string) *http.Request {
+	req := httptest.NewRequest("POST", "/webhook", bytes.NewBuffer(body))
+	req.Header.Set("X-GitHub-Event", event)
+	req.Header.Set("X-Hub-Signature-256", signature)
+	return req
+}
+
+func TestWebhook(t *testing.T) {
+	config.Config.ServerGitWebhookSecret = "secret"
+	config.Config.ServerGitRepository = "https://github.com/myorg/teams.git"
+	config.Config.ServerGitBranch = "main"
+	defer func() {
+		config.Config.ServerGitWebhookSecret = ""
+		config.Config.ServerGitRepository = ""
+	}()
+
+	newServer := func() (*GoliacServerImpl, *GoliacMock) {
+		goliac := NewGoliacMock(fixtureGoliacLocal()).(*GoliacMock)
+		server := NewGoliacServer(goliac).(*GoliacServerImpl)
+		server.syncInterval.Store(600)
+		return server, goliac
+	}
+
+	t.Run("happy path: push on the teams repository branch", func(t *testing.T) {
+		server, goliac := newServer()
+		body := []byte(`{"ref":"refs/heads/main","repository":{"name":"teams"},"sender":{"login":"user1"}}`)
+
+		rec := httptest.NewRecorder()
+		server.ServeWebhook(rec, newWebhookRequest("push", body, signWebhookPayload("secret", body)))
+
+		assert.Equal(t, http.StatusAccepted, rec.Code)
+		assert.Eventually(t, func() bool {
+			server.applyLobbyMutex.Lock()
+			defer server.applyLobbyMutex.Unlock()
+			return goliac.nbApply == 1 && !server.applyCurrent
+		}, time.Second, 10*time.Millisecond)
+		assert.False(t, goliac.lastForceResync)
+	})
+
+	t.Run("happy path: push on another branch is ignored", func(t *testing.T) {
+		server, goliac := newServer()
+		body := []byte(`{"ref":"refs/heads/feature","repository":{"name":"teams"},"sender":{"login":"user1"}}`)
+
+		rec := httptest.NewRecorder()
+		server.ServeWebhook(rec, newWebhookRequest("push", body, signWebhookPayload("secret", body)))
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, 0, goliac.nbApply)
+	})
+
+	t.Run("happy path: organization event schedules a drift sync", func(t *testing.T) {
+		server, goliac := newServer()
+		body := []byte(`{"action":"member_added","sender":{"login":"admin"}}`)
+
+		rec := httptest.NewRecorder()
+		server.ServeWebhook(rec, newWebhookRequest("organization", body, signWebhookPayload("secret", body)))
+
+		assert.Equal(t, http.StatusAccepted, rec.Code)
+		assert.Equal(t, int64(webhookDriftDelay), server.syncInterval.Load())
+		assert.True(t, server.driftScheduled.Load())
+		assert.Equal(t, []string{engine.CacheUsers}, goliac.invalidatedCaches)
+	})
+
+	t.Run("happy path: team event only invalidates the teams caches", func(t *testing.T) {
+		server, goliac := newServer()
+		body := []byte(`{"action":"added_to_repository","sender":{"login":"admin"}}`)
+
+		rec := httptest.NewRecorder()
+		server.ServeWebhook(rec, newWebhookRequest("team", body, signWebhookPayload("secret", body)))
+
+		assert.Equal(t, http.StatusAccepted, rec.Code)
+		assert.Equal(t, []string{engine.CacheTeams, engine.CacheTeamsRepos}, goliac.invalidatedCaches)
+	})
+
+	t.Run("happy path: events from goliac itself are ignored", func(t *testing.T) {
+		server, goliac := newServer()
+		body := []byte(`{"action":"created","repository":{"name":"repo1"},"sender":{"login":"goliac-app[bot]"}}`)
+
+		rec := httptest.NewRecorder()
+		server.ServeWebhook(rec, newWebhookRequest("repository", body, signWebhookPayload("secret", body)))
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, int64(600), server.syncInterval.Load())
+		assert.False(t, server.driftScheduled.Load())
+		assert.Equal(t, 0, len(goliac.invalidatedCaches))
+	})
+
+	t.Run("not happy path: invalid signature", func(t *testing.T) {
+		server, goliac := newServer()
+		body := []byte(`{"ref":"refs/heads/main","repository":{"name":"teams"},"sender":{"login":"user1"}}`)
+
+		rec := httptest.NewRecorder()
+		server.ServeWebhook(rec, newWebhookRequest("push", body, signWebhookPayload("another secret", body)))
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+		rec = httptest.NewRecorder()
+		server.ServeWebhook(rec, newWebhookRequest("push", body, ""))
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+		assert.Equal(t, 0, goliac.nbApply)
+	})
+
+	t.Run("happy path: teams repository name", func(t *testing.T) {
+		assert.Equal(t, "teams", teamsRepositoryName("https://github.com/myorg/teams.git"))
+		assert.Equal(t, "teams", teamsRepositoryName("https://github.com/myorg/teams"))
+		assert.Equal(t, "teams", teamsRepositoryName("git@github.com:myorg/teams.git"))
+	})
+}
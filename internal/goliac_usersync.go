@@ -0,0 +1,77 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+
+	"github.com/Alayacare/goliac/internal/config"
+	"github.com/Alayacare/goliac/internal/engine"
+	"github.com/Alayacare/goliac/internal/github"
+	"github.com/sirupsen/logrus"
+)
+
+const userSyncPullRequestTitle = "Goliac user sync"
+
+type pullRequest struct {
+	Number  int    `json:"number"`
+	HtmlUrl string `json:"html_url"`
+}
+
+/*
+ * syncUsersPullRequest opens (or updates) the pull request from the engine.UserSyncBranch
+ * branch to branch, describing the user sync changes.
+ * If there is no change anymore, the pull request (if any) is closed
+ */
+func syncUsersPullRequest(ctx context.Context, client github.GitHubClient, repositoryUrl string, branch string, report *engine.UserSyncReport) error {
+	org := config.Config.GithubAppOrganization
+	repo := teamsRepositoryName(repositoryUrl)
+
+	body, err := client.CallRestAPI(ctx, fmt.Sprintf("/repos/%s/%s/pulls?state=open&head=%s&base=%s", org, repo, url.QueryEscape(org+":"+engine.UserSyncBranch), url.QueryEscape(branch)), "GET", nil)
+	if err != nil {
+		return fmt.Errorf("not able to list the pull requests of %s: %v", repo, err)
+	}
+	var pulls []pullRequest
+	if err := json.Unmarshal(body, &pulls); err != nil {
+		return fmt.Errorf("not able to parse the pull requests of %s: %v", repo, err)
+	}
+
+	if report.IsEmpty() {
+		for _, pr := range pulls {
+			logrus.Infof("no user sync change anymore: closing the pull request %s", pr.HtmlUrl)
+			if _, err := client.CallRestAPI(ctx, fmt.Sprintf("/repos/%s/%s/pulls/%d", org, repo, pr.Number), "PATCH", map[string]interface{}{"state": "closed"}); err != nil {
+				return fmt.Errorf("not able to close the pull request %s: %v", pr.HtmlUrl, err)
+			}
+		}
+		return nil
+	}
+
+	if len(pulls) > 0 {
+		pr := pulls[0]
+		_, err := client.CallRestAPI(ctx, fmt.Sprintf("/repos/%s/%s/pulls/%d", org, repo, pr.Number), "PATCH", map[string]interface{}{
+			"title": userSyncPullRequestTitle,
+			"body":  report.Summary(),
+		})
+		if err != nil {
+			return fmt.Errorf("not able to update the pull request %s: %v", pr.HtmlUrl, err)
+		}
+		logrus.Infof("user sync pull request updated: %s", pr.HtmlUrl)
+		return nil
+	}
+
+	body, err = client.CallRestAPI(ctx, fmt.Sprintf("/repos/%s/%s/pulls", org, repo), "POST", map[string]interface{}{
+		"title": userSyncPullRequestTitle,
+		"head":  engine.UserSyncBranch,
+		"base":  branch,
+		"body":  report.Summary(),
+	})
+	if err != nil {
+		return fmt.Errorf("not able to open the user sync pull request: %v", err)
+	}
+	var pr pullRequest
+	if err := json.Unmarshal(body, &pr); err == nil {
+		logrus.Infof("user sync pull request opened: %s", pr.HtmlUrl)
+	}
+	return nil
+}
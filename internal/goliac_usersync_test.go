@@ -0,0 +1,88 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/Alayacare/goliac/internal/config"
+	"github.com/Alayacare/goliac/internal/engine"
+	"github.com/stretchr/testify/assert"
+)
+
+/*
+ * PullRequestGithubClientMock serves the open pull requests, and records the REST calls
+ */
+type PullRequestGithubClientMock struct {
+	openPulls string
+	calls     []string
+	bodies    []map[string]interface{}
+}
+
+func (m *PullRequestGithubClientMock) QueryGraphQLAPI(ctx context.Context, query string, variables map[string]interface{}) ([]byte, error) {
+	return nil, nil
+}
+func (m *PullRequestGithubClientMock) CallRestAPI(ctx context.Context, endpoint, method string, body map[string]interface{}) ([]byte, error) {
+	m.calls = append(m.calls, fmt.Sprintf("%s %s", method, endpoint))
+	m.bodies = append(m.bodies, body)
+	switch method {
+	case "GET":
+		return []byte(m.openPulls), nil
+	case "POST":
+		return []byte(`{"number": 2, "html_url": "https://github.com/myorg/teams/pull/2"}`), nil
+	}
+	return []byte(`{}`), nil
+}
+func (m *PullRequestGithubClientMock) GetAccessToken(ctx context.Context) (string, error) {
+	return "token", nil
+}
+func (m *PullRequestGithubClientMock) GetActor() string {
+	return "goliac-project-app[bot]"
+}
+
+func TestSyncUsersPullRequest(t *testing.T) {
+	config.Config.GithubAppOrganization = "myorg"
+	defer func() { config.Config.GithubAppOrganization = "" }()
+
+	report := &engine.UserSyncReport{
+		AddedUsers:   []string{"alice"},
+		RemovedUsers: []string{"bob"},
+		RenamedUsers: map[string]string{},
+	}
+
+	t.Run("happy path: open a pull request", func(t *testing.T) {
+		client := &PullRequestGithubClientMock{openPulls: `[]`}
+
+		err := syncUsersPullRequest(context.TODO(), client, "https://github.com/myorg/teams.git", "main", report)
+
+		assert.Nil(t, err)
+		assert.Equal(t, []string{
+			"GET /repos/myorg/teams/pulls?state=open&head=myorg%3Agoliac%2Fusersync&base=main",
+			"POST /repos/myorg/teams/pulls",
+		}, client.calls)
+		assert.Equal(t, "goliac/usersync", client.bodies[1]["head"])
+		assert.Equal(t, "main", client.bodies[1]["base"])
+		assert.Contains(t, client.bodies[1]["body"], "- bob")
+	})
+
+	t.Run("happy path: update the open pull request", func(t *testing.T) {
+		client := &PullRequestGithubClientMock{openPulls: `[{"number": 1, "html_url": "https://github.com/myorg/teams/pull/1"}]`}
+
+		err := syncUsersPullRequest(context.TODO(), client, "https://github.com/myorg/teams.git", "main", report)
+
+		assert.Nil(t, err)
+		assert.Equal(t, 2, len(client.calls))
+		assert.Equal(t, "PATCH /repos/myorg/teams/pulls/1", client.calls[1])
+		assert.Contains(t, client.bodies[1]["body"], "- alice")
+	})
+
+	t.Run("happy path: close the pull request without changes", func(t *testing.T) {
+		client := &PullRequestGithubClientMock{openPulls: `[{"number": 1, "html_url": "https://github.com/myorg/teams/pull/1"}]`}
+
+		err := syncUsersPullRequest(context.TODO(), client, "https://github.com/myorg/teams.git", "main", &engine.UserSyncReport{})
+
+		assert.Nil(t, err)
+		assert.Equal(t, "PATCH /repos/myorg/teams/pulls/1", client.calls[1])
+		assert.Equal(t, "closed", client.bodies[1]["state"])
+	})
+}
@@ -0,0 +1,121 @@
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+/*
+ * Prometheus metrics exposed by the goliac server on /metrics
+ */
+var (
+	ApplyDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "goliac",
+		Name:      "apply_duration_seconds",
+		Help:      "Duration of a sync (apply) to Github",
+		Buckets:   []float64{1, 5, 10, 30, 60, 120, 300, 600, 1200},
+	}, []string{"result"})
+
+	LastApplySuccess = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: "goliac",
+		Name:      "last_apply_success_timestamp_seconds",
+		Help:      "Unix timestamp of the last successful sync (apply) to Github",
+	})
+
+	Operations = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "goliac",
+		Name:      "github_operations_total",
+		Help:      "Number of operations applied to Github, by command type",
+	}, []string{"command"})
+
+	ChangesetsRejected = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "goliac",
+		Name:      "changesets_rejected_total",
+		Help:      "Number of changesets rejected because they contained more than maxChangesets operations",
+	})
+
+	GithubApiCalls = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "goliac",
+		Name:      "github_api_calls_total",
+		Help:      "Number of calls to the Github API",
+	}, []string{"api"})
+
+	GithubRateLimitWaits = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "goliac",
+		Name:      "github_rate_limit_waits_total",
+		Help:      "Number of times Goliac had to wait for the Github rate limit to reset",
+	})
+
+	GithubRateLimitWaitSeconds = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "goliac",
+		Name:      "github_rate_limit_wait_seconds_total",
+		Help:      "Total time spent waiting for the Github rate limit to reset",
+	})
+
+	GithubRetries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "goliac",
+		Name:      "github_retries_total",
+		Help:      "Number of retried calls to the Github API (network errors, 5xx or rate limits)",
+	}, []string{"api"})
+
+	GithubNotModified = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "goliac",
+		Name:      "github_not_modified_total",
+		Help:      "Number of Github REST calls answered with 304 Not Modified (served from the ETag cache, not counted in the rate limit)",
+	})
+
+	CacheHits = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "goliac",
+		Name:      "github_cache_hits_total",
+		Help:      "Number of Github remote objects served from the cache",
+	}, []string{"cache"})
+
+	CacheMisses = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "goliac",
+		Name:      "github_cache_misses_total",
+		Help:      "Number of Github remote objects (re)loaded because the cache expired",
+	}, []string{"cache"})
+
+	registry = prometheus.NewRegistry()
+)
+
+func init() {
+	registry.MustRegister(
+		prometheus.NewGoCollector(),
+		prometheus.NewProcessCollector(prometheus.ProcessCollectorOpts{}),
+		ApplyDuration,
+		LastApplySuccess,
+		Operations,
+		ChangesetsRejected,
+		GithubApiCalls,
+		GithubRateLimitWaits,
+		GithubRateLimitWaitSeconds,
+		GithubRetries,
+		GithubNotModified,
+		CacheHits,
+		CacheMisses,
+	)
+}
+
+/*
+ * Handler returns the http handler serving the metrics
+ * in the Prometheus exposition format
+ */
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+}
+
+/*
+ * CacheExpired tells if a cached object must be reloaded
+ * and records the cache hit (or miss)
+ */
+func CacheExpired(cache string, expired bool) bool {
+	if expired {
+		CacheMisses.WithLabelValues(cache).Inc()
+	} else {
+		CacheHits.WithLabelValues(cache).Inc()
+	}
+	return expired
+}
@@ -0,0 +1,39 @@
+package metrics
+
+import (
+	"io"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetrics(t *testing.T) {
+	t.Run("happy path: cache hits and misses", func(t *testing.T) {
+		hits := testutil.ToFloat64(CacheHits.WithLabelValues("test"))
+		misses := testutil.ToFloat64(CacheMisses.WithLabelValues("test"))
+
+		assert.True(t, CacheExpired("test", true))
+		assert.False(t, CacheExpired("test", false))
+		assert.False(t, CacheExpired("test", false))
+
+		assert.Equal(t, hits+2, testutil.ToFloat64(CacheHits.WithLabelValues("test")))
+		assert.Equal(t, misses+1, testutil.ToFloat64(CacheMisses.WithLabelValues("test")))
+	})
+
+	t.Run("happy path: metrics are served", func(t *testing.T) {
+		Operations.WithLabelValues("create_team").Inc()
+		ChangesetsRejected.Inc()
+		LastApplySuccess.SetToCurrentTime()
+
+		rec := httptest.NewRecorder()
+		Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+
+		assert.Equal(t, 200, rec.Code)
+		body, _ := io.ReadAll(rec.Body)
+		assert.Contains(t, string(body), `goliac_github_operations_total{command="create_team"}`)
+		assert.Contains(t, string(body), "goliac_changesets_rejected_total 1")
+		assert.Contains(t, string(body), "goliac_last_apply_success_timestamp_seconds")
+	})
+}
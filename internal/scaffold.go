@@ -1,20 +1,20 @@
 package internal
 
 import (
+	"context"
 	"fmt"
 	"path"
 	"strings"
 
-	"github.com/Alayacare/goliac/internal/config"
 	"github.com/Alayacare/goliac/internal/engine"
 	"github.com/Alayacare/goliac/internal/entity"
-	"github.com/Alayacare/goliac/internal/github"
+	"github.com/Alayacare/goliac/internal/secrets"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/afero"
 	"gopkg.in/yaml.v3"
 )
 
-type LoadGithubSamlUsers func() (map[string]*entity.User, error)
+type LoadGithubSamlUsers func(ctx context.Context) (map[string]*entity.User, error)
 
 type Scaffold struct {
 	remote                     engine.GoliacRemote
@@ -22,13 +22,9 @@ type Scaffold struct {
 }
 
 func NewScaffold() (*Scaffold, error) {
-	githubClient, err := github.NewGitHubClientImpl(
-		config.Config.GithubServer,
-		config.Config.GithubAppOrganization,
-		config.Config.GithubAppID,
-		config.Config.GithubAppPrivateKeyFile,
-	)
+	secrets.InitProviders()
 
+	githubClient, err := newGithubClient()
 	if err != nil {
 		return nil, err
 	}
@@ -37,8 +33,8 @@ func NewScaffold() (*Scaffold, error) {
 
 	return &Scaffold{
 		remote: remote,
-		loadUsersFromGithubOrgSaml: func() (map[string]*entity.User, error) {
-			return engine.LoadUsersFromGithubOrgSaml(githubClient)
+		loadUsersFromGithubOrgSaml: func(ctx context.Context) (map[string]*entity.User, error) {
+			return engine.LoadUsersFromGithubOrgSaml(ctx, githubClient)
 		},
 	}, nil
 }
@@ -46,25 +42,25 @@ func NewScaffold() (*Scaffold, error) {
 /*
  * Generate will generate a full teams directory structure compatible with Goliac
  */
-func (s *Scaffold) Generate(rootpath string, adminteam string) error {
+func (s *Scaffold) Generate(ctx context.Context, rootpath string, adminteam string) error {
 	fs := afero.NewOsFs()
-	if err := s.remote.Load(); err != nil {
+	if err := s.remote.Load(ctx); err != nil {
 		return fmt.Errorf("Not able to load all information from Github: %v", err)
 	}
-	return s.generate(fs, rootpath, adminteam)
+	return s.generate(ctx, fs, rootpath, adminteam)
 }
 
-func (s *Scaffold) generate(fs afero.Fs, rootpath string, adminteam string) error {
+func (s *Scaffold) generate(ctx context.Context, fs afero.Fs, rootpath string, adminteam string) error {
 	fs.MkdirAll(path.Join(rootpath, "archived"), 0755)
 	fs.MkdirAll(path.Join(rootpath, "rulesets"), 0755)
 	fs.MkdirAll(path.Join(rootpath, "teams"), 0755)
 
-	usermap, err := s.generateUsers(fs, path.Join(rootpath, "users"))
+	usermap, err := s.generateUsers(ctx, fs, path.Join(rootpath, "users"))
 	if err != nil {
 		return fmt.Errorf("Error creaing the users directory: %v", err)
 	}
 
-	err, foundAdmin := s.generateTeams(fs, path.Join(rootpath, "teams"), usermap, adminteam)
+	err, foundAdmin := s.generateTeams(ctx, fs, path.Join(rootpath, "teams"), usermap, adminteam)
 	if err != nil {
 		return fmt.Errorf("Error creating the teams directory: %v", err)
 	}
@@ -92,11 +88,11 @@ func (s *Scaffold) generate(fs afero.Fs, rootpath string, adminteam string) erro
 	return nil
 }
 
-func (s *Scaffold) generateTeams(fs afero.Fs, teamspath string, usermap map[string]string, adminteam string) (error, bool) {
+func (s *Scaffold) generateTeams(ctx context.Context, fs afero.Fs, teamspath string, usermap map[string]string, adminteam string) (error, bool) {
 	adminTeamFound := false
 
-	teamsRepositories := s.remote.TeamRepositories()
-	teams := s.remote.Teams()
+	teamsRepositories := s.remote.TeamRepositories(ctx)
+	teams := s.remote.Teams(ctx)
 
 	// to ensure only one owner
 	repoAdmin := make(map[string]string)
@@ -226,14 +222,14 @@ func (s *Scaffold) generateTeams(fs afero.Fs, teamspath string, usermap map[stri
 /*
  * Returns a map[<githubid>]<username>
  */
-func (s *Scaffold) generateUsers(fs afero.Fs, userspath string) (map[string]string, error) {
+func (s *Scaffold) generateUsers(ctx context.Context, fs afero.Fs, userspath string) (map[string]string, error) {
 	fs.MkdirAll(path.Join(userspath, "protected"), 0755)
 	fs.MkdirAll(path.Join(userspath, "org"), 0755)
 	fs.MkdirAll(path.Join(userspath, "external"), 0755)
 
 	usermap := make(map[string]string)
 	// test SAML integration
-	users, err := s.loadUsersFromGithubOrgSaml()
+	users, err := s.loadUsersFromGithubOrgSaml(ctx)
 
 	if len(users) > 0 && err == nil {
 		for username, user := range users {
@@ -249,7 +245,10 @@ func (s *Scaffold) generateUsers(fs afero.Fs, userspath string) (map[string]stri
 		}
 	} else {
 		// fail back on github id
-		for githubid := range s.remote.Users() {
+		for githubid, state := range s.remote.Users(ctx) {
+			if state == engine.UserStateInvited || state == engine.UserStateInvitationFailed {
+				continue
+			}
 			usermap[githubid] = githubid
 			user := entity.User{}
 			user.ApiVersion = "v1"
@@ -335,6 +334,9 @@ jobs:
 		runs-on: ubuntu-latest
 		steps:
 			- uses: actions/checkout@v3
+				with:
+					# the goliac tag is needed to check the protected users
+					fetch-depth: 0
 
 			- name: Verify
 			uses: addnab/docker-run-action@v3
@@ -1,6 +1,7 @@
 package internal
 
 import (
+	"context"
 	"fmt"
 	"testing"
 
@@ -18,35 +19,61 @@ type ScaffoldGoliacRemoteMock struct {
 	teamsRepos map[string]map[string]*engine.GithubTeamRepo
 }
 
-func (s *ScaffoldGoliacRemoteMock) Load() error {
+func (s *ScaffoldGoliacRemoteMock) Load(ctx context.Context) error {
+	return nil
+}
+func (s *ScaffoldGoliacRemoteMock) ExternalGroups(ctx context.Context) map[string]*engine.GithubExternalGroup {
 	return nil
 }
 func (s *ScaffoldGoliacRemoteMock) FlushCache() {
 }
-func (s *ScaffoldGoliacRemoteMock) Users() map[string]string {
+func (s *ScaffoldGoliacRemoteMock) InvalidateCache(caches ...string) {
+}
+func (s *ScaffoldGoliacRemoteMock) Users(ctx context.Context) map[string]string {
 	return s.users
 }
-func (s *ScaffoldGoliacRemoteMock) TeamSlugByName() map[string]string {
+func (s *ScaffoldGoliacRemoteMock) TeamSlugByName(ctx context.Context) map[string]string {
 	return nil
 }
-func (s *ScaffoldGoliacRemoteMock) Teams() map[string]*engine.GithubTeam {
+func (s *ScaffoldGoliacRemoteMock) Teams(ctx context.Context) map[string]*engine.GithubTeam {
 	return s.teams
 }
-func (s *ScaffoldGoliacRemoteMock) Repositories() map[string]*engine.GithubRepository {
+func (s *ScaffoldGoliacRemoteMock) Repositories(ctx context.Context) map[string]*engine.GithubRepository {
 	return s.repos
 }
-func (s *ScaffoldGoliacRemoteMock) TeamRepositories() map[string]map[string]*engine.GithubTeamRepo {
+func (s *ScaffoldGoliacRemoteMock) TeamRepositories(ctx context.Context) map[string]map[string]*engine.GithubTeamRepo {
 	return s.teamsRepos
 }
-func (s *ScaffoldGoliacRemoteMock) RuleSets() map[string]*engine.GithubRuleSet {
+func (s *ScaffoldGoliacRemoteMock) RuleSets(ctx context.Context) map[string]*engine.GithubRuleSet {
+	return nil
+}
+func (s *ScaffoldGoliacRemoteMock) AppIds(ctx context.Context) map[string]int {
+	return nil
+}
+func (s *ScaffoldGoliacRemoteMock) LoadActions(ctx context.Context) error {
+	return nil
+}
+func (s *ScaffoldGoliacRemoteMock) RepositoriesActions(ctx context.Context) map[string]*engine.GithubActions {
+	return nil
+}
+func (s *ScaffoldGoliacRemoteMock) OrgActions(ctx context.Context) *engine.GithubActions {
+	return nil
+}
+func (s *ScaffoldGoliacRemoteMock) CustomProperties(ctx context.Context) map[string]*engine.GithubCustomProperty {
+	return nil
+}
+func (s *ScaffoldGoliacRemoteMock) RepositoriesCustomProperties(ctx context.Context) map[string]map[string][]string {
 	return nil
 }
-func (s *ScaffoldGoliacRemoteMock) AppIds() map[string]int {
+func (s *ScaffoldGoliacRemoteMock) OrganizationSettings(ctx context.Context) *engine.GithubOrganizationSettings {
 	return nil
 }
 func (s *ScaffoldGoliacRemoteMock) IsEnterprise() bool {
 	return true
 }
+func (s *ScaffoldGoliacRemoteMock) GetUserLogin(ctx context.Context, githubid string) (string, error) {
+	return githubid, nil
+}
 
 func NewScaffoldGoliacRemoteMock() engine.GoliacRemote {
 	users := make(map[string]string)
@@ -111,7 +138,7 @@ func NewScaffoldGoliacRemoteMock() engine.GoliacRemote {
 	return &mock
 }
 
-func LoadGithubSamlUsersMock() (map[string]*entity.User, error) {
+func LoadGithubSamlUsersMock(ctx context.Context) (map[string]*entity.User, error) {
 	users := make(map[string]*entity.User)
 	user1 := &entity.User{}
 	user1.ApiVersion = "v1"
@@ -137,7 +164,7 @@ func LoadGithubSamlUsersMock() (map[string]*entity.User, error) {
 	return users, nil
 }
 
-func NoLoadGithubSamlUsersMock() (map[string]*entity.User, error) {
+func NoLoadGithubSamlUsersMock(ctx context.Context) (map[string]*entity.User, error) {
 	return nil, fmt.Errorf("not able to fetch SAML data")
 }
 
@@ -153,7 +180,7 @@ func TestScaffoldUnit(t *testing.T) {
 			loadUsersFromGithubOrgSaml: NoLoadGithubSamlUsersMock,
 		}
 
-		users, err := scaffold.generateUsers(fs, "/users")
+		users, err := scaffold.generateUsers(context.TODO(), fs, "/users")
 		assert.Nil(t, err)
 		assert.Equal(t, 4, len(users))
 
@@ -171,7 +198,7 @@ func TestScaffoldUnit(t *testing.T) {
 			loadUsersFromGithubOrgSaml: LoadGithubSamlUsersMock,
 		}
 
-		users, err := scaffold.generateUsers(fs, "/users")
+		users, err := scaffold.generateUsers(context.TODO(), fs, "/users")
 		assert.Nil(t, err)
 		assert.Equal(t, 3, len(users))
 
@@ -242,11 +269,11 @@ func TestScaffoldFull(t *testing.T) {
 			loadUsersFromGithubOrgSaml: NoLoadGithubSamlUsersMock,
 		}
 
-		users, err := scaffold.generateUsers(fs, "/users")
+		users, err := scaffold.generateUsers(context.TODO(), fs, "/users")
 		assert.Nil(t, err)
 		assert.Equal(t, 4, len(users))
 
-		err, foundAdmin := scaffold.generateTeams(fs, "/teams", users, "admin")
+		err, foundAdmin := scaffold.generateTeams(context.TODO(), fs, "/teams", users, "admin")
 		assert.Nil(t, err)
 		assert.Equal(t, true, foundAdmin)
 
@@ -268,11 +295,11 @@ func TestScaffoldFull(t *testing.T) {
 			loadUsersFromGithubOrgSaml: LoadGithubSamlUsersMock,
 		}
 
-		users, err := scaffold.generateUsers(fs, "/users")
+		users, err := scaffold.generateUsers(context.TODO(), fs, "/users")
 		assert.Nil(t, err)
 		assert.Equal(t, 3, len(users))
 
-		err, foundAdmin := scaffold.generateTeams(fs, "/teams", users, "admin")
+		err, foundAdmin := scaffold.generateTeams(context.TODO(), fs, "/teams", users, "admin")
 		assert.Nil(t, err)
 		assert.Equal(t, true, foundAdmin)
 
@@ -0,0 +1,36 @@
+package secrets
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/Alayacare/goliac/internal/engine"
+)
+
+// the only environment variables repository secrets can read
+const RepositorySecretEnvPrefix = "GOLIAC_SECRET_"
+
+/*
+ * SecretProviderEnv fetches the secret value from an environment variable
+ */
+type SecretProviderEnv struct{}
+
+func NewSecretProviderEnv() engine.SecretProvider {
+	return &SecretProviderEnv{}
+}
+
+func (p *SecretProviderEnv) GetSecret(key string) (string, error) {
+	value, ok := os.LookupEnv(key)
+	if !ok {
+		return "", fmt.Errorf("environment variable %s not found", key)
+	}
+	return value, nil
+}
+
+func (p *SecretProviderEnv) CheckRepositoryKey(key string) error {
+	if !strings.HasPrefix(key, RepositorySecretEnvPrefix) {
+		return fmt.Errorf("the environment variable %s doesn't start with %s", key, RepositorySecretEnvPrefix)
+	}
+	return nil
+}
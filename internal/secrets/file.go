@@ -0,0 +1,60 @@
+package secrets
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/Alayacare/goliac/internal/config"
+	"github.com/Alayacare/goliac/internal/engine"
+	"github.com/spf13/afero"
+)
+
+/*
+ * SecretProviderFile fetches the secret value from a file (like a
+ * Kubernetes mounted secret, or a Vault agent output).
+ * Relative filenames are relative to GOLIAC_SECRETS_DIRECTORY
+ */
+type SecretProviderFile struct {
+	Fs afero.Fs
+}
+
+func NewSecretProviderFile() engine.SecretProvider {
+	return &SecretProviderFile{
+		Fs: afero.NewOsFs(),
+	}
+}
+
+func (p *SecretProviderFile) GetSecret(key string) (string, error) {
+	filename := key
+	if !filepath.IsAbs(filename) {
+		filename = filepath.Join(config.Config.SecretsDirectory, filename)
+	}
+	content, err := afero.ReadFile(p.Fs, filename)
+	if err != nil {
+		return "", fmt.Errorf("not able to read secret file %s: %v", filename, err)
+	}
+	return strings.TrimRight(string(content), "\r\n"), nil
+}
+
+/*
+ * CheckRepositoryKey only accepts the files of GOLIAC_SECRETS_DIRECTORY
+ */
+func (p *SecretProviderFile) CheckRepositoryKey(key string) error {
+	if config.Config.SecretsDirectory == "" {
+		return fmt.Errorf("GOLIAC_SECRETS_DIRECTORY is not set")
+	}
+	if filepath.IsAbs(key) {
+		return fmt.Errorf("%s must be relative to GOLIAC_SECRETS_DIRECTORY", key)
+	}
+	for _, part := range strings.Split(filepath.ToSlash(key), "/") {
+		if part == ".." {
+			return fmt.Errorf("%s must not contain ..", key)
+		}
+	}
+	rel, err := filepath.Rel(config.Config.SecretsDirectory, filepath.Join(config.Config.SecretsDirectory, key))
+	if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+		return fmt.Errorf("%s is not in GOLIAC_SECRETS_DIRECTORY", key)
+	}
+	return nil
+}
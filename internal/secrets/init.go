@@ -0,0 +1,10 @@
+package secrets
+
+import (
+	"github.com/Alayacare/goliac/internal/engine"
+)
+
+func InitProviders() {
+	engine.RegisterSecretProvider("env", NewSecretProviderEnv())
+	engine.RegisterSecretProvider("file", NewSecretProviderFile())
+}
@@ -0,0 +1,34 @@
+package secrets
+
+import (
+	"testing"
+
+	"github.com/Alayacare/goliac/internal/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckRepositoryKey(t *testing.T) {
+	t.Run("happy path: env variables with the GOLIAC_SECRET_ prefix", func(t *testing.T) {
+		p := &SecretProviderEnv{}
+		assert.Nil(t, p.CheckRepositoryKey("GOLIAC_SECRET_NPM_TOKEN"))
+		assert.NotNil(t, p.CheckRepositoryKey("GOLIAC_GITHUB_APP_PRIVATE_KEY"))
+		assert.NotNil(t, p.CheckRepositoryKey("GOLIAC_GITHUB_TOKEN"))
+	})
+
+	t.Run("happy path: files in the secrets directory", func(t *testing.T) {
+		config.Config.SecretsDirectory = "/secrets"
+		defer func() { config.Config.SecretsDirectory = "" }()
+
+		p := &SecretProviderFile{}
+		assert.Nil(t, p.CheckRepositoryKey("myrepo/npm-token"))
+		assert.NotNil(t, p.CheckRepositoryKey("/etc/goliac/private-key.pem"))
+		assert.NotNil(t, p.CheckRepositoryKey("../etc/goliac/private-key.pem"))
+		assert.NotNil(t, p.CheckRepositoryKey("myrepo/../../private-key.pem"))
+		assert.NotNil(t, p.CheckRepositoryKey("."))
+	})
+
+	t.Run("not happy path: no secrets directory", func(t *testing.T) {
+		p := &SecretProviderFile{}
+		assert.NotNil(t, p.CheckRepositoryKey("private-key.pem"))
+	})
+}
@@ -0,0 +1,38 @@
+package usersync
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Alayacare/goliac/internal/config"
+	"github.com/Alayacare/goliac/internal/engine"
+	"github.com/Alayacare/goliac/internal/entity"
+	"github.com/spf13/afero"
+)
+
+/*
+ * UserSyncPluginFile: this plugin sync users from a JSON or CSV user list
+ * read from usersync.path
+ */
+type UserSyncPluginFile struct {
+	Fs afero.Fs
+}
+
+func NewUserSyncPluginFile() engine.UserSyncPlugin {
+	return &UserSyncPluginFile{
+		Fs: afero.NewOsFs(),
+	}
+}
+
+func (p *UserSyncPluginFile) UpdateUsers(ctx context.Context, repoconfig *config.RepositoryConfig, orguserdirrectorypath string) (map[string]*entity.User, error) {
+	if repoconfig.UserSync.Path == "" {
+		return nil, fmt.Errorf("the file usersync plugin needs usersync.path")
+	}
+
+	data, err := afero.ReadFile(p.Fs, repoconfig.UserSync.Path)
+	if err != nil {
+		return nil, fmt.Errorf("not able to read the user list: %v", err)
+	}
+
+	return parseUserList(data, userListFormat(repoconfig.UserSync.Format, repoconfig.UserSync.Path), repoconfig.UserSync.Mapping)
+}
@@ -1,6 +1,7 @@
 package usersync
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/Alayacare/goliac/internal/config"
@@ -12,6 +13,8 @@ import (
 /*
  * UserSyncPluginFromGithubSaml: this plugin sync users from Github if the SAML IdP
  * integration has been added (to enable this feature you need a Github Entreprise subscription)
+ * On an Enterprise Managed Users organization (emu.enabled), the users are the
+ * organization members (all provisioned by the IdP)
  *
  * Note: this plugin doesn't clear the Remote cache.
  */
@@ -25,9 +28,19 @@ func NewUserSyncPluginFromGithubSaml(client github.GitHubClient) engine.UserSync
 	}
 }
 
-func (p *UserSyncPluginFromGithubSaml) UpdateUsers(repoconfig *config.RepositoryConfig, orguserdirrectorypath string) (map[string]*entity.User, error) {
+func (p *UserSyncPluginFromGithubSaml) UpdateUsers(ctx context.Context, repoconfig *config.RepositoryConfig, orguserdirrectorypath string) (map[string]*entity.User, error) {
+	if repoconfig.Emu.Enabled {
+		users, err := engine.LoadUsersFromGithubOrgEmu(ctx, p.client, repoconfig.Emu.Shortcode)
+		if err != nil {
+			return nil, err
+		}
+		if len(users) == 0 {
+			return nil, fmt.Errorf("Not able to find any organization member")
+		}
+		return users, nil
+	}
 
-	users, err := engine.LoadUsersFromGithubOrgSaml(p.client)
+	users, err := engine.LoadUsersFromGithubOrgSaml(ctx, p.client)
 
 	if len(users) == 0 {
 		return nil, fmt.Errorf("Not able to find any SAML identities")
@@ -0,0 +1,61 @@
+package usersync
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/Alayacare/goliac/internal/config"
+	"github.com/Alayacare/goliac/internal/engine"
+	"github.com/Alayacare/goliac/internal/entity"
+)
+
+/*
+ * UserSyncPluginHttp: this plugin sync users from a JSON or CSV user list
+ * fetched from usersync.url (with the optional GOLIAC_USERSYNC_HTTP_TOKEN bearer token)
+ */
+type UserSyncPluginHttp struct {
+	client *http.Client
+}
+
+func NewUserSyncPluginHttp() engine.UserSyncPlugin {
+	return &UserSyncPluginHttp{
+		client: http.DefaultClient,
+	}
+}
+
+func (p *UserSyncPluginHttp) UpdateUsers(ctx context.Context, repoconfig *config.RepositoryConfig, orguserdirrectorypath string) (map[string]*entity.User, error) {
+	if repoconfig.UserSync.Url == "" {
+		return nil, fmt.Errorf("the http usersync plugin needs usersync.url")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", repoconfig.UserSync.Url, nil)
+	if err != nil {
+		return nil, err
+	}
+	if config.Config.UserSyncHttpToken != "" {
+		req.Header.Set("Authorization", "Bearer "+config.Config.UserSyncHttpToken)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("not able to fetch the user list: %v", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("not able to fetch the user list: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("not able to fetch the user list: %s", resp.Status)
+	}
+
+	format := repoconfig.UserSync.Format
+	if format == "" && strings.Contains(resp.Header.Get("Content-Type"), "csv") {
+		format = "csv"
+	}
+
+	return parseUserList(body, userListFormat(format, repoconfig.UserSync.Url), repoconfig.UserSync.Mapping)
+}
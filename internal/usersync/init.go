@@ -9,4 +9,8 @@ func InitPlugins(client github.GitHubClient) {
 	engine.RegisterPlugin("noop", NewUserSyncPluginNoop())
 	engine.RegisterPlugin("shellscript", NewUserSyncPluginShellScript())
 	engine.RegisterPlugin("fromgithubsaml", NewUserSyncPluginFromGithubSaml(client))
+	engine.RegisterPlugin("ldap", NewUserSyncPluginLdap())
+	engine.RegisterPlugin("scim", NewUserSyncPluginScim())
+	engine.RegisterPlugin("http", NewUserSyncPluginHttp())
+	engine.RegisterPlugin("file", NewUserSyncPluginFile())
 }
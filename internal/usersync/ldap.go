@@ -0,0 +1,192 @@
+package usersync
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"strings"
+
+	"github.com/Alayacare/goliac/internal/config"
+	"github.com/Alayacare/goliac/internal/engine"
+	"github.com/Alayacare/goliac/internal/entity"
+	"github.com/go-ldap/ldap/v3"
+	"github.com/sirupsen/logrus"
+)
+
+// number of entries fetched per LDAP search page
+const ldapPageSize = 500
+
+/*
+ * UserSyncPluginLdap: this plugin sync users from a LDAP directory (or an Active Directory)
+ * configured in the usersync.ldap section of goliac.yaml
+ */
+type UserSyncPluginLdap struct{}
+
+func NewUserSyncPluginLdap() engine.UserSyncPlugin {
+	return &UserSyncPluginLdap{}
+}
+
+func (p *UserSyncPluginLdap) UpdateUsers(ctx context.Context, repoconfig *config.RepositoryConfig, orguserdirrectorypath string) (map[string]*entity.User, error) {
+	ldapconfig := repoconfig.UserSync.Ldap
+
+	conn, closeConn, err := ldapConnect(ctx, ldapconfig)
+	if err != nil {
+		return nil, err
+	}
+	defer closeConn()
+
+	usersByDN, err := ldapSearchUsers(ctx, conn, ldapconfig)
+	if err != nil {
+		return nil, err
+	}
+
+	users := make(map[string]*entity.User)
+	for _, user := range usersByDN {
+		users[user.Name] = user
+	}
+
+	if len(users) == 0 {
+		return nil, fmt.Errorf("not able to find any user in %s matching %s", ldapconfig.BaseDN, ldapconfig.Filter)
+	}
+
+	return users, nil
+}
+
+func (p *UserSyncPluginLdap) UpdateGroups(ctx context.Context, repoconfig *config.RepositoryConfig, groupnames []string) (map[string][]string, error) {
+	ldapconfig := repoconfig.UserSync.Ldap
+
+	conn, closeConn, err := ldapConnect(ctx, ldapconfig)
+	if err != nil {
+		return nil, err
+	}
+	defer closeConn()
+
+	usersByDN, err := ldapSearchUsers(ctx, conn, ldapconfig)
+	if err != nil {
+		return nil, err
+	}
+
+	baseDN := ldapconfig.GroupBaseDN
+	if baseDN == "" {
+		baseDN = ldapconfig.BaseDN
+	}
+	names := ""
+	for _, groupname := range groupnames {
+		names += fmt.Sprintf("(%s=%s)", ldapconfig.GroupNameAttribute, ldap.EscapeFilter(groupname))
+	}
+	request := ldap.NewSearchRequest(
+		baseDN,
+		ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 0, 0, false,
+		fmt.Sprintf("(&%s(|%s))", ldapconfig.GroupFilter, names),
+		[]string{ldapconfig.GroupNameAttribute, ldapconfig.GroupMemberAttribute},
+		nil,
+	)
+	result, err := conn.SearchWithPaging(request, ldapPageSize)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, fmt.Errorf("ldap groups search failed: %v", err)
+	}
+
+	groups := make(map[string][]string)
+	for _, entry := range result.Entries {
+		members := []string{}
+		for _, memberDN := range entry.GetAttributeValues(ldapconfig.GroupMemberAttribute) {
+			if user, ok := usersByDN[strings.ToLower(memberDN)]; ok {
+				members = append(members, user.Name)
+			}
+		}
+		groups[entry.GetAttributeValue(ldapconfig.GroupNameAttribute)] = members
+	}
+	return groups, nil
+}
+
+/*
+ * ldapConnect connects (and binds) to the directory. The returned function closes the connection
+ */
+func ldapConnect(ctx context.Context, ldapconfig config.UserSyncLdap) (*ldap.Conn, func(), error) {
+	if ldapconfig.Url == "" || ldapconfig.BaseDN == "" || ldapconfig.GithubIDAttribute == "" {
+		return nil, nil, fmt.Errorf("the ldap usersync plugin needs usersync.ldap.url, usersync.ldap.base_dn and usersync.ldap.githubid_attribute")
+	}
+
+	tlsConfig := &tls.Config{InsecureSkipVerify: ldapconfig.InsecureSkipVerify}
+	conn, err := ldap.DialURL(ldapconfig.Url, ldap.DialWithTLSConfig(tlsConfig))
+	if err != nil {
+		return nil, nil, fmt.Errorf("not able to connect to %s: %v", ldapconfig.Url, err)
+	}
+
+	// the ldap client doesn't take a context: closing the connection stops the search
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-done:
+		}
+	}()
+	closeConn := func() {
+		close(done)
+		conn.Close()
+	}
+
+	if ldapconfig.StartTLS {
+		if err := conn.StartTLS(tlsConfig); err != nil {
+			closeConn()
+			return nil, nil, fmt.Errorf("not able to start TLS: %v", err)
+		}
+	}
+
+	if config.Config.LdapBindDN != "" {
+		if err := conn.Bind(config.Config.LdapBindDN, config.Config.LdapBindPassword); err != nil {
+			closeConn()
+			return nil, nil, fmt.Errorf("not able to bind as %s: %v", config.Config.LdapBindDN, err)
+		}
+	}
+
+	return conn, closeConn, nil
+}
+
+/*
+ * ldapSearchUsers returns the users found in the directory, by (lower case) DN
+ */
+func ldapSearchUsers(ctx context.Context, conn *ldap.Conn, ldapconfig config.UserSyncLdap) (map[string]*entity.User, error) {
+	request := ldap.NewSearchRequest(
+		ldapconfig.BaseDN,
+		ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 0, 0, false,
+		ldapconfig.Filter,
+		[]string{ldapconfig.UsernameAttribute, ldapconfig.GithubIDAttribute},
+		nil,
+	)
+	result, err := conn.SearchWithPaging(request, ldapPageSize)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, fmt.Errorf("ldap search failed: %v", err)
+	}
+
+	users := make(map[string]*entity.User)
+	usernames := make(map[string]bool)
+	for _, entry := range result.Entries {
+		username := entry.GetAttributeValue(ldapconfig.UsernameAttribute)
+		githubid := entry.GetAttributeValue(ldapconfig.GithubIDAttribute)
+		if username == "" || githubid == "" {
+			logrus.Debugf("ldap entry %s skipped: no %s or %s attribute", entry.DN, ldapconfig.UsernameAttribute, ldapconfig.GithubIDAttribute)
+			continue
+		}
+		if usernames[username] {
+			logrus.Warnf("ldap entry %s skipped: user %s already found", entry.DN, username)
+			continue
+		}
+		usernames[username] = true
+
+		user := &entity.User{}
+		user.ApiVersion = "v1"
+		user.Kind = "User"
+		user.Name = username
+		user.Spec.GithubID = githubid
+		users[strings.ToLower(entry.DN)] = user
+	}
+	return users, nil
+}
@@ -0,0 +1,196 @@
+package usersync
+
+import (
+	"context"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/Alayacare/goliac/internal/config"
+	ber "github.com/go-asn1-ber/asn1-ber"
+	"github.com/go-ldap/ldap/v3"
+	"github.com/stretchr/testify/assert"
+)
+
+/*
+ * fakeLdapServer is a minimal in-process LDAP server: it answers binds
+ * (checking the password) and returns all its entries under the base DN
+ * to any search (the filter is only recorded)
+ */
+type fakeLdapServer struct {
+	listener   net.Listener
+	bindDN     string
+	password   string
+	entries    map[string]map[string][]string // dn -> attribute -> values
+	lastFilter string
+}
+
+func newFakeLdapServer(t *testing.T, bindDN, password string, entries map[string]map[string][]string) *fakeLdapServer {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	s := &fakeLdapServer{listener: listener, bindDN: bindDN, password: password, entries: entries}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *fakeLdapServer) url() string {
+	return "ldap://" + s.listener.Addr().String()
+}
+
+func (s *fakeLdapServer) serve(conn net.Conn) {
+	defer conn.Close()
+	for {
+		packet, err := ber.ReadPacket(conn)
+		if err != nil || len(packet.Children) < 2 {
+			return
+		}
+		messageID := packet.Children[0].Value.(int64)
+		op := packet.Children[1]
+
+		switch op.Tag {
+		case ldap.ApplicationBindRequest:
+			dn := string(op.Children[1].ByteValue)
+			password := op.Children[2].Data.String()
+			resultCode := ldap.LDAPResultSuccess
+			if dn != s.bindDN || password != s.password {
+				resultCode = ldap.LDAPResultInvalidCredentials
+			}
+			s.reply(conn, messageID, ldapResult(ldap.ApplicationBindResponse, resultCode))
+		case ldap.ApplicationSearchRequest:
+			baseDN := op.Children[0].Data.String()
+			if filter, err := ldap.DecompileFilter(op.Children[6]); err == nil {
+				s.lastFilter = filter
+			}
+			for dn, attributes := range s.entries {
+				if !strings.HasSuffix(dn, baseDN) {
+					continue
+				}
+				entry := ber.Encode(ber.ClassApplication, ber.TypeConstructed, ldap.ApplicationSearchResultEntry, nil, "")
+				entry.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, dn, ""))
+				list := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "")
+				for name, attributeValues := range attributes {
+					attribute := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "")
+					attribute.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, name, ""))
+					values := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSet, nil, "")
+					for _, value := range attributeValues {
+						values.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, value, ""))
+					}
+					attribute.AppendChild(values)
+					list.AppendChild(attribute)
+				}
+				entry.AppendChild(list)
+				s.reply(conn, messageID, entry)
+			}
+			s.reply(conn, messageID, ldapResult(ldap.ApplicationSearchResultDone, ldap.LDAPResultSuccess))
+		default:
+			// unbind (or anything else)
+			return
+		}
+	}
+}
+
+func ldapResult(tag ber.Tag, resultCode int) *ber.Packet {
+	result := ber.Encode(ber.ClassApplication, ber.TypeConstructed, tag, nil, "")
+	result.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagEnumerated, int64(resultCode), ""))
+	result.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", ""))
+	result.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", ""))
+	return result
+}
+
+func (s *fakeLdapServer) reply(conn net.Conn, messageID int64, op *ber.Packet) {
+	packet := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "")
+	packet.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagInteger, messageID, ""))
+	packet.AppendChild(op)
+	conn.Write(packet.Bytes())
+}
+
+func TestUserSyncPluginLdap(t *testing.T) {
+	entries := map[string]map[string][]string{
+		"uid=alice,ou=people,dc=example,dc=com": {"uid": {"alice"}, "githubID": {"alice-gh"}},
+		"uid=bob,ou=people,dc=example,dc=com":   {"uid": {"bob"}, "githubID": {"bob-gh"}},
+		"uid=carol,ou=people,dc=example,dc=com": {"uid": {"carol"}}, // no github account
+		"cn=eng-payments,ou=groups,dc=example,dc=com": {
+			"cn":     {"eng-payments"},
+			"member": {"uid=alice,ou=people,dc=example,dc=com", "uid=carol,ou=people,dc=example,dc=com"},
+		},
+	}
+	server := newFakeLdapServer(t, "cn=goliac,dc=example,dc=com", "secret", entries)
+	defer server.listener.Close()
+
+	newRepoConfig := func() *config.RepositoryConfig {
+		repoconfig := &config.RepositoryConfig{}
+		repoconfig.UserSync.Plugin = "ldap"
+		repoconfig.UserSync.Ldap = config.UserSyncLdap{
+			Url:               server.url(),
+			BaseDN:            "ou=people,dc=example,dc=com",
+			Filter:            "(memberOf=cn=github,ou=groups,dc=example,dc=com)",
+			UsernameAttribute: "uid",
+			GithubIDAttribute: "githubID",
+		}
+		return repoconfig
+	}
+
+	t.Run("happy path: users are loaded from the directory", func(t *testing.T) {
+		config.Config.LdapBindDN = "cn=goliac,dc=example,dc=com"
+		config.Config.LdapBindPassword = "secret"
+		defer func() {
+			config.Config.LdapBindDN = ""
+			config.Config.LdapBindPassword = ""
+		}()
+
+		users, err := NewUserSyncPluginLdap().UpdateUsers(context.TODO(), newRepoConfig(), "/users/org")
+
+		assert.Nil(t, err)
+		assert.Equal(t, 2, len(users))
+		assert.Equal(t, "alice-gh", users["alice"].Spec.GithubID)
+		assert.Equal(t, "bob", users["bob"].Name)
+		assert.Equal(t, "User", users["bob"].Kind)
+		assert.Equal(t, "(memberOf=cn=github,ou=groups,dc=example,dc=com)", server.lastFilter)
+	})
+
+	t.Run("happy path: groups members", func(t *testing.T) {
+		repoconfig := newRepoConfig()
+		repoconfig.UserSync.Ldap.GroupBaseDN = "ou=groups,dc=example,dc=com"
+		repoconfig.UserSync.Ldap.GroupFilter = "(objectClass=groupOfNames)"
+		repoconfig.UserSync.Ldap.GroupNameAttribute = "cn"
+		repoconfig.UserSync.Ldap.GroupMemberAttribute = "member"
+
+		groups, err := NewUserSyncPluginLdap().(*UserSyncPluginLdap).UpdateGroups(context.TODO(), repoconfig, []string{"eng-payments"})
+
+		assert.Nil(t, err)
+		// carol has no github account
+		assert.Equal(t, map[string][]string{"eng-payments": {"alice"}}, groups)
+		assert.Equal(t, "(&(objectClass=groupOfNames)(|(cn=eng-payments)))", server.lastFilter)
+	})
+
+	t.Run("not happy path: wrong bind password", func(t *testing.T) {
+		config.Config.LdapBindDN = "cn=goliac,dc=example,dc=com"
+		config.Config.LdapBindPassword = "wrong"
+		defer func() {
+			config.Config.LdapBindDN = ""
+			config.Config.LdapBindPassword = ""
+		}()
+
+		_, err := NewUserSyncPluginLdap().UpdateUsers(context.TODO(), newRepoConfig(), "/users/org")
+
+		assert.NotNil(t, err)
+		assert.Contains(t, err.Error(), "not able to bind")
+	})
+
+	t.Run("not happy path: missing configuration", func(t *testing.T) {
+		repoconfig := newRepoConfig()
+		repoconfig.UserSync.Ldap.GithubIDAttribute = ""
+
+		_, err := NewUserSyncPluginLdap().UpdateUsers(context.TODO(), repoconfig, "/users/org")
+
+		assert.NotNil(t, err)
+	})
+}
@@ -1,6 +1,7 @@
 package usersync
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/Alayacare/goliac/internal/config"
@@ -19,7 +20,7 @@ func NewUserSyncPluginNoop() engine.UserSyncPlugin {
 	}
 }
 
-func (p *UserSyncPluginNoop) UpdateUsers(repoconfig *config.RepositoryConfig, orguserdirrectorypath string) (map[string]*entity.User, error) {
+func (p *UserSyncPluginNoop) UpdateUsers(ctx context.Context, repoconfig *config.RepositoryConfig, orguserdirrectorypath string) (map[string]*entity.User, error) {
 	users, errs, _ := entity.ReadUserDirectory(p.Fs, orguserdirrectorypath)
 	if len(errs) > 0 {
 		return nil, fmt.Errorf("cannot load org users (for example: %v)", errs[0])
@@ -0,0 +1,269 @@
+package usersync
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/Alayacare/goliac/internal/config"
+	"github.com/Alayacare/goliac/internal/engine"
+	"github.com/Alayacare/goliac/internal/entity"
+	"github.com/sirupsen/logrus"
+)
+
+// number of SCIM resources fetched per page
+const scimPageSize = 100
+
+/*
+ * UserSyncPluginScim: this plugin sync users from a SCIM 2.0 service provider
+ * (like Okta or Entra ID) configured in the usersync.scim section of goliac.yaml
+ */
+type UserSyncPluginScim struct {
+	client *http.Client
+}
+
+func NewUserSyncPluginScim() engine.UserSyncPlugin {
+	return &UserSyncPluginScim{
+		client: http.DefaultClient,
+	}
+}
+
+type scimListResponse struct {
+	TotalResults int                      `json:"totalResults"`
+	StartIndex   int                      `json:"startIndex"`
+	ItemsPerPage int                      `json:"itemsPerPage"`
+	Resources    []map[string]interface{} `json:"Resources"`
+}
+
+func (p *UserSyncPluginScim) UpdateUsers(ctx context.Context, repoconfig *config.RepositoryConfig, orguserdirrectorypath string) (map[string]*entity.User, error) {
+	scimconfig := repoconfig.UserSync.Scim
+	if scimconfig.Url == "" || scimconfig.GithubIDAttribute == "" {
+		return nil, fmt.Errorf("the scim usersync plugin needs usersync.scim.url and usersync.scim.githubid_attribute")
+	}
+
+	var members map[string]bool
+	if scimconfig.Group != "" {
+		groups, err := p.loadGroups(ctx, scimconfig)
+		if err != nil {
+			return nil, err
+		}
+		ids, ok := groups[scimconfig.Group]
+		if !ok {
+			return nil, fmt.Errorf("SCIM group %s not found", scimconfig.Group)
+		}
+		members = make(map[string]bool)
+		for _, id := range ids {
+			members[id] = true
+		}
+	}
+
+	scimUsers, err := p.loadUsers(ctx, scimconfig)
+	if err != nil {
+		return nil, err
+	}
+
+	users := make(map[string]*entity.User)
+	for id, user := range scimUsers {
+		if members != nil && !members[id] {
+			continue
+		}
+		users[user.Name] = user
+	}
+
+	if len(users) == 0 {
+		return nil, fmt.Errorf("not able to find any SCIM user")
+	}
+
+	return users, nil
+}
+
+func (p *UserSyncPluginScim) UpdateGroups(ctx context.Context, repoconfig *config.RepositoryConfig, groupnames []string) (map[string][]string, error) {
+	scimconfig := repoconfig.UserSync.Scim
+
+	scimUsers, err := p.loadUsers(ctx, scimconfig)
+	if err != nil {
+		return nil, err
+	}
+	scimGroups, err := p.loadGroups(ctx, scimconfig)
+	if err != nil {
+		return nil, err
+	}
+
+	groups := make(map[string][]string)
+	for _, groupname := range groupnames {
+		ids, ok := scimGroups[groupname]
+		if !ok {
+			continue
+		}
+		members := []string{}
+		for _, id := range ids {
+			if user, ok := scimUsers[id]; ok {
+				members = append(members, user.Name)
+			}
+		}
+		groups[groupname] = members
+	}
+	return groups, nil
+}
+
+/*
+ * loadUsers returns the active SCIM users (with a Github login), by SCIM id
+ */
+func (p *UserSyncPluginScim) loadUsers(ctx context.Context, scimconfig config.UserSyncScim) (map[string]*entity.User, error) {
+	resources, err := p.list(ctx, scimconfig.Url, "Users")
+	if err != nil {
+		return nil, err
+	}
+
+	users := make(map[string]*entity.User)
+	for _, resource := range resources {
+		if active, ok := resource["active"].(bool); ok && !active {
+			continue
+		}
+		user, err := ScimResourceToUser(resource, scimconfig.UsernameAttribute, scimconfig.GithubIDAttribute)
+		if err != nil {
+			logrus.Debugf("SCIM user %s skipped: %v", ScimAttribute(resource, "id"), err)
+			continue
+		}
+		users[ScimAttribute(resource, "id")] = user
+	}
+	return users, nil
+}
+
+/*
+ * loadGroups returns the members (SCIM ids) of each SCIM group, by display name
+ */
+func (p *UserSyncPluginScim) loadGroups(ctx context.Context, scimconfig config.UserSyncScim) (map[string][]string, error) {
+	resources, err := p.list(ctx, scimconfig.Url, "Groups")
+	if err != nil {
+		return nil, err
+	}
+
+	groups := make(map[string][]string)
+	for _, group := range resources {
+		members := []string{}
+		if list, ok := group["members"].([]interface{}); ok {
+			for _, m := range list {
+				if member, ok := m.(map[string]interface{}); ok {
+					members = append(members, fmt.Sprintf("%v", member["value"]))
+				}
+			}
+		}
+		groups[ScimAttribute(group, "displayName")] = members
+	}
+	return groups, nil
+}
+
+/*
+ * list fetches all the resources of a SCIM endpoint (Users or Groups), page by page
+ */
+func (p *UserSyncPluginScim) list(ctx context.Context, baseUrl string, endpoint string) ([]map[string]interface{}, error) {
+	resources := []map[string]interface{}{}
+	startIndex := 1
+	for {
+		query := url.Values{}
+		query.Set("startIndex", fmt.Sprintf("%d", startIndex))
+		query.Set("count", fmt.Sprintf("%d", scimPageSize))
+		req, err := http.NewRequestWithContext(ctx, "GET", strings.TrimSuffix(baseUrl, "/")+"/"+endpoint+"?"+query.Encode(), nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Accept", "application/scim+json")
+		if config.Config.ScimToken != "" {
+			req.Header.Set("Authorization", "Bearer "+config.Config.ScimToken)
+		}
+
+		resp, err := p.client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("not able to list SCIM %s: %s", endpoint, resp.Status)
+		}
+
+		var page scimListResponse
+		if err := json.Unmarshal(body, &page); err != nil {
+			return nil, fmt.Errorf("not able to parse SCIM %s: %v", endpoint, err)
+		}
+		resources = append(resources, page.Resources...)
+
+		startIndex += len(page.Resources)
+		if len(page.Resources) == 0 || startIndex > page.TotalResults {
+			break
+		}
+	}
+	return resources, nil
+}
+
+/*
+ * ScimAttribute returns the (string) value of a SCIM resource attribute:
+ * - a simple attribute: userName
+ * - a sub-attribute: name.givenName
+ * - the primary (or first) value of a multi-valued attribute: emails
+ * - an extension attribute: urn:ietf:params:scim:schemas:extension:enterprise:2.0:User:employeeNumber
+ */
+func ScimAttribute(resource map[string]interface{}, path string) string {
+	if i := strings.LastIndex(path, ":"); i >= 0 {
+		extension, ok := resource[path[:i]].(map[string]interface{})
+		if !ok {
+			return ""
+		}
+		return ScimAttribute(extension, path[i+1:])
+	}
+
+	var value interface{} = resource
+	for _, name := range strings.Split(path, ".") {
+		attributes, ok := value.(map[string]interface{})
+		if !ok {
+			return ""
+		}
+		value = attributes[name]
+	}
+
+	switch v := value.(type) {
+	case string:
+		return v
+	case []interface{}:
+		// multi-valued attribute
+		first := ""
+		for _, item := range v {
+			if m, ok := item.(map[string]interface{}); ok {
+				if primary, _ := m["primary"].(bool); primary {
+					return fmt.Sprintf("%v", m["value"])
+				}
+				if first == "" {
+					first = fmt.Sprintf("%v", m["value"])
+				}
+			}
+		}
+		return first
+	case nil:
+		return ""
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
+
+/*
+ * ScimResourceToUser converts a SCIM User resource into a Goliac user
+ */
+func ScimResourceToUser(resource map[string]interface{}, usernameAttribute string, githubidAttribute string) (*entity.User, error) {
+	username := ScimAttribute(resource, usernameAttribute)
+	githubid := ScimAttribute(resource, githubidAttribute)
+	if username == "" {
+		return nil, fmt.Errorf("no %s attribute", usernameAttribute)
+	}
+	if githubid == "" {
+		return nil, fmt.Errorf("no %s attribute", githubidAttribute)
+	}
+	return newUser(username, githubid)
+}
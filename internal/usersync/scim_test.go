@@ -0,0 +1,155 @@
+package usersync
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/Alayacare/goliac/internal/config"
+	"github.com/stretchr/testify/assert"
+)
+
+/*
+ * newFakeScimServer serves the given Users and Groups, one resource per page
+ * (to exercise the pagination), if the bearer token is correct
+ */
+func newFakeScimServer(token string, users []map[string]interface{}, groups []map[string]interface{}) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer "+token {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		resources := users
+		if r.URL.Path == "/scim/v2/Groups" {
+			resources = groups
+		}
+		startIndex, _ := strconv.Atoi(r.URL.Query().Get("startIndex"))
+		page := []map[string]interface{}{}
+		if startIndex >= 1 && startIndex <= len(resources) {
+			page = resources[startIndex-1 : startIndex]
+		}
+		w.Header().Set("Content-Type", "application/scim+json")
+		json.NewEncoder(w).Encode(scimListResponse{
+			TotalResults: len(resources),
+			StartIndex:   startIndex,
+			ItemsPerPage: len(page),
+			Resources:    page,
+		})
+	}))
+}
+
+func TestUserSyncPluginScim(t *testing.T) {
+	users := []map[string]interface{}{
+		{
+			"id":       "1",
+			"userName": "alice@mycompany.com",
+			"active":   true,
+			"urn:ietf:params:scim:schemas:extension:enterprise:2.0:User": map[string]interface{}{"githubLogin": "alice-gh"},
+		},
+		{
+			"id":       "2",
+			"userName": "bob@mycompany.com",
+			"active":   true,
+			"urn:ietf:params:scim:schemas:extension:enterprise:2.0:User": map[string]interface{}{"githubLogin": "bob-gh"},
+		},
+		{
+			"id":       "3",
+			"userName": "carol@mycompany.com",
+			"active":   false,
+			"urn:ietf:params:scim:schemas:extension:enterprise:2.0:User": map[string]interface{}{"githubLogin": "carol-gh"},
+		},
+		{
+			"id":       "4",
+			"userName": "dave@mycompany.com", // no github login
+			"active":   true,
+		},
+	}
+	groups := []map[string]interface{}{
+		{"id": "g1", "displayName": "everyone"},
+		{"id": "g2", "displayName": "github", "members": []interface{}{map[string]interface{}{"value": "2"}}},
+	}
+	server := newFakeScimServer("scimtoken", users, groups)
+	defer server.Close()
+
+	config.Config.ScimToken = "scimtoken"
+	defer func() { config.Config.ScimToken = "" }()
+
+	newRepoConfig := func() *config.RepositoryConfig {
+		repoconfig := &config.RepositoryConfig{}
+		repoconfig.UserSync.Plugin = "scim"
+		repoconfig.UserSync.Scim = config.UserSyncScim{
+			Url:               server.URL + "/scim/v2",
+			UsernameAttribute: "userName",
+			GithubIDAttribute: "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User:githubLogin",
+		}
+		return repoconfig
+	}
+
+	t.Run("happy path: active users are loaded", func(t *testing.T) {
+		result, err := NewUserSyncPluginScim().UpdateUsers(context.TODO(), newRepoConfig(), "/users/org")
+
+		assert.Nil(t, err)
+		assert.Equal(t, 2, len(result))
+		assert.Equal(t, "alice-gh", result["alice@mycompany.com"].Spec.GithubID)
+		assert.Equal(t, "bob-gh", result["bob@mycompany.com"].Spec.GithubID)
+	})
+
+	t.Run("happy path: only the group members are loaded", func(t *testing.T) {
+		repoconfig := newRepoConfig()
+		repoconfig.UserSync.Scim.Group = "github"
+
+		result, err := NewUserSyncPluginScim().UpdateUsers(context.TODO(), repoconfig, "/users/org")
+
+		assert.Nil(t, err)
+		assert.Equal(t, 1, len(result))
+		assert.NotNil(t, result["bob@mycompany.com"])
+	})
+
+	t.Run("happy path: groups members", func(t *testing.T) {
+		groups, err := NewUserSyncPluginScim().(*UserSyncPluginScim).UpdateGroups(context.TODO(), newRepoConfig(), []string{"github", "unknown"})
+
+		assert.Nil(t, err)
+		assert.Equal(t, map[string][]string{"github": {"bob@mycompany.com"}}, groups)
+	})
+
+	t.Run("not happy path: unknown group", func(t *testing.T) {
+		repoconfig := newRepoConfig()
+		repoconfig.UserSync.Scim.Group = "unknown"
+
+		_, err := NewUserSyncPluginScim().UpdateUsers(context.TODO(), repoconfig, "/users/org")
+
+		assert.NotNil(t, err)
+	})
+
+	t.Run("not happy path: wrong token", func(t *testing.T) {
+		config.Config.ScimToken = "wrong"
+		defer func() { config.Config.ScimToken = "scimtoken" }()
+
+		_, err := NewUserSyncPluginScim().UpdateUsers(context.TODO(), newRepoConfig(), "/users/org")
+
+		assert.NotNil(t, err)
+	})
+
+	t.Run("happy path: scim attributes", func(t *testing.T) {
+		resource := map[string]interface{}{
+			"userName": "alice",
+			"name":     map[string]interface{}{"givenName": "Alice"},
+			"emails": []interface{}{
+				map[string]interface{}{"value": "alice@home.com"},
+				map[string]interface{}{"value": "alice@mycompany.com", "primary": true},
+			},
+		}
+		assert.Equal(t, "alice", ScimAttribute(resource, "userName"))
+		assert.Equal(t, "Alice", ScimAttribute(resource, "name.givenName"))
+		assert.Equal(t, "alice@mycompany.com", ScimAttribute(resource, "emails"))
+		assert.Equal(t, "", ScimAttribute(resource, "nickName"))
+	})
+
+	t.Run("not happy path: a username cannot be a path", func(t *testing.T) {
+		_, err := ScimResourceToUser(map[string]interface{}{"userName": "../../etc/passwd"}, "userName", "userName")
+		assert.NotNil(t, err)
+	})
+}
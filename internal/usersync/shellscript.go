@@ -1,26 +1,36 @@
 package usersync
 
 import (
+	"context"
 	"fmt"
 	"os/exec"
+	"strings"
 
 	"github.com/Alayacare/goliac/internal/config"
 	"github.com/Alayacare/goliac/internal/engine"
 	"github.com/Alayacare/goliac/internal/entity"
+	"github.com/sirupsen/logrus"
 	"github.com/spf13/afero"
 )
 
+/*
+ * UserSyncPluginShellScript: this plugin runs usersync.path, that must write the users
+ * into the users/org directory.
+ * Deprecated: the http and file plugins are easier to operate
+ */
 type UserSyncPluginShellScript struct{}
 
 func NewUserSyncPluginShellScript() engine.UserSyncPlugin {
 	return &UserSyncPluginShellScript{}
 }
 
-func (p *UserSyncPluginShellScript) UpdateUsers(repoconfig *config.RepositoryConfig, orguserdirrectorypath string) (map[string]*entity.User, error) {
-	cmd := exec.Command(repoconfig.UserSync.Path, orguserdirrectorypath)
-	_, err := cmd.CombinedOutput()
+func (p *UserSyncPluginShellScript) UpdateUsers(ctx context.Context, repoconfig *config.RepositoryConfig, orguserdirrectorypath string) (map[string]*entity.User, error) {
+	logrus.Warnf("the shellscript usersync plugin is deprecated, please use the http or file plugin")
+
+	cmd := exec.CommandContext(ctx, repoconfig.UserSync.Path, orguserdirrectorypath)
+	output, err := cmd.CombinedOutput()
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("not able to run %s: %v: %s", repoconfig.UserSync.Path, err, strings.TrimSpace(string(output)))
 	}
 
 	fs := afero.NewOsFs()
@@ -0,0 +1,112 @@
+package usersync
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/Alayacare/goliac/internal/config"
+	"github.com/Alayacare/goliac/internal/entity"
+	"github.com/sirupsen/logrus"
+)
+
+/*
+ * userListFormat returns the format of a user list: the configured one,
+ * else the one of the url/path extension, else json
+ */
+func userListFormat(format string, location string) string {
+	if format != "" {
+		return strings.ToLower(format)
+	}
+	if u, _, _ := strings.Cut(location, "?"); strings.EqualFold(path.Ext(u), ".csv") {
+		return "csv"
+	}
+	return "json"
+}
+
+/*
+ * parseUserList reads a user list, either
+ * - a JSON array of objects, like [{"username": "alice", "githubID": "alice-gh"}]
+ * - a CSV document with a header row, like "username,githubID\nalice,alice-gh"
+ * The fields holding the username and the Github login are given by the mapping
+ */
+func parseUserList(data []byte, format string, mapping config.UserSyncListMapping) (map[string]*entity.User, error) {
+	records := []map[string]string{}
+
+	switch format {
+	case "json":
+		var list []map[string]interface{}
+		if err := json.Unmarshal(data, &list); err != nil {
+			return nil, fmt.Errorf("not able to parse the JSON user list (an array of objects is expected): %v", err)
+		}
+		for _, item := range list {
+			record := make(map[string]string)
+			for k, v := range item {
+				if v != nil {
+					record[k] = fmt.Sprintf("%v", v)
+				}
+			}
+			records = append(records, record)
+		}
+	case "csv":
+		rows, err := csv.NewReader(bytes.NewReader(data)).ReadAll()
+		if err != nil {
+			return nil, fmt.Errorf("not able to parse the CSV user list: %v", err)
+		}
+		if len(rows) == 0 {
+			return nil, fmt.Errorf("the CSV user list has no header row")
+		}
+		header := rows[0]
+		for _, row := range rows[1:] {
+			record := make(map[string]string)
+			for i, value := range row {
+				if i < len(header) {
+					record[strings.TrimSpace(header[i])] = value
+				}
+			}
+			records = append(records, record)
+		}
+	default:
+		return nil, fmt.Errorf("unknown user list format %s (json or csv expected)", format)
+	}
+
+	users := make(map[string]*entity.User)
+	for i, record := range records {
+		username := strings.TrimSpace(record[mapping.Username])
+		githubid := strings.TrimSpace(record[mapping.GithubID])
+		if username == "" || githubid == "" {
+			logrus.Debugf("user list entry %d skipped: no %s or %s", i+1, mapping.Username, mapping.GithubID)
+			continue
+		}
+		user, err := newUser(username, githubid)
+		if err != nil {
+			logrus.Warnf("user list entry %d skipped: %v", i+1, err)
+			continue
+		}
+		users[username] = user
+	}
+
+	if len(users) == 0 {
+		return nil, fmt.Errorf("not able to find any user with a %s and a %s", mapping.Username, mapping.GithubID)
+	}
+	return users, nil
+}
+
+/*
+ * newUser returns a user. The username is checked, since it is used as the user filename
+ */
+func newUser(username string, githubid string) (*entity.User, error) {
+	if strings.ContainsAny(username, "/\\") || strings.HasPrefix(username, ".") {
+		return nil, fmt.Errorf("invalid username %s", username)
+	}
+
+	user := &entity.User{}
+	user.ApiVersion = "v1"
+	user.Kind = "User"
+	user.Name = username
+	user.Spec.GithubID = githubid
+	return user, nil
+}
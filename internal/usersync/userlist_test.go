@@ -0,0 +1,125 @@
+package usersync
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Alayacare/goliac/internal/config"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUserSyncPluginHttp(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer httptoken" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`[
+			{"login": "alice", "github": "alice-gh"},
+			{"login": "bob", "github": "bob-gh", "team": "ops"},
+			{"login": "carol"}
+		]`))
+	}))
+	defer server.Close()
+
+	config.Config.UserSyncHttpToken = "httptoken"
+	defer func() { config.Config.UserSyncHttpToken = "" }()
+
+	newRepoConfig := func() *config.RepositoryConfig {
+		repoconfig := &config.RepositoryConfig{}
+		repoconfig.UserSync.Plugin = "http"
+		repoconfig.UserSync.Url = server.URL + "/users"
+		repoconfig.UserSync.Mapping = config.UserSyncListMapping{Username: "login", GithubID: "github"}
+		return repoconfig
+	}
+
+	t.Run("happy path: users are loaded", func(t *testing.T) {
+		result, err := NewUserSyncPluginHttp().UpdateUsers(context.TODO(), newRepoConfig(), "/users/org")
+
+		assert.Nil(t, err)
+		assert.Equal(t, 2, len(result))
+		assert.Equal(t, "alice-gh", result["alice"].Spec.GithubID)
+		assert.Equal(t, "bob-gh", result["bob"].Spec.GithubID)
+	})
+
+	t.Run("not happy path: wrong token", func(t *testing.T) {
+		config.Config.UserSyncHttpToken = "wrong"
+		defer func() { config.Config.UserSyncHttpToken = "httptoken" }()
+
+		_, err := NewUserSyncPluginHttp().UpdateUsers(context.TODO(), newRepoConfig(), "/users/org")
+
+		assert.NotNil(t, err)
+	})
+
+	t.Run("not happy path: no url", func(t *testing.T) {
+		repoconfig := newRepoConfig()
+		repoconfig.UserSync.Url = ""
+
+		_, err := NewUserSyncPluginHttp().UpdateUsers(context.TODO(), repoconfig, "/users/org")
+
+		assert.NotNil(t, err)
+	})
+}
+
+func TestUserSyncPluginFile(t *testing.T) {
+	newRepoConfig := func(path string) *config.RepositoryConfig {
+		repoconfig := &config.RepositoryConfig{}
+		repoconfig.UserSync.Plugin = "file"
+		repoconfig.UserSync.Path = path
+		repoconfig.UserSync.Mapping = config.UserSyncListMapping{Username: "username", GithubID: "githubID"}
+		return repoconfig
+	}
+
+	t.Run("happy path: csv user list", func(t *testing.T) {
+		fs := afero.NewMemMapFs()
+		afero.WriteFile(fs, "/etc/users.csv", []byte("username,githubID,email\nalice,alice-gh,alice@mycompany.com\nbob,bob-gh,\n,nobody-gh,\n"), 0644)
+
+		result, err := (&UserSyncPluginFile{Fs: fs}).UpdateUsers(context.TODO(), newRepoConfig("/etc/users.csv"), "/users/org")
+
+		assert.Nil(t, err)
+		assert.Equal(t, 2, len(result))
+		assert.Equal(t, "alice-gh", result["alice"].Spec.GithubID)
+		assert.Equal(t, "bob-gh", result["bob"].Spec.GithubID)
+	})
+
+	t.Run("happy path: json user list", func(t *testing.T) {
+		fs := afero.NewMemMapFs()
+		afero.WriteFile(fs, "/etc/users", []byte(`[{"username": "alice", "githubID": "alice-gh"}]`), 0644)
+
+		result, err := (&UserSyncPluginFile{Fs: fs}).UpdateUsers(context.TODO(), newRepoConfig("/etc/users"), "/users/org")
+
+		assert.Nil(t, err)
+		assert.Equal(t, 1, len(result))
+		assert.Equal(t, "alice-gh", result["alice"].Spec.GithubID)
+	})
+
+	t.Run("not happy path: forced format mismatch", func(t *testing.T) {
+		fs := afero.NewMemMapFs()
+		afero.WriteFile(fs, "/etc/users.csv", []byte("username,githubID\nalice,alice-gh\n"), 0644)
+		repoconfig := newRepoConfig("/etc/users.csv")
+		repoconfig.UserSync.Format = "json"
+
+		_, err := (&UserSyncPluginFile{Fs: fs}).UpdateUsers(context.TODO(), repoconfig, "/users/org")
+
+		assert.NotNil(t, err)
+	})
+
+	t.Run("not happy path: no user found", func(t *testing.T) {
+		fs := afero.NewMemMapFs()
+		afero.WriteFile(fs, "/etc/users.json", []byte(`[{"login": "alice"}]`), 0644)
+
+		_, err := (&UserSyncPluginFile{Fs: fs}).UpdateUsers(context.TODO(), newRepoConfig("/etc/users.json"), "/users/org")
+
+		assert.NotNil(t, err)
+	})
+
+	t.Run("not happy path: missing file", func(t *testing.T) {
+		_, err := (&UserSyncPluginFile{Fs: afero.NewMemMapFs()}).UpdateUsers(context.TODO(), newRepoConfig("/etc/users.csv"), "/users/org")
+
+		assert.NotNil(t, err)
+	})
+}
@@ -7,6 +7,7 @@ package models
 
 import (
 	"context"
+	"strconv"
 
 	"github.com/go-openapi/errors"
 	"github.com/go-openapi/strfmt"
@@ -37,6 +38,9 @@ type Status struct {
 
 	// nb users external
 	NbUsersExternal int64 `json:"nbUsersExternal"`
+
+	// upcoming expirations
+	UpcomingExpirations []*Expiration `json:"upcomingExpirations"`
 }
 
 // Validate validates this status
@@ -47,6 +51,10 @@ func (m *Status) Validate(formats strfmt.Registry) error {
 		res = append(res, err)
 	}
 
+	if err := m.validateUpcomingExpirations(formats); err != nil {
+		res = append(res, err)
+	}
+
 	if len(res) > 0 {
 		return errors.CompositeValidationError(res...)
 	}
@@ -65,8 +73,68 @@ func (m *Status) validateLastSyncTime(formats strfmt.Registry) error {
 	return nil
 }
 
-// ContextValidate validates this status based on context it is used
+func (m *Status) validateUpcomingExpirations(formats strfmt.Registry) error {
+	if swag.IsZero(m.UpcomingExpirations) { // not required
+		return nil
+	}
+
+	for i := 0; i < len(m.UpcomingExpirations); i++ {
+		if swag.IsZero(m.UpcomingExpirations[i]) { // not required
+			continue
+		}
+
+		if m.UpcomingExpirations[i] != nil {
+			if err := m.UpcomingExpirations[i].Validate(formats); err != nil {
+				if ve, ok := err.(*errors.Validation); ok {
+					return ve.ValidateName("upcomingExpirations" + "." + strconv.Itoa(i))
+				} else if ce, ok := err.(*errors.CompositeError); ok {
+					return ce.ValidateName("upcomingExpirations" + "." + strconv.Itoa(i))
+				}
+				return err
+			}
+		}
+
+	}
+
+	return nil
+}
+
+// ContextValidate validate this status based on the context it is used
 func (m *Status) ContextValidate(ctx context.Context, formats strfmt.Registry) error {
+	var res []error
+
+	if err := m.contextValidateUpcomingExpirations(ctx, formats); err != nil {
+		res = append(res, err)
+	}
+
+	if len(res) > 0 {
+		return errors.CompositeValidationError(res...)
+	}
+	return nil
+}
+
+func (m *Status) contextValidateUpcomingExpirations(ctx context.Context, formats strfmt.Registry) error {
+
+	for i := 0; i < len(m.UpcomingExpirations); i++ {
+
+		if m.UpcomingExpirations[i] != nil {
+
+			if swag.IsZero(m.UpcomingExpirations[i]) { // not required
+				return nil
+			}
+
+			if err := m.UpcomingExpirations[i].ContextValidate(ctx, formats); err != nil {
+				if ve, ok := err.(*errors.Validation); ok {
+					return ve.ValidateName("upcomingExpirations" + "." + strconv.Itoa(i))
+				} else if ce, ok := err.(*errors.CompositeError); ok {
+					return ce.ValidateName("upcomingExpirations" + "." + strconv.Itoa(i))
+				}
+				return err
+			}
+		}
+
+	}
+
 	return nil
 }
 
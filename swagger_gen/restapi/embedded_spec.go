@@ -35,6 +35,39 @@ func init() {
   },
   "basePath": "/api/v1",
   "paths": {
+    "/audit": {
+      "get": {
+        "description": "Get the changes applied by Goliac to an entity, the most recent first",
+        "tags": [
+          "app"
+        ],
+        "operationId": "getAudit",
+        "parameters": [
+          {
+            "minLength": 1,
+            "type": "string",
+            "description": "entity to get the audit trail for (like repo/foo or team/bar)",
+            "name": "entity",
+            "in": "query",
+            "required": true
+          }
+        ],
+        "responses": {
+          "200": {
+            "description": "get the audit trail of the entity",
+            "schema": {
+              "$ref": "#/definitions/auditRecords"
+            }
+          },
+          "default": {
+            "description": "generic error response",
+            "schema": {
+              "$ref": "#/definitions/error"
+            }
+          }
+        }
+      }
+    },
     "/collaborators": {
       "get": {
         "description": "Get all external collaborators",
@@ -91,6 +124,29 @@ func init() {
         }
       }
     },
+    "/drift": {
+      "get": {
+        "description": "Get the changes made outside of Goliac, found during the last resync",
+        "tags": [
+          "app"
+        ],
+        "operationId": "getDrift",
+        "responses": {
+          "200": {
+            "description": "get the list of drifts",
+            "schema": {
+              "$ref": "#/definitions/drifts"
+            }
+          },
+          "default": {
+            "description": "generic error response",
+            "schema": {
+              "$ref": "#/definitions/error"
+            }
+          }
+        }
+      }
+    },
     "/flushcache": {
       "post": {
         "description": "Flush the Github remote cache",
@@ -134,6 +190,29 @@ func init() {
         }
       }
     },
+    "/orphans": {
+      "get": {
+        "description": "Get the teams without any owner (only the admin team can approve their changes), and their repositories",
+        "tags": [
+          "app"
+        ],
+        "operationId": "getOrphans",
+        "responses": {
+          "200": {
+            "description": "get the list of orphaned teams",
+            "schema": {
+              "$ref": "#/definitions/orphans"
+            }
+          },
+          "default": {
+            "description": "generic error response",
+            "schema": {
+              "$ref": "#/definitions/error"
+            }
+          }
+        }
+      }
+    },
     "/readiness": {
       "get": {
         "description": "Check if Goliac is ready to serve",
@@ -370,6 +449,53 @@ func init() {
     }
   },
   "definitions": {
+    "auditRecord": {
+      "type": "object",
+      "properties": {
+        "author": {
+          "type": "string",
+          "x-isnullable": false
+        },
+        "commitSha": {
+          "type": "string",
+          "x-isnullable": false
+        },
+        "entities": {
+          "type": "array",
+          "items": {
+            "type": "string"
+          }
+        },
+        "error": {
+          "type": "string",
+          "x-isnullable": false
+        },
+        "operation": {
+          "type": "string",
+          "x-isnullable": false
+        },
+        "parameters": {
+          "type": "object",
+          "additionalProperties": {
+            "type": "string"
+          }
+        },
+        "success": {
+          "type": "boolean",
+          "x-isnullable": false
+        },
+        "timestamp": {
+          "type": "string",
+          "x-isnullable": false
+        }
+      }
+    },
+    "auditRecords": {
+      "type": "array",
+      "items": {
+        "$ref": "#/definitions/auditRecord"
+      }
+    },
     "collaboratorDetails": {
       "type": "object",
       "properties": {
@@ -385,6 +511,45 @@ func init() {
         }
       }
     },
+    "drift": {
+      "type": "object",
+      "properties": {
+        "author": {
+          "type": "string",
+          "x-isnullable": false
+        },
+        "command": {
+          "type": "string",
+          "x-isnullable": false
+        },
+        "details": {
+          "type": "string",
+          "x-isnullable": false
+        },
+        "detectedAt": {
+          "type": "string",
+          "x-isnullable": false
+        },
+        "name": {
+          "type": "string",
+          "x-isnullable": false
+        },
+        "policy": {
+          "type": "string",
+          "x-isnullable": false
+        },
+        "resource": {
+          "type": "string",
+          "x-isnullable": false
+        }
+      }
+    },
+    "drifts": {
+      "type": "array",
+      "items": {
+        "$ref": "#/definitions/drift"
+      }
+    },
     "error": {
       "type": "object",
       "required": [
@@ -397,6 +562,27 @@ func init() {
         }
       }
     },
+    "expiration": {
+      "type": "object",
+      "properties": {
+        "expires": {
+          "type": "string",
+          "x-isnullable": false
+        },
+        "githubid": {
+          "type": "string",
+          "x-isnullable": false
+        },
+        "repository": {
+          "type": "string",
+          "x-isnullable": false
+        },
+        "username": {
+          "type": "string",
+          "x-isnullable": false
+        }
+      }
+    },
     "health": {
       "type": "object",
       "properties": {
@@ -405,6 +591,27 @@ func init() {
         }
       }
     },
+    "orphan": {
+      "type": "object",
+      "properties": {
+        "repositories": {
+          "type": "array",
+          "items": {
+            "type": "string"
+          }
+        },
+        "team": {
+          "type": "string",
+          "x-isnullable": false
+        }
+      }
+    },
+    "orphans": {
+      "type": "array",
+      "items": {
+        "$ref": "#/definitions/orphan"
+      }
+    },
     "repositories": {
       "type": "array",
       "items": {
@@ -506,6 +713,12 @@ func init() {
         "nbUsersExternal": {
           "type": "integer",
           "x-omitempty": false
+        },
+        "upcomingExpirations": {
+          "type": "array",
+          "items": {
+            "$ref": "#/definitions/expiration"
+          }
         }
       }
     },
@@ -675,6 +888,39 @@ func init() {
   },
   "basePath": "/api/v1",
   "paths": {
+    "/audit": {
+      "get": {
+        "description": "Get the changes applied by Goliac to an entity, the most recent first",
+        "tags": [
+          "app"
+        ],
+        "operationId": "getAudit",
+        "parameters": [
+          {
+            "minLength": 1,
+            "type": "string",
+            "description": "entity to get the audit trail for (like repo/foo or team/bar)",
+            "name": "entity",
+            "in": "query",
+            "required": true
+          }
+        ],
+        "responses": {
+          "200": {
+            "description": "get the audit trail of the entity",
+            "schema": {
+              "$ref": "#/definitions/auditRecords"
+            }
+          },
+          "default": {
+            "description": "generic error response",
+            "schema": {
+              "$ref": "#/definitions/error"
+            }
+          }
+        }
+      }
+    },
     "/collaborators": {
       "get": {
         "description": "Get all external collaborators",
@@ -731,6 +977,29 @@ func init() {
         }
       }
     },
+    "/drift": {
+      "get": {
+        "description": "Get the changes made outside of Goliac, found during the last resync",
+        "tags": [
+          "app"
+        ],
+        "operationId": "getDrift",
+        "responses": {
+          "200": {
+            "description": "get the list of drifts",
+            "schema": {
+              "$ref": "#/definitions/drifts"
+            }
+          },
+          "default": {
+            "description": "generic error response",
+            "schema": {
+              "$ref": "#/definitions/error"
+            }
+          }
+        }
+      }
+    },
     "/flushcache": {
       "post": {
         "description": "Flush the Github remote cache",
@@ -774,6 +1043,29 @@ func init() {
         }
       }
     },
+    "/orphans": {
+      "get": {
+        "description": "Get the teams without any owner (only the admin team can approve their changes), and their repositories",
+        "tags": [
+          "app"
+        ],
+        "operationId": "getOrphans",
+        "responses": {
+          "200": {
+            "description": "get the list of orphaned teams",
+            "schema": {
+              "$ref": "#/definitions/orphans"
+            }
+          },
+          "default": {
+            "description": "generic error response",
+            "schema": {
+              "$ref": "#/definitions/error"
+            }
+          }
+        }
+      }
+    },
     "/readiness": {
       "get": {
         "description": "Check if Goliac is ready to serve",
@@ -1072,6 +1364,53 @@ func init() {
         }
       }
     },
+    "auditRecord": {
+      "type": "object",
+      "properties": {
+        "author": {
+          "type": "string",
+          "x-isnullable": false
+        },
+        "commitSha": {
+          "type": "string",
+          "x-isnullable": false
+        },
+        "entities": {
+          "type": "array",
+          "items": {
+            "type": "string"
+          }
+        },
+        "error": {
+          "type": "string",
+          "x-isnullable": false
+        },
+        "operation": {
+          "type": "string",
+          "x-isnullable": false
+        },
+        "parameters": {
+          "type": "object",
+          "additionalProperties": {
+            "type": "string"
+          }
+        },
+        "success": {
+          "type": "boolean",
+          "x-isnullable": false
+        },
+        "timestamp": {
+          "type": "string",
+          "x-isnullable": false
+        }
+      }
+    },
+    "auditRecords": {
+      "type": "array",
+      "items": {
+        "$ref": "#/definitions/auditRecord"
+      }
+    },
     "collaboratorDetails": {
       "type": "object",
       "properties": {
@@ -1087,6 +1426,45 @@ func init() {
         }
       }
     },
+    "drift": {
+      "type": "object",
+      "properties": {
+        "author": {
+          "type": "string",
+          "x-isnullable": false
+        },
+        "command": {
+          "type": "string",
+          "x-isnullable": false
+        },
+        "details": {
+          "type": "string",
+          "x-isnullable": false
+        },
+        "detectedAt": {
+          "type": "string",
+          "x-isnullable": false
+        },
+        "name": {
+          "type": "string",
+          "x-isnullable": false
+        },
+        "policy": {
+          "type": "string",
+          "x-isnullable": false
+        },
+        "resource": {
+          "type": "string",
+          "x-isnullable": false
+        }
+      }
+    },
+    "drifts": {
+      "type": "array",
+      "items": {
+        "$ref": "#/definitions/drift"
+      }
+    },
     "error": {
       "type": "object",
       "required": [
@@ -1099,6 +1477,27 @@ func init() {
         }
       }
     },
+    "expiration": {
+      "type": "object",
+      "properties": {
+        "expires": {
+          "type": "string",
+          "x-isnullable": false
+        },
+        "githubid": {
+          "type": "string",
+          "x-isnullable": false
+        },
+        "repository": {
+          "type": "string",
+          "x-isnullable": false
+        },
+        "username": {
+          "type": "string",
+          "x-isnullable": false
+        }
+      }
+    },
     "health": {
       "type": "object",
       "properties": {
@@ -1107,6 +1506,27 @@ func init() {
         }
       }
     },
+    "orphan": {
+      "type": "object",
+      "properties": {
+        "repositories": {
+          "type": "array",
+          "items": {
+            "type": "string"
+          }
+        },
+        "team": {
+          "type": "string",
+          "x-isnullable": false
+        }
+      }
+    },
+    "orphans": {
+      "type": "array",
+      "items": {
+        "$ref": "#/definitions/orphan"
+      }
+    },
     "repositories": {
       "type": "array",
       "items": {
@@ -1188,6 +1608,12 @@ func init() {
         "nbUsersExternal": {
           "type": "integer",
           "x-omitempty": false
+        },
+        "upcomingExpirations": {
+          "type": "array",
+          "items": {
+            "$ref": "#/definitions/expiration"
+          }
         }
       }
     },
@@ -45,15 +45,24 @@ func NewGoliacAPI(spec *loads.Document) *GoliacAPI {
 
 		JSONProducer: runtime.JSONProducer(),
 
+		AppGetAuditHandler: app.GetAuditHandlerFunc(func(params app.GetAuditParams) middleware.Responder {
+			return middleware.NotImplemented("operation app.GetAudit has not yet been implemented")
+		}),
 		AppGetCollaboratorHandler: app.GetCollaboratorHandlerFunc(func(params app.GetCollaboratorParams) middleware.Responder {
 			return middleware.NotImplemented("operation app.GetCollaborator has not yet been implemented")
 		}),
 		AppGetCollaboratorsHandler: app.GetCollaboratorsHandlerFunc(func(params app.GetCollaboratorsParams) middleware.Responder {
 			return middleware.NotImplemented("operation app.GetCollaborators has not yet been implemented")
 		}),
+		AppGetDriftHandler: app.GetDriftHandlerFunc(func(params app.GetDriftParams) middleware.Responder {
+			return middleware.NotImplemented("operation app.GetDrift has not yet been implemented")
+		}),
 		HealthGetLivenessHandler: health.GetLivenessHandlerFunc(func(params health.GetLivenessParams) middleware.Responder {
 			return middleware.NotImplemented("operation health.GetLiveness has not yet been implemented")
 		}),
+		AppGetOrphansHandler: app.GetOrphansHandlerFunc(func(params app.GetOrphansParams) middleware.Responder {
+			return middleware.NotImplemented("operation app.GetOrphans has not yet been implemented")
+		}),
 		HealthGetReadinessHandler: health.GetReadinessHandlerFunc(func(params health.GetReadinessParams) middleware.Responder {
 			return middleware.NotImplemented("operation health.GetReadiness has not yet been implemented")
 		}),
@@ -121,12 +130,18 @@ type GoliacAPI struct {
 	//   - application/json
 	JSONProducer runtime.Producer
 
+	// AppGetAuditHandler sets the operation handler for the get audit operation
+	AppGetAuditHandler app.GetAuditHandler
 	// AppGetCollaboratorHandler sets the operation handler for the get collaborator operation
 	AppGetCollaboratorHandler app.GetCollaboratorHandler
 	// AppGetCollaboratorsHandler sets the operation handler for the get collaborators operation
 	AppGetCollaboratorsHandler app.GetCollaboratorsHandler
+	// AppGetDriftHandler sets the operation handler for the get drift operation
+	AppGetDriftHandler app.GetDriftHandler
 	// HealthGetLivenessHandler sets the operation handler for the get liveness operation
 	HealthGetLivenessHandler health.GetLivenessHandler
+	// AppGetOrphansHandler sets the operation handler for the get orphans operation
+	AppGetOrphansHandler app.GetOrphansHandler
 	// HealthGetReadinessHandler sets the operation handler for the get readiness operation
 	HealthGetReadinessHandler health.GetReadinessHandler
 	// AppGetRepositoriesHandler sets the operation handler for the get repositories operation
@@ -224,15 +239,24 @@ func (o *GoliacAPI) Validate() error {
 		unregistered = append(unregistered, "JSONProducer")
 	}
 
+	if o.AppGetAuditHandler == nil {
+		unregistered = append(unregistered, "app.GetAuditHandler")
+	}
 	if o.AppGetCollaboratorHandler == nil {
 		unregistered = append(unregistered, "app.GetCollaboratorHandler")
 	}
 	if o.AppGetCollaboratorsHandler == nil {
 		unregistered = append(unregistered, "app.GetCollaboratorsHandler")
 	}
+	if o.AppGetDriftHandler == nil {
+		unregistered = append(unregistered, "app.GetDriftHandler")
+	}
 	if o.HealthGetLivenessHandler == nil {
 		unregistered = append(unregistered, "health.GetLivenessHandler")
 	}
+	if o.AppGetOrphansHandler == nil {
+		unregistered = append(unregistered, "app.GetOrphansHandler")
+	}
 	if o.HealthGetReadinessHandler == nil {
 		unregistered = append(unregistered, "health.GetReadinessHandler")
 	}
@@ -351,6 +375,10 @@ func (o *GoliacAPI) initHandlerCache() {
 		o.handlers = make(map[string]map[string]http.Handler)
 	}
 
+	if o.handlers["GET"] == nil {
+		o.handlers["GET"] = make(map[string]http.Handler)
+	}
+	o.handlers["GET"]["/audit"] = app.NewGetAudit(o.context, o.AppGetAuditHandler)
 	if o.handlers["GET"] == nil {
 		o.handlers["GET"] = make(map[string]http.Handler)
 	}
@@ -362,10 +390,18 @@ func (o *GoliacAPI) initHandlerCache() {
 	if o.handlers["GET"] == nil {
 		o.handlers["GET"] = make(map[string]http.Handler)
 	}
+	o.handlers["GET"]["/drift"] = app.NewGetDrift(o.context, o.AppGetDriftHandler)
+	if o.handlers["GET"] == nil {
+		o.handlers["GET"] = make(map[string]http.Handler)
+	}
 	o.handlers["GET"]["/liveness"] = health.NewGetLiveness(o.context, o.HealthGetLivenessHandler)
 	if o.handlers["GET"] == nil {
 		o.handlers["GET"] = make(map[string]http.Handler)
 	}
+	o.handlers["GET"]["/orphans"] = app.NewGetOrphans(o.context, o.AppGetOrphansHandler)
+	if o.handlers["GET"] == nil {
+		o.handlers["GET"] = make(map[string]http.Handler)
+	}
 	o.handlers["GET"]["/readiness"] = health.NewGetReadiness(o.context, o.HealthGetReadinessHandler)
 	if o.handlers["GET"] == nil {
 		o.handlers["GET"] = make(map[string]http.Handler)